@@ -244,4 +244,39 @@ const (
 			AND (max_uses IS NULL OR uses_count < max_uses)
 		)
 	`
+
+	// snapshot queries (session version history)
+	queryCreateSnapshot = `
+		INSERT INTO session_snapshots (session_id, code, authors, reason, label, created_by, restored_from)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, '')::uuid, NULLIF($7, '')::uuid)
+		RETURNING id, session_id, code, authors, reason, label, created_by, restored_from, created_at
+	`
+
+	queryListSnapshots = `
+		SELECT id, session_id, code, authors, reason, label, created_by, restored_from, created_at
+		FROM session_snapshots
+		WHERE session_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	queryCountSnapshots = `
+		SELECT COUNT(*)
+		FROM session_snapshots
+		WHERE session_id = $1
+	`
+
+	queryGetSnapshot = `
+		SELECT id, session_id, code, authors, reason, label, created_by, restored_from, created_at
+		FROM session_snapshots
+		WHERE session_id = $1 AND id = $2
+	`
+
+	queryGetLatestSnapshot = `
+		SELECT id, session_id, code, authors, reason, label, created_by, restored_from, created_at
+		FROM session_snapshots
+		WHERE session_id = $1
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
 )
@@ -4,9 +4,11 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -744,3 +746,98 @@ func (r *repository) HasActiveInviteTokens(ctx context.Context, sessionID string
 	err := r.db.QueryRow(ctx, queryHasActiveInviteTokens, sessionID).Scan(&exists)
 	return exists, err
 }
+
+// records an immutable snapshot of session code
+func (r *repository) CreateSnapshot(ctx context.Context, req *CreateSnapshotRequest) (*Snapshot, error) {
+	authors := req.Authors
+	if authors == nil {
+		authors = []SnapshotAuthor{}
+	}
+
+	// explicitly marshal authors to JSON string for pgx JSONB compatibility
+	authorsJSON, err := json.Marshal(authors)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal snapshot authors: %w", err)
+	}
+
+	row := r.db.QueryRow(
+		ctx,
+		queryCreateSnapshot,
+		req.SessionID,
+		req.Code,
+		string(authorsJSON),
+		req.Reason,
+		req.Label,
+		req.CreatedBy,
+		req.RestoredFrom,
+	)
+
+	return scanSnapshot(row)
+}
+
+// lists a session's snapshots newest first with the total count
+func (r *repository) ListSnapshots(ctx context.Context, sessionID string, limit, offset int) ([]*Snapshot, int, error) {
+	var total int
+
+	if err := r.db.QueryRow(ctx, queryCountSnapshots, sessionID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.db.Query(ctx, queryListSnapshots, sessionID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer rows.Close()
+	snapshots := make([]*Snapshot, 0)
+
+	for rows.Next() {
+		snapshot, err := scanSnapshot(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return snapshots, total, nil
+}
+
+// retrieves a snapshot belonging to a session
+func (r *repository) GetSnapshot(ctx context.Context, sessionID, snapshotID string) (*Snapshot, error) {
+	return scanSnapshot(r.db.QueryRow(ctx, queryGetSnapshot, sessionID, snapshotID))
+}
+
+// retrieves the most recent snapshot of a session
+func (r *repository) GetLatestSnapshot(ctx context.Context, sessionID string) (*Snapshot, error) {
+	return scanSnapshot(r.db.QueryRow(ctx, queryGetLatestSnapshot, sessionID))
+}
+
+// scans a snapshot row in the column order used by the snapshot queries
+func scanSnapshot(row pgx.Row) (*Snapshot, error) {
+	var s Snapshot
+
+	err := row.Scan(
+		&s.ID,
+		&s.SessionID,
+		&s.Code,
+		&s.Authors,
+		&s.Reason,
+		&s.Label,
+		&s.CreatedBy,
+		&s.RestoredFrom,
+		&s.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.Authors == nil {
+		s.Authors = []SnapshotAuthor{}
+	}
+
+	return &s, nil
+}
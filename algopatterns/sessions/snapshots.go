@@ -0,0 +1,139 @@
+package sessions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"codeberg.org/algopatterns/server/internal/logger"
+)
+
+// handles periodic and on-demand snapshots of session code
+type SnapshotService struct {
+	repo           Repository
+	interval       time.Duration
+	liveSessions   LiveSessionsFunc
+	sessionAuthors SessionAuthorsFunc
+}
+
+// returns the IDs of sessions with connected WebSocket clients
+type LiveSessionsFunc func() []string
+
+// returns the connected clients with write access to a session
+type SessionAuthorsFunc func(sessionID string) []SnapshotAuthor
+
+// creates a new snapshot service
+func NewSnapshotService(
+	repo Repository,
+	interval time.Duration,
+	liveSessions LiveSessionsFunc,
+	sessionAuthors SessionAuthorsFunc,
+) *SnapshotService {
+	return &SnapshotService{
+		repo:           repo,
+		interval:       interval,
+		liveSessions:   liveSessions,
+		sessionAuthors: sessionAuthors,
+	}
+}
+
+// begins the periodic snapshot background loop
+func (s *SnapshotService) Start(ctx context.Context) {
+	logger.Info("starting session snapshot service", "interval", s.interval)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("session snapshot service stopped")
+			return
+		case <-ticker.C:
+			s.snapshotLiveSessions(ctx)
+		}
+	}
+}
+
+// snapshots every live session whose code changed since its last snapshot
+func (s *SnapshotService) snapshotLiveSessions(ctx context.Context) {
+	if s.liveSessions == nil {
+		return
+	}
+
+	for _, sessionID := range s.liveSessions() {
+		if _, err := s.snapshotIfChanged(ctx, sessionID); err != nil {
+			logger.ErrorErr(err, "failed to snapshot session", "session_id", sessionID)
+		}
+	}
+}
+
+// writes a periodic snapshot unless the code matches the latest one.
+// returns nil when nothing was written.
+func (s *SnapshotService) snapshotIfChanged(ctx context.Context, sessionID string) (*Snapshot, error) {
+	session, err := s.repo.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	if session.Code == "" {
+		return nil, nil
+	}
+
+	latest, err := s.repo.GetLatestSnapshot(ctx, sessionID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get latest snapshot: %w", err)
+	}
+
+	if latest != nil && latest.Code == session.Code {
+		return nil, nil
+	}
+
+	return s.repo.CreateSnapshot(ctx, &CreateSnapshotRequest{
+		SessionID: sessionID,
+		Code:      session.Code,
+		Authors:   s.authors(sessionID),
+		Reason:    SnapshotReasonPeriodic,
+	})
+}
+
+// writes a snapshot of the current session code on request
+func (s *SnapshotService) CreateManualSnapshot(ctx context.Context, sessionID, userID, label string) (*Snapshot, error) {
+	session, err := s.repo.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	return s.repo.CreateSnapshot(ctx, &CreateSnapshotRequest{
+		SessionID: sessionID,
+		Code:      session.Code,
+		Authors:   s.authors(sessionID),
+		Reason:    SnapshotReasonManual,
+		Label:     label,
+		CreatedBy: userID,
+	})
+}
+
+// records that a snapshot's code was restored into the session
+func (s *SnapshotService) RecordRestore(ctx context.Context, restored *Snapshot, userID string) (*Snapshot, error) {
+	return s.repo.CreateSnapshot(ctx, &CreateSnapshotRequest{
+		SessionID:    restored.SessionID,
+		Code:         restored.Code,
+		Authors:      s.authors(restored.SessionID),
+		Reason:       SnapshotReasonRestore,
+		CreatedBy:    userID,
+		RestoredFrom: restored.ID,
+	})
+}
+
+// returns the connected writers of a session, or none when not wired to a hub
+func (s *SnapshotService) authors(sessionID string) []SnapshotAuthor {
+	if s.sessionAuthors == nil {
+		return []SnapshotAuthor{}
+	}
+
+	return s.sessionAuthors(sessionID)
+}
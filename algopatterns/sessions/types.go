@@ -12,6 +12,13 @@ const (
 	MessageTypeChat       = "chat"
 )
 
+// snapshot reason constants (must match DB check constraint)
+const (
+	SnapshotReasonPeriodic = "periodic"
+	SnapshotReasonManual   = "manual"
+	SnapshotReasonRestore  = "restore"
+)
+
 // SystemUserID is the UUID for anonymous sessions (nil UUID pattern)
 const SystemUserID = "00000000-0000-0000-0000-000000000000"
 
@@ -60,6 +67,12 @@ type Repository interface {
 	GetLastUserSession(ctx context.Context, userID string) (*Session, error)
 	ListStaleSessions(ctx context.Context, threshold time.Time) ([]*Session, error)
 	CountActiveParticipants(ctx context.Context, sessionID string) (int, error)
+
+	// snapshot operations (session version history)
+	CreateSnapshot(ctx context.Context, req *CreateSnapshotRequest) (*Snapshot, error)
+	ListSnapshots(ctx context.Context, sessionID string, limit, offset int) ([]*Snapshot, int, error)
+	GetSnapshot(ctx context.Context, sessionID, snapshotID string) (*Snapshot, error)
+	GetLatestSnapshot(ctx context.Context, sessionID string) (*Snapshot, error)
 }
 
 // represents a collaborative coding session
@@ -149,3 +162,34 @@ type CreateInviteTokenRequest struct {
 	MaxUses   *int       `json:"max_uses,omitempty"`
 	ExpiresAt *time.Time `json:"expires_at,omitempty"`
 }
+
+// represents a point-in-time copy of a session's code
+type Snapshot struct {
+	ID           string           `json:"id"`
+	SessionID    string           `json:"session_id"`
+	Code         string           `json:"code"`
+	Authors      []SnapshotAuthor `json:"authors"`
+	Reason       string           `json:"reason"`
+	Label        *string          `json:"label,omitempty"`
+	CreatedBy    *string          `json:"created_by,omitempty"`
+	RestoredFrom *string          `json:"restored_from,omitempty"`
+	CreatedAt    time.Time        `json:"created_at"`
+}
+
+// a client with write access that was connected when a snapshot was taken
+type SnapshotAuthor struct {
+	UserID      string `json:"user_id,omitempty"`
+	DisplayName string `json:"display_name"`
+	Role        string `json:"role"`
+}
+
+// contains data for creating a snapshot
+type CreateSnapshotRequest struct {
+	SessionID    string
+	Code         string
+	Authors      []SnapshotAuthor
+	Reason       string
+	Label        string
+	CreatedBy    string
+	RestoredFrom string
+}
@@ -0,0 +1,117 @@
+package strudels
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"codeberg.org/algopatterns/server/internal/llm"
+	"codeberg.org/algopatterns/server/internal/logger"
+)
+
+// embeds trainable strudels that have no embedding yet with the active
+// embedding model, which also refills the vectors a model cutover cleared
+type EmbeddingService struct {
+	repo      *Repository
+	embedder  ActiveEmbedder
+	interval  time.Duration
+	batchSize int
+}
+
+// resolves the embedder of the active embedding model and the model its
+// vectors are stored with
+type ActiveEmbedder interface {
+	Resolve(ctx context.Context) (llm.Embedder, string, error)
+}
+
+// creates a new embedding backfill service
+func NewEmbeddingService(repo *Repository, embedder ActiveEmbedder, interval time.Duration, batchSize int) *EmbeddingService {
+	return &EmbeddingService{
+		repo:      repo,
+		embedder:  embedder,
+		interval:  interval,
+		batchSize: batchSize,
+	}
+}
+
+// begins the embedding backfill background loop
+func (s *EmbeddingService) Start(ctx context.Context) {
+	logger.Info("starting strudel embedding service",
+		"interval", s.interval,
+		"batch_size", s.batchSize,
+	)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("strudel embedding service stopped")
+			return
+		case <-ticker.C:
+			embedded, err := s.backfill(ctx)
+			if err != nil {
+				logger.ErrorErr(err, "failed to backfill strudel embeddings", "embedded", embedded)
+			} else if embedded > 0 {
+				logger.Info("backfilled strudel embeddings", "count", embedded)
+			}
+		}
+	}
+}
+
+// embeds batches of strudels without an embedding until none are left.
+// returns the number of strudels embedded.
+func (s *EmbeddingService) backfill(ctx context.Context) (int, error) {
+	// resolved once per run, a cutover during the run fails the writes and
+	// the next run picks up the new model
+	embedder, model, err := s.embedder.Resolve(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+
+	for {
+		strudels, err := s.repo.ListTrainableWithoutEmbedding(ctx, s.batchSize)
+		if err != nil {
+			return total, fmt.Errorf("failed to list strudels without embedding: %w", err)
+		}
+
+		if len(strudels) == 0 {
+			return total, nil
+		}
+
+		texts := make([]string, len(strudels))
+		for i, strudel := range strudels {
+			texts[i] = embeddingText(strudel)
+		}
+
+		embeddings, err := embedder.GenerateEmbeddings(ctx, texts)
+		if err != nil {
+			return total, fmt.Errorf("failed to generate embeddings: %w", err)
+		}
+
+		if len(embeddings) != len(strudels) {
+			return total, fmt.Errorf("expected %d embeddings, got %d", len(strudels), len(embeddings))
+		}
+
+		for i, strudel := range strudels {
+			if err := s.repo.UpdateEmbedding(ctx, strudel.ID, embeddings[i], model); err != nil {
+				return total, fmt.Errorf("failed to update embedding of strudel %s: %w", strudel.ID, err)
+			}
+
+			total++
+		}
+
+		if len(strudels) < s.batchSize {
+			return total, nil
+		}
+	}
+}
+
+// the text a strudel is embedded from, the same the re-embed stages
+func embeddingText(s Strudel) string {
+	return strings.Join([]string{s.Title, s.Description, s.Code}, "\n")
+}
@@ -0,0 +1,123 @@
+package strudels
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// the seven supported licenses, least restrictive first
+var licenses = []string{
+	LicenseCC0, LicenseBY, LicenseBYSA, LicenseBYNC, LicenseBYNCSA, LicenseBYND, LicenseBYNCND,
+}
+
+// SPDX identifiers of the supported licenses
+var spdxLicenseIDs = map[string]string{
+	LicenseCC0:    "CC0-1.0",
+	LicenseBY:     "CC-BY-4.0",
+	LicenseBYSA:   "CC-BY-SA-4.0",
+	LicenseBYNC:   "CC-BY-NC-4.0",
+	LicenseBYNCSA: "CC-BY-NC-SA-4.0",
+	LicenseBYND:   "CC-BY-ND-4.0",
+	LicenseBYNCND: "CC-BY-NC-ND-4.0",
+}
+
+// returned when a fork's license is not allowed by its parent's license
+type LicenseError struct {
+	ParentLicense string
+	License       string   // empty when the fork has no license
+	Allowed       []string // licenses the fork may use, empty when it may not be shared
+}
+
+func (e *LicenseError) Error() string {
+	if len(e.Allowed) == 0 {
+		return fmt.Sprintf("strudels licensed %s do not allow sharing derivatives, keep the fork private", e.ParentLicense)
+	}
+
+	license := e.License
+	if license == "" {
+		license = "no license"
+	}
+
+	return fmt.Sprintf("a fork of a %s strudel cannot use %s, allowed: %s",
+		e.ParentLicense, license, strings.Join(e.Allowed, ", "))
+}
+
+// reports whether license is one of the supported Creative Commons licenses
+func IsValidLicense(license string) bool {
+	return slices.Contains(licenses, license)
+}
+
+// returns the SPDX identifier of a supported license, NOASSERTION otherwise
+func SPDXLicenseID(license string) string {
+	if id, ok := spdxLicenseIDs[license]; ok {
+		return id
+	}
+
+	return "NOASSERTION"
+}
+
+// returns the licenses a derivative of a strudel with the given license may
+// use, or nil when the license does not allow sharing derivatives.
+//   - CC0 waives all rights, anything goes
+//   - BY requires attribution, so the derivative cannot be CC0
+//   - NC derivatives must stay non-commercial
+//   - SA derivatives must use the same license
+//   - ND derivatives cannot be shared at all
+func DerivativeLicenses(parent string) []string {
+	if !IsValidLicense(parent) || strings.Contains(parent, "-ND") {
+		return nil
+	}
+
+	if strings.Contains(parent, "-SA") {
+		return []string{parent}
+	}
+
+	var allowed []string
+
+	for _, license := range licenses {
+		if parent != LicenseCC0 && license == LicenseCC0 {
+			continue
+		}
+
+		if strings.Contains(parent, "-NC") && !strings.Contains(license, "-NC") {
+			continue
+		}
+
+		allowed = append(allowed, license)
+	}
+
+	return allowed
+}
+
+// checks the license of a fork against its parent's. nothing is enforced for
+// parents without a supported license. ND parents only allow private forks.
+func CheckDerivativeLicense(parent, license *string, isPublic bool) error {
+	if parent == nil || !IsValidLicense(*parent) {
+		return nil
+	}
+
+	allowed := DerivativeLicenses(*parent)
+
+	if len(allowed) == 0 {
+		if !isPublic {
+			return nil
+		}
+
+		return &LicenseError{ParentLicense: *parent, License: stringValue(license)}
+	}
+
+	if license != nil && slices.Contains(allowed, *license) {
+		return nil
+	}
+
+	return &LicenseError{ParentLicense: *parent, License: stringValue(license), Allowed: allowed}
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+}
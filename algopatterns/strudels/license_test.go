@@ -0,0 +1,92 @@
+package strudels
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestDerivativeLicenses(t *testing.T) {
+	tests := []struct {
+		parent  string
+		allowed []string
+	}{
+		{LicenseCC0, []string{LicenseCC0, LicenseBY, LicenseBYSA, LicenseBYNC, LicenseBYNCSA, LicenseBYND, LicenseBYNCND}},
+		{LicenseBY, []string{LicenseBY, LicenseBYSA, LicenseBYNC, LicenseBYNCSA, LicenseBYND, LicenseBYNCND}},
+		{LicenseBYSA, []string{LicenseBYSA}},
+		{LicenseBYNC, []string{LicenseBYNC, LicenseBYNCSA, LicenseBYNCND}},
+		{LicenseBYNCSA, []string{LicenseBYNCSA}},
+		{LicenseBYND, nil},
+		{LicenseBYNCND, nil},
+		{"MIT", nil},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.parent, func(t *testing.T) {
+			got := DerivativeLicenses(tt.parent)
+			if !slices.Equal(got, tt.allowed) {
+				t.Errorf("DerivativeLicenses(%q) = %v, want %v", tt.parent, got, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestCheckDerivativeLicense(t *testing.T) {
+	ptr := func(s string) *string { return &s }
+
+	tests := []struct {
+		name     string
+		parent   *string
+		license  *string
+		isPublic bool
+		wantErr  bool
+		allowed  []string // expected LicenseError.Allowed when wantErr
+	}{
+		{"nil parent", nil, ptr(LicenseCC0), true, false, nil},
+		{"unknown parent", ptr("MIT"), ptr(LicenseCC0), true, false, nil},
+		{"nil fork license of a CC0 parent", ptr(LicenseCC0), nil, true, true, DerivativeLicenses(LicenseCC0)},
+		{"CC0 parent allows CC0", ptr(LicenseCC0), ptr(LicenseCC0), true, false, nil},
+		{"BY parent rejects CC0", ptr(LicenseBY), ptr(LicenseCC0), true, true, DerivativeLicenses(LicenseBY)},
+		{"BY parent allows BY-NC", ptr(LicenseBY), ptr(LicenseBYNC), true, false, nil},
+		{"BY-SA parent requires BY-SA", ptr(LicenseBYSA), ptr(LicenseBY), true, true, []string{LicenseBYSA}},
+		{"BY-SA parent allows BY-SA", ptr(LicenseBYSA), ptr(LicenseBYSA), true, false, nil},
+		{"nil fork license of a private BY-NC fork", ptr(LicenseBYNC), nil, false, true, DerivativeLicenses(LicenseBYNC)},
+		{"BY-NC parent stays NC", ptr(LicenseBYNC), ptr(LicenseBY), true, true, []string{LicenseBYNC, LicenseBYNCSA, LicenseBYNCND}},
+		{"BY-NC parent allows BY-NC-SA", ptr(LicenseBYNC), ptr(LicenseBYNCSA), true, false, nil},
+		{"BY-NC-SA parent requires BY-NC-SA", ptr(LicenseBYNCSA), ptr(LicenseBYNC), true, true, []string{LicenseBYNCSA}},
+		{"BY-ND parent with a public fork", ptr(LicenseBYND), ptr(LicenseBYND), true, true, nil},
+		{"BY-ND parent with a private fork", ptr(LicenseBYND), ptr(LicenseBYND), false, false, nil},
+		{"BY-NC-ND parent with a public fork", ptr(LicenseBYNCND), nil, true, true, nil},
+		{"BY-NC-ND parent with a private fork", ptr(LicenseBYNCND), nil, false, false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckDerivativeLicense(tt.parent, tt.license, tt.isPublic)
+			if !tt.wantErr {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+
+			var licenseErr *LicenseError
+			if !errors.As(err, &licenseErr) {
+				t.Fatalf("expected a LicenseError, got %v", err)
+			}
+
+			if licenseErr.ParentLicense != *tt.parent {
+				t.Errorf("expected parent license %q, got %q", *tt.parent, licenseErr.ParentLicense)
+			}
+
+			if licenseErr.License != stringValue(tt.license) {
+				t.Errorf("expected license %q, got %q", stringValue(tt.license), licenseErr.License)
+			}
+
+			if !slices.Equal(licenseErr.Allowed, tt.allowed) {
+				t.Errorf("expected allowed %v, got %v", tt.allowed, licenseErr.Allowed)
+			}
+		})
+	}
+}
@@ -60,7 +60,7 @@ const (
 
 	queryUpdateEmbedding = `
 		UPDATE user_strudels
-		SET embedding = $1
+		SET embedding = $1, embedding_model = $3, embedding_dim = $4
 		WHERE id = $2
 	`
 
@@ -95,6 +95,10 @@ const (
 		SELECT cc_signal FROM user_strudels WHERE id = $1
 	`
 
+	queryGetParentLicense = `
+		SELECT license FROM user_strudels WHERE id = $1
+	`
+
 	queryGetStrudelForkedFrom = `
 		SELECT forked_from FROM user_strudels WHERE id = $1
 	`
@@ -151,4 +155,56 @@ const (
 		WHERE cc_signal = 'no-ai'
 		  AND LENGTH(code) >= $1
 	`
+
+	// revision history: locks the row so concurrent saves number revisions in order
+	// also reads the parent's license, forks must keep a compatible one
+	queryGetForUpdate = `
+		SELECT s.title, s.code, s.license, s.is_public,
+		       (SELECT p.license FROM user_strudels p WHERE p.id = s.forked_from)
+		FROM user_strudels s
+		WHERE s.id = $1 AND s.user_id = $2
+		FOR UPDATE OF s
+	`
+
+	queryCreateRevision = `
+		INSERT INTO strudel_revisions (strudel_id, revision, title, code, license, restored_from, created_by)
+		SELECT $1, COALESCE(MAX(revision), 0) + 1, $2, $3, $4, $5, $6
+		FROM strudel_revisions
+		WHERE strudel_id = $1
+		RETURNING id, strudel_id, revision, title, code, license, restored_from, created_by, created_at
+	`
+
+	queryCountRevisions = `
+		SELECT COUNT(*)
+		FROM strudel_revisions r
+		INNER JOIN user_strudels s ON r.strudel_id = s.id
+		WHERE r.strudel_id = $1 AND s.user_id = $2
+	`
+
+	// code is left out of listings, fetch a single revision to read it
+	queryListRevisions = `
+		SELECT r.id, r.strudel_id, r.revision, r.title, '', r.license, r.restored_from, r.created_by, r.created_at
+		FROM strudel_revisions r
+		INNER JOIN user_strudels s ON r.strudel_id = s.id
+		WHERE r.strudel_id = $1 AND s.user_id = $2
+		ORDER BY r.revision DESC
+		LIMIT $3 OFFSET $4
+	`
+
+	queryGetRevision = `
+		SELECT r.id, r.strudel_id, r.revision, r.title, r.code, r.license, r.restored_from, r.created_by, r.created_at
+		FROM strudel_revisions r
+		INNER JOIN user_strudels s ON r.strudel_id = s.id
+		WHERE r.strudel_id = $1 AND s.user_id = $2 AND r.revision = $3
+	`
+
+	queryRestoreRevision = `
+		UPDATE user_strudels
+		SET title = $1,
+		    code = $2,
+		    license = $3,
+		    updated_at = NOW()
+		WHERE id = $4 AND user_id = $5
+		RETURNING id, user_id, title, code, is_public, license, cc_signal, use_in_training, ai_assist_count, forked_from, description, tags, categories, conversation_history, created_at, updated_at
+	`
 )
@@ -0,0 +1,180 @@
+package strudels
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// lists a strudel's revisions newest first (without code) with the total count.
+// only the owner's strudels are visible.
+func (r *Repository) ListRevisions(ctx context.Context, strudelID, userID string, limit, offset int) ([]Revision, int, error) {
+	var total int
+
+	if err := r.db.QueryRow(ctx, queryCountRevisions, strudelID, userID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.db.Query(ctx, queryListRevisions, strudelID, userID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer rows.Close()
+	revisions := make([]Revision, 0)
+
+	for rows.Next() {
+		revision, err := scanRevision(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		revisions = append(revisions, *revision)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return revisions, total, nil
+}
+
+// retrieves a single revision of an owned strudel
+func (r *Repository) GetRevision(ctx context.Context, strudelID, userID string, revision int) (*Revision, error) {
+	return scanRevision(r.db.QueryRow(ctx, queryGetRevision, strudelID, userID, revision))
+}
+
+// rolls a strudel back to an earlier revision. the rollback is recorded as a new
+// revision pointing at the restored one, so history is never rewritten.
+func (r *Repository) RestoreRevision(ctx context.Context, strudelID, userID string, revision int) (*Strudel, *Revision, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // no-op after commit
+
+	// lock the strudel so concurrent saves cannot interleave revision numbers
+	var current Strudel
+	var parentLicense *string
+
+	err = tx.QueryRow(ctx, queryGetForUpdate, strudelID, userID).Scan(
+		&current.Title,
+		&current.Code,
+		&current.License,
+		&current.IsPublic,
+		&parentLicense,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	target, err := scanRevision(tx.QueryRow(ctx, queryGetRevision, strudelID, userID, revision))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// an old revision may carry a license the fork can no longer use
+	if stringValue(target.License) != stringValue(current.License) {
+		if err := CheckDerivativeLicense(parentLicense, target.License, current.IsPublic); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var strudel Strudel
+
+	err = tx.QueryRow(
+		ctx,
+		queryRestoreRevision,
+		target.Title,
+		target.Code,
+		target.License,
+		strudelID,
+		userID,
+	).Scan(
+		&strudel.ID,
+		&strudel.UserID,
+		&strudel.Title,
+		&strudel.Code,
+		&strudel.IsPublic,
+		&strudel.License,
+		&strudel.CCSignal,
+		&strudel.UseInTraining,
+		&strudel.AIAssistCount,
+		&strudel.ForkedFrom,
+		&strudel.Description,
+		&strudel.Tags,
+		&strudel.Categories,
+		&strudel.ConversationHistory,
+		&strudel.CreatedAt,
+		&strudel.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	restored, err := createRevision(ctx, tx, &strudel, &target.Revision, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &strudel, restored, nil
+}
+
+// records the strudel's current code, title and license as its next revision
+func createRevision(ctx context.Context, tx pgx.Tx, strudel *Strudel, restoredFrom *int, userID string) (*Revision, error) {
+	revision, err := scanRevision(tx.QueryRow(
+		ctx,
+		queryCreateRevision,
+		strudel.ID,
+		strudel.Title,
+		strudel.Code,
+		strudel.License,
+		restoredFrom,
+		userID,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create revision: %w", err)
+	}
+
+	return revision, nil
+}
+
+// reports whether an update touched any versioned field
+func revisionChanged(previous, current *Strudel) bool {
+	if previous.Title != current.Title || previous.Code != current.Code {
+		return true
+	}
+
+	if previous.License == nil || current.License == nil {
+		return previous.License != current.License
+	}
+
+	return *previous.License != *current.License
+}
+
+// scans a revision row in the column order used by the revision queries
+func scanRevision(row pgx.Row) (*Revision, error) {
+	var revision Revision
+
+	err := row.Scan(
+		&revision.ID,
+		&revision.StrudelID,
+		&revision.Revision,
+		&revision.Title,
+		&revision.Code,
+		&revision.License,
+		&revision.RestoredFrom,
+		&revision.CreatedBy,
+		&revision.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &revision, nil
+}
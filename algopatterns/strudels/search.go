@@ -0,0 +1,162 @@
+package strudels
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pgvector/pgvector-go"
+)
+
+const (
+	// candidates taken from each of the vector and full text searches
+	publicSearchCandidates = 200
+
+	// same weighting as the retriever's hybrid example search
+	publicVectorWeight = 0.7
+	publicTextWeight   = 0.3
+)
+
+// searches public strudels by embedding similarity and full text rank, merged
+// into one weighted score. returns the page of results and the total number of
+// matches, which is capped by the candidates taken from each search.
+func (r *Repository) SearchPublic(ctx context.Context, search PublicSearch, limit, offset int) ([]ScoredStrudel, int, error) {
+	countQuery, listQuery, args := buildPublicSearchQueries(search)
+	if countQuery == "" {
+		return []ScoredStrudel{}, 0, nil
+	}
+
+	// get total count first
+	var total int
+
+	if err := r.db.QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	args = append(args, limit, offset)
+
+	rows, err := r.db.Query(ctx, listQuery, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer rows.Close()
+	results := make([]ScoredStrudel, 0)
+
+	for rows.Next() {
+		var s ScoredStrudel
+		var authorName *string
+		err := rows.Scan(
+			&s.ID,
+			&s.UserID,
+			&authorName,
+			&s.Title,
+			&s.Code,
+			&s.IsPublic,
+			&s.License,
+			&s.CCSignal,
+			&s.UseInTraining,
+			&s.AIAssistCount,
+			&s.ForkedFrom,
+			&s.Description,
+			&s.Tags,
+			&s.Categories,
+			&s.ConversationHistory,
+			&s.CreatedAt,
+			&s.UpdatedAt,
+			&s.Score,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if authorName != nil {
+			s.AuthorName = *authorName
+		}
+
+		results = append(results, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return results, total, nil
+}
+
+// builds the count and page queries of a public search and the arguments they
+// share. the page query takes the limit and offset as its last two arguments.
+// both queries are empty when there is nothing to search for.
+func buildPublicSearchQueries(search PublicSearch) (countQuery, listQuery string, args []interface{}) {
+	args = []interface{}{}
+	argIndex := 1
+	var matches []string
+
+	// the vector is a scalar subquery so the ivfflat index can still be used
+	var vector string
+
+	switch {
+	case search.SimilarTo != "":
+		vector = fmt.Sprintf("(SELECT embedding FROM user_strudels WHERE id = $%d AND is_public = true)", argIndex)
+		args = append(args, search.SimilarTo)
+		argIndex++
+	case len(search.Embedding) > 0:
+		vector = fmt.Sprintf("$%d::vector", argIndex)
+		args = append(args, pgvector.NewVector(search.Embedding))
+		argIndex++
+	}
+
+	if vector != "" {
+		matches = append(matches, fmt.Sprintf(`
+			(SELECT s.id, %v * (1 - (s.embedding <=> %s)) AS score
+			FROM user_strudels s
+			WHERE s.is_public = true AND s.embedding IS NOT NULL AND %s IS NOT NULL
+			ORDER BY s.embedding <=> %s
+			LIMIT %d)`, publicVectorWeight, vector, vector, vector, publicSearchCandidates))
+	}
+
+	if search.Query != "" {
+		tsquery := fmt.Sprintf("websearch_to_tsquery('english', $%d)", argIndex)
+		args = append(args, search.Query)
+		argIndex++
+
+		matches = append(matches, fmt.Sprintf(`
+			(SELECT s.id, %v * ts_rank(s.searchable_tsvector, %s) AS score
+			FROM user_strudels s
+			WHERE s.is_public = true AND s.searchable_tsvector @@ %s
+			ORDER BY score DESC
+			LIMIT %d)`, publicTextWeight, tsquery, tsquery, publicSearchCandidates))
+	}
+
+	if len(matches) == 0 {
+		return "", "", nil
+	}
+
+	exclude := ""
+	if search.ExcludeID != "" {
+		exclude = fmt.Sprintf("WHERE id <> $%d", argIndex)
+		args = append(args, search.ExcludeID)
+		argIndex++
+	}
+
+	ranked := fmt.Sprintf(`
+		WITH ranked AS (
+			SELECT id, SUM(score)::float8 AS score
+			FROM (%s) matches
+			%s
+			GROUP BY id
+		)`, strings.Join(matches, " UNION ALL "), exclude)
+
+	countQuery = ranked + " SELECT COUNT(*) FROM ranked"
+
+	listQuery = fmt.Sprintf(`%s
+		SELECT s.id, s.user_id, u.name, s.title, s.code, s.is_public, s.license, s.cc_signal, s.use_in_training, s.ai_assist_count, s.forked_from, s.description, s.tags, s.categories, s.conversation_history, s.created_at, s.updated_at, r.score
+		FROM ranked r
+		INNER JOIN user_strudels s ON r.id = s.id
+		LEFT JOIN users u ON s.user_id = u.id
+		ORDER BY r.score DESC, s.created_at DESC
+		LIMIT $%d OFFSET $%d
+	`, ranked, argIndex, argIndex+1)
+
+	return countQuery, listQuery, args
+}
@@ -0,0 +1,131 @@
+package strudels
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pgvector/pgvector-go"
+)
+
+func TestBuildPublicSearchQueries(t *testing.T) {
+	tests := []struct {
+		name      string
+		search    PublicSearch
+		args      []interface{}
+		contains  []string
+		excludes  []string
+		pageLimit string
+	}{
+		{
+			name:      "similar only",
+			search:    PublicSearch{SimilarTo: "strudel-1"},
+			args:      []interface{}{"strudel-1"},
+			contains:  []string{"WHERE id = $1 AND is_public = true", "s.embedding <=>"},
+			excludes:  []string{"websearch_to_tsquery", "UNION ALL", "WHERE id <>"},
+			pageLimit: "LIMIT $2 OFFSET $3",
+		},
+		{
+			name:      "embedding only",
+			search:    PublicSearch{Embedding: []float32{0.1, 0.2}},
+			args:      []interface{}{pgvector.NewVector([]float32{0.1, 0.2})},
+			contains:  []string{"$1::vector", "s.embedding <=>"},
+			excludes:  []string{"websearch_to_tsquery", "UNION ALL"},
+			pageLimit: "LIMIT $2 OFFSET $3",
+		},
+		{
+			name:      "text only",
+			search:    PublicSearch{Query: "acid bass"},
+			args:      []interface{}{"acid bass"},
+			contains:  []string{"websearch_to_tsquery('english', $1)", "s.searchable_tsvector @@"},
+			excludes:  []string{"<=>", "UNION ALL"},
+			pageLimit: "LIMIT $2 OFFSET $3",
+		},
+		{
+			name:      "similar and text",
+			search:    PublicSearch{SimilarTo: "strudel-1", Query: "acid bass"},
+			args:      []interface{}{"strudel-1", "acid bass"},
+			contains:  []string{"WHERE id = $1 AND is_public = true", "websearch_to_tsquery('english', $2)", "UNION ALL"},
+			excludes:  []string{"WHERE id <>"},
+			pageLimit: "LIMIT $3 OFFSET $4",
+		},
+		{
+			name:      "similar and text excluding a strudel",
+			search:    PublicSearch{SimilarTo: "strudel-1", Query: "acid bass", ExcludeID: "strudel-1"},
+			args:      []interface{}{"strudel-1", "acid bass", "strudel-1"},
+			contains:  []string{"websearch_to_tsquery('english', $2)", "UNION ALL", "WHERE id <> $3"},
+			pageLimit: "LIMIT $4 OFFSET $5",
+		},
+		{
+			name:      "text excluding a strudel",
+			search:    PublicSearch{Query: "acid bass", ExcludeID: "strudel-1"},
+			args:      []interface{}{"acid bass", "strudel-1"},
+			contains:  []string{"websearch_to_tsquery('english', $1)", "WHERE id <> $2"},
+			excludes:  []string{"UNION ALL"},
+			pageLimit: "LIMIT $3 OFFSET $4",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			countQuery, listQuery, args := buildPublicSearchQueries(tt.search)
+
+			if len(args) != len(tt.args) {
+				t.Fatalf("expected %d args, got %d: %v", len(tt.args), len(args), args)
+			}
+
+			for i := range args {
+				if got, want := argString(args[i]), argString(tt.args[i]); got != want {
+					t.Errorf("arg %d: expected %s, got %s", i+1, want, got)
+				}
+			}
+
+			for _, query := range []string{countQuery, listQuery} {
+				// every candidate search must stay within public strudels
+				candidates := strings.Count(query, "FROM user_strudels s\n")
+				if public := strings.Count(query, "WHERE s.is_public = true"); public != candidates || candidates == 0 {
+					t.Errorf("expected all %d candidate searches to filter is_public = true, got %d", candidates, public)
+				}
+
+				for _, want := range tt.contains {
+					if !strings.Contains(query, want) {
+						t.Errorf("expected query to contain %q:\n%s", want, query)
+					}
+				}
+
+				for _, unwanted := range tt.excludes {
+					if strings.Contains(query, unwanted) {
+						t.Errorf("expected query not to contain %q:\n%s", unwanted, query)
+					}
+				}
+			}
+
+			if !strings.HasSuffix(strings.TrimSpace(countQuery), "SELECT COUNT(*) FROM ranked") {
+				t.Errorf("expected a count query, got:\n%s", countQuery)
+			}
+
+			if strings.Contains(countQuery, "LIMIT $") {
+				t.Errorf("expected the count query to take no page arguments:\n%s", countQuery)
+			}
+
+			if !strings.Contains(listQuery, tt.pageLimit) {
+				t.Errorf("expected page query to contain %q:\n%s", tt.pageLimit, listQuery)
+			}
+		})
+	}
+}
+
+func TestBuildPublicSearchQueriesNothingToSearch(t *testing.T) {
+	countQuery, listQuery, args := buildPublicSearchQueries(PublicSearch{ExcludeID: "strudel-1"})
+
+	if countQuery != "" || listQuery != "" || len(args) != 0 {
+		t.Errorf("expected no queries, got %q, %q, %v", countQuery, listQuery, args)
+	}
+}
+
+func argString(arg interface{}) string {
+	if v, ok := arg.(pgvector.Vector); ok {
+		return v.String()
+	}
+
+	return arg.(string)
+}
@@ -6,7 +6,9 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pgvector/pgvector-go"
 )
 
 var (
@@ -60,20 +62,49 @@ func (r *Repository) Create(
 		}
 	}
 
+	// forks keep their parent's license unless they pick a compatible one
+	license := req.License
+
+	if req.ForkedFrom != nil {
+		var parentLicense *string
+
+		// a missing parent is no fork to check, any other error must not skip the check
+		err := r.db.QueryRow(ctx, queryGetParentLicense, *req.ForkedFrom).Scan(&parentLicense)
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+		case err != nil:
+			return nil, fmt.Errorf("failed to get parent license: %w", err)
+		default:
+			if license == nil {
+				license = parentLicense
+			}
+
+			if err := CheckDerivativeLicense(parentLicense, license, req.IsPublic); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	// explicitly marshal conversation history to JSON string for pgx JSONB compatibility
 	conversationHistoryJSON, err := json.Marshal(req.ConversationHistory)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal conversation history: %w", err)
 	}
 
-	err = r.db.QueryRow(
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // no-op after commit
+
+	err = tx.QueryRow(
 		ctx,
 		queryCreate,
 		userID,
 		req.Title,
 		req.Code,
 		req.IsPublic,
-		req.License,
+		license,
 		ccSignal,
 		aiAssistCount,
 		req.ForkedFrom,
@@ -104,6 +135,15 @@ func (r *Repository) Create(
 		return nil, err
 	}
 
+	// the first revision is the strudel as created
+	if _, err := createRevision(ctx, tx, &strudel, nil, userID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return &strudel, nil
 }
 
@@ -370,7 +410,47 @@ func (r *Repository) Update(
 		conversationHistoryJSON = string(jsonBytes)
 	}
 
-	err := r.db.QueryRow(
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // no-op after commit
+
+	// lock the row and keep its versioned fields to detect a new revision
+	var previous Strudel
+
+	var parentLicense *string
+
+	err = tx.QueryRow(ctx, queryGetForUpdate, strudelID, userID).Scan(
+		&previous.Title,
+		&previous.Code,
+		&previous.License,
+		&previous.IsPublic,
+		&parentLicense,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// only changes to the license or visibility are checked, so saving forks
+	// created before licenses were enforced keeps working
+	if req.License != nil || req.IsPublic != nil {
+		license, isPublic := previous.License, previous.IsPublic
+
+		if req.License != nil {
+			license = req.License
+		}
+
+		if req.IsPublic != nil {
+			isPublic = *req.IsPublic
+		}
+
+		if err := CheckDerivativeLicense(parentLicense, license, isPublic); err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.QueryRow(
 		ctx,
 		queryUpdate,
 		req.Title,
@@ -408,6 +488,16 @@ func (r *Repository) Update(
 		return nil, err
 	}
 
+	if revisionChanged(&previous, &strudel) {
+		if _, err := createRevision(ctx, tx, &strudel, nil, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return &strudel, nil
 }
 
@@ -468,9 +558,9 @@ func (r *Repository) ListTrainableWithoutEmbedding(ctx context.Context, limit in
 	return strudels, nil
 }
 
-// sets the embedding vector for a strudel
-func (r *Repository) UpdateEmbedding(ctx context.Context, strudelID string, embedding []float32) error {
-	_, err := r.db.Exec(ctx, queryUpdateEmbedding, embedding, strudelID)
+// sets the embedding vector for a strudel, tagged with the model that produced it
+func (r *Repository) UpdateEmbedding(ctx context.Context, strudelID string, embedding []float32, model string) error {
+	_, err := r.db.Exec(ctx, queryUpdateEmbedding, pgvector.NewVector(embedding), strudelID, model, len(embedding))
 	return err
 }
 
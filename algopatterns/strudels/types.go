@@ -140,11 +140,39 @@ type UpdateStrudelRequest struct {
 	ConversationHistory ConversationHistory `json:"conversation_history,omitempty" binding:"max=100"`
 }
 
+// an immutable version of a strudel's code, title and license
+type Revision struct {
+	ID           string    `json:"id"`
+	StrudelID    string    `json:"strudel_id"`
+	Revision     int       `json:"revision"`
+	Title        string    `json:"title"`
+	Code         string    `json:"code,omitempty"`
+	License      *string   `json:"license,omitempty"`
+	RestoredFrom *int      `json:"restored_from,omitempty"`
+	CreatedBy    *string   `json:"created_by,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
 type ListFilter struct {
 	Search string   // search in title and description
 	Tags   []string // filter by tags (any match)
 }
 
+// hybrid search over public strudels. vector and text matches are merged,
+// either may be left empty.
+type PublicSearch struct {
+	Embedding []float32 // query vector, from the active embedding model
+	SimilarTo string    // use this strudel's stored embedding instead of Embedding
+	Query     string    // websearch-style full text query
+	ExcludeID string    // strudel left out of the results
+}
+
+// a public strudel with its hybrid search score
+type ScoredStrudel struct {
+	Strudel
+	Score float64 `json:"score"`
+}
+
 // represents an AI conversation message for a saved strudel
 type StrudelMessage struct {
 	ID                  string             `json:"id"`
@@ -40,7 +40,7 @@ type UpdateProfileRequest struct {
 type UsageLogRequest struct {
 	UserID       *string // nil for anonymous
 	SessionID    string  // for anonymous users
-	Provider     string  // "anthropic", "openai"
+	Provider     string  // provider that served the request: "anthropic", "openai", "local"
 	Model        string  // model name
 	InputTokens  int     // estimated input tokens
 	OutputTokens int     // estimated output tokens
@@ -8,6 +8,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"codeberg.org/algopatterns/server/algopatterns/sessions"
 	"codeberg.org/algopatterns/server/algopatterns/strudels"
 	"codeberg.org/algopatterns/server/algopatterns/users"
 	agentcore "codeberg.org/algopatterns/server/internal/agent"
@@ -41,7 +42,7 @@ const (
 // @Failure 400 {object} errors.ErrorResponse
 // @Failure 500 {object} errors.ErrorResponse
 // @Router /api/v1/agent/generate [post]
-func GenerateHandler(agentClient *agentcore.Agent, _ llm.LLM, strudelRepo *strudels.Repository, userRepo *users.Repository, attrService *attribution.Service, sessionBuffer *buffer.SessionBuffer) gin.HandlerFunc {
+func GenerateHandler(agentClient *agentcore.Agent, _ llm.LLM, strudelRepo *strudels.Repository, userRepo *users.Repository, attrService *attribution.Service, sessionBuffer *buffer.SessionBuffer, sessionRepo sessions.Repository, codeUpdater CodeUpdater, localLLM llm.OpenAIConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req GenerateRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
@@ -50,7 +51,7 @@ func GenerateHandler(agentClient *agentcore.Agent, _ llm.LLM, strudelRepo *strud
 		}
 
 		// check if BYOK is required (free tier disabled)
-		isBYOK := req.ProviderAPIKey != ""
+		isBYOK := isBYOKRequest(&req)
 		if !freeTierEnabled && !isBYOK {
 			c.JSON(http.StatusForbidden, gin.H{
 				"error":   "byok_required",
@@ -177,11 +178,14 @@ func GenerateHandler(agentClient *agentcore.Agent, _ llm.LLM, strudelRepo *strud
 			UserQuery:           req.UserQuery,
 			EditorState:         req.EditorState,
 			ConversationHistory: conversationHistory,
+			Agentic:             req.Agentic,
+			PatchMode:           req.PatchMode,
+			FormatCode:          req.FormatCode,
 		}
 
-		// create custom generator if BYOK key provided
-		if req.ProviderAPIKey != "" {
-			customGenerator, err := createBYOKGenerator(req.Provider, req.ProviderAPIKey)
+		// create custom generator if BYOK key provided or the local provider was chosen
+		if isBYOK || req.Provider == "local" {
+			customGenerator, err := createBYOKGenerator(req.Provider, req.ProviderAPIKey, localLLM)
 			if err != nil {
 				errors.BadRequest(c, "invalid provider configuration", err)
 				return
@@ -202,6 +206,8 @@ func GenerateHandler(agentClient *agentcore.Agent, _ llm.LLM, strudelRepo *strud
 			return
 		}
 
+		logUsage(c, userRepo, &req, resp, isBYOK)
+
 		// record attributions if examples were used (runs async)
 		if attrService != nil && len(resp.Examples) > 0 {
 			userID, _ := c.Get("user_id")
@@ -269,6 +275,17 @@ func GenerateHandler(agentClient *agentcore.Agent, _ llm.LLM, strudelRepo *strud
 			}
 		}
 
+		// patch mode in a live session: collaborators get the merged code right away
+		broadcast := false
+		if len(resp.Edits) > 0 && resp.ValidationError == "" && req.SessionID != "" {
+			broadcast = broadcastPatch(c, sessionRepo, codeUpdater, &req, resp.Code)
+		}
+
+		edits := make([]Edit, len(resp.Edits))
+		for i, edit := range resp.Edits {
+			edits[i] = Edit(edit)
+		}
+
 		// map internal references to API types
 		strudelRefs := make([]StrudelReference, len(resp.StrudelReferences))
 		for i, ref := range resp.StrudelReferences {
@@ -299,12 +316,94 @@ func GenerateHandler(agentClient *agentcore.Agent, _ llm.LLM, strudelRepo *strud
 			StrudelReferences:   strudelRefs,
 			DocReferences:       docRefs,
 			Model:               resp.Model,
+			Provider:            resp.Provider,
+			ToolSteps:           resp.ToolSteps,
+			Edits:               edits,
+			ValidationError:     resp.ValidationError,
+			Broadcast:           broadcast,
 		})
 	}
 }
 
-// creates a byok generator based on provider
-func createBYOKGenerator(provider, apiKey string) (llm.TextGenerator, error) {
+// sends patched code to a live session as code_ops from the ai, rebased from
+// the revision editor_state was read at onto edits made since. only
+// authenticated hosts and co-authors may change the shared code, anything
+// else leaves the session untouched and the client applies the code locally.
+func broadcastPatch(c *gin.Context, sessionRepo sessions.Repository, codeUpdater CodeUpdater, req *GenerateRequest, code string) bool {
+	if sessionRepo == nil || codeUpdater == nil || req.EditorRevision == nil {
+		return false
+	}
+
+	sessionID := req.SessionID
+
+	userID, exists := auth.GetUserID(c)
+	if !exists {
+		return false
+	}
+
+	participant, err := sessionRepo.GetAuthenticatedParticipant(c.Request.Context(), sessionID, userID)
+	if err != nil {
+		return false
+	}
+
+	if participant.Role != "host" && participant.Role != "co-author" {
+		return false
+	}
+
+	if err := codeUpdater.ApplyCodeChanges(sessionID, userID, participant.DisplayName, *req.EditorRevision, req.EditorState, code, "ai"); err != nil {
+		log.Printf("failed to broadcast ai patch to session %s: %v", sessionID, err)
+		return false
+	}
+
+	return true
+}
+
+// reports whether a request runs on the user's own key. the "local" provider
+// runs on the server's own endpoint, so it counts as platform usage.
+func isBYOKRequest(req *GenerateRequest) bool {
+	return req.ProviderAPIKey != "" && req.Provider != "local"
+}
+
+// records token usage with the provider that actually served the request.
+// platform usage (is_byok = false) counts toward the daily quotas checked by
+// CheckUserRateLimit and CheckSessionRateLimit. failures are logged, the
+// response is already generated.
+func logUsage(c *gin.Context, userRepo *users.Repository, req *GenerateRequest, resp *agentcore.GenerateResponse, isBYOK bool) {
+	usage := newUsageLog(req, resp, isBYOK)
+
+	if userID, ok := auth.GetUserID(c); ok {
+		usage.UserID = &userID
+	}
+
+	if err := userRepo.LogUsage(c.Request.Context(), usage); err != nil {
+		log.Printf("failed to log usage: %v", err)
+	}
+}
+
+// builds the usage log of a generation, without the user
+func newUsageLog(req *GenerateRequest, resp *agentcore.GenerateResponse, isBYOK bool) *users.UsageLogRequest {
+	provider := resp.Provider
+	if provider == "" {
+		provider = req.Provider
+	}
+	if provider == "" {
+		provider = string(llm.ProviderAnthropic) // byok default, see createBYOKGenerator
+	}
+
+	return &users.UsageLogRequest{
+		SessionID:    req.SessionID,
+		Provider:     provider,
+		Model:        resp.Model,
+		InputTokens:  resp.InputTokens,
+		OutputTokens: resp.OutputTokens,
+		IsBYOK:       isBYOK,
+	}
+}
+
+// creates a byok generator based on provider. "local" targets the server's
+// configured OpenAI-compatible endpoint with the server's key, never a
+// client-supplied URL or key.
+func createBYOKGenerator(provider, apiKey string, localLLM llm.OpenAIConfig) (llm.TextGenerator, error) {
 	switch provider {
 	case "anthropic", "":
 		return llm.NewAnthropicTransformer(llm.AnthropicConfig{
@@ -318,6 +417,16 @@ func createBYOKGenerator(provider, apiKey string) (llm.TextGenerator, error) {
 			APIKey: apiKey,
 			Model:  defaultOpenAIModel,
 		}), nil
+	case "local":
+		if localLLM.BaseURL == "" {
+			return nil, fmt.Errorf("local provider is not configured on this server")
+		}
+
+		return llm.NewLocalGenerator(llm.OpenAIConfig{
+			APIKey:  localLLM.APIKey,
+			Model:   localLLM.Model,
+			BaseURL: localLLM.BaseURL,
+		}), nil
 	default:
 		return nil, fmt.Errorf("unsupported provider: %s", provider)
 	}
@@ -325,7 +434,7 @@ func createBYOKGenerator(provider, apiKey string) (llm.TextGenerator, error) {
 
 // GenerateStreamHandler godoc
 // @Summary Stream generate code with AI (SSE)
-// @Description Stream Strudel code generation using Server-Sent Events. BYOK required.
+// @Description Stream Strudel code generation using Server-Sent Events. BYOK required, the "local" provider is not available for streaming.
 // @Tags agent
 // @Accept json
 // @Produce text/event-stream
@@ -334,7 +443,7 @@ func createBYOKGenerator(provider, apiKey string) (llm.TextGenerator, error) {
 // @Failure 400 {object} errors.ErrorResponse
 // @Failure 403 {object} errors.ErrorResponse
 // @Router /api/v1/agent/generate/stream [post]
-func GenerateStreamHandler(agentClient *agentcore.Agent, strudelRepo *strudels.Repository, sessionBuffer *buffer.SessionBuffer) gin.HandlerFunc {
+func GenerateStreamHandler(agentClient *agentcore.Agent, strudelRepo *strudels.Repository, sessionBuffer *buffer.SessionBuffer, localLLM llm.OpenAIConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req GenerateRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
@@ -342,8 +451,8 @@ func GenerateStreamHandler(agentClient *agentcore.Agent, strudelRepo *strudels.R
 			return
 		}
 
-		// streaming requires BYOK
-		if req.ProviderAPIKey == "" {
+		// streaming requires BYOK, the local provider is the server's own and rate limited
+		if req.ProviderAPIKey == "" || req.Provider == "local" {
 			c.JSON(http.StatusForbidden, gin.H{
 				"error":   "byok_required",
 				"message": "Streaming requires your own API key. Add your API key in Settings.",
@@ -389,7 +498,7 @@ func GenerateStreamHandler(agentClient *agentcore.Agent, strudelRepo *strudels.R
 		}
 
 		// create BYOK generator
-		customGenerator, err := createBYOKGenerator(req.Provider, req.ProviderAPIKey)
+		customGenerator, err := createBYOKGenerator(req.Provider, req.ProviderAPIKey, localLLM)
 		if err != nil {
 			errors.BadRequest(c, "invalid provider configuration", err)
 			return
@@ -400,6 +509,7 @@ func GenerateStreamHandler(agentClient *agentcore.Agent, strudelRepo *strudels.R
 			EditorState:         req.EditorState,
 			ConversationHistory: conversationHistory,
 			CustomGenerator:     customGenerator,
+			FormatCode:          req.FormatCode,
 		}
 
 		// enable RAG caching
@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"testing"
+
+	agentcore "codeberg.org/algopatterns/server/internal/agent"
+)
+
+// only usage logged with is_byok = false counts toward the daily quotas
+func TestUsageLogCountsTowardQuota(t *testing.T) {
+	tests := []struct {
+		name         string
+		req          GenerateRequest
+		resp         agentcore.GenerateResponse
+		countsQuota  bool
+		wantProvider string
+	}{
+		{
+			name:         "platform key",
+			req:          GenerateRequest{SessionID: "session-1"},
+			resp:         agentcore.GenerateResponse{Provider: "openai", Model: "gpt-4o"},
+			countsQuota:  true,
+			wantProvider: "openai",
+		},
+		{
+			name:         "local provider",
+			req:          GenerateRequest{Provider: "local", SessionID: "session-1"},
+			resp:         agentcore.GenerateResponse{Model: "llama"},
+			countsQuota:  true,
+			wantProvider: "local",
+		},
+		{
+			name:         "local provider with a key",
+			req:          GenerateRequest{Provider: "local", ProviderAPIKey: "sk-user"},
+			countsQuota:  true,
+			wantProvider: "local",
+		},
+		{
+			name:         "byok openai",
+			req:          GenerateRequest{Provider: "openai", ProviderAPIKey: "sk-user"},
+			countsQuota:  false,
+			wantProvider: "openai",
+		},
+		{
+			name:         "byok default provider",
+			req:          GenerateRequest{ProviderAPIKey: "sk-user"},
+			countsQuota:  false,
+			wantProvider: "anthropic",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usage := newUsageLog(&tt.req, &tt.resp, isBYOKRequest(&tt.req))
+
+			if usage.IsBYOK == tt.countsQuota {
+				t.Errorf("expected is_byok %v, got %v", !tt.countsQuota, usage.IsBYOK)
+			}
+
+			if usage.Provider != tt.wantProvider {
+				t.Errorf("expected provider %q, got %q", tt.wantProvider, usage.Provider)
+			}
+
+			if usage.SessionID != tt.req.SessionID {
+				t.Errorf("expected session %q, got %q", tt.req.SessionID, usage.SessionID)
+			}
+		})
+	}
+}
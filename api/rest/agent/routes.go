@@ -3,6 +3,7 @@ package agent
 import (
 	"github.com/gin-gonic/gin"
 
+	"codeberg.org/algopatterns/server/algopatterns/sessions"
 	"codeberg.org/algopatterns/server/algopatterns/strudels"
 	"codeberg.org/algopatterns/server/algopatterns/users"
 	agentcore "codeberg.org/algopatterns/server/internal/agent"
@@ -11,10 +12,10 @@ import (
 	"codeberg.org/algopatterns/server/internal/llm"
 )
 
-func RegisterRoutes(router *gin.RouterGroup, agentClient *agentcore.Agent, platformLLM llm.LLM, strudelRepo *strudels.Repository, userRepo *users.Repository, attrService *attribution.Service, sessionBuffer *buffer.SessionBuffer) {
+func RegisterRoutes(router *gin.RouterGroup, agentClient *agentcore.Agent, platformLLM llm.LLM, strudelRepo *strudels.Repository, userRepo *users.Repository, attrService *attribution.Service, sessionBuffer *buffer.SessionBuffer, sessionRepo sessions.Repository, codeUpdater CodeUpdater, localLLM llm.OpenAIConfig) {
 	agentGroup := router.Group("/agent")
 	{
-		agentGroup.POST("/generate", GenerateHandler(agentClient, platformLLM, strudelRepo, userRepo, attrService, sessionBuffer))
-		agentGroup.POST("/generate/stream", GenerateStreamHandler(agentClient, strudelRepo, sessionBuffer))
+		agentGroup.POST("/generate", GenerateHandler(agentClient, platformLLM, strudelRepo, userRepo, attrService, sessionBuffer, sessionRepo, codeUpdater, localLLM))
+		agentGroup.POST("/generate/stream", GenerateStreamHandler(agentClient, strudelRepo, sessionBuffer, localLLM))
 	}
 }
@@ -5,11 +5,15 @@ type GenerateRequest struct {
 	UserQuery           string    `json:"user_query" binding:"required"`
 	EditorState         string    `json:"editor_state"`
 	ConversationHistory []Message `json:"conversation_history"`
-	Provider            string    `json:"provider,omitempty"`         // "anthropic" or "openai"
+	Provider            string    `json:"provider,omitempty"`         // "anthropic", "openai" or "local"
 	ProviderAPIKey      string    `json:"provider_api_key,omitempty"` // BYOK key
 	StrudelID           string    `json:"strudel_id,omitempty"`       // optional: for persisting conversation
 	ForkedFromID        string    `json:"forked_from_id,omitempty"`   // optional: for blocking AI on restricted forks
 	SessionID           string    `json:"session_id,omitempty"`       // optional: for paste lock validation
+	EditorRevision      *uint64   `json:"editor_revision,omitempty"`  // optional: session document revision editor_state was read at
+	Agentic             bool      `json:"agentic,omitempty"`          // optional: let the AI search docs and validate code mid-generation
+	PatchMode           bool      `json:"patch_mode,omitempty"`       // optional: return line edits against editor_state plus the merged code
+	FormatCode          bool      `json:"format_code,omitempty"`      // optional: format generated code before returning it
 }
 
 // conversation message
@@ -44,4 +48,22 @@ type GenerateResponse struct {
 	StrudelReferences   []StrudelReference `json:"strudel_references,omitempty"`
 	DocReferences       []DocReference     `json:"doc_references,omitempty"`
 	Model               string             `json:"model"`
+	Provider            string             `json:"provider,omitempty"`   // provider that served the request
+	ToolSteps           int                `json:"tool_steps,omitempty"` // tool-calling rounds used in agentic mode
+	Edits               []Edit             `json:"edits,omitempty"`      // applied line edits in patch mode, code holds the merged result
+	ValidationError     string             `json:"validation_error,omitempty"`
+	Broadcast           bool               `json:"broadcast,omitempty"` // true if the merged code was sent to the live session
+}
+
+// a line-anchored edit against the numbered editor state
+type Edit struct {
+	Op        string `json:"op"` // "replace", "insert" or "delete"
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line,omitempty"`
+	Content   string `json:"content,omitempty"`
+}
+
+// applies server-originated code changes to live WebSocket sessions
+type CodeUpdater interface {
+	ApplyCodeChanges(sessionID, userID, displayName string, baseRevision uint64, baseCode, code, source string) error
 }
@@ -9,6 +9,7 @@ import (
 	"codeberg.org/algopatterns/server/algopatterns/sessions"
 	"codeberg.org/algopatterns/server/api/rest/pagination"
 	"codeberg.org/algopatterns/server/internal/auth"
+	"codeberg.org/algopatterns/server/internal/diff"
 	"codeberg.org/algopatterns/server/internal/errors"
 	"codeberg.org/algopatterns/server/internal/logger"
 )
@@ -1224,3 +1225,313 @@ func GetSessionLiveStatusHandler(sessionRepo sessions.Repository) gin.HandlerFun
 		})
 	}
 }
+
+// ListSessionHistoryHandler godoc
+// @Summary List session history
+// @Description List saved snapshots of the session code, newest first (participants only). Code is omitted, fetch a snapshot to read it.
+// @Tags sessions
+// @Produce json
+// @Param id path string true "Session ID (UUID)"
+// @Param limit query int false "Max snapshots to return (max 100)" default(20)
+// @Param offset query int false "Number of snapshots to skip" default(0)
+// @Success 200 {object} SnapshotsListResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /api/v1/sessions/{id}/history [get]
+// @Security BearerAuth
+func ListSessionHistoryHandler(sessionRepo sessions.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessionID, _, ok := authorizeHistoryAccess(c, sessionRepo, false)
+		if !ok {
+			return
+		}
+
+		limit, offset := parsePaginationParams(c)
+		params := pagination.DefaultParams(limit, offset, 20, 100)
+
+		snapshots, total, err := sessionRepo.ListSnapshots(c.Request.Context(), sessionID, params.Limit, params.Offset)
+		if err != nil {
+			errors.InternalError(c, "failed to list session history", err)
+			return
+		}
+
+		responses := make([]SnapshotResponse, 0, len(snapshots))
+
+		for _, snapshot := range snapshots {
+			response := toSnapshotResponse(snapshot)
+			response.Code = ""
+			responses = append(responses, response)
+		}
+
+		c.JSON(http.StatusOK, SnapshotsListResponse{
+			Snapshots:  responses,
+			Pagination: pagination.NewMeta(params, total),
+		})
+	}
+}
+
+// GetSessionSnapshotHandler godoc
+// @Summary Get session snapshot
+// @Description Get a saved snapshot of the session code (participants only)
+// @Tags sessions
+// @Produce json
+// @Param id path string true "Session ID (UUID)"
+// @Param snapshot_id path string true "Snapshot ID (UUID)"
+// @Success 200 {object} SnapshotResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Router /api/v1/sessions/{id}/history/{snapshot_id} [get]
+// @Security BearerAuth
+func GetSessionSnapshotHandler(sessionRepo sessions.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessionID, _, ok := authorizeHistoryAccess(c, sessionRepo, false)
+		if !ok {
+			return
+		}
+
+		snapshotID, ok := errors.ValidatePathUUID(c, "snapshot_id")
+		if !ok {
+			return
+		}
+
+		snapshot, err := sessionRepo.GetSnapshot(c.Request.Context(), sessionID, snapshotID)
+		if err != nil {
+			errors.NotFound(c, "snapshot")
+			return
+		}
+
+		c.JSON(http.StatusOK, toSnapshotResponse(snapshot))
+	}
+}
+
+// CreateSessionSnapshotHandler godoc
+// @Summary Save session snapshot
+// @Description Save the current session code as a snapshot, optionally labelled (host or co-author only)
+// @Tags sessions
+// @Accept json
+// @Produce json
+// @Param id path string true "Session ID (UUID)"
+// @Param request body CreateSnapshotRequest false "Snapshot label"
+// @Success 201 {object} SnapshotResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /api/v1/sessions/{id}/history [post]
+// @Security BearerAuth
+func CreateSessionSnapshotHandler(sessionRepo sessions.Repository, snapshotService *sessions.SnapshotService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessionID, participant, ok := authorizeHistoryAccess(c, sessionRepo, true)
+		if !ok {
+			return
+		}
+
+		// the label is optional, so an empty body is fine
+		var req CreateSnapshotRequest
+		if c.Request.ContentLength > 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				errors.ValidationError(c, err)
+				return
+			}
+		}
+
+		snapshot, err := snapshotService.CreateManualSnapshot(c.Request.Context(), sessionID, participant.UserID, req.Label)
+		if err != nil {
+			errors.InternalError(c, "failed to save snapshot", err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, toSnapshotResponse(snapshot))
+	}
+}
+
+// DiffSessionSnapshotsHandler godoc
+// @Summary Diff session snapshots
+// @Description Line diff between two snapshots, or between a snapshot and the current code when "to" is omitted (participants only). Code over 10000 lines is rejected with 400
+// @Tags sessions
+// @Produce json
+// @Param id path string true "Session ID (UUID)"
+// @Param from query string true "Base snapshot ID (UUID)"
+// @Param to query string false "Target snapshot ID (UUID), defaults to the current code"
+// @Success 200 {object} SnapshotDiffResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Router /api/v1/sessions/{id}/history/diff [get]
+// @Security BearerAuth
+func DiffSessionSnapshotsHandler(sessionRepo sessions.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessionID, _, ok := authorizeHistoryAccess(c, sessionRepo, false)
+		if !ok {
+			return
+		}
+
+		fromID := c.Query("from")
+		if fromID == "" {
+			errors.BadRequest(c, "missing from", nil)
+			return
+		}
+
+		if !errors.ValidateUUID(c, fromID, "snapshot") {
+			return
+		}
+
+		from, err := sessionRepo.GetSnapshot(c.Request.Context(), sessionID, fromID)
+		if err != nil {
+			errors.NotFound(c, "snapshot")
+			return
+		}
+
+		toID := c.Query("to")
+		var toCode string
+
+		if toID == "" {
+			session, err := sessionRepo.GetSession(c.Request.Context(), sessionID)
+			if err != nil {
+				errors.SessionNotFound(c)
+				return
+			}
+
+			toID = "current"
+			toCode = session.Code
+		} else {
+			if !errors.ValidateUUID(c, toID, "snapshot") {
+				return
+			}
+
+			to, err := sessionRepo.GetSnapshot(c.Request.Context(), sessionID, toID)
+			if err != nil {
+				errors.NotFound(c, "snapshot")
+				return
+			}
+
+			toCode = to.Code
+		}
+
+		if diff.TooLarge(from.Code, toCode) {
+			errors.BadRequest(c, fmt.Sprintf("code is too large to diff, at most %d lines", diff.MaxLines), nil)
+			return
+		}
+
+		lines := diff.Lines(from.Code, toCode)
+
+		c.JSON(http.StatusOK, SnapshotDiffResponse{
+			From:  from.ID,
+			To:    toID,
+			Lines: lines,
+			Stats: diff.Summarize(lines),
+		})
+	}
+}
+
+// RestoreSessionSnapshotHandler godoc
+// @Summary Restore session snapshot
+// @Description Replace the session code with a snapshot. Connected clients receive a code_update with source "restored" (host or co-author only)
+// @Tags sessions
+// @Produce json
+// @Param id path string true "Session ID (UUID)"
+// @Param snapshot_id path string true "Snapshot ID (UUID)"
+// @Success 200 {object} RestoreSnapshotResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 403 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /api/v1/sessions/{id}/history/{snapshot_id}/restore [post]
+// @Security BearerAuth
+func RestoreSessionSnapshotHandler(
+	sessionRepo sessions.Repository,
+	snapshotService *sessions.SnapshotService,
+	codeUpdater CodeUpdater,
+) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessionID, participant, ok := authorizeHistoryAccess(c, sessionRepo, true)
+		if !ok {
+			return
+		}
+
+		snapshotID, ok := errors.ValidatePathUUID(c, "snapshot_id")
+		if !ok {
+			return
+		}
+
+		snapshot, err := sessionRepo.GetSnapshot(c.Request.Context(), sessionID, snapshotID)
+		if err != nil {
+			errors.NotFound(c, "snapshot")
+			return
+		}
+
+		err = codeUpdater.ApplyCodeUpdate(sessionID, participant.UserID, participant.DisplayName, snapshot.Code, "restored")
+		if err != nil {
+			errors.InternalError(c, "failed to restore snapshot", err)
+			return
+		}
+
+		// the restore is recorded as its own snapshot so history stays linear
+		restored, err := snapshotService.RecordRestore(c.Request.Context(), snapshot, participant.UserID)
+		if err != nil {
+			errors.InternalError(c, "failed to record restore", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, RestoreSnapshotResponse{
+			Message:  "snapshot restored successfully",
+			Code:     snapshot.Code,
+			Snapshot: toSnapshotResponse(restored),
+		})
+	}
+}
+
+// checks the caller is a participant of the session, and a host or co-author when write is set.
+// writes the error response and returns ok=false otherwise.
+func authorizeHistoryAccess(c *gin.Context, sessionRepo sessions.Repository, write bool) (string, *sessions.Participant, bool) {
+	sessionID, ok := errors.ValidatePathUUID(c, "id")
+	if !ok {
+		return "", nil, false
+	}
+
+	userID, exists := auth.GetUserID(c)
+	if !exists {
+		errors.Unauthorized(c, "")
+		return "", nil, false
+	}
+
+	if _, err := sessionRepo.GetSession(c.Request.Context(), sessionID); err != nil {
+		errors.SessionNotFound(c)
+		return "", nil, false
+	}
+
+	participant, err := sessionRepo.GetAuthenticatedParticipant(c.Request.Context(), sessionID, userID)
+	if err != nil {
+		errors.Forbidden(c, "you are not a participant in this session")
+		return "", nil, false
+	}
+
+	if write && participant.Role != "host" && participant.Role != "co-author" {
+		errors.Forbidden(c, "you don't have permission to edit this session")
+		return "", nil, false
+	}
+
+	return sessionID, participant, true
+}
+
+func toSnapshotResponse(snapshot *sessions.Snapshot) SnapshotResponse {
+	return SnapshotResponse{
+		ID:           snapshot.ID,
+		SessionID:    snapshot.SessionID,
+		Code:         snapshot.Code,
+		Authors:      snapshot.Authors,
+		Reason:       snapshot.Reason,
+		Label:        snapshot.Label,
+		CreatedBy:    snapshot.CreatedBy,
+		RestoredFrom: snapshot.RestoredFrom,
+		CreatedAt:    snapshot.CreatedAt,
+	}
+}
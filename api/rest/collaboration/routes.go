@@ -7,7 +7,13 @@ import (
 	"codeberg.org/algopatterns/server/internal/auth"
 )
 
-func RegisterRoutes(router *gin.RouterGroup, sessionRepo sessions.Repository, sessionEnder SessionEnder) {
+func RegisterRoutes(
+	router *gin.RouterGroup,
+	sessionRepo sessions.Repository,
+	sessionEnder SessionEnder,
+	snapshotService *sessions.SnapshotService,
+	codeUpdater CodeUpdater,
+) {
 	// live sessions (optional auth - includes user's sessions if authenticated)
 	router.GET("/sessions/live", auth.OptionalAuthMiddleware(), ListLiveSessionsHandler(sessionRepo))
 
@@ -32,6 +38,13 @@ func RegisterRoutes(router *gin.RouterGroup, sessionRepo sessions.Repository, se
 	// session messages
 	router.GET("/sessions/:id/messages", auth.AuthMiddleware(), GetSessionMessagesHandler(sessionRepo))
 
+	// version history
+	router.GET("/sessions/:id/history", auth.AuthMiddleware(), ListSessionHistoryHandler(sessionRepo))
+	router.POST("/sessions/:id/history", auth.AuthMiddleware(), CreateSessionSnapshotHandler(sessionRepo, snapshotService))
+	router.GET("/sessions/:id/history/diff", auth.AuthMiddleware(), DiffSessionSnapshotsHandler(sessionRepo))
+	router.GET("/sessions/:id/history/:snapshot_id", auth.AuthMiddleware(), GetSessionSnapshotHandler(sessionRepo))
+	router.POST("/sessions/:id/history/:snapshot_id/restore", auth.AuthMiddleware(), RestoreSessionSnapshotHandler(sessionRepo, snapshotService, codeUpdater))
+
 	// invite tokens (host only)
 	router.POST("/sessions/:id/invite", auth.AuthMiddleware(), CreateInviteTokenHandler(sessionRepo))
 	router.GET("/sessions/:id/invite", auth.AuthMiddleware(), ListInviteTokensHandler(sessionRepo))
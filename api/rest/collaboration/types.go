@@ -5,6 +5,7 @@ import (
 
 	"codeberg.org/algopatterns/server/algopatterns/sessions"
 	"codeberg.org/algopatterns/server/api/rest/pagination"
+	"codeberg.org/algopatterns/server/internal/diff"
 )
 
 // allows ending WebSocket sessions
@@ -12,6 +13,11 @@ type SessionEnder interface {
 	EndSession(sessionID string, reason string)
 }
 
+// applies server-originated code changes to live WebSocket sessions
+type CodeUpdater interface {
+	ApplyCodeUpdate(sessionID, userID, displayName, code, source string) error
+}
+
 type CreateSessionRequest struct {
 	Title          string `json:"title" binding:"required,max=200"`
 	Code           string `json:"code" binding:"max=1048576"` // 1MB limit
@@ -157,3 +163,43 @@ type IsLiveResponse struct {
 	HasActiveInviteTokens bool `json:"has_active_invite_tokens"`
 	IsDiscoverable        bool `json:"is_discoverable"`
 }
+
+// CreateSnapshotRequest for saving a named version of the session code
+type CreateSnapshotRequest struct {
+	Label string `json:"label,omitempty" binding:"max=200"`
+}
+
+// SnapshotResponse describes one saved version of the session code
+type SnapshotResponse struct {
+	ID           string                    `json:"id"`
+	SessionID    string                    `json:"session_id"`
+	Code         string                    `json:"code,omitempty"`
+	Authors      []sessions.SnapshotAuthor `json:"authors"`
+	Reason       string                    `json:"reason"`
+	Label        *string                   `json:"label,omitempty"`
+	CreatedBy    *string                   `json:"created_by,omitempty"`
+	RestoredFrom *string                   `json:"restored_from,omitempty"`
+	CreatedAt    time.Time                 `json:"created_at"`
+}
+
+// SnapshotsListResponse wraps a page of session snapshots
+type SnapshotsListResponse struct {
+	Snapshots  []SnapshotResponse `json:"snapshots"`
+	Pagination pagination.Meta    `json:"pagination"`
+}
+
+// SnapshotDiffResponse is a line diff between two versions of the session code.
+// To is "current" when diffing against the live code.
+type SnapshotDiffResponse struct {
+	From  string      `json:"from"`
+	To    string      `json:"to"`
+	Lines []diff.Line `json:"lines"`
+	Stats diff.Stats  `json:"stats"`
+}
+
+// RestoreSnapshotResponse returned after restoring a snapshot
+type RestoreSnapshotResponse struct {
+	Message  string           `json:"message"`
+	Code     string           `json:"code"`
+	Snapshot SnapshotResponse `json:"snapshot"`
+}
@@ -3,6 +3,7 @@ package health
 import (
 	"net/http"
 
+	"codeberg.org/algopatterns/server/internal/strudel"
 	"github.com/gin-gonic/gin"
 )
 
@@ -33,3 +34,24 @@ func PingHandler(c *gin.Context) {
 		Message: "pong",
 	})
 }
+
+// ValidatorHandler godoc
+// @Summary Validator status
+// @Description Get the code validator backend and, for the node pool, worker and queue metrics
+// @Tags health
+// @Produce json
+// @Success 200 {object} ValidatorResponse
+// @Router /health/validator [get]
+func ValidatorHandler(validator strudel.CodeValidator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch v := validator.(type) {
+		case *strudel.ValidatorPool:
+			stats := v.Stats()
+			c.JSON(http.StatusOK, ValidatorResponse{Backend: "node", Ready: stats.Ready > 0, Pool: &stats})
+		case nil:
+			c.JSON(http.StatusOK, ValidatorResponse{Backend: "none"})
+		default:
+			c.JSON(http.StatusOK, ValidatorResponse{Backend: "static", Ready: true})
+		}
+	}
+}
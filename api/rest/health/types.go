@@ -1,5 +1,7 @@
 package health
 
+import "codeberg.org/algopatterns/server/internal/strudel"
+
 type Response struct {
 	Status  string `json:"status"`
 	Service string `json:"service"`
@@ -9,3 +11,9 @@ type Response struct {
 type PingResponse struct {
 	Message string `json:"message"`
 }
+
+type ValidatorResponse struct {
+	Backend string             `json:"backend"` // "node", "static" or "none"
+	Ready   bool               `json:"ready"`
+	Pool    *strudel.PoolStats `json:"pool,omitempty"`
+}
@@ -1,9 +1,13 @@
 package strudels
 
 import (
+	stderrors "errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"codeberg.org/algopatterns/server/algopatterns/strudels"
 	"codeberg.org/algopatterns/server/api/rest/pagination"
@@ -12,12 +16,15 @@ import (
 	"codeberg.org/algopatterns/server/internal/auth"
 	"codeberg.org/algopatterns/server/internal/ccsignals"
 	"codeberg.org/algopatterns/server/internal/errors"
+	"codeberg.org/algopatterns/server/internal/lineage"
+	"codeberg.org/algopatterns/server/internal/logger"
+	"codeberg.org/algopatterns/server/internal/strudel"
 	"github.com/gin-gonic/gin"
 )
 
 // CreateStrudelHandler godoc
 // @Summary Create strudel
-// @Description Save a new Strudel pattern with code, title, and metadata
+// @Description Save a new Strudel pattern with code, title, and metadata. Forks default to their parent's license and must use a compatible one
 // @Tags strudels
 // @Accept json
 // @Produce json
@@ -49,6 +56,11 @@ func CreateStrudelHandler(strudelRepo *strudels.Repository, fpIndexer Fingerprin
 		}
 
 		strudel, err := strudelRepo.Create(c.Request.Context(), userID, req)
+		if licenseErr, ok := asLicenseError(err); ok {
+			errors.IncompatibleLicense(c, licenseErr.Error(), licenseErr.Allowed)
+			return
+		}
+
 		if err != nil {
 			errors.InternalError(c, "failed to create strudel", err)
 			return
@@ -265,6 +277,11 @@ func UpdateStrudelHandler(strudelRepo *strudels.Repository, fpIndexer Fingerprin
 		}
 
 		strudel, err := strudelRepo.Update(c.Request.Context(), strudelID, userID, req)
+		if licenseErr, ok := asLicenseError(err); ok {
+			errors.IncompatibleLicense(c, licenseErr.Error(), licenseErr.Allowed)
+			return
+		}
+
 		if err != nil {
 			errors.NotFound(c, "strudel")
 			return
@@ -384,6 +401,110 @@ func GetPublicStrudelHandler(strudelRepo *strudels.Repository) gin.HandlerFunc {
 	}
 }
 
+// SearchPublicStrudelsHandler godoc
+// @Summary Search public strudels
+// @Description Hybrid search over public strudels, combining embedding similarity with full text rank. At most 30 requests per minute
+// @Tags strudels
+// @Produce json
+// @Param q query string true "Search query (max 200 characters)"
+// @Param limit query int false "Items per page (max 100)" default(20)
+// @Param offset query int false "Number of items to skip" default(0)
+// @Success 200 {object} ScoredStrudelsListResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 429 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /api/v1/public/strudels/search [get]
+func SearchPublicStrudelsHandler(strudelRepo *strudels.Repository, embedder QueryEmbedder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := strings.TrimSpace(c.Query("q"))
+		if query == "" {
+			errors.BadRequest(c, "search query is required", nil)
+			return
+		}
+
+		if utf8.RuneCountInString(query) > maxSearchQueryLen {
+			errors.BadRequest(c, fmt.Sprintf("search query is too long, at most %d characters", maxSearchQueryLen), nil)
+			return
+		}
+
+		limit, offset := parsePaginationParams(c)
+		params := pagination.DefaultParams(limit, offset, 20, 100)
+		search := strudels.PublicSearch{Query: query}
+
+		// without an embedding the search falls back to full text only
+		if embedder != nil {
+			embedding, err := embedder.EmbedQuery(c.Request.Context(), query)
+			if err != nil {
+				logger.Warn("public strudel search without embedding", "error", err)
+			} else {
+				search.Embedding = embedding
+			}
+		}
+
+		results, total, err := strudelRepo.SearchPublic(c.Request.Context(), search, params.Limit, params.Offset)
+		if err != nil {
+			errors.InternalError(c, "failed to search public strudels", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, ScoredStrudelsListResponse{
+			Strudels:   results,
+			Pagination: pagination.NewMeta(params, total),
+		})
+	}
+}
+
+// SimilarPublicStrudelsHandler godoc
+// @Summary List similar public strudels
+// @Description Get public strudels similar to a public strudel, by its stored embedding and its title and tags
+// @Tags strudels
+// @Produce json
+// @Param id path string true "Strudel ID (UUID)"
+// @Param limit query int false "Items per page (max 100)" default(20)
+// @Param offset query int false "Number of items to skip" default(0)
+// @Success 200 {object} ScoredStrudelsListResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /api/v1/public/strudels/{id}/similar [get]
+func SimilarPublicStrudelsHandler(strudelRepo *strudels.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		strudelID := c.Param("id")
+
+		if !errors.IsValidUUID(strudelID) {
+			errors.BadRequest(c, "invalid strudel ID format", nil)
+			return
+		}
+
+		strudel, err := strudelRepo.GetPublic(c.Request.Context(), strudelID)
+		if err != nil {
+			errors.NotFound(c, "strudel")
+			return
+		}
+
+		limit, offset := parsePaginationParams(c)
+		params := pagination.DefaultParams(limit, offset, 20, 100)
+
+		// the stored embedding is used as is, code is never sent for embedding here
+		search := strudels.PublicSearch{
+			SimilarTo: strudelID,
+			Query:     similarityQuery(strudel),
+			ExcludeID: strudelID,
+		}
+
+		results, total, err := strudelRepo.SearchPublic(c.Request.Context(), search, params.Limit, params.Offset)
+		if err != nil {
+			errors.InternalError(c, "failed to find similar strudels", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, ScoredStrudelsListResponse{
+			Strudels:   results,
+			Pagination: pagination.NewMeta(params, total),
+		})
+	}
+}
+
 // ListPublicTagsHandler godoc
 // @Summary List public tags
 // @Description Get all unique tags from public strudels
@@ -461,6 +582,17 @@ func parsePaginationParams(c *gin.Context) (limit, offset int) {
 	return limit, offset
 }
 
+// reads a lineage depth, missing or invalid values use the default
+func parseDepthParam(c *gin.Context, name string) int {
+	if value, ok := c.GetQuery(name); ok {
+		if depth, err := parseInt(value); err == nil && depth >= 0 {
+			return depth
+		}
+	}
+
+	return lineage.DefaultDepth
+}
+
 func parseFilterParams(c *gin.Context) strudels.ListFilter {
 	filter := strudels.ListFilter{}
 
@@ -480,6 +612,35 @@ func parseFilterParams(c *gin.Context) strudels.ListFilter {
 	return filter
 }
 
+// builds a full text query matching any word of a strudel's title or tags
+func similarityQuery(strudel *strudels.Strudel) string {
+	isSeparator := func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}
+
+	seen := make(map[string]bool)
+	var words []string
+
+	for _, text := range append([]string{strudel.Title}, strudel.Tags...) {
+		for _, word := range strings.FieldsFunc(strings.ToLower(text), isSeparator) {
+			if !seen[word] {
+				seen[word] = true
+				words = append(words, word)
+			}
+		}
+	}
+
+	return strings.Join(words, " or ")
+}
+
+// unwraps a license compatibility error from the repository
+func asLicenseError(err error) (*strudels.LicenseError, bool) {
+	var licenseErr *strudels.LicenseError
+	ok := stderrors.As(err, &licenseErr)
+
+	return licenseErr, ok
+}
+
 // checks if the no-ai signal is being used with AI-assisted content
 func validateNoAISignal(signal *strudels.CCSignal, history strudels.ConversationHistory) error {
 	if signal == nil || *signal != strudels.CCSignalNoAI {
@@ -558,3 +719,342 @@ func convertDocRefs(refs []agent.DocReference) []strudels.DocReference {
 	}
 	return result
 }
+
+// ListStrudelRevisionsHandler godoc
+// @Summary List strudel revisions
+// @Description Get the revision history of a strudel, newest first (must be owner). Code is omitted, fetch a revision to read it.
+// @Tags strudels
+// @Produce json
+// @Param id path string true "Strudel ID (UUID)"
+// @Param limit query int false "Items per page (max 100)" default(20)
+// @Param offset query int false "Number of items to skip" default(0)
+// @Success 200 {object} RevisionsListResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /api/v1/strudels/{id}/revisions [get]
+// @Security BearerAuth
+func ListStrudelRevisionsHandler(strudelRepo *strudels.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := auth.GetUserID(c)
+		if !exists {
+			errors.Unauthorized(c, "")
+			return
+		}
+
+		strudelID, ok := errors.ValidatePathUUID(c, "id")
+		if !ok {
+			return
+		}
+
+		if _, err := strudelRepo.Get(c.Request.Context(), strudelID, userID); err != nil {
+			errors.NotFound(c, "strudel")
+			return
+		}
+
+		limit, offset := parsePaginationParams(c)
+		params := pagination.DefaultParams(limit, offset, 20, 100)
+
+		revisions, total, err := strudelRepo.ListRevisions(c.Request.Context(), strudelID, userID, params.Limit, params.Offset)
+		if err != nil {
+			errors.InternalError(c, "failed to list revisions", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, RevisionsListResponse{
+			Revisions:  revisions,
+			Pagination: pagination.NewMeta(params, total),
+		})
+	}
+}
+
+// GetStrudelRevisionHandler godoc
+// @Summary Get strudel revision
+// @Description Get a single revision of a strudel including its code (must be owner)
+// @Tags strudels
+// @Produce json
+// @Param id path string true "Strudel ID (UUID)"
+// @Param rev path int true "Revision number"
+// @Success 200 {object} strudels.Revision
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Router /api/v1/strudels/{id}/revisions/{rev} [get]
+// @Security BearerAuth
+func GetStrudelRevisionHandler(strudelRepo *strudels.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := auth.GetUserID(c)
+		if !exists {
+			errors.Unauthorized(c, "")
+			return
+		}
+
+		strudelID, ok := errors.ValidatePathUUID(c, "id")
+		if !ok {
+			return
+		}
+
+		rev, ok := parseRevisionParam(c)
+		if !ok {
+			return
+		}
+
+		revision, err := strudelRepo.GetRevision(c.Request.Context(), strudelID, userID, rev)
+		if err != nil {
+			errors.NotFound(c, "revision")
+			return
+		}
+
+		c.JSON(http.StatusOK, revision)
+	}
+}
+
+// RestoreStrudelRevisionHandler godoc
+// @Summary Restore strudel revision
+// @Description Roll a strudel's code, title and license back to a revision (must be owner). The rollback is saved as a new revision.
+// @Tags strudels
+// @Produce json
+// @Param id path string true "Strudel ID (UUID)"
+// @Param rev path int true "Revision number"
+// @Success 200 {object} RestoreRevisionResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 401 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Router /api/v1/strudels/{id}/revisions/{rev}/restore [post]
+// @Security BearerAuth
+func RestoreStrudelRevisionHandler(strudelRepo *strudels.Repository, fpIndexer FingerprintIndexer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := auth.GetUserID(c)
+		if !exists {
+			errors.Unauthorized(c, "")
+			return
+		}
+
+		strudelID, ok := errors.ValidatePathUUID(c, "id")
+		if !ok {
+			return
+		}
+
+		rev, ok := parseRevisionParam(c)
+		if !ok {
+			return
+		}
+
+		strudel, revision, err := strudelRepo.RestoreRevision(c.Request.Context(), strudelID, userID, rev)
+		if licenseErr, ok := asLicenseError(err); ok {
+			errors.IncompatibleLicense(c, licenseErr.Error(), licenseErr.Allowed)
+			return
+		}
+
+		if err != nil {
+			errors.NotFound(c, "revision")
+			return
+		}
+
+		// restored code replaces the indexed code, same as a code update
+		if fpIndexer != nil {
+			if strudel.CCSignal != nil {
+				fpIndexer.UpdateStrudel(strudel.ID, strudel.UserID, strudel.Code, ccsignals.CCSignal(*strudel.CCSignal))
+			} else {
+				fpIndexer.RemoveStrudel(strudel.ID)
+			}
+		}
+
+		c.JSON(http.StatusOK, RestoreRevisionResponse{
+			Strudel:  strudel,
+			Revision: revision,
+		})
+	}
+}
+
+// parses the :rev path parameter, writing a 400 when it is not a positive integer
+func parseRevisionParam(c *gin.Context) (int, bool) {
+	rev, err := strconv.Atoi(c.Param("rev"))
+	if err != nil || rev < 1 {
+		errors.BadRequest(c, "revision must be a positive integer", nil)
+		return 0, false
+	}
+
+	return rev, true
+}
+
+// LintStrudelHandler godoc
+// @Summary Lint strudel code
+// @Description Flag likely musical mistakes: unknown sounds, notes outside the declared scale, out of range effect values, fast() on dense patterns and unused variables. At most 64 KB of code and 60 requests per minute
+// @Tags strudels
+// @Accept json
+// @Produce json
+// @Param request body LintRequest true "Code to lint"
+// @Success 200 {object} LintResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 429 {object} errors.ErrorResponse
+// @Router /api/v1/strudels/lint [post]
+func LintStrudelHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxCodeToolBodySize)
+
+		var req LintRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			errors.ValidationError(c, err)
+			return
+		}
+
+		findings := strudel.Lint(req.Code)
+
+		response := LintResponse{Findings: make([]LintFindingDTO, len(findings))}
+		for i, finding := range findings {
+			response.Findings[i] = LintFindingDTO{
+				Rule:     finding.Rule,
+				Severity: string(finding.Severity),
+				Message:  finding.Message,
+				Range:    convertRange(finding.Range),
+			}
+
+			if finding.Fix != nil {
+				response.Findings[i].Fix = &LintFixDTO{
+					Description: finding.Fix.Description,
+					Range:       convertRange(finding.Fix.Range),
+					Replacement: finding.Fix.Replacement,
+				}
+			}
+		}
+
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// FormatStrudelHandler godoc
+// @Summary Format strudel code
+// @Description Format code deterministically: method chain indentation, stack() layers one per line, double quoted mini-notation with normalized spacing. Comments are kept. At most 64 KB of code and 60 requests per minute
+// @Tags strudels
+// @Accept json
+// @Produce json
+// @Param request body FormatRequest true "Code to format"
+// @Success 200 {object} FormatResponse
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 429 {object} errors.ErrorResponse
+// @Router /api/v1/strudels/format [post]
+func FormatStrudelHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxCodeToolBodySize)
+
+		var req FormatRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			errors.ValidationError(c, err)
+			return
+		}
+
+		formatted, err := strudel.Format(req.Code)
+		if err != nil {
+			errors.BadRequest(c, "code cannot be formatted", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, FormatResponse{Code: formatted})
+	}
+}
+
+// converts a source range to its API form
+func convertRange(r strudel.Range) RangeDTO {
+	return RangeDTO{
+		StartLine:   r.Start.Line,
+		StartColumn: r.Start.Column,
+		EndLine:     r.End.Line,
+		EndColumn:   r.End.Column,
+	}
+}
+
+// GetStrudelCreditsHandler godoc
+// @Summary Get strudel credits
+// @Description Get the credits of a public strudel: the strudels it was forked from, the example strudels and docs the AI assistant used. Formats: json, text or spdx (REUSE-style SPDX tags)
+// @Tags strudels
+// @Produce json
+// @Produce plain
+// @Param id path string true "Strudel ID (UUID)"
+// @Param format query string false "Output format" Enums(json, text, spdx) default(json)
+// @Success 200 {object} attribution.StrudelCredits
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /api/v1/public/strudels/{id}/credits [get]
+func GetStrudelCreditsHandler(strudelRepo *strudels.Repository, attrService *attribution.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		strudelID := c.Param("id")
+
+		if !errors.IsValidUUID(strudelID) {
+			errors.BadRequest(c, "invalid strudel ID format", nil)
+			return
+		}
+
+		format := c.DefaultQuery("format", "json")
+		if format != "json" && format != "text" && format != "spdx" {
+			errors.BadRequest(c, "format must be json, text or spdx", nil)
+			return
+		}
+
+		// verify strudel exists and is public
+		_, err := strudelRepo.GetPublic(c.Request.Context(), strudelID)
+		if err != nil {
+			errors.NotFound(c, "strudel")
+			return
+		}
+
+		credits, err := attrService.GetStrudelCredits(c.Request.Context(), strudelID)
+		if err != nil {
+			errors.InternalError(c, "failed to get strudel credits", err)
+			return
+		}
+
+		switch format {
+		case "text":
+			c.String(http.StatusOK, attribution.FormatCreditsText(credits))
+		case "spdx":
+			c.String(http.StatusOK, attribution.FormatCreditsSPDX(credits))
+		default:
+			c.JSON(http.StatusOK, credits)
+		}
+	}
+}
+
+// GetStrudelLineageHandler godoc
+// @Summary Get strudel lineage
+// @Description Get the fork ancestors and descendant tree of a public strudel, with CC signals inherited along each fork
+// @Tags strudels
+// @Produce json
+// @Param id path string true "Strudel ID (UUID)"
+// @Param ancestor_depth query int false "Ancestors to list (max 20)" default(5)
+// @Param descendant_depth query int false "Fork levels to list (max 20)" default(5)
+// @Success 200 {object} lineage.Lineage
+// @Failure 400 {object} errors.ErrorResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Failure 500 {object} errors.ErrorResponse
+// @Router /api/v1/public/strudels/{id}/lineage [get]
+func GetStrudelLineageHandler(lineageService *lineage.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		strudelID := c.Param("id")
+
+		if !errors.IsValidUUID(strudelID) {
+			errors.BadRequest(c, "invalid strudel ID format", nil)
+			return
+		}
+
+		opts := lineage.Options{
+			AncestorDepth:   parseDepthParam(c, "ancestor_depth"),
+			DescendantDepth: parseDepthParam(c, "descendant_depth"),
+		}
+
+		result, err := lineageService.Get(c.Request.Context(), strudelID, opts)
+		if err != nil {
+			if stderrors.Is(err, lineage.ErrStrudelNotFound) {
+				errors.NotFound(c, "strudel")
+				return
+			}
+
+			errors.InternalError(c, "failed to get strudel lineage", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
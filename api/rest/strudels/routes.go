@@ -1,10 +1,13 @@
 package strudels
 
 import (
+	"context"
+
 	"codeberg.org/algopatterns/server/algopatterns/strudels"
 	"codeberg.org/algopatterns/server/internal/attribution"
 	"codeberg.org/algopatterns/server/internal/auth"
 	"codeberg.org/algopatterns/server/internal/ccsignals"
+	"codeberg.org/algopatterns/server/internal/lineage"
 	"github.com/gin-gonic/gin"
 )
 
@@ -15,10 +18,39 @@ type FingerprintIndexer interface {
 	RemoveStrudel(strudelID string)
 }
 
-func RegisterRoutes(router *gin.RouterGroup, strudelRepo *strudels.Repository, attrService *attribution.Service, fpIndexer FingerprintIndexer) {
+// embeds search queries with the model used for stored strudel embeddings
+type QueryEmbedder interface {
+	EmbedQuery(ctx context.Context, text string) ([]float32, error)
+}
+
+// limits requests per client to routes that are costly to serve
+type RateLimiter interface {
+	RouteRateLimit(route string, limit int) gin.HandlerFunc
+}
+
+const (
+	codeToolRateLimit   = 60        // requests per client and minute to the anonymous code tools
+	maxCodeToolBodySize = 128 << 10 // 64KB of code with room for JSON escaping
+	searchRateLimit     = 30        // requests per client and minute to public search, each embeds its query
+	maxSearchQueryLen   = 200       // characters
+)
+
+func RegisterRoutes(
+	router *gin.RouterGroup,
+	strudelRepo *strudels.Repository,
+	attrService *attribution.Service,
+	lineageService *lineage.Service,
+	fpIndexer FingerprintIndexer,
+	embedder QueryEmbedder,
+	limiter RateLimiter,
+) {
 	// GET strudel by ID - allows owner OR public access (optional auth)
 	router.GET("/strudels/:id", auth.OptionalAuthMiddleware(), GetStrudelHandler(strudelRepo))
 
+	// code tools, no account needed
+	router.POST("/strudels/lint", limiter.RouteRateLimit("lint", codeToolRateLimit), LintStrudelHandler())
+	router.POST("/strudels/format", limiter.RouteRateLimit("format", codeToolRateLimit), FormatStrudelHandler())
+
 	// authenticated strudel operations
 	strudelsGroup := router.Group("/strudels")
 	strudelsGroup.Use(auth.AuthMiddleware())
@@ -28,11 +60,20 @@ func RegisterRoutes(router *gin.RouterGroup, strudelRepo *strudels.Repository, a
 		strudelsGroup.GET("/tags", ListUserTagsHandler(strudelRepo))
 		strudelsGroup.PUT("/:id", UpdateStrudelHandler(strudelRepo, fpIndexer))
 		strudelsGroup.DELETE("/:id", DeleteStrudelHandler(strudelRepo, fpIndexer))
+
+		// revision history (owner only)
+		strudelsGroup.GET("/:id/revisions", ListStrudelRevisionsHandler(strudelRepo))
+		strudelsGroup.GET("/:id/revisions/:rev", GetStrudelRevisionHandler(strudelRepo))
+		strudelsGroup.POST("/:id/revisions/:rev/restore", RestoreStrudelRevisionHandler(strudelRepo, fpIndexer))
 	}
 
 	// public strudels (no auth required)
 	router.GET("/public/strudels", ListPublicStrudelsHandler(strudelRepo))
 	router.GET("/public/strudels/tags", ListPublicTagsHandler(strudelRepo))
+	router.GET("/public/strudels/search", limiter.RouteRateLimit("search", searchRateLimit), SearchPublicStrudelsHandler(strudelRepo, embedder))
 	router.GET("/public/strudels/:id", GetPublicStrudelHandler(strudelRepo))
+	router.GET("/public/strudels/:id/similar", SimilarPublicStrudelsHandler(strudelRepo))
 	router.GET("/public/strudels/:id/stats", GetStrudelStatsHandler(strudelRepo, attrService))
+	router.GET("/public/strudels/:id/lineage", GetStrudelLineageHandler(lineageService))
+	router.GET("/public/strudels/:id/credits", GetStrudelCreditsHandler(strudelRepo, attrService))
 }
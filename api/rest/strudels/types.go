@@ -13,6 +13,12 @@ type StrudelsListResponse struct {
 	Pagination pagination.Meta    `json:"pagination"`
 }
 
+// ScoredStrudelsListResponse wraps search results, best match first, with pagination
+type ScoredStrudelsListResponse struct {
+	Strudels   []strudels.ScoredStrudel `json:"strudels"`
+	Pagination pagination.Meta          `json:"pagination"`
+}
+
 // MessageResponse for simple success messages
 type MessageResponse struct {
 	Message string `json:"message"`
@@ -68,3 +74,59 @@ type ConversationMessageDTO struct {
 	DocReferences       []DocReferenceDTO     `json:"doc_references,omitempty"`
 	CreatedAt           time.Time             `json:"created_at"`
 }
+
+// RevisionsListResponse wraps a page of strudel revisions (code omitted)
+type RevisionsListResponse struct {
+	Revisions  []strudels.Revision `json:"revisions"`
+	Pagination pagination.Meta     `json:"pagination"`
+}
+
+// RestoreRevisionResponse returned after rolling a strudel back to a revision
+type RestoreRevisionResponse struct {
+	Strudel  *strudels.Strudel  `json:"strudel"`
+	Revision *strudels.Revision `json:"revision"`
+}
+
+// LintRequest carries code to lint
+type LintRequest struct {
+	Code string `json:"code" binding:"required,max=65536"` // 64KB limit
+}
+
+// LintResponse lists findings ordered by position
+type LintResponse struct {
+	Findings []LintFindingDTO `json:"findings"`
+}
+
+// LintFindingDTO is a likely musical mistake in the code
+type LintFindingDTO struct {
+	Rule     string      `json:"rule"`
+	Severity string      `json:"severity"` // "error", "warning" or "info"
+	Message  string      `json:"message"`
+	Range    RangeDTO    `json:"range"`
+	Fix      *LintFixDTO `json:"fix,omitempty"`
+}
+
+// LintFixDTO replaces the range with the replacement text
+type LintFixDTO struct {
+	Description string   `json:"description"`
+	Range       RangeDTO `json:"range"`
+	Replacement string   `json:"replacement"`
+}
+
+// FormatRequest carries code to format
+type FormatRequest struct {
+	Code string `json:"code" binding:"required,max=65536"` // 64KB limit
+}
+
+// FormatResponse holds the formatted code
+type FormatResponse struct {
+	Code string `json:"code"`
+}
+
+// RangeDTO is a span of code, lines and columns are 1-based and end is exclusive
+type RangeDTO struct {
+	StartLine   int `json:"start_line"`
+	StartColumn int `json:"start_column"`
+	EndLine     int `json:"end_line"`
+	EndColumn   int `json:"end_column"`
+}
@@ -274,6 +274,8 @@ func WebSocketHandler(hub *ws.Hub, sessionRepo sessions.Repository, userRepo *us
 		}
 
 		client := ws.NewClient(clientID, params.SessionID, userID, displayName, role, ipAddress, initialCode, chatHistory, isAuthenticated, conn, hub)
+		client.LastSequence = params.LastSeq
+		client.PreviousClientID = params.PreviousClientID
 
 		// add participant to session (authenticated or anonymous)
 		// note: anonymous hosts are not added to participants table as they're already tracked via the session itself
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+
+	"codeberg.org/algopatterns/server/internal/config"
+	"codeberg.org/algopatterns/server/internal/evaluation"
+	"codeberg.org/algopatterns/server/internal/llm"
+	"codeberg.org/algopatterns/server/internal/logger"
+	"codeberg.org/algopatterns/server/internal/retriever"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// scores vector, BM25 and hybrid doc search against a golden query set
+func main() {
+	flags := config.ParseEvalFlags()
+
+	if flags.K <= 0 {
+		logger.Fatal("--k must be positive", "k", flags.K)
+	}
+
+	for _, method := range flags.Methods {
+		if _, ok := searchMethods[method]; !ok {
+			logger.Fatal("unknown search method", "method", method, "supported", "vector, bm25, hybrid")
+		}
+	}
+
+	golden, err := evaluation.LoadGoldenSet(flags.Golden)
+	if err != nil {
+		logger.Fatal("failed to load golden set", "error", err)
+	}
+
+	cfg, err := config.LoadEnvironmentVariables()
+	if err != nil {
+		logger.Fatal("failed to load configuration", "error", err)
+	}
+
+	ctx := context.Background()
+	db, err := pgxpool.New(ctx, cfg.SupabaseConnString)
+	if err != nil {
+		logger.Fatal("failed to create database pool", "error", err)
+	}
+
+	defer db.Close()
+
+	if err := db.Ping(ctx); err != nil {
+		logger.Fatal("failed to ping database", "error", err)
+	}
+
+	if flags.Seed != "" {
+		if err := seedDocs(ctx, db, flags.Seed); err != nil {
+			logger.Fatal("failed to seed docs", "error", err)
+		}
+	}
+
+	llmClient, err := llm.NewLLM(ctx)
+	if err != nil {
+		logger.Fatal("failed to create LLM client", "error", err)
+	}
+
+	client := retriever.New(db, llmClient)
+	results := make([]methodMetrics, 0, len(flags.Methods))
+
+	for _, method := range flags.Methods {
+		metrics, err := evaluateMethod(ctx, client, method, golden, flags.K)
+		if err != nil {
+			logger.Fatal("failed to evaluate search method", "method", method, "error", err)
+		}
+
+		results = append(results, methodMetrics{Method: method, Metrics: metrics})
+	}
+
+	if flags.JSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+
+		if err := encoder.Encode(results); err != nil {
+			logger.Fatal("failed to encode metrics", "error", err)
+		}
+
+		return
+	}
+
+	printMetrics(os.Stdout, results, flags.Verbose)
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"context"
+
+	"codeberg.org/algopatterns/server/internal/evaluation"
+	"codeberg.org/algopatterns/server/internal/retriever"
+)
+
+// runs one search method for a golden query
+type searchFunc func(ctx context.Context, client *retriever.Client, query evaluation.GoldenQuery, k int) ([]retriever.SearchResult, error)
+
+type methodMetrics struct {
+	Method string `json:"method"`
+	evaluation.Metrics
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"codeberg.org/algopatterns/server/internal/chunker"
+	"codeberg.org/algopatterns/server/internal/evaluation"
+	"codeberg.org/algopatterns/server/internal/llm"
+	"codeberg.org/algopatterns/server/internal/logger"
+	"codeberg.org/algopatterns/server/internal/retriever"
+	"codeberg.org/algopatterns/server/internal/storage"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var searchMethods = map[string]searchFunc{
+	"vector": func(ctx context.Context, client *retriever.Client, query evaluation.GoldenQuery, k int) ([]retriever.SearchResult, error) {
+		return client.VectorSearch(ctx, query.Query, k)
+	},
+	"bm25": func(ctx context.Context, client *retriever.Client, query evaluation.GoldenQuery, k int) ([]retriever.SearchResult, error) {
+		return client.BM25Search(ctx, query.Query, k)
+	},
+	"hybrid": func(ctx context.Context, client *retriever.Client, query evaluation.GoldenQuery, k int) ([]retriever.SearchResult, error) {
+		// the fused ranking, page organization reorders chunks and adds page summaries
+		return client.HybridRankDocs(ctx, query.Query, query.EditorState, k, retriever.SearchOptions{Rerank: true})
+	},
+}
+
+// runs every golden query through method and scores the results
+func evaluateMethod(ctx context.Context, client *retriever.Client, method string, golden *evaluation.GoldenSet, k int) (evaluation.Metrics, error) {
+	search := searchMethods[method]
+	results := make([]evaluation.QueryResult, 0, len(golden.Queries))
+
+	for _, query := range golden.Queries {
+		docs, err := search(ctx, client, query, k)
+		if err != nil {
+			return evaluation.Metrics{}, fmt.Errorf("failed to search %q: %w", query.Query, err)
+		}
+
+		ranked := make([]evaluation.RankedDoc, len(docs))
+		for i, doc := range docs {
+			ranked[i] = evaluation.RankedDoc{Page: doc.PageName, Section: doc.SectionTitle}
+		}
+
+		results = append(results, evaluation.Evaluate(query, ranked, k))
+	}
+
+	return evaluation.Summarize(results, k), nil
+}
+
+// chunks, embeds and upserts the fixture docs. only allowed against a local
+// database so the evaluation fixtures never end up in a shared one.
+func seedDocs(ctx context.Context, db *pgxpool.Pool, docsPath string) error {
+	host := db.Config().ConnConfig.Host
+	if host != "localhost" && host != "127.0.0.1" && host != "::1" {
+		return fmt.Errorf("refusing to seed fixtures into non-local database host %q", host)
+	}
+
+	storageClient := storage.NewClientFromPool(db)
+
+	configured, err := llm.NewEmbedder(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create embedder: %w", err)
+	}
+
+	// fixtures are embedded with the model retrieval queries them with
+	embedder, model, err := retriever.NewActiveEmbedder(db, configured).Resolve(ctx)
+	if err != nil {
+		return err
+	}
+
+	chunks, errors := chunker.ChunkDocuments(docsPath)
+	if len(errors) > 0 {
+		return fmt.Errorf("failed to chunk fixtures: %w", errors[0])
+	}
+
+	if len(chunks) == 0 {
+		return fmt.Errorf("no chunks generated from %s", docsPath)
+	}
+
+	texts := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		texts[i] = chunk.Content
+	}
+
+	embeddings, err := embedder.GenerateEmbeddings(ctx, texts)
+	if err != nil {
+		return fmt.Errorf("failed to generate embeddings: %w", err)
+	}
+
+	if err := storageClient.UpsertChunksBatch(ctx, chunks, embeddings, model); err != nil {
+		return err
+	}
+
+	logger.Info("seeded fixture docs", "path", docsPath, "chunks", len(chunks), "model", model)
+
+	return nil
+}
+
+// prints a summary table and, if verbose, the scores of each query
+func printMetrics(w io.Writer, results []methodMetrics, verbose bool) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	if len(results) > 0 {
+		k := results[0].K
+		fmt.Fprintf(tw, "method\trecall@%d\tmrr\tndcg@%d\n", k, k)
+	}
+
+	for _, result := range results {
+		fmt.Fprintf(tw, "%s\t%.3f\t%.3f\t%.3f\n", result.Method, result.Recall, result.MRR, result.NDCG)
+	}
+
+	tw.Flush() //nolint:errcheck,gosec // output goes to stdout
+
+	if !verbose {
+		return
+	}
+
+	for _, result := range results {
+		fmt.Fprintf(w, "\n%s\n", result.Method)
+
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "query\tfirst rank\trecall\tndcg")
+
+		for _, query := range result.Queries {
+			fmt.Fprintf(tw, "%s\t%d\t%.3f\t%.3f\n", query.Query, query.FirstRank, query.Recall, query.NDCG)
+		}
+
+		tw.Flush() //nolint:errcheck,gosec // output goes to stdout
+	}
+}
@@ -6,14 +6,13 @@ import (
 
 	"codeberg.org/algopatterns/server/internal/chunker"
 	"codeberg.org/algopatterns/server/internal/config"
-	"codeberg.org/algopatterns/server/internal/llm"
 	"codeberg.org/algopatterns/server/internal/logger"
 	"codeberg.org/algopatterns/server/internal/storage"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // chunks and embeds teaching concept files from MDX
-func IngestConcepts(cfg *config.Config, db *pgxpool.Pool, flags config.Flags) error {
+func IngestConcepts(_ *config.Config, db *pgxpool.Pool, flags config.Flags) error {
 	ctx := context.Background()
 
 	logger.Info("starting concepts ingestion", "path", flags.Path, "clear", flags.Clear)
@@ -47,32 +46,19 @@ func IngestConcepts(cfg *config.Config, db *pgxpool.Pool, flags config.Flags) er
 
 	logger.Info("generated concept chunks", "count", len(chunks))
 
-	// create OpenAI embedder
-	embedder := llm.NewOpenAIEmbedder(llm.OpenAIConfig{
-		APIKey: cfg.OpenAIKey,
-		Model:  "text-embedding-3-small",
-	})
-
-	// generate embeddings for all concept chunks
-	logger.Info("generating embeddings for concept chunks")
-	texts := make([]string, len(chunks))
-
-	for i, chunk := range chunks {
-		texts[i] = chunk.Content
+	// new vectors must match the ones retrieval compares them with
+	embedder, model, err := activeEmbedder(ctx, db)
+	if err != nil {
+		return err
 	}
 
-	embeddings, err := embedder.GenerateEmbeddings(ctx, texts)
+	// only new and changed chunks are embedded, chunks of removed pages are deleted
+	summary, err := syncChunks(ctx, storageClient, embedder, model, chunks, chunker.SourceURLPrefix(flags.Path), len(errors) > 0)
 	if err != nil {
-		return fmt.Errorf("failed to generate embeddings: %w", err)
+		return err
 	}
 
-	logger.Info("generated embeddings", "count", len(embeddings))
-
-	// insert concept chunks with embeddings into database
-	logger.Info("inserting concept chunks into database")
-	if err := storageClient.InsertChunksBatch(ctx, chunks, embeddings); err != nil {
-		return fmt.Errorf("failed to insert concept chunks: %w", err)
-	}
+	logChanges(summary)
 
 	// verify insertion
 	count, err := storageClient.GetChunkCount(ctx)
@@ -81,7 +67,7 @@ func IngestConcepts(cfg *config.Config, db *pgxpool.Pool, flags config.Flags) er
 	}
 
 	logger.Info("successfully ingested concepts",
-		"chunks_inserted", len(chunks),
+		"chunks_processed", len(chunks),
 		"total_chunks", count,
 	)
 
@@ -6,14 +6,13 @@ import (
 
 	"codeberg.org/algopatterns/server/internal/chunker"
 	"codeberg.org/algopatterns/server/internal/config"
-	"codeberg.org/algopatterns/server/internal/llm"
 	"codeberg.org/algopatterns/server/internal/logger"
 	"codeberg.org/algopatterns/server/internal/storage"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // chunks and embeds documentation files from the specified path
-func IngestDocs(cfg *config.Config, db *pgxpool.Pool, flags config.Flags) error {
+func IngestDocs(_ *config.Config, db *pgxpool.Pool, flags config.Flags) error {
 	ctx := context.Background()
 	logger.Info("starting docs ingestion", "path", flags.Path, "clear", flags.Clear)
 
@@ -21,6 +20,12 @@ func IngestDocs(cfg *config.Config, db *pgxpool.Pool, flags config.Flags) error
 	storageClient := storage.NewClientFromPool(db)
 	defer storageClient.Close() // no-op since we don't own the pool
 
+	// resolved before clearing so a failed lookup leaves the docs intact
+	embedder, model, err := activeEmbedder(ctx, db)
+	if err != nil {
+		return err
+	}
+
 	// clear existing docs if requested
 	if flags.Clear {
 		logger.Info("clearing existing documentation chunks")
@@ -50,32 +55,13 @@ func IngestDocs(cfg *config.Config, db *pgxpool.Pool, flags config.Flags) error
 
 	logger.Info("generated chunks", "count", len(chunks))
 
-	// create OpenAI embedder
-	embedder := llm.NewOpenAIEmbedder(llm.OpenAIConfig{
-		APIKey: cfg.OpenAIKey,
-		Model:  "text-embedding-3-small",
-	})
-
-	// generate embeddings for all chunks
-	logger.Info("generating embeddings for chunks")
-	texts := make([]string, len(chunks))
-
-	for i, chunk := range chunks {
-		texts[i] = chunk.Content
-	}
-
-	embeddings, err := embedder.GenerateEmbeddings(ctx, texts)
+	// only new and changed chunks are embedded, chunks of removed pages are deleted
+	summary, err := syncChunks(ctx, storageClient, embedder, model, chunks, chunker.SourceURLPrefix(flags.Path), len(errors) > 0)
 	if err != nil {
-		return fmt.Errorf("failed to generate embeddings: %w", err)
+		return err
 	}
 
-	logger.Info("generated embeddings", "count", len(embeddings))
-
-	// insert chunks with embeddings into database
-	logger.Info("inserting chunks into database")
-	if err := storageClient.InsertChunksBatch(ctx, chunks, embeddings); err != nil {
-		return fmt.Errorf("failed to insert chunks: %w", err)
-	}
+	logChanges(summary)
 
 	// verify insertion
 	count, err := storageClient.GetChunkCount(ctx)
@@ -84,7 +70,7 @@ func IngestDocs(cfg *config.Config, db *pgxpool.Pool, flags config.Flags) error
 	}
 
 	logger.Info("successfully ingested documentation",
-		"chunks_inserted", len(chunks),
+		"chunks_processed", len(chunks),
 		"total_chunks", count,
 	)
 
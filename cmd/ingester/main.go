@@ -17,9 +17,15 @@ func main() {
 		fmt.Println("  docs      - ingest documentation from markdown files")
 		fmt.Println("  concepts  - ingest teaching concepts from MDX files")
 		fmt.Println("  all       - ingest everything (docs, concepts)")
+		fmt.Println("  reembed   - embed stored docs and strudels with a new model")
 		fmt.Println("\nOptions:")
 		fmt.Println("  --path <path>  - Custom path to ingest from")
 		fmt.Println("  --clear        - Clear existing data before ingesting")
+		fmt.Println("\nReembed options:")
+		fmt.Println("  --model <name>     - Embedding model to build (required)")
+		fmt.Println("  --provider <name>  - Embedding provider: openai, local (default openai)")
+		fmt.Println("  --batch <n>        - Texts embedded per request (default 100)")
+		fmt.Println("  --cutover          - Switch retrieval to the model once it is complete")
 		os.Exit(1)
 	}
 
@@ -85,6 +91,12 @@ func main() {
 
 		logger.Info("successfully ingested all data")
 
+	case "reembed":
+		flags := config.ParseReembedFlags()
+		if err := Reembed(cfg, db, flags); err != nil {
+			logger.Fatal("failed to re-embed", "error", err)
+		}
+
 	default:
 		fmt.Printf("Unknown command: %s\n", command)
 		os.Exit(1)
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"codeberg.org/algopatterns/server/internal/config"
+	"codeberg.org/algopatterns/server/internal/llm"
+	"codeberg.org/algopatterns/server/internal/logger"
+	"codeberg.org/algopatterns/server/internal/storage"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// embeds stored doc chunks and strudels with a new model next to the live
+// vectors, which keep serving queries. with --cutover the model is activated
+// in one transaction once every chunk has a vector. safe to rerun, only rows
+// without a staged vector are embedded.
+func Reembed(_ *config.Config, db *pgxpool.Pool, flags config.ReembedFlags) error {
+	if flags.Model == "" {
+		return fmt.Errorf("--model is required")
+	}
+
+	if flags.BatchSize <= 0 {
+		return fmt.Errorf("--batch must be positive")
+	}
+
+	ctx := context.Background()
+	logger.Info("starting re-embed", "model", flags.Model, "provider", flags.Provider, "cutover", flags.Cutover)
+
+	// use shared connection pool
+	storageClient := storage.NewClientFromPool(db)
+	defer storageClient.Close() // no-op since we don't own the pool
+
+	embedder, err := llm.NewEmbedderForModel(ctx, llm.Provider(flags.Provider), flags.Model)
+	if err != nil {
+		return fmt.Errorf("failed to create embedder: %w", err)
+	}
+
+	// the dimension is only known once the model has produced a vector
+	probe, err := embedder.GenerateEmbedding(ctx, "strudel")
+	if err != nil {
+		return fmt.Errorf("failed to probe embedding dimension: %w", err)
+	}
+
+	registered, err := storageClient.RegisterEmbeddingModel(ctx, flags.Model, flags.Provider, len(probe))
+	if err != nil {
+		return err
+	}
+
+	if registered.Status == "active" {
+		logger.Info("embedding model is already active, nothing to do", "model", flags.Model)
+		return nil
+	}
+
+	if registered.Dimension != len(probe) {
+		return fmt.Errorf("model %s was registered with dimension %d, but returns %d", flags.Model, registered.Dimension, len(probe))
+	}
+
+	chunks, err := stageMissingEmbeddings(ctx, embedder, flags.Model, flags.BatchSize, "chunks",
+		storageClient.ListChunksMissingEmbedding, storageClient.StageChunkEmbeddings)
+	if err != nil {
+		return fmt.Errorf("failed to re-embed chunks: %w", err)
+	}
+
+	strudels, err := stageMissingEmbeddings(ctx, embedder, flags.Model, flags.BatchSize, "strudels",
+		storageClient.ListStrudelsMissingEmbedding, storageClient.StageStrudelEmbeddings)
+	if err != nil {
+		return fmt.Errorf("failed to re-embed strudels: %w", err)
+	}
+
+	logger.Info("re-embed complete",
+		"model", flags.Model,
+		"dimension", registered.Dimension,
+		"chunks_embedded", chunks,
+		"strudels_embedded", strudels,
+	)
+
+	if !flags.Cutover {
+		logger.Info("retrieval still uses the active model, rerun with --cutover to switch")
+		return nil
+	}
+
+	if err := storageClient.ActivateEmbeddingModel(ctx, flags.Model); err != nil {
+		return err
+	}
+
+	logger.Info("activated embedding model, ingestion and running servers switch to it",
+		"model", flags.Model,
+		"provider", flags.Provider,
+	)
+
+	return nil
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"codeberg.org/algopatterns/server/internal/storage"
+)
+
+// embeds every text as a vector holding its length
+type lengthEmbedder struct {
+	calls int
+	drop  bool // return one embedding less than asked for
+}
+
+func (e *lengthEmbedder) GenerateEmbedding(_ context.Context, text string) ([]float32, error) {
+	return []float32{float32(len(text))}, nil
+}
+
+func (e *lengthEmbedder) GenerateEmbeddings(_ context.Context, texts []string) ([][]float32, error) {
+	e.calls++
+
+	embeddings := make([][]float32, 0, len(texts))
+	for _, text := range texts {
+		embeddings = append(embeddings, []float32{float32(len(text))})
+	}
+
+	if e.drop {
+		embeddings = embeddings[1:]
+	}
+
+	return embeddings, nil
+}
+
+// rows to embed, staged ones are no longer listed
+type stagingTable struct {
+	rows   []storage.EmbeddingSource
+	staged map[string][]float32
+	limits []int
+}
+
+func newStagingTable(n int) *stagingTable {
+	table := &stagingTable{staged: make(map[string][]float32)}
+
+	for i := range n {
+		table.rows = append(table.rows, storage.EmbeddingSource{ID: fmt.Sprintf("row-%d", i), Text: fmt.Sprintf("text %d", i)})
+	}
+
+	return table
+}
+
+func (s *stagingTable) list(_ context.Context, _ string, limit int) ([]storage.EmbeddingSource, error) {
+	s.limits = append(s.limits, limit)
+
+	var missing []storage.EmbeddingSource
+	for _, row := range s.rows {
+		if _, ok := s.staged[row.ID]; !ok && len(missing) < limit {
+			missing = append(missing, row)
+		}
+	}
+
+	return missing, nil
+}
+
+func (s *stagingTable) stage(_ context.Context, _ string, sources []storage.EmbeddingSource, embeddings [][]float32) error {
+	if len(sources) != len(embeddings) {
+		return fmt.Errorf("sources and embeddings length mismatch")
+	}
+
+	for i, source := range sources {
+		s.staged[source.ID] = embeddings[i]
+	}
+
+	return nil
+}
+
+func TestStageMissingEmbeddings(t *testing.T) {
+	tests := []struct {
+		name        string
+		rows        int
+		batchSize   int
+		wantBatches int
+	}{
+		{"nothing to embed", 0, 10, 0},
+		{"single partial batch", 3, 10, 1},
+		{"exact batches", 20, 10, 2},
+		{"last batch partial", 25, 10, 3},
+		{"batch of one", 3, 1, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := newStagingTable(tt.rows)
+			embedder := &lengthEmbedder{}
+
+			total, err := stageMissingEmbeddings(context.Background(), embedder, "nomic-embed-text", tt.batchSize, "chunks", table.list, table.stage)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if total != tt.rows {
+				t.Errorf("expected %d rows staged, got %d", tt.rows, total)
+			}
+
+			if embedder.calls != tt.wantBatches {
+				t.Errorf("expected %d embedding requests, got %d", tt.wantBatches, embedder.calls)
+			}
+
+			if len(table.staged) != tt.rows {
+				t.Errorf("expected %d staged rows, got %d", tt.rows, len(table.staged))
+			}
+
+			for _, row := range table.rows {
+				if got := table.staged[row.ID]; len(got) != 1 || got[0] != float32(len(row.Text)) {
+					t.Errorf("row %s staged with the wrong embedding: %v", row.ID, got)
+				}
+			}
+
+			for _, limit := range table.limits {
+				if limit != tt.batchSize {
+					t.Errorf("expected rows listed %d at a time, got %d", tt.batchSize, limit)
+				}
+			}
+		})
+	}
+}
+
+func TestStageMissingEmbeddingsErrors(t *testing.T) {
+	t.Run("list fails", func(t *testing.T) {
+		listErr := errors.New("connection reset")
+		list := func(context.Context, string, int) ([]storage.EmbeddingSource, error) { return nil, listErr }
+
+		_, err := stageMissingEmbeddings(context.Background(), &lengthEmbedder{}, "nomic-embed-text", 10, "chunks", list, newStagingTable(0).stage)
+		if !errors.Is(err, listErr) {
+			t.Errorf("expected the list error, got %v", err)
+		}
+	})
+
+	t.Run("stage fails after a batch", func(t *testing.T) {
+		table := newStagingTable(15)
+		stageErr := errors.New("deadlock detected")
+		batches := 0
+
+		stage := func(ctx context.Context, model string, sources []storage.EmbeddingSource, embeddings [][]float32) error {
+			batches++
+			if batches == 2 {
+				return stageErr
+			}
+
+			return table.stage(ctx, model, sources, embeddings)
+		}
+
+		total, err := stageMissingEmbeddings(context.Background(), &lengthEmbedder{}, "nomic-embed-text", 10, "chunks", table.list, stage)
+		if !errors.Is(err, stageErr) {
+			t.Errorf("expected the stage error, got %v", err)
+		}
+
+		// the first batch stays staged, a rerun continues from there
+		if total != 10 {
+			t.Errorf("expected 10 rows staged before the error, got %d", total)
+		}
+	})
+
+	t.Run("embedder returns too few embeddings", func(t *testing.T) {
+		table := newStagingTable(5)
+
+		_, err := stageMissingEmbeddings(context.Background(), &lengthEmbedder{drop: true}, "nomic-embed-text", 10, "chunks", table.list, table.stage)
+		if err == nil || err.Error() != "expected 5 embeddings, got 4" {
+			t.Errorf("expected an embedding count error, got %v", err)
+		}
+
+		if len(table.staged) != 0 {
+			t.Errorf("expected nothing staged, got %d rows", len(table.staged))
+		}
+	})
+}
@@ -0,0 +1,10 @@
+package main
+
+// what an ingestion run changed for one page
+type pageChanges struct {
+	PageName  string
+	Added     int
+	Updated   int
+	Removed   int
+	Unchanged int
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+
+	"codeberg.org/algopatterns/server/internal/chunker"
+	"codeberg.org/algopatterns/server/internal/llm"
+	"codeberg.org/algopatterns/server/internal/logger"
+	"codeberg.org/algopatterns/server/internal/retriever"
+	"codeberg.org/algopatterns/server/internal/storage"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// creates the embedder of the active embedding model, the same one retrieval
+// embeds queries with. the configured embedder (EMBEDDER_PROVIDER) is used
+// when it matches or when no model is registered.
+func activeEmbedder(ctx context.Context, db *pgxpool.Pool) (llm.Embedder, string, error) {
+	configured, err := llm.NewEmbedder(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create embedder: %w", err)
+	}
+
+	embedder, model, err := retriever.NewActiveEmbedder(db, configured).Resolve(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	logger.Info("embedding with model", "model", model)
+
+	return embedder, model, nil
+}
+
+// embeds and stages rows until list returns none. returns the number of rows staged.
+func stageMissingEmbeddings(
+	ctx context.Context,
+	embedder llm.Embedder,
+	model string,
+	batchSize int,
+	kind string,
+	list func(ctx context.Context, model string, limit int) ([]storage.EmbeddingSource, error),
+	stage func(ctx context.Context, model string, sources []storage.EmbeddingSource, embeddings [][]float32) error,
+) (int, error) {
+	total := 0
+
+	for {
+		sources, err := list(ctx, model, batchSize)
+		if err != nil {
+			return total, err
+		}
+
+		if len(sources) == 0 {
+			return total, nil
+		}
+
+		texts := make([]string, len(sources))
+		for i, source := range sources {
+			texts[i] = source.Text
+		}
+
+		embeddings, err := embedder.GenerateEmbeddings(ctx, texts)
+		if err != nil {
+			return total, fmt.Errorf("failed to generate embeddings: %w", err)
+		}
+
+		if len(embeddings) != len(sources) {
+			return total, fmt.Errorf("expected %d embeddings, got %d", len(sources), len(embeddings))
+		}
+
+		if err := stage(ctx, model, sources, embeddings); err != nil {
+			return total, err
+		}
+
+		total += len(sources)
+		logger.Info("staged embeddings", "kind", kind, "model", model, "total", total)
+	}
+}
+
+// embeds new and changed chunks and deletes stored chunks under urlPrefix that
+// were not produced again. deletions are skipped when partial is set (some
+// files failed to chunk), since their pages would otherwise look removed.
+func syncChunks(
+	ctx context.Context,
+	storageClient *storage.Client,
+	embedder llm.Embedder,
+	model string,
+	chunks []chunker.Chunk,
+	urlPrefix string,
+	partial bool,
+) ([]*pageChanges, error) {
+	stored, err := storageClient.ListChunkHashes(ctx, urlPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	storedHashes := make(map[string]map[string]string) // page url -> chunk key -> hash
+	changes := make(map[string]*pageChanges)           // page url -> changes
+
+	changesFor := func(pageURL, pageName string) *pageChanges {
+		if _, ok := changes[pageURL]; !ok {
+			changes[pageURL] = &pageChanges{PageName: pageName}
+		}
+		return changes[pageURL]
+	}
+
+	for _, hash := range stored {
+		if _, ok := storedHashes[hash.PageURL]; !ok {
+			storedHashes[hash.PageURL] = make(map[string]string)
+		}
+
+		storedHashes[hash.PageURL][hash.Key] = hash.ContentHash
+		changesFor(hash.PageURL, hash.PageName)
+	}
+
+	produced := make(map[string][]string) // page url -> chunk keys
+	var changed []chunker.Chunk
+
+	for _, chunk := range chunks {
+		produced[chunk.PageURL] = append(produced[chunk.PageURL], chunk.Key)
+		page := changesFor(chunk.PageURL, chunk.PageName)
+		page.PageName = chunk.PageName
+
+		hash, exists := storedHashes[chunk.PageURL][chunk.Key]
+
+		switch {
+		case !exists:
+			page.Added++
+			changed = append(changed, chunk)
+		case hash != chunk.Hash():
+			page.Updated++
+			changed = append(changed, chunk)
+		default:
+			page.Unchanged++
+		}
+	}
+
+	if len(changed) > 0 {
+		logger.Info("generating embeddings for new and changed chunks", "count", len(changed))
+		texts := make([]string, len(changed))
+
+		for i, chunk := range changed {
+			texts[i] = chunk.Content
+		}
+
+		embeddings, err := embedder.GenerateEmbeddings(ctx, texts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate embeddings: %w", err)
+		}
+
+		if err := storageClient.UpsertChunksBatch(ctx, changed, embeddings, model); err != nil {
+			return nil, fmt.Errorf("failed to upsert chunks: %w", err)
+		}
+	}
+
+	if partial {
+		logger.Warn("some files failed to chunk, skipping removal of stale chunks")
+	} else {
+		for pageURL, hashes := range storedHashes {
+			keep := produced[pageURL]
+
+			stale := false
+			for key := range hashes {
+				if !slices.Contains(keep, key) {
+					stale = true
+					break
+				}
+			}
+
+			if !stale {
+				continue
+			}
+
+			deleted, err := storageClient.DeleteChunksByPage(ctx, pageURL, keep)
+			if err != nil {
+				return nil, err
+			}
+
+			changes[pageURL].Removed += int(deleted)
+		}
+	}
+
+	summary := make([]*pageChanges, 0, len(changes))
+	for _, page := range changes {
+		summary = append(summary, page)
+	}
+
+	sort.Slice(summary, func(i, j int) bool {
+		return summary[i].PageName < summary[j].PageName
+	})
+
+	return summary, nil
+}
+
+// logs added/updated/removed chunks for every page that changed, then the totals
+func logChanges(summary []*pageChanges) {
+	var total pageChanges
+
+	for _, page := range summary {
+		total.Added += page.Added
+		total.Updated += page.Updated
+		total.Removed += page.Removed
+		total.Unchanged += page.Unchanged
+
+		if page.Added == 0 && page.Updated == 0 && page.Removed == 0 {
+			continue
+		}
+
+		logger.Info("page changed",
+			"page", page.PageName,
+			"added", page.Added,
+			"updated", page.Updated,
+			"removed", page.Removed,
+		)
+	}
+
+	logger.Info("ingestion changes",
+		"pages", len(summary),
+		"added", total.Added,
+		"updated", total.Updated,
+		"removed", total.Removed,
+		"unchanged", total.Unchanged,
+	)
+}
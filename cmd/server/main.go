@@ -90,13 +90,23 @@ func main() {
 	cleanupCtx, cleanupCancel := context.WithCancel(context.Background())
 	go srv.cleanupService.Start(cleanupCtx)
 
+	// start session snapshot service with cancellable context
+	snapshotCtx, snapshotCancel := context.WithCancel(context.Background())
+	go srv.snapshotService.Start(snapshotCtx)
+
+	// start strudel embedding service with cancellable context
+	embeddingCtx, embeddingCancel := context.WithCancel(context.Background())
+	go srv.embeddingService.Start(embeddingCtx)
+
 	// wait for interrupt signal for graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	// stop cleanup service
+	// stop cleanup, snapshot and embedding services
 	cleanupCancel()
+	snapshotCancel()
+	embeddingCancel()
 
 	logger.Info("shutting down server")
 
@@ -22,6 +22,7 @@ func RegisterRoutes(router *gin.Engine, server *Server) {
 	}
 
 	router.GET("/health", health.Handler)
+	router.GET("/health/validator", health.ValidatorHandler(server.services.Validator))
 
 	v1 := router.Group("/api/v1")
 
@@ -29,11 +30,11 @@ func RegisterRoutes(router *gin.Engine, server *Server) {
 		v1.GET("/ping", health.PingHandler)
 
 		auth.RegisterRoutes(v1, server.userRepo)
-		strudels.RegisterRoutes(v1, server.strudelRepo, server.services.Attribution, server.ccSignals)
-		collaboration.RegisterRoutes(v1, server.sessionRepo, server.hub)
+		strudels.RegisterRoutes(v1, server.strudelRepo, server.services.Attribution, server.services.Lineage, server.ccSignals, server.services.Retriever, server.botDefense)
+		collaboration.RegisterRoutes(v1, server.sessionRepo, server.hub, server.snapshotService, server.hub)
 		users.RegisterRoutes(v1, server.db)
 		admin.RegisterRoutes(v1, server.strudelRepo)
-		agent.RegisterRoutes(v1, server.services.Agent, server.services.LLM, server.strudelRepo, server.userRepo, server.services.Attribution, server.buffer)
+		agent.RegisterRoutes(v1, server.services.Agent, server.services.LLM, server.strudelRepo, server.userRepo, server.services.Attribution, server.buffer, server.sessionRepo, server.hub, server.services.LocalLLM)
 		websocket.RegisterRoutes(v1, server.hub, server.sessionRepo, server.userRepo)
 	}
 }
@@ -28,6 +28,13 @@ const (
 
 	// sessions inactive for longer than this will be ended
 	sessionInactivityThreshold = 30 * time.Minute
+
+	// how often live sessions with changed code are snapshotted
+	sessionSnapshotInterval = 5 * time.Minute
+
+	// how often strudels without an embedding are embedded, and how many per request
+	strudelEmbeddingInterval  = 5 * time.Minute
+	strudelEmbeddingBatchSize = 50
 )
 
 // creates and configures a new server instance with all dependencies
@@ -115,8 +122,33 @@ func NewServer(cfg *config.Config) (*Server, error) {
 
 	hub := ws.NewHub()
 
+	// share sessions with other server instances through Redis
+	if cfg.WSBackplane == "redis" {
+		instanceID, err := ws.GenerateInstanceID()
+		if err != nil {
+			sessionBuffer.Close() //nolint:errcheck,gosec // best-effort cleanup on init failure
+			db.Close()
+			return nil, fmt.Errorf("failed to generate websocket instance id: %w", err)
+		}
+
+		backplane, err := ws.NewRedisBackplane(sessionBuffer.Client(), instanceID)
+		if err != nil {
+			sessionBuffer.Close() //nolint:errcheck,gosec // best-effort cleanup on init failure
+			db.Close()
+			return nil, fmt.Errorf("failed to initialize websocket backplane: %w", err)
+		}
+
+		hub.WithBackplane(backplane)
+
+		logger.Info("websocket backplane initialized",
+			"backend", cfg.WSBackplane,
+			"instance_id", instanceID,
+		)
+	}
+
 	// register websocket message handlers (handlers use sessionRepo interface, unaware of Redis)
 	hub.RegisterHandler(ws.TypeCodeUpdate, ws.CodeUpdateHandler(sessionRepo, detector))
+	hub.RegisterHandler(ws.TypeCodeOps, ws.CodeOpsHandler(sessionRepo, detector))
 	hub.RegisterHandler(ws.TypeChatMessage, ws.ChatHandler(sessionRepo))
 	hub.RegisterHandler(ws.TypePlay, ws.PlayHandler())
 	hub.RegisterHandler(ws.TypeStop, ws.StopHandler())
@@ -191,20 +223,51 @@ func NewServer(cfg *config.Config) (*Server, error) {
 		},
 	)
 
+	// create session snapshot service (periodic version history of live sessions)
+	snapshotService := sessions.NewSnapshotService(
+		sessionRepo, // buffered repo so snapshots see the latest unflushed code
+		sessionSnapshotInterval,
+		hub.ActiveSessionIDs,
+		func(sessionID string) []sessions.SnapshotAuthor {
+			writers := hub.SessionWriters(sessionID)
+			authors := make([]sessions.SnapshotAuthor, 0, len(writers))
+
+			for _, writer := range writers {
+				authors = append(authors, sessions.SnapshotAuthor{
+					UserID:      writer.UserID,
+					DisplayName: writer.DisplayName,
+					Role:        writer.Role,
+				})
+			}
+
+			return authors
+		},
+	)
+
+	// create strudel embedding service (embeds trainable strudels, refills vectors after a model cutover)
+	embeddingService := strudels.NewEmbeddingService(
+		strudelRepo,
+		services.Retriever.ActiveEmbedder(),
+		strudelEmbeddingInterval,
+		strudelEmbeddingBatchSize,
+	)
+
 	server := &Server{
-		db:             db,
-		config:         cfg,
-		userRepo:       userRepo,
-		strudelRepo:    strudelRepo,
-		sessionRepo:    sessionRepo,
-		services:       services,
-		hub:            hub,
-		router:         router,
-		buffer:         sessionBuffer,
-		flusher:        flusher,
-		cleanupService: cleanupService,
-		ccSignals:      ccSignals,
-		botDefense:     botDefense,
+		db:               db,
+		config:           cfg,
+		userRepo:         userRepo,
+		strudelRepo:      strudelRepo,
+		sessionRepo:      sessionRepo,
+		services:         services,
+		hub:              hub,
+		router:           router,
+		buffer:           sessionBuffer,
+		flusher:          flusher,
+		cleanupService:   cleanupService,
+		snapshotService:  snapshotService,
+		embeddingService: embeddingService,
+		ccSignals:        ccSignals,
+		botDefense:       botDefense,
 	}
 
 	RegisterRoutes(router, server)
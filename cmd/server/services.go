@@ -9,6 +9,7 @@ import (
 	"codeberg.org/algopatterns/server/internal/agent"
 	"codeberg.org/algopatterns/server/internal/attribution"
 	"codeberg.org/algopatterns/server/internal/config"
+	"codeberg.org/algopatterns/server/internal/lineage"
 	"codeberg.org/algopatterns/server/internal/llm"
 	"codeberg.org/algopatterns/server/internal/logger"
 	"codeberg.org/algopatterns/server/internal/retriever"
@@ -18,27 +19,18 @@ import (
 )
 
 // creates and configures all service clients
-func InitializeServices(_ *config.Config, db *pgxpool.Pool) (*Services, error) {
+func InitializeServices(cfg *config.Config, db *pgxpool.Pool) (*Services, error) {
 	llmClient, err := llm.NewLLM(context.Background())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create LLM client: %w", err)
 	}
 
-	retrieverClient := retriever.New(db, llmClient)
+	retrieverClient := retriever.NewWithReranker(db, llmClient, newReranker(cfg.RetrievalReranker, llmClient))
 	storageClient := &storage.Client{}
 
-	// initialize validator (optional/continues without if unavailable)
-	var validator *strudel.Validator
-	if scriptDir := findValidatorScriptDir(); scriptDir != "" {
-		v, err := strudel.NewValidator(scriptDir)
-		if err != nil {
-			logger.Warn("strudel validator unavailable, continuing without validation", "error", err)
-		} else {
-			validator = v
-			logger.Info("strudel validator initialized")
-		}
-	} else {
-		logger.Warn("strudel validator script not found, continuing without validation")
+	validator, err := newValidator(cfg.StrudelValidator, cfg.ValidatorWorkers)
+	if err != nil {
+		return nil, err
 	}
 
 	agentClient := agent.NewWithValidator(retrieverClient, llmClient, validator)
@@ -47,13 +39,73 @@ func InitializeServices(_ *config.Config, db *pgxpool.Pool) (*Services, error) {
 	return &Services{
 		Agent:       agentClient,
 		Attribution: attrService,
+		Lineage:     lineage.New(db),
 		LLM:         llmClient,
 		Retriever:   retrieverClient,
 		Storage:     storageClient,
 		Validator:   validator,
+		LocalLLM: llm.OpenAIConfig{
+			BaseURL: cfg.LocalLLMBaseURL,
+			APIKey:  cfg.LocalLLMAPIKey,
+			Model:   cfg.LocalLLMModel,
+		},
 	}, nil
 }
 
+// creates the configured retrieval reranker, nil keeps the merged order
+func newReranker(kind string, llmClient llm.LLM) retriever.Reranker {
+	switch kind {
+	case "llm":
+		generator, ok := llm.TransformerGenerator(llmClient)
+		if !ok {
+			logger.Warn("transformer cannot generate text, falling back to lexical reranking")
+			return retriever.NewLexicalReranker()
+		}
+
+		logger.Info("retrieval reranking enabled", "reranker", "llm", "model", generator.Model())
+		return retriever.NewLLMReranker(generator)
+	case "lexical":
+		logger.Info("retrieval reranking enabled", "reranker", "lexical")
+		return retriever.NewLexicalReranker()
+	default:
+		return nil
+	}
+}
+
+// creates the configured code validator. "node" requires the node validator
+// pool, left empty it falls back to static checks when its script or runtime
+// is unavailable.
+func newValidator(kind string, workers int) (strudel.CodeValidator, error) {
+	if kind == "static" {
+		logger.Info("strudel validator initialized", "validator", "static")
+		return strudel.NewStaticValidator(), nil
+	}
+
+	scriptDir := findValidatorScriptDir()
+	if scriptDir == "" {
+		if kind == "node" {
+			return nil, fmt.Errorf("failed to start strudel validator: validate-strudel script not found")
+		}
+
+		logger.Warn("strudel validator script not found, falling back to static validation, set STRUDEL_VALIDATOR=static to silence this")
+		return strudel.NewStaticValidator(), nil
+	}
+
+	pool, err := strudel.NewValidatorPool(scriptDir, strudel.PoolOptions{Workers: workers})
+	if err != nil {
+		if kind == "node" {
+			return nil, fmt.Errorf("failed to start strudel validator: %w", err)
+		}
+
+		logger.Warn("strudel validator unavailable, falling back to static validation, set STRUDEL_VALIDATOR=static to silence this", "error", err)
+		return strudel.NewStaticValidator(), nil
+	}
+
+	logger.Info("strudel validator initialized", "validator", "node", "workers", pool.Stats().Workers)
+
+	return pool, nil
+}
+
 // locates the validator script directory
 func findValidatorScriptDir() string {
 	candidates := []string{
@@ -9,6 +9,7 @@ import (
 	"codeberg.org/algopatterns/server/internal/botdefense"
 	"codeberg.org/algopatterns/server/internal/buffer"
 	"codeberg.org/algopatterns/server/internal/config"
+	"codeberg.org/algopatterns/server/internal/lineage"
 	"codeberg.org/algopatterns/server/internal/llm"
 	"codeberg.org/algopatterns/server/internal/retriever"
 	"codeberg.org/algopatterns/server/internal/storage"
@@ -20,27 +21,31 @@ import (
 
 // holds all dependencies and state for the API server
 type Server struct {
-	db             *pgxpool.Pool
-	config         *config.Config
-	userRepo       *users.Repository
-	strudelRepo    *strudels.Repository
-	sessionRepo    sessions.Repository
-	services       *Services
-	hub            *ws.Hub
-	router         *gin.Engine
-	buffer         *buffer.SessionBuffer
-	flusher        *buffer.Flusher
-	cleanupService *sessions.CleanupService
-	ccSignals      *CCSignalsSystem
-	botDefense     *botdefense.Defense
+	db               *pgxpool.Pool
+	config           *config.Config
+	userRepo         *users.Repository
+	strudelRepo      *strudels.Repository
+	sessionRepo      sessions.Repository
+	services         *Services
+	hub              *ws.Hub
+	router           *gin.Engine
+	buffer           *buffer.SessionBuffer
+	flusher          *buffer.Flusher
+	cleanupService   *sessions.CleanupService
+	snapshotService  *sessions.SnapshotService
+	embeddingService *strudels.EmbeddingService
+	ccSignals        *CCSignalsSystem
+	botDefense       *botdefense.Defense
 }
 
 // holds all external service clients (LLM, storage, retriever, agent)
 type Services struct {
 	Agent       *agent.Agent
 	Attribution *attribution.Service
+	Lineage     *lineage.Service
 	LLM         llm.LLM
 	Retriever   *retriever.Client
 	Storage     *storage.Client
-	Validator   *strudel.Validator
+	Validator   strudel.CodeValidator
+	LocalLLM    llm.OpenAIConfig // self-hosted endpoint offered to byok users as provider "local"
 }
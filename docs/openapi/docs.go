@@ -190,6 +190,52 @@ const docTemplate = `{
                 }
             }
         },
+        "/api/v1/agent/generate/stream": {
+            "post": {
+                "description": "Stream Strudel code generation using Server-Sent Events. BYOK required, the \"local\" provider is not available for streaming.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "agent"
+                ],
+                "summary": "Stream generate code with AI (SSE)",
+                "parameters": [
+                    {
+                        "description": "Generation request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api_rest_agent.GenerateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_agent.StreamEvent"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/api/v1/auth/logout": {
             "post": {
                 "description": "Clear authentication session",
@@ -475,6 +521,67 @@ const docTemplate = `{
                 }
             }
         },
+        "/api/v1/public/strudels/search": {
+            "get": {
+                "description": "Hybrid search over public strudels, combining embedding similarity with full text rank. At most 30 requests per minute",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "strudels"
+                ],
+                "summary": "Search public strudels",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Search query (max 200 characters)",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Items per page (max 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Number of items to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api_rest_strudels.ScoredStrudelsListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/api/v1/public/strudels/tags": {
             "get": {
                 "description": "Get all unique tags from public strudels",
@@ -542,6 +649,188 @@ const docTemplate = `{
                 }
             }
         },
+        "/api/v1/public/strudels/{id}/credits": {
+            "get": {
+                "description": "Get the credits of a public strudel: the strudels it was forked from, the example strudels and docs the AI assistant used. Formats: json, text or spdx (REUSE-style SPDX tags)",
+                "produces": [
+                    "application/json",
+                    "text/plain"
+                ],
+                "tags": [
+                    "strudels"
+                ],
+                "summary": "Get strudel credits",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Strudel ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "text",
+                            "spdx"
+                        ],
+                        "type": "string",
+                        "default": "json",
+                        "description": "Output format",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_attribution.StrudelCredits"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/public/strudels/{id}/lineage": {
+            "get": {
+                "description": "Get the fork ancestors and descendant tree of a public strudel, with CC signals inherited along each fork",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "strudels"
+                ],
+                "summary": "Get strudel lineage",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Strudel ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 5,
+                        "description": "Ancestors to list (max 20)",
+                        "name": "ancestor_depth",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 5,
+                        "description": "Fork levels to list (max 20)",
+                        "name": "descendant_depth",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_lineage.Lineage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/public/strudels/{id}/similar": {
+            "get": {
+                "description": "Get public strudels similar to a public strudel, by its stored embedding and its title and tags",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "strudels"
+                ],
+                "summary": "List similar public strudels",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Strudel ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Items per page (max 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Number of items to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api_rest_strudels.ScoredStrudelsListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/api/v1/public/strudels/{id}/stats": {
             "get": {
                 "description": "Get attribution stats for a public strudel (how many times it was used as RAG context)",
@@ -1154,21 +1443,21 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/sessions/{id}/invite": {
+        "/api/v1/sessions/{id}/history": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get all invite tokens for a session (host only)",
+                "description": "List saved snapshots of the session code, newest first (participants only). Code is omitted, fetch a snapshot to read it.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "sessions"
                 ],
-                "summary": "List invite tokens",
+                "summary": "List session history",
                 "parameters": [
                     {
                         "type": "string",
@@ -1176,13 +1465,27 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Max snapshots to return (max 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Number of snapshots to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api_rest_collaboration.InviteTokensListResponse"
+                            "$ref": "#/definitions/api_rest_collaboration.SnapshotsListResponse"
                         }
                     },
                     "400": {
@@ -1223,7 +1526,7 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Generate an invite link for joining the session (host only)",
+                "description": "Save the current session code as a snapshot, optionally labelled (host or co-author only)",
                 "consumes": [
                     "application/json"
                 ],
@@ -1233,7 +1536,7 @@ const docTemplate = `{
                 "tags": [
                     "sessions"
                 ],
-                "summary": "Create invite token",
+                "summary": "Save session snapshot",
                 "parameters": [
                     {
                         "type": "string",
@@ -1243,12 +1546,11 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "description": "Token settings",
+                        "description": "Snapshot label",
                         "name": "request",
                         "in": "body",
-                        "required": true,
                         "schema": {
-                            "$ref": "#/definitions/api_rest_collaboration.CreateInviteTokenRequest"
+                            "$ref": "#/definitions/api_rest_collaboration.CreateSnapshotRequest"
                         }
                     }
                 ],
@@ -1256,7 +1558,7 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/api_rest_collaboration.InviteTokenResponse"
+                            "$ref": "#/definitions/api_rest_collaboration.SnapshotResponse"
                         }
                     },
                     "400": {
@@ -1292,21 +1594,21 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/sessions/{id}/invite/{token_id}": {
-            "delete": {
+        "/api/v1/sessions/{id}/history/diff": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Revoke an invite token to prevent further use (host only)",
+                "description": "Line diff between two snapshots, or between a snapshot and the current code when \"to\" is omitted (participants only). Code over 10000 lines is rejected with 400",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "sessions"
                 ],
-                "summary": "Revoke invite token",
+                "summary": "Diff session snapshots",
                 "parameters": [
                     {
                         "type": "string",
@@ -1317,17 +1619,23 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Token ID (UUID)",
-                        "name": "token_id",
-                        "in": "path",
+                        "description": "Base snapshot ID (UUID)",
+                        "name": "from",
+                        "in": "query",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Target snapshot ID (UUID), defaults to the current code",
+                        "name": "to",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api_rest_collaboration.MessageResponse"
+                            "$ref": "#/definitions/api_rest_collaboration.SnapshotDiffResponse"
                         }
                     },
                     "400": {
@@ -1353,31 +1661,25 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
                         }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
-                        }
                     }
                 }
             }
         },
-        "/api/v1/sessions/{id}/leave": {
-            "post": {
+        "/api/v1/sessions/{id}/history/{snapshot_id}": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Leave a collaborative session",
+                "description": "Get a saved snapshot of the session code (participants only)",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "sessions"
                 ],
-                "summary": "Leave session",
+                "summary": "Get session snapshot",
                 "parameters": [
                     {
                         "type": "string",
@@ -1385,13 +1687,20 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Snapshot ID (UUID)",
+                        "name": "snapshot_id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api_rest_collaboration.MessageResponse"
+                            "$ref": "#/definitions/api_rest_collaboration.SnapshotResponse"
                         }
                     },
                     "400": {
@@ -1406,14 +1715,14 @@ const docTemplate = `{
                             "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
                         }
@@ -1421,21 +1730,21 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/sessions/{id}/live-status": {
-            "get": {
+        "/api/v1/sessions/{id}/history/{snapshot_id}/restore": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Returns whether a session is currently live (has other participants or active invite tokens)",
+                "description": "Replace the session code with a snapshot. Connected clients receive a code_update with source \"restored\" (host or co-author only)",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "sessions"
                 ],
-                "summary": "Check if session is live",
+                "summary": "Restore session snapshot",
                 "parameters": [
                     {
                         "type": "string",
@@ -1443,13 +1752,20 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Snapshot ID (UUID)",
+                        "name": "snapshot_id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api_rest_collaboration.IsLiveResponse"
+                            "$ref": "#/definitions/api_rest_collaboration.RestoreSnapshotResponse"
                         }
                     },
                     "400": {
@@ -1464,6 +1780,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -1479,21 +1801,21 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/sessions/{id}/messages": {
+        "/api/v1/sessions/{id}/invite": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieve chat messages from a session (AI conversations are strudel-scoped and fetched separately)",
+                "description": "Get all invite tokens for a session (host only)",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "sessions"
                 ],
-                "summary": "Get session chat messages",
+                "summary": "List invite tokens",
                 "parameters": [
                     {
                         "type": "string",
@@ -1501,20 +1823,13 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "default": 100,
-                        "description": "Max messages to return (max 1000)",
-                        "name": "limit",
-                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api_rest_collaboration.MessagesResponse"
+                            "$ref": "#/definitions/api_rest_collaboration.InviteTokensListResponse"
                         }
                     },
                     "400": {
@@ -1529,6 +1844,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -1536,23 +1863,24 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/api/v1/sessions/{id}/participants": {
-            "get": {
+            },
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get all participants in a session (authenticated and anonymous)",
+                "description": "Generate an invite link for joining the session (host only)",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "sessions"
                 ],
-                "summary": "List session participants",
+                "summary": "Create invite token",
                 "parameters": [
                     {
                         "type": "string",
@@ -1560,13 +1888,22 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Token settings",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api_rest_collaboration.CreateInviteTokenRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/api_rest_collaboration.ParticipantsListResponse"
+                            "$ref": "#/definitions/api_rest_collaboration.InviteTokenResponse"
                         }
                     },
                     "400": {
@@ -1581,6 +1918,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -1590,21 +1939,21 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/sessions/{id}/participants/{participant_id}": {
+        "/api/v1/sessions/{id}/invite/{token_id}": {
             "delete": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Remove a participant from the session (host only)",
+                "description": "Revoke an invite token to prevent further use (host only)",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "sessions"
                 ],
-                "summary": "Remove participant",
+                "summary": "Revoke invite token",
                 "parameters": [
                     {
                         "type": "string",
@@ -1615,8 +1964,8 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Participant ID (UUID)",
-                        "name": "participant_id",
+                        "description": "Token ID (UUID)",
+                        "name": "token_id",
                         "in": "path",
                         "required": true
                     }
@@ -1661,58 +2010,41 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/strudels": {
-            "get": {
+        "/api/v1/sessions/{id}/leave": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get strudels owned by the authenticated user with pagination, search, and filtering",
+                "description": "Leave a collaborative session",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "strudels"
+                    "sessions"
                 ],
-                "summary": "List user's strudels",
+                "summary": "Leave session",
                 "parameters": [
-                    {
-                        "type": "integer",
-                        "default": 20,
-                        "description": "Items per page (max 100)",
-                        "name": "limit",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "default": 0,
-                        "description": "Number of items to skip",
-                        "name": "offset",
-                        "in": "query"
-                    },
                     {
                         "type": "string",
-                        "description": "Search in title and description",
-                        "name": "search",
-                        "in": "query"
-                    },
-                    {
-                        "type": "array",
-                        "items": {
-                            "type": "string"
-                        },
-                        "collectionFormat": "csv",
-                        "description": "Filter by tags (comma-separated)",
-                        "name": "tags",
-                        "in": "query"
+                        "description": "Session ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api_rest_strudels.StrudelsListResponse"
+                            "$ref": "#/definitions/api_rest_collaboration.MessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
                         }
                     },
                     "401": {
@@ -1721,6 +2053,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -1728,40 +2066,37 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "post": {
+            }
+        },
+        "/api/v1/sessions/{id}/live-status": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Save a new Strudel pattern with code, title, and metadata",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Returns whether a session is currently live (has other participants or active invite tokens)",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "strudels"
+                    "sessions"
                 ],
-                "summary": "Create strudel",
+                "summary": "Check if session is live",
                 "parameters": [
                     {
-                        "description": "Strudel data",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/codeberg_org_algopatterns_server_algopatterns_strudels.CreateStrudelRequest"
-                        }
+                        "type": "string",
+                        "description": "Session ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/codeberg_org_algopatterns_server_algopatterns_strudels.Strudel"
+                            "$ref": "#/definitions/api_rest_collaboration.IsLiveResponse"
                         }
                     },
                     "400": {
@@ -1776,6 +2111,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -1785,26 +2126,643 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/strudels/tags": {
+        "/api/v1/sessions/{id}/messages": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get all unique tags from the authenticated user's strudels",
+                "description": "Retrieve chat messages from a session (AI conversations are strudel-scoped and fetched separately)",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "strudels"
+                    "sessions"
                 ],
-                "summary": "List user's tags",
-                "responses": {
+                "summary": "Get session chat messages",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 100,
+                        "description": "Max messages to return (max 1000)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api_rest_collaboration.MessagesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/sessions/{id}/participants": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get all participants in a session (authenticated and anonymous)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sessions"
+                ],
+                "summary": "List session participants",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api_rest_collaboration.ParticipantsListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/sessions/{id}/participants/{participant_id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Remove a participant from the session (host only)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sessions"
+                ],
+                "summary": "Remove participant",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Participant ID (UUID)",
+                        "name": "participant_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api_rest_collaboration.MessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/strudels": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get strudels owned by the authenticated user with pagination, search, and filtering",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "strudels"
+                ],
+                "summary": "List user's strudels",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Items per page (max 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Number of items to skip",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Search in title and description",
+                        "name": "search",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "csv",
+                        "description": "Filter by tags (comma-separated)",
+                        "name": "tags",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api_rest_strudels.StrudelsListResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Save a new Strudel pattern with code, title, and metadata. Forks default to their parent's license and must use a compatible one",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "strudels"
+                ],
+                "summary": "Create strudel",
+                "parameters": [
+                    {
+                        "description": "Strudel data",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_algopatterns_strudels.CreateStrudelRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_algopatterns_strudels.Strudel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/strudels/format": {
+            "post": {
+                "description": "Format code deterministically: method chain indentation, stack() layers one per line, double quoted mini-notation with normalized spacing. Comments are kept. At most 64 KB of code and 60 requests per minute",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "strudels"
+                ],
+                "summary": "Format strudel code",
+                "parameters": [
+                    {
+                        "description": "Code to format",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api_rest_strudels.FormatRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api_rest_strudels.FormatResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/strudels/lint": {
+            "post": {
+                "description": "Flag likely musical mistakes: unknown sounds, notes outside the declared scale, out of range effect values, fast() on dense patterns and unused variables. At most 64 KB of code and 60 requests per minute",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "strudels"
+                ],
+                "summary": "Lint strudel code",
+                "parameters": [
+                    {
+                        "description": "Code to lint",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api_rest_strudels.LintRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api_rest_strudels.LintResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/strudels/tags": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get all unique tags from the authenticated user's strudels",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "strudels"
+                ],
+                "summary": "List user's tags",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api_rest_strudels.TagsListResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/strudels/{id}": {
+            "get": {
+                "description": "Get a specific strudel by ID (owner or public)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "strudels"
+                ],
+                "summary": "Get strudel by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Strudel ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api_rest_strudels.StrudelDetailResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Update a strudel's properties (must be owner)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "strudels"
+                ],
+                "summary": "Update strudel",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Strudel ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Update data",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_algopatterns_strudels.UpdateStrudelRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_algopatterns_strudels.Strudel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Delete a strudel (must be owner)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "strudels"
+                ],
+                "summary": "Delete strudel",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Strudel ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api_rest_strudels.MessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/strudels/{id}/revisions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get the revision history of a strudel, newest first (must be owner). Code is omitted, fetch a revision to read it.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "strudels"
+                ],
+                "summary": "List strudel revisions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Strudel ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Items per page (max 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Number of items to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api_rest_strudels.TagsListResponse"
+                            "$ref": "#/definitions/api_rest_strudels.RevisionsListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
                         }
                     },
                     "401": {
@@ -1813,6 +2771,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -1822,16 +2786,21 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/strudels/{id}": {
+        "/api/v1/strudels/{id}/revisions/{rev}": {
             "get": {
-                "description": "Get a specific strudel by ID (owner or public)",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get a single revision of a strudel including its code (must be owner)",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "strudels"
                 ],
-                "summary": "Get strudel by ID",
+                "summary": "Get strudel revision",
                 "parameters": [
                     {
                         "type": "string",
@@ -1839,13 +2808,20 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Revision number",
+                        "name": "rev",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api_rest_strudels.StrudelDetailResponse"
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_algopatterns_strudels.Revision"
                         }
                     },
                     "400": {
@@ -1854,6 +2830,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -1861,24 +2843,23 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "put": {
+            }
+        },
+        "/api/v1/strudels/{id}/revisions/{rev}/restore": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Update a strudel's properties (must be owner)",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Roll a strudel's code, title and license back to a revision (must be owner). The rollback is saved as a new revision.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "strudels"
                 ],
-                "summary": "Update strudel",
+                "summary": "Restore strudel revision",
                 "parameters": [
                     {
                         "type": "string",
@@ -1888,20 +2869,18 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "description": "Update data",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/codeberg_org_algopatterns_server_algopatterns_strudels.UpdateStrudelRequest"
-                        }
+                        "type": "integer",
+                        "description": "Revision number",
+                        "name": "rev",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/codeberg_org_algopatterns_server_algopatterns_strudels.Strudel"
+                            "$ref": "#/definitions/api_rest_strudels.RestoreRevisionResponse"
                         }
                     },
                     "400": {
@@ -1923,35 +2902,42 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/api/v1/users/ai-features-enabled": {
+            "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Delete a strudel (must be owner)",
+                "description": "Toggle whether AI features (prompt bar, code generation) are enabled for the user",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "strudels"
+                    "users"
                 ],
-                "summary": "Delete strudel",
+                "summary": "Update user's AI features setting",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Strudel ID (UUID)",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "AI features enabled data",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api_rest_users.AIFeaturesEnabledRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/api_rest_strudels.MessageResponse"
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_algopatterns_users.User"
                         }
                     },
                     "400": {
@@ -1966,8 +2952,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_errors.ErrorResponse"
                         }
@@ -1975,14 +2961,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/users/ai-features-enabled": {
+        "/api/v1/users/display-name": {
             "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Toggle whether AI features (prompt bar, code generation) are enabled for the user",
+                "description": "Update the authenticated user's display name (shown in sessions and jams)",
                 "consumes": [
                     "application/json"
                 ],
@@ -1992,15 +2978,15 @@ const docTemplate = `{
                 "tags": [
                     "users"
                 ],
-                "summary": "Update user's AI features setting",
+                "summary": "Update user's display name",
                 "parameters": [
                     {
-                        "description": "AI features enabled data",
+                        "description": "Display name data",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/api_rest_users.AIFeaturesEnabledRequest"
+                            "$ref": "#/definitions/api_rest_users.UpdateDisplayNameRequest"
                         }
                     }
                 ],
@@ -2145,6 +3131,26 @@ const docTemplate = `{
                     }
                 }
             }
+        },
+        "/health/validator": {
+            "get": {
+                "description": "Get the code validator backend and, for the node pool, worker and queue metrics",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Validator status",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api_rest_health.ValidatorResponse"
+                        }
+                    }
+                }
+            }
         }
     },
     "definitions": {
@@ -2202,18 +3208,44 @@ const docTemplate = `{
                 }
             }
         },
+        "api_rest_agent.Edit": {
+            "type": "object",
+            "properties": {
+                "content": {
+                    "type": "string"
+                },
+                "end_line": {
+                    "type": "integer"
+                },
+                "op": {
+                    "description": "\"replace\", \"insert\" or \"delete\"",
+                    "type": "string"
+                },
+                "start_line": {
+                    "type": "integer"
+                }
+            }
+        },
         "api_rest_agent.GenerateRequest": {
             "type": "object",
             "required": [
                 "user_query"
             ],
             "properties": {
+                "agentic": {
+                    "description": "optional: let the AI search docs and validate code mid-generation",
+                    "type": "boolean"
+                },
                 "conversation_history": {
                     "type": "array",
                     "items": {
                         "$ref": "#/definitions/api_rest_agent.Message"
                     }
                 },
+                "editor_revision": {
+                    "description": "optional: session document revision editor_state was read at",
+                    "type": "integer"
+                },
                 "editor_state": {
                     "type": "string"
                 },
@@ -2221,8 +3253,16 @@ const docTemplate = `{
                     "description": "optional: for blocking AI on restricted forks",
                     "type": "string"
                 },
+                "format_code": {
+                    "description": "optional: format generated code before returning it",
+                    "type": "boolean"
+                },
+                "patch_mode": {
+                    "description": "optional: return line edits against editor_state plus the merged code",
+                    "type": "boolean"
+                },
                 "provider": {
-                    "description": "\"anthropic\" or \"openai\"",
+                    "description": "\"anthropic\", \"openai\" or \"local\"",
                     "type": "string"
                 },
                 "provider_api_key": {
@@ -2245,6 +3285,10 @@ const docTemplate = `{
         "api_rest_agent.GenerateResponse": {
             "type": "object",
             "properties": {
+                "broadcast": {
+                    "description": "true if the merged code was sent to the live session",
+                    "type": "boolean"
+                },
                 "clarifying_questions": {
                     "type": "array",
                     "items": {
@@ -2263,6 +3307,13 @@ const docTemplate = `{
                 "docs_retrieved": {
                     "type": "integer"
                 },
+                "edits": {
+                    "description": "applied line edits in patch mode, code holds the merged result",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api_rest_agent.Edit"
+                    }
+                },
                 "examples_retrieved": {
                     "type": "integer"
                 },
@@ -2275,11 +3326,22 @@ const docTemplate = `{
                 "model": {
                     "type": "string"
                 },
+                "provider": {
+                    "description": "provider that served the request",
+                    "type": "string"
+                },
                 "strudel_references": {
                     "type": "array",
                     "items": {
                         "$ref": "#/definitions/api_rest_agent.StrudelReference"
                     }
+                },
+                "tool_steps": {
+                    "description": "tool-calling rounds used in agentic mode",
+                    "type": "integer"
+                },
+                "validation_error": {
+                    "type": "string"
                 }
             }
         },
@@ -2425,6 +3487,15 @@ const docTemplate = `{
                 }
             }
         },
+        "api_rest_collaboration.CreateSnapshotRequest": {
+            "type": "object",
+            "properties": {
+                "label": {
+                    "type": "string",
+                    "maxLength": 200
+                }
+            }
+        },
         "api_rest_collaboration.InviteTokenResponse": {
             "type": "object",
             "properties": {
@@ -2471,6 +3542,9 @@ const docTemplate = `{
                 "has_active_invite_tokens": {
                     "type": "boolean"
                 },
+                "is_discoverable": {
+                    "type": "boolean"
+                },
                 "is_live": {
                     "type": "boolean"
                 },
@@ -2604,63 +3678,146 @@ const docTemplate = `{
                 }
             }
         },
-        "api_rest_collaboration.SessionResponse": {
+        "api_rest_collaboration.RestoreSnapshotResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "snapshot": {
+                    "$ref": "#/definitions/api_rest_collaboration.SnapshotResponse"
+                }
+            }
+        },
+        "api_rest_collaboration.SessionResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "ended_at": {
+                    "type": "string"
+                },
+                "host_user_id": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "is_active": {
+                    "type": "boolean"
+                },
+                "is_discoverable": {
+                    "type": "boolean"
+                },
+                "last_activity": {
+                    "type": "string"
+                },
+                "participants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api_rest_collaboration.ParticipantResponse"
+                    }
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "api_rest_collaboration.SessionsListResponse": {
+            "type": "object",
+            "properties": {
+                "sessions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api_rest_collaboration.SessionResponse"
+                    }
+                }
+            }
+        },
+        "api_rest_collaboration.SetDiscoverableRequest": {
+            "type": "object",
+            "properties": {
+                "is_discoverable": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "api_rest_collaboration.SnapshotDiffResponse": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "type": "string"
+                },
+                "lines": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_diff.Line"
+                    }
+                },
+                "stats": {
+                    "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_diff.Stats"
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "api_rest_collaboration.SnapshotResponse": {
             "type": "object",
             "properties": {
+                "authors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/codeberg_org_algopatterns_server_algopatterns_sessions.SnapshotAuthor"
+                    }
+                },
                 "code": {
                     "type": "string"
                 },
                 "created_at": {
                     "type": "string"
                 },
-                "ended_at": {
-                    "type": "string"
-                },
-                "host_user_id": {
+                "created_by": {
                     "type": "string"
                 },
                 "id": {
                     "type": "string"
                 },
-                "is_active": {
-                    "type": "boolean"
-                },
-                "is_discoverable": {
-                    "type": "boolean"
+                "label": {
+                    "type": "string"
                 },
-                "last_activity": {
+                "reason": {
                     "type": "string"
                 },
-                "participants": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/api_rest_collaboration.ParticipantResponse"
-                    }
+                "restored_from": {
+                    "type": "string"
                 },
-                "title": {
+                "session_id": {
                     "type": "string"
                 }
             }
         },
-        "api_rest_collaboration.SessionsListResponse": {
+        "api_rest_collaboration.SnapshotsListResponse": {
             "type": "object",
             "properties": {
-                "sessions": {
+                "pagination": {
+                    "$ref": "#/definitions/codeberg_org_algopatterns_server_api_rest_pagination.Meta"
+                },
+                "snapshots": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/api_rest_collaboration.SessionResponse"
+                        "$ref": "#/definitions/api_rest_collaboration.SnapshotResponse"
                     }
                 }
             }
         },
-        "api_rest_collaboration.SetDiscoverableRequest": {
-            "type": "object",
-            "properties": {
-                "is_discoverable": {
-                    "type": "boolean"
-                }
-            }
-        },
         "api_rest_collaboration.SoftEndSessionResponse": {
             "type": "object",
             "properties": {
@@ -2721,6 +3878,21 @@ const docTemplate = `{
                 }
             }
         },
+        "api_rest_health.ValidatorResponse": {
+            "type": "object",
+            "properties": {
+                "backend": {
+                    "description": "\"node\", \"static\" or \"none\"",
+                    "type": "string"
+                },
+                "pool": {
+                    "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_strudel.PoolStats"
+                },
+                "ready": {
+                    "type": "boolean"
+                }
+            }
+        },
         "api_rest_strudels.ConversationMessageDTO": {
             "type": "object",
             "properties": {
@@ -2776,6 +3948,86 @@ const docTemplate = `{
                 }
             }
         },
+        "api_rest_strudels.FormatRequest": {
+            "type": "object",
+            "required": [
+                "code"
+            ],
+            "properties": {
+                "code": {
+                    "description": "64KB limit",
+                    "type": "string",
+                    "maxLength": 65536
+                }
+            }
+        },
+        "api_rest_strudels.FormatResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                }
+            }
+        },
+        "api_rest_strudels.LintFindingDTO": {
+            "type": "object",
+            "properties": {
+                "fix": {
+                    "$ref": "#/definitions/api_rest_strudels.LintFixDTO"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "range": {
+                    "$ref": "#/definitions/api_rest_strudels.RangeDTO"
+                },
+                "rule": {
+                    "type": "string"
+                },
+                "severity": {
+                    "description": "\"error\", \"warning\" or \"info\"",
+                    "type": "string"
+                }
+            }
+        },
+        "api_rest_strudels.LintFixDTO": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "range": {
+                    "$ref": "#/definitions/api_rest_strudels.RangeDTO"
+                },
+                "replacement": {
+                    "type": "string"
+                }
+            }
+        },
+        "api_rest_strudels.LintRequest": {
+            "type": "object",
+            "required": [
+                "code"
+            ],
+            "properties": {
+                "code": {
+                    "description": "64KB limit",
+                    "type": "string",
+                    "maxLength": 65536
+                }
+            }
+        },
+        "api_rest_strudels.LintResponse": {
+            "type": "object",
+            "properties": {
+                "findings": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api_rest_strudels.LintFindingDTO"
+                    }
+                }
+            }
+        },
         "api_rest_strudels.MessageResponse": {
             "type": "object",
             "properties": {
@@ -2784,6 +4036,62 @@ const docTemplate = `{
                 }
             }
         },
+        "api_rest_strudels.RangeDTO": {
+            "type": "object",
+            "properties": {
+                "end_column": {
+                    "type": "integer"
+                },
+                "end_line": {
+                    "type": "integer"
+                },
+                "start_column": {
+                    "type": "integer"
+                },
+                "start_line": {
+                    "type": "integer"
+                }
+            }
+        },
+        "api_rest_strudels.RestoreRevisionResponse": {
+            "type": "object",
+            "properties": {
+                "revision": {
+                    "$ref": "#/definitions/codeberg_org_algopatterns_server_algopatterns_strudels.Revision"
+                },
+                "strudel": {
+                    "$ref": "#/definitions/codeberg_org_algopatterns_server_algopatterns_strudels.Strudel"
+                }
+            }
+        },
+        "api_rest_strudels.RevisionsListResponse": {
+            "type": "object",
+            "properties": {
+                "pagination": {
+                    "$ref": "#/definitions/codeberg_org_algopatterns_server_api_rest_pagination.Meta"
+                },
+                "revisions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/codeberg_org_algopatterns_server_algopatterns_strudels.Revision"
+                    }
+                }
+            }
+        },
+        "api_rest_strudels.ScoredStrudelsListResponse": {
+            "type": "object",
+            "properties": {
+                "pagination": {
+                    "$ref": "#/definitions/codeberg_org_algopatterns_server_api_rest_pagination.Meta"
+                },
+                "strudels": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/codeberg_org_algopatterns_server_algopatterns_strudels.ScoredStrudel"
+                    }
+                }
+            }
+        },
         "api_rest_strudels.StrudelDetailResponse": {
             "type": "object",
             "properties": {
@@ -2911,6 +4219,19 @@ const docTemplate = `{
                 }
             }
         },
+        "api_rest_users.UpdateDisplayNameRequest": {
+            "type": "object",
+            "required": [
+                "display_name"
+            ],
+            "properties": {
+                "display_name": {
+                    "type": "string",
+                    "maxLength": 50,
+                    "minLength": 1
+                }
+            }
+        },
         "api_rest_users.UsageResponse": {
             "type": "object",
             "properties": {
@@ -2969,6 +4290,20 @@ const docTemplate = `{
                 }
             }
         },
+        "codeberg_org_algopatterns_server_algopatterns_sessions.SnapshotAuthor": {
+            "type": "object",
+            "properties": {
+                "display_name": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
         "codeberg_org_algopatterns_server_algopatterns_strudels.CCSignal": {
             "type": "string",
             "enum": [
@@ -3045,16 +4380,113 @@ const docTemplate = `{
                     "type": "string"
                 },
                 "tags": {
-                    "description": "max 20 tags, each max 50 chars",
+                    "description": "max 20 tags, each max 50 chars",
+                    "type": "array",
+                    "maxItems": 20,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "title": {
+                    "type": "string",
+                    "maxLength": 200
+                }
+            }
+        },
+        "codeberg_org_algopatterns_server_algopatterns_strudels.Revision": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "license": {
+                    "type": "string"
+                },
+                "restored_from": {
+                    "type": "integer"
+                },
+                "revision": {
+                    "type": "integer"
+                },
+                "strudel_id": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "codeberg_org_algopatterns_server_algopatterns_strudels.ScoredStrudel": {
+            "type": "object",
+            "properties": {
+                "ai_assist_count": {
+                    "type": "integer"
+                },
+                "author_name": {
+                    "type": "string"
+                },
+                "categories": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "cc_signal": {
+                    "$ref": "#/definitions/codeberg_org_algopatterns_server_algopatterns_strudels.CCSignal"
+                },
+                "code": {
+                    "type": "string"
+                },
+                "conversation_history": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_agent.Message"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "forked_from": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "is_public": {
+                    "type": "boolean"
+                },
+                "license": {
+                    "type": "string"
+                },
+                "score": {
+                    "type": "number"
+                },
+                "tags": {
                     "type": "array",
-                    "maxItems": 20,
                     "items": {
                         "type": "string"
                     }
                 },
                 "title": {
-                    "type": "string",
-                    "maxLength": 200
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
                 }
             }
         },
@@ -3064,6 +4496,9 @@ const docTemplate = `{
                 "ai_assist_count": {
                     "type": "integer"
                 },
+                "author_name": {
+                    "type": "string"
+                },
                 "categories": {
                     "type": "array",
                     "items": {
@@ -3214,13 +4649,43 @@ const docTemplate = `{
                 }
             }
         },
+        "codeberg_org_algopatterns_server_internal_agent.DocReference": {
+            "type": "object",
+            "properties": {
+                "page_name": {
+                    "type": "string"
+                },
+                "section_title": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
         "codeberg_org_algopatterns_server_internal_agent.Message": {
             "type": "object",
             "properties": {
+                "clarifying_questions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
                 "content": {
                     "description": "message content",
                     "type": "string"
                 },
+                "doc_references": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_agent.DocReference"
+                    }
+                },
+                "is_actionable": {
+                    "description": "true if response can be applied",
+                    "type": "boolean"
+                },
                 "is_code_response": {
                     "description": "true if AI generated code",
                     "type": "boolean"
@@ -3228,6 +4693,142 @@ const docTemplate = `{
                 "role": {
                     "description": "\"user\" or \"assistant\"",
                     "type": "string"
+                },
+                "strudel_references": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_agent.StrudelReference"
+                    }
+                }
+            }
+        },
+        "codeberg_org_algopatterns_server_internal_agent.StreamEvent": {
+            "type": "object",
+            "properties": {
+                "content": {
+                    "description": "text chunk for type=\"chunk\"",
+                    "type": "string"
+                },
+                "doc_references": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_agent.DocReference"
+                    }
+                },
+                "error": {
+                    "description": "error message for type=\"error\"",
+                    "type": "string"
+                },
+                "input_tokens": {
+                    "type": "integer"
+                },
+                "is_code_response": {
+                    "type": "boolean"
+                },
+                "model": {
+                    "type": "string"
+                },
+                "output_tokens": {
+                    "type": "integer"
+                },
+                "provider": {
+                    "type": "string"
+                },
+                "strudel_references": {
+                    "description": "final metadata sent with type=\"done\"",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_agent.StrudelReference"
+                    }
+                },
+                "type": {
+                    "description": "\"chunk\", \"refs\", \"done\", \"error\"",
+                    "type": "string"
+                }
+            }
+        },
+        "codeberg_org_algopatterns_server_internal_agent.StrudelReference": {
+            "type": "object",
+            "properties": {
+                "author_name": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "codeberg_org_algopatterns_server_internal_attribution.CreditedDoc": {
+            "type": "object",
+            "properties": {
+                "page_name": {
+                    "type": "string"
+                },
+                "section_title": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "codeberg_org_algopatterns_server_internal_attribution.CreditedStrudel": {
+            "type": "object",
+            "properties": {
+                "author_name": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "license": {
+                    "type": "string"
+                },
+                "private": {
+                    "type": "boolean"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "codeberg_org_algopatterns_server_internal_attribution.StrudelCredits": {
+            "type": "object",
+            "properties": {
+                "docs": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_attribution.CreditedDoc"
+                    }
+                },
+                "examples": {
+                    "description": "strudels the AI assistant used as examples",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_attribution.CreditedStrudel"
+                    }
+                },
+                "strudel": {
+                    "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_attribution.CreditedStrudel"
+                },
+                "upstream": {
+                    "description": "fork ancestors, parent first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_attribution.CreditedStrudel"
+                    }
                 }
             }
         },
@@ -3288,9 +4889,57 @@ const docTemplate = `{
                 }
             }
         },
+        "codeberg_org_algopatterns_server_internal_diff.Line": {
+            "type": "object",
+            "properties": {
+                "new_line": {
+                    "type": "integer"
+                },
+                "old_line": {
+                    "type": "integer"
+                },
+                "op": {
+                    "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_diff.Op"
+                },
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "codeberg_org_algopatterns_server_internal_diff.Op": {
+            "type": "string",
+            "enum": [
+                "equal",
+                "insert",
+                "delete"
+            ],
+            "x-enum-varnames": [
+                "OpEqual",
+                "OpInsert",
+                "OpDelete"
+            ]
+        },
+        "codeberg_org_algopatterns_server_internal_diff.Stats": {
+            "type": "object",
+            "properties": {
+                "added": {
+                    "type": "integer"
+                },
+                "removed": {
+                    "type": "integer"
+                }
+            }
+        },
         "codeberg_org_algopatterns_server_internal_errors.ErrorResponse": {
             "type": "object",
             "properties": {
+                "allowed": {
+                    "description": "values the request may use instead",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
                 "details": {
                     "description": "optional details (sanitized in production)",
                     "type": "string"
@@ -3308,6 +4957,122 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "codeberg_org_algopatterns_server_internal_lineage.Lineage": {
+            "type": "object",
+            "properties": {
+                "ancestors": {
+                    "description": "parent first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_lineage.LineageNode"
+                    }
+                },
+                "ancestors_truncated": {
+                    "type": "boolean"
+                },
+                "descendant_count": {
+                    "type": "integer"
+                },
+                "descendants_truncated": {
+                    "type": "boolean"
+                },
+                "strudel": {
+                    "description": "descendants are its children",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_lineage.LineageNode"
+                        }
+                    ]
+                }
+            }
+        },
+        "codeberg_org_algopatterns_server_internal_lineage.LineageNode": {
+            "type": "object",
+            "properties": {
+                "author_id": {
+                    "type": "string"
+                },
+                "author_name": {
+                    "type": "string"
+                },
+                "cc_signal": {
+                    "description": "set on the strudel itself",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_algopatterns_strudels.CCSignal"
+                        }
+                    ]
+                },
+                "children": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/codeberg_org_algopatterns_server_internal_lineage.LineageNode"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "depth": {
+                    "description": "fork edges away from the requested strudel",
+                    "type": "integer"
+                },
+                "effective_signal": {
+                    "description": "the more restrictive of the two",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_algopatterns_strudels.CCSignal"
+                        }
+                    ]
+                },
+                "id": {
+                    "type": "string"
+                },
+                "inherited_signal": {
+                    "description": "carried along the edge from the parent",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/codeberg_org_algopatterns_server_algopatterns_strudels.CCSignal"
+                        }
+                    ]
+                },
+                "private": {
+                    "type": "boolean"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "codeberg_org_algopatterns_server_internal_strudel.PoolStats": {
+            "type": "object",
+            "properties": {
+                "idle": {
+                    "type": "integer"
+                },
+                "in_flight": {
+                    "type": "integer"
+                },
+                "queue_depth": {
+                    "description": "requests waiting for a free worker",
+                    "type": "integer"
+                },
+                "ready": {
+                    "type": "integer"
+                },
+                "restarts": {
+                    "type": "integer"
+                },
+                "timeouts": {
+                    "type": "integer"
+                },
+                "validations": {
+                    "type": "integer"
+                },
+                "workers": {
+                    "type": "integer"
+                }
+            }
         }
     },
     "securityDefinitions": {
@@ -3323,7 +5088,7 @@ const docTemplate = `{
 // SwaggerInfo holds exported Swagger Info so clients can modify it
 var SwaggerInfo = &swag.Spec{
 	Version:          "1.0",
-	Host:             "algopatterns.dev",
+	Host:             "algopatterns.cc",
 	BasePath:         "",
 	Schemes:          []string{},
 	Title:            "Algopatterns API",
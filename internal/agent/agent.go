@@ -19,7 +19,7 @@ func New(ret Retriever, llmClient llm.LLM) *Agent {
 }
 
 // creates an agent with code validation enabled
-func NewWithValidator(ret Retriever, llmClient llm.LLM, validator *strudel.Validator) *Agent {
+func NewWithValidator(ret Retriever, llmClient llm.LLM, validator strudel.CodeValidator) *Agent {
 	return &Agent{
 		retriever: ret,
 		generator: llmClient,
@@ -28,7 +28,7 @@ func NewWithValidator(ret Retriever, llmClient llm.LLM, validator *strudel.Valid
 }
 
 // sets the validator for the agent
-func (a *Agent) SetValidator(v *strudel.Validator) {
+func (a *Agent) SetValidator(v strudel.CodeValidator) {
 	a.validator = v
 }
 
@@ -40,6 +40,13 @@ func (a *Agent) Generate(ctx context.Context, req GenerateRequest) (*GenerateRes
 		textGenerator = req.CustomGenerator
 	}
 
+	// agentic mode replaces the fixed retrieval pipeline when the provider supports tool use
+	if req.Agentic {
+		if toolCaller, ok := llm.ToolCallerFor(textGenerator); ok {
+			return a.generateWithTools(ctx, req, textGenerator, toolCaller)
+		}
+	}
+
 	// for byok users: skip AnalyzeQuery to save ~1-3s latency
 	// the main llm will naturally determine if it's a code request or question
 	var analysis *llm.QueryAnalysis
@@ -107,6 +114,7 @@ func (a *Agent) Generate(ctx context.Context, req GenerateRequest) (*GenerateRes
 		Conversations: req.ConversationHistory,
 		QueryAnalysis: analysis,
 		UsedRAGCache:  usedCache, // tell prompt builder to add "need docs" instruction
+		PatchMode:     usePatchMode(req),
 	})
 
 	// call llm for code generation (uses custom generator if byok)
@@ -161,6 +169,7 @@ func (a *Agent) Generate(ctx context.Context, req GenerateRequest) (*GenerateRes
 				Conversations: req.ConversationHistory,
 				QueryAnalysis: analysis,
 				UsedRAGCache:  false, // fresh docs, no need for "need docs" instruction
+				PatchMode:     usePatchMode(req),
 			})
 
 			response, err = a.callGeneratorWithClient(ctx, textGenerator, systemPrompt, req.UserQuery, req.ConversationHistory)
@@ -174,12 +183,28 @@ func (a *Agent) Generate(ctx context.Context, req GenerateRequest) (*GenerateRes
 
 	didRetry := false
 	var validationError string
+	var edits []Edit
 
 	// analyze response to determine if it's code and extract from markdown if needed
 	content, isCode := analyzeResponse(response.Text)
 
-	// validate and retry only for code responses
-	if a.validator != nil && isCode && content != "" {
+	// patch mode: apply line edits to the editor state (validated and retried in resolvePatch)
+	patched := false
+	if usePatchMode(req) {
+		if patch, ok := a.resolvePatch(ctx, textGenerator, systemPrompt, req, response.Text); ok {
+			patched = true
+			edits = patch.Edits
+			content = patch.Code
+			isCode = patch.Code != ""
+			didRetry = patch.DidRetry
+			validationError = patch.ValidationError
+			totalInputTokens += patch.Usage.InputTokens
+			totalOutputTokens += patch.Usage.OutputTokens
+		}
+	}
+
+	// validate and retry only for full code responses
+	if a.validator != nil && !patched && isCode && content != "" {
 		result, err := a.validator.Validate(ctx, content)
 		if err == nil && !result.Valid {
 			retryResponse, retryErr := a.retryWithValidationError(
@@ -198,30 +223,13 @@ func (a *Agent) Generate(ctx context.Context, req GenerateRequest) (*GenerateRes
 		}
 	}
 
-	// build references for frontend display
-	strudelRefs := make([]StrudelReference, 0, len(examples))
-	for _, ex := range examples {
-		strudelRefs = append(strudelRefs, StrudelReference{
-			ID:         ex.ID,
-			Title:      ex.Title,
-			AuthorName: ex.AuthorName,
-			URL:        fmt.Sprintf("/strudel/%s", ex.ID),
-		})
+	if req.FormatCode && !patched && isCode {
+		content = formatGeneratedCode(content)
 	}
 
-	docRefs := make([]DocReference, 0, len(docs))
-	seen := make(map[string]bool) // dedupe by page URL
-	for _, doc := range docs {
-		if seen[doc.PageURL] {
-			continue
-		}
-		seen[doc.PageURL] = true
-		docRefs = append(docRefs, DocReference{
-			PageName:     doc.PageName,
-			SectionTitle: doc.SectionTitle,
-			URL:          doc.PageURL,
-		})
-	}
+	// build references for frontend display
+	strudelRefs, docRefs := buildReferences(docs, examples)
+	provider, model := servedBy(textGenerator, response.Provider, response.Model)
 
 	return &GenerateResponse{
 		Code:              content,
@@ -231,13 +239,15 @@ func (a *Agent) Generate(ctx context.Context, req GenerateRequest) (*GenerateRes
 		Docs:              docs,
 		StrudelReferences: strudelRefs,
 		DocReferences:     docRefs,
-		Model:             textGenerator.Model(),
+		Model:             model,
+		Provider:          provider,
 		IsActionable:      true,
 		IsCodeResponse:    isCode,
 		InputTokens:       totalInputTokens,
 		OutputTokens:      totalOutputTokens,
 		DidRetry:          didRetry,
 		ValidationError:   validationError,
+		Edits:             edits,
 	}, nil
 }
 
@@ -295,29 +305,7 @@ func (a *Agent) GenerateStream(ctx context.Context, req GenerateRequest, onEvent
 	}
 
 	// send references early so frontend can display them while streaming
-	strudelRefs := make([]StrudelReference, 0, len(examples))
-	for _, ex := range examples {
-		strudelRefs = append(strudelRefs, StrudelReference{
-			ID:         ex.ID,
-			Title:      ex.Title,
-			AuthorName: ex.AuthorName,
-			URL:        fmt.Sprintf("/strudel/%s", ex.ID),
-		})
-	}
-
-	docRefs := make([]DocReference, 0, len(docs))
-	seen := make(map[string]bool)
-	for _, doc := range docs {
-		if seen[doc.PageURL] {
-			continue
-		}
-		seen[doc.PageURL] = true
-		docRefs = append(docRefs, DocReference{
-			PageName:     doc.PageName,
-			SectionTitle: doc.SectionTitle,
-			URL:          doc.PageURL,
-		})
-	}
+	strudelRefs, docRefs := buildReferences(docs, examples)
 
 	// send refs event first
 	if err := onEvent(StreamEvent{
@@ -369,12 +357,18 @@ func (a *Agent) GenerateStream(ctx context.Context, req GenerateRequest, onEvent
 
 	// analyze final response
 	content, isCode := analyzeResponse(response.Text)
+	if req.FormatCode && isCode {
+		content = formatGeneratedCode(content)
+	}
+
+	provider, model := servedBy(textGenerator, response.Provider, response.Model)
 
 	// send done event with final metadata
 	return onEvent(StreamEvent{
 		Type:              "done",
 		Content:           content, // processed content (extracted from markdown if needed)
-		Model:             textGenerator.Model(),
+		Model:             model,
+		Provider:          provider,
 		IsCodeResponse:    isCode,
 		InputTokens:       response.Usage.InputTokens,
 		OutputTokens:      response.Usage.OutputTokens,
@@ -2,10 +2,14 @@ package agent
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
 	"testing"
 
 	"codeberg.org/algopatterns/server/internal/llm"
 	"codeberg.org/algopatterns/server/internal/retriever"
+	"codeberg.org/algopatterns/server/internal/strudel"
 )
 
 // implements llm.LLM for testing
@@ -187,6 +191,26 @@ func TestGenerateCode(t *testing.T) {
 	}
 }
 
+func TestGenerateFormatsCode(t *testing.T) {
+	mockGen := &mockLLM{
+		generateTextFunc: func(_ context.Context, _ llm.TextGenerationRequest) (*llm.TextGenerationResponse, error) {
+			return &llm.TextGenerationResponse{Text: "```javascript\nnote(\"c  e g\").s(\"piano\").lpf(800).room(.5);\n```"}, nil
+		},
+	}
+
+	agent := New(&mockRetriever{}, mockGen)
+
+	resp, err := agent.Generate(context.Background(), GenerateRequest{UserQuery: "play a piano melody", FormatCode: true})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	want := "note(\"c e g\")\n  .s(\"piano\")\n  .lpf(800)\n  .room(.5)"
+	if resp.Code != want {
+		t.Errorf("expected formatted code %q, got %q", want, resp.Code)
+	}
+}
+
 func TestGenerateCodeWithConversationHistory(t *testing.T) {
 	ctx := context.Background()
 
@@ -489,6 +513,18 @@ func TestHasCodePatterns(t *testing.T) {
 	}
 }
 
+func TestLintWarnings(t *testing.T) {
+	code := "const unused = s(\"bd\")\ns(\"hh\").lpf(50000)"
+
+	warnings := lintWarnings(strudel.Lint(code))
+
+	// the unused variable is only informational and left out
+	want := []string{`line 2: lpf(50000) is outside the usable range 20 to 20000 (fix: use 20000)`}
+	if !reflect.DeepEqual(warnings, want) {
+		t.Errorf("lintWarnings() = %v, want %v", warnings, want)
+	}
+}
+
 func TestEnhancedInstructionsPresent(t *testing.T) {
 	instructions := getInstructions()
 
@@ -528,3 +564,353 @@ func TestEnhancedInstructionsPresent(t *testing.T) {
 
 	t.Logf("✓ All instruction sections and keywords present")
 }
+
+// implements llm.LLM and llm.ToolCaller for testing agentic mode
+type mockToolLLM struct {
+	mockLLM
+	generateWithToolsFunc func(ctx context.Context, req llm.ToolGenerationRequest) (*llm.ToolGenerationResponse, error)
+}
+
+func (m *mockToolLLM) GenerateWithTools(ctx context.Context, req llm.ToolGenerationRequest) (*llm.ToolGenerationResponse, error) {
+	return m.generateWithToolsFunc(ctx, req)
+}
+
+func TestGenerateAgentic(t *testing.T) {
+	ctx := context.Background()
+	calls := 0
+
+	mockGen := &mockToolLLM{
+		generateWithToolsFunc: func(_ context.Context, req llm.ToolGenerationRequest) (*llm.ToolGenerationResponse, error) {
+			calls++
+
+			switch calls {
+			case 1:
+				if len(req.Tools) != 3 {
+					t.Errorf("expected 3 tools without validator, got %d", len(req.Tools))
+				}
+
+				if !containsSubstr(req.SystemPrompt, "TOOLS") {
+					t.Error("expected system prompt to describe tools")
+				}
+
+				return &llm.ToolGenerationResponse{
+					ToolCalls: []llm.ToolCall{
+						{ID: "call_1", Name: "search_docs", Input: json.RawMessage(`{"query":"drums"}`)},
+						{ID: "call_2", Name: "analyze_code", Input: json.RawMessage(`{"code":"sound(\"bd\")"}`)},
+					},
+					Usage: llm.Usage{InputTokens: 100, OutputTokens: 10},
+				}, nil
+			case 2:
+				if len(req.Messages) != 3 {
+					t.Fatalf("expected query, tool calls and tool results, got %d messages", len(req.Messages))
+				}
+
+				results := req.Messages[2].ToolResults
+				if len(results) != 2 || results[0].ToolCallID != "call_1" || results[1].ToolCallID != "call_2" {
+					t.Errorf("unexpected tool results: %+v", results)
+				}
+
+				if results[0].IsError || !containsSubstr(results[0].Content, "Use sound() to play samples") {
+					t.Errorf("unexpected search_docs result: %+v", results[0])
+				}
+
+				if results[1].IsError || !containsSubstr(results[1].Content, `"sound_tags"`) {
+					t.Errorf("unexpected analyze_code result: %+v", results[1])
+				}
+
+				return &llm.ToolGenerationResponse{
+					ToolCalls: []llm.ToolCall{
+						{ID: "call_3", Name: "search_docs", Input: json.RawMessage(`{"query":"kick"}`)},
+						{ID: "call_4", Name: "unknown_tool", Input: json.RawMessage(`{}`)},
+					},
+					Usage: llm.Usage{InputTokens: 100, OutputTokens: 10},
+				}, nil
+			default:
+				results := req.Messages[len(req.Messages)-1].ToolResults
+				if len(results) != 2 || !results[1].IsError {
+					t.Errorf("expected unknown tool to return an error result, got %+v", results)
+				}
+
+				return &llm.ToolGenerationResponse{
+					Text:  "sound(\"bd*4\")",
+					Usage: llm.Usage{InputTokens: 100, OutputTokens: 10},
+				}, nil
+			}
+		},
+	}
+
+	agent := New(&mockRetriever{}, mockGen)
+
+	resp, err := agent.Generate(ctx, GenerateRequest{
+		UserQuery: "make a drum beat",
+		Agentic:   true,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if resp.Code != "sound(\"bd*4\")" || !resp.IsCodeResponse {
+		t.Errorf("unexpected code response: %q (is code: %v)", resp.Code, resp.IsCodeResponse)
+	}
+
+	if resp.ToolSteps != 2 {
+		t.Errorf("expected 2 tool steps, got %d", resp.ToolSteps)
+	}
+
+	// the same doc was found twice and must only be counted once
+	if resp.DocsRetrieved != 1 || len(resp.DocReferences) != 1 {
+		t.Errorf("expected 1 deduplicated doc, got %d (%d refs)", resp.DocsRetrieved, len(resp.DocReferences))
+	}
+
+	if resp.InputTokens != 300 || resp.OutputTokens != 30 {
+		t.Errorf("expected tokens summed across steps, got %d/%d", resp.InputTokens, resp.OutputTokens)
+	}
+}
+
+func TestGenerateAgenticStepBudget(t *testing.T) {
+	ctx := context.Background()
+	calls := 0
+
+	mockGen := &mockToolLLM{
+		generateWithToolsFunc: func(_ context.Context, req llm.ToolGenerationRequest) (*llm.ToolGenerationResponse, error) {
+			calls++
+
+			if req.DisableTools {
+				return &llm.ToolGenerationResponse{Text: "sound(\"hh*8\")"}, nil
+			}
+
+			// keep asking for tools until the budget runs out
+			return &llm.ToolGenerationResponse{
+				ToolCalls: []llm.ToolCall{
+					{ID: fmt.Sprintf("call_%d", calls), Name: "search_examples", Input: json.RawMessage(`{"query":"hats"}`)},
+				},
+			}, nil
+		},
+	}
+
+	agent := New(&mockRetriever{}, mockGen)
+
+	resp, err := agent.Generate(ctx, GenerateRequest{
+		UserQuery:    "add hats",
+		Agentic:      true,
+		MaxToolSteps: 2,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if calls != 3 {
+		t.Errorf("expected 2 tool rounds and a final call, got %d calls", calls)
+	}
+
+	if resp.ToolSteps != 2 {
+		t.Errorf("expected 2 tool steps, got %d", resp.ToolSteps)
+	}
+
+	if resp.Code != "sound(\"hh*8\")" {
+		t.Errorf("unexpected code: %s", resp.Code)
+	}
+}
+
+func TestGenerateAgenticFallsBackWithoutToolSupport(t *testing.T) {
+	ctx := context.Background()
+
+	agent := New(&mockRetriever{}, &mockLLM{})
+
+	resp, err := agent.Generate(ctx, GenerateRequest{
+		UserQuery: "make a drum beat",
+		Agentic:   true,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if resp.ToolSteps != 0 {
+		t.Errorf("expected fixed pipeline without tool steps, got %d", resp.ToolSteps)
+	}
+
+	if resp.DocsRetrieved != 1 {
+		t.Errorf("expected fixed pipeline retrieval, got %d docs", resp.DocsRetrieved)
+	}
+}
+
+func TestApplyEdits(t *testing.T) {
+	code := "setcpm(120)\n$: sound(\"bd*4\")\n$: sound(\"hh*8\")\n$: note(\"c2 g2\")"
+
+	tests := []struct {
+		name    string
+		edits   []Edit
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "replace single line",
+			edits: []Edit{{Op: EditReplace, StartLine: 3, Content: "$: sound(\"hh*8\").swing(4)"}},
+			want:  "setcpm(120)\n$: sound(\"bd*4\")\n$: sound(\"hh*8\").swing(4)\n$: note(\"c2 g2\")",
+		},
+		{
+			name:  "replace range with more lines",
+			edits: []Edit{{Op: EditReplace, StartLine: 2, EndLine: 3, Content: "$: stack(\n  sound(\"bd*4\"),\n  sound(\"hh*8\")\n)\n"}},
+			want:  "setcpm(120)\n$: stack(\n  sound(\"bd*4\"),\n  sound(\"hh*8\")\n)\n$: note(\"c2 g2\")",
+		},
+		{
+			name: "insert at top, delete and insert at end",
+			edits: []Edit{
+				{Op: EditInsert, StartLine: 0, Content: "// intro"},
+				{Op: EditDelete, StartLine: 4},
+				{Op: EditInsert, StartLine: 4, Content: "$: sound(\"cp\")"},
+			},
+			want: "// intro\nsetcpm(120)\n$: sound(\"bd*4\")\n$: sound(\"hh*8\")\n$: sound(\"cp\")",
+		},
+		{
+			name: "insert after replaced range",
+			edits: []Edit{
+				{Op: EditReplace, StartLine: 1, EndLine: 2, Content: "setcpm(90)"},
+				{Op: EditInsert, StartLine: 2, Content: "$: sound(\"sd\")"},
+			},
+			want: "setcpm(90)\n$: sound(\"sd\")\n$: sound(\"hh*8\")\n$: note(\"c2 g2\")",
+		},
+		{
+			name:    "out of range",
+			edits:   []Edit{{Op: EditReplace, StartLine: 5, Content: "x"}},
+			wantErr: true,
+		},
+		{
+			name: "overlapping edits",
+			edits: []Edit{
+				{Op: EditReplace, StartLine: 1, EndLine: 3, Content: "x"},
+				{Op: EditDelete, StartLine: 2},
+			},
+			wantErr: true,
+		},
+		{
+			name: "insert inside replaced range",
+			edits: []Edit{
+				{Op: EditReplace, StartLine: 1, EndLine: 3, Content: "x"},
+				{Op: EditInsert, StartLine: 2, Content: "y"},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "unknown op",
+			edits:   []Edit{{Op: "move", StartLine: 1}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyEdits(code, tt.edits)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyEdits() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("applyEdits() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEdits(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		want     int
+		wantOK   bool
+	}{
+		{
+			name:     "bare json",
+			response: `{"edits": [{"op": "delete", "start_line": 2}]}`,
+			want:     1,
+			wantOK:   true,
+		},
+		{
+			name:     "json fence",
+			response: "```json\n{\"edits\": [{\"op\": \"replace\", \"start_line\": 1, \"content\": \"x\"}, {\"op\": \"delete\", \"start_line\": 3}]}\n```",
+			want:     2,
+			wantOK:   true,
+		},
+		{
+			name:     "full code",
+			response: `$: stack(sound("bd*4"), sound("hh*8"))`,
+			wantOK:   false,
+		},
+		{
+			name:     "explanation",
+			response: "The .swing() function delays every other event.",
+			wantOK:   false,
+		},
+		{
+			name:     "empty edits",
+			response: `{"edits": []}`,
+			wantOK:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseEdits(tt.response)
+			if ok != tt.wantOK {
+				t.Fatalf("parseEdits() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if len(got) != tt.want {
+				t.Errorf("parseEdits() returned %d edits, want %d", len(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestGeneratePatchMode(t *testing.T) {
+	ctx := context.Background()
+	editorState := "setcpm(120)\n$: sound(\"bd*4\")\n$: sound(\"hh*8\")"
+	calls := 0
+
+	mockGen := &mockLLM{
+		generateTextFunc: func(_ context.Context, req llm.TextGenerationRequest) (*llm.TextGenerationResponse, error) {
+			calls++
+
+			if !containsSubstr(req.SystemPrompt, "  3 | $: sound(\"hh*8\")") {
+				t.Error("expected numbered editor state in system prompt")
+			}
+
+			// first answer is out of range, the retry fixes it
+			if calls == 1 {
+				return &llm.TextGenerationResponse{
+					Text: `{"edits": [{"op": "replace", "start_line": 4, "content": "$: sound(\"hh*8\").swing(4)"}]}`,
+				}, nil
+			}
+
+			if !containsSubstr(req.Messages[len(req.Messages)-1].Content, "out of range") {
+				t.Error("expected retry prompt to include the patch error")
+			}
+
+			return &llm.TextGenerationResponse{
+				Text: `{"edits": [{"op": "replace", "start_line": 3, "content": "$: sound(\"hh*8\").swing(4)"}]}`,
+			}, nil
+		},
+	}
+
+	agent := New(&mockRetriever{}, mockGen)
+
+	resp, err := agent.Generate(ctx, GenerateRequest{
+		UserQuery:   "make the hats swing",
+		EditorState: editorState,
+		PatchMode:   true,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	want := "setcpm(120)\n$: sound(\"bd*4\")\n$: sound(\"hh*8\").swing(4)"
+	if resp.Code != want || !resp.IsCodeResponse {
+		t.Errorf("unexpected merged code: %q", resp.Code)
+	}
+
+	if len(resp.Edits) != 1 || resp.Edits[0].StartLine != 3 {
+		t.Errorf("unexpected edits: %+v", resp.Edits)
+	}
+
+	if !resp.DidRetry || resp.ValidationError != "" {
+		t.Errorf("expected successful retry, got retry=%v error=%q", resp.DidRetry, resp.ValidationError)
+	}
+}
@@ -0,0 +1,245 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"codeberg.org/algopatterns/server/internal/llm"
+)
+
+// outcome of resolving a patch-mode response
+type patchResult struct {
+	Edits           []Edit
+	Code            string // merged code, empty if no valid patch could be applied
+	ValidationError string
+	DidRetry        bool
+	Usage           llm.Usage
+}
+
+// the json object the model returns in patch mode
+type patchResponse struct {
+	Edits []Edit `json:"edits"`
+}
+
+// reports whether a request should be answered with edits instead of full code
+func usePatchMode(req GenerateRequest) bool {
+	return req.PatchMode && strings.TrimSpace(req.EditorState) != ""
+}
+
+// turns a patch-mode response into edits and merged code. ok is false when the
+// model answered without edits (e.g. an explanation), so the caller handles the
+// response as usual. an unusable patch is retried once with the error.
+func (a *Agent) resolvePatch(
+	ctx context.Context,
+	generator llm.TextGenerator,
+	systemPrompt string,
+	req GenerateRequest,
+	responseText string,
+) (*patchResult, bool) {
+	edits, ok := parseEdits(responseText)
+	if !ok {
+		return nil, false
+	}
+
+	result := &patchResult{}
+	code, problem := a.checkPatch(ctx, req.EditorState, edits)
+
+	if problem != "" {
+		retryResponse, err := a.retryWithPatchError(ctx, generator, systemPrompt, req, responseText, problem)
+		if err == nil {
+			result.Usage.InputTokens += retryResponse.Usage.InputTokens
+			result.Usage.OutputTokens += retryResponse.Usage.OutputTokens
+
+			if retryEdits, ok := parseEdits(retryResponse.Text); ok {
+				if retryCode, retryProblem := a.checkPatch(ctx, req.EditorState, retryEdits); retryCode != "" {
+					edits, code, problem = retryEdits, retryCode, retryProblem
+					result.DidRetry = true
+				}
+			}
+		}
+	}
+
+	result.ValidationError = problem
+
+	// nothing applicable, leave the editor untouched
+	if code == "" {
+		return result, true
+	}
+
+	result.Edits = edits
+	result.Code = code
+
+	return result, true
+}
+
+// applies edits and validates the merged code. code is empty if the edits
+// cannot be applied, problem describes what is wrong with the patch or result.
+func (a *Agent) checkPatch(ctx context.Context, editorState string, edits []Edit) (code, problem string) {
+	merged, err := applyEdits(editorState, edits)
+	if err != nil {
+		return "", fmt.Sprintf("invalid patch: %v", err)
+	}
+
+	if a.validator != nil {
+		result, err := a.validator.Validate(ctx, merged)
+		if err == nil && !result.Valid {
+			return merged, formatValidationError(result)
+		}
+	}
+
+	return merged, ""
+}
+
+// asks the model to correct edits that could not be applied or produced invalid code
+func (a *Agent) retryWithPatchError(
+	ctx context.Context,
+	generator llm.TextGenerator,
+	systemPrompt string,
+	req GenerateRequest,
+	invalidPatch, problem string,
+) (*llm.TextGenerationResponse, error) {
+	retryHistory := make([]Message, 0, len(req.ConversationHistory)+2)
+	retryHistory = append(retryHistory, req.ConversationHistory...)
+	retryHistory = append(retryHistory, Message{
+		Role:    "user",
+		Content: req.UserQuery,
+	})
+
+	retryHistory = append(retryHistory, Message{
+		Role:    "assistant",
+		Content: invalidPatch,
+	})
+
+	retryPrompt := fmt.Sprintf(`
+	the edits you returned cannot be used: %s.
+	return corrected edits against the ORIGINAL numbered editor state, in the same json format.
+	do not include any explanation.`, problem)
+
+	return a.callGeneratorWithClient(ctx, generator, systemPrompt, retryPrompt, retryHistory)
+}
+
+// extracts edits from a response. accepts a bare json object or one inside a
+// markdown fence. returns false if the response contains no edits.
+func parseEdits(response string) ([]Edit, bool) {
+	trimmed := strings.TrimSpace(response)
+
+	if fenced := extractCodeFromFence(trimmed); fenced != "" {
+		trimmed = fenced
+	}
+
+	start := strings.Index(trimmed, "{")
+	end := strings.LastIndex(trimmed, "}")
+
+	if start == -1 || end <= start {
+		return nil, false
+	}
+
+	var patch patchResponse
+	if err := json.Unmarshal([]byte(trimmed[start:end+1]), &patch); err != nil {
+		return nil, false
+	}
+
+	if len(patch.Edits) == 0 {
+		return nil, false
+	}
+
+	return patch.Edits, true
+}
+
+// applies line-anchored edits to code. all edits refer to the original line
+// numbers (as shown by addLineNumbers) and must not overlap.
+func applyEdits(code string, edits []Edit) (string, error) {
+	lines := strings.Split(code, "\n")
+	lineCount := len(lines)
+
+	ranges := make(map[int]Edit)  // first line -> replace/delete edit
+	inserts := make(map[int]Edit) // anchor line -> insert edit
+	sorted := make([]Edit, 0, len(edits))
+
+	for _, edit := range edits {
+		switch edit.Op {
+		case EditReplace, EditDelete:
+			if edit.EndLine == 0 {
+				edit.EndLine = edit.StartLine
+			}
+
+			if edit.StartLine < 1 || edit.EndLine < edit.StartLine || edit.EndLine > lineCount {
+				return "", fmt.Errorf("%s lines %d-%d out of range (1-%d)", edit.Op, edit.StartLine, edit.EndLine, lineCount)
+			}
+
+			sorted = append(sorted, edit)
+
+		case EditInsert:
+			if edit.StartLine < 0 || edit.StartLine > lineCount {
+				return "", fmt.Errorf("insert after line %d out of range (0-%d)", edit.StartLine, lineCount)
+			}
+
+			if edit.Content == "" {
+				return "", fmt.Errorf("insert after line %d has no content", edit.StartLine)
+			}
+
+			if _, exists := inserts[edit.StartLine]; exists {
+				return "", fmt.Errorf("multiple inserts after line %d", edit.StartLine)
+			}
+
+			inserts[edit.StartLine] = edit
+
+		default:
+			return "", fmt.Errorf("unknown edit op %q", edit.Op)
+		}
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].StartLine < sorted[j].StartLine
+	})
+
+	for i, edit := range sorted {
+		if i > 0 && edit.StartLine <= sorted[i-1].EndLine {
+			return "", fmt.Errorf("edits overlap at line %d", edit.StartLine)
+		}
+
+		// an insert anchored inside a replaced range has no defined position
+		for anchor := edit.StartLine; anchor < edit.EndLine; anchor++ {
+			if _, exists := inserts[anchor]; exists {
+				return "", fmt.Errorf("insert after line %d falls inside edited lines %d-%d", anchor, edit.StartLine, edit.EndLine)
+			}
+		}
+
+		ranges[edit.StartLine] = edit
+	}
+
+	merged := make([]string, 0, lineCount)
+
+	if insert, exists := inserts[0]; exists {
+		merged = append(merged, editLines(insert.Content)...)
+	}
+
+	for line := 1; line <= lineCount; {
+		last := line
+
+		if edit, exists := ranges[line]; exists {
+			if edit.Op == EditReplace && edit.Content != "" {
+				merged = append(merged, editLines(edit.Content)...)
+			}
+			last = edit.EndLine
+		} else {
+			merged = append(merged, lines[line-1])
+		}
+
+		if insert, exists := inserts[last]; exists {
+			merged = append(merged, editLines(insert.Content)...)
+		}
+
+		line = last + 1
+	}
+
+	return strings.Join(merged, "\n"), nil
+}
+
+// splits edit content into lines, ignoring one trailing newline
+func editLines(content string) []string {
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
@@ -17,6 +17,8 @@ type SystemPromptContext struct {
 	Conversations []Message
 	QueryAnalysis *llm.QueryAnalysis // optional: helps generator tailor response
 	UsedRAGCache  bool               // if true, add instruction for requesting more docs
+	ToolsEnabled  bool               // if true, describe the tools the model can call
+	PatchMode     bool               // if true, number editor lines and ask for edits instead of full code
 }
 
 // assembles the complete system prompt
@@ -35,7 +37,11 @@ func buildSystemPrompt(ctx SystemPromptContext) string {
 		builder.WriteString("═══════════════════════════════════════════════════════════\n")
 		builder.WriteString("CURRENT EDITOR STATE\n")
 		builder.WriteString("═══════════════════════════════════════════════════════════\n\n")
-		builder.WriteString(ctx.EditorState)
+		if ctx.PatchMode {
+			builder.WriteString(addLineNumbers(ctx.EditorState))
+		} else {
+			builder.WriteString(ctx.EditorState)
+		}
 		builder.WriteString("\n\n")
 	}
 
@@ -145,6 +151,45 @@ func buildSystemPrompt(ctx SystemPromptContext) string {
 		builder.WriteString("Only use this if the provided docs are clearly insufficient for the current question.\n")
 	}
 
+	// section 8: patch instructions (only in patch mode)
+	if ctx.PatchMode {
+		builder.WriteString("\n\n")
+		builder.WriteString("═══════════════════════════════════════════════════════════\n")
+		builder.WriteString("PATCH MODE\n")
+		builder.WriteString("═══════════════════════════════════════════════════════════\n\n")
+		builder.WriteString("This overrides the rule to return the complete editor state.\n")
+		builder.WriteString("When changing code, respond ONLY with a JSON object of line edits against the numbered\n")
+		builder.WriteString("editor state above:\n\n")
+		builder.WriteString(`{"edits": [`)
+		builder.WriteString("\n")
+		builder.WriteString(`  {"op": "replace", "start_line": 3, "end_line": 4, "content": "new line 3\nnew line 4"},`)
+		builder.WriteString("\n")
+		builder.WriteString(`  {"op": "insert", "start_line": 7, "content": "line added after line 7"},`)
+		builder.WriteString("\n")
+		builder.WriteString(`  {"op": "delete", "start_line": 9}`)
+		builder.WriteString("\n]}\n\n")
+		builder.WriteString("- Line numbers always refer to the ORIGINAL numbered editor state\n")
+		builder.WriteString("- end_line defaults to start_line; insert with start_line 0 adds lines at the top\n")
+		builder.WriteString("- Edits must not overlap; content never includes line numbers or the \" | \" prefix\n")
+		builder.WriteString("- Only touch the lines the request needs, everything else is kept as-is\n")
+		builder.WriteString("- For questions and explanations, answer in plain text as usual\n")
+	}
+
+	// section 9: tool instructions (only in agentic mode)
+	if ctx.ToolsEnabled {
+		builder.WriteString("\n\n")
+		builder.WriteString("═══════════════════════════════════════════════════════════\n")
+		builder.WriteString("TOOLS\n")
+		builder.WriteString("═══════════════════════════════════════════════════════════\n\n")
+		builder.WriteString("You can call tools before answering:\n")
+		builder.WriteString("- search_docs: look up Strudel documentation for functions you are unsure about\n")
+		builder.WriteString("- search_examples: find community patterns for a style or technique\n")
+		builder.WriteString("- analyze_code: inspect the sounds, effects and complexity of a pattern\n")
+		builder.WriteString("- validate_code (when offered): check your code for syntax errors\n\n")
+		builder.WriteString("Search the docs instead of guessing. If validate_code is offered, validate generated code\n")
+		builder.WriteString("and fix any errors before giving your final answer. The final answer follows the instructions above.\n")
+	}
+
 	return builder.String()
 }
 
@@ -0,0 +1,449 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"codeberg.org/algopatterns/server/internal/llm"
+	"codeberg.org/algopatterns/server/internal/retriever"
+	"codeberg.org/algopatterns/server/internal/strudel"
+)
+
+const (
+	// default number of tool-calling rounds before the model must answer
+	defaultMaxToolSteps = 6
+
+	toolSearchDocs     = "search_docs"
+	toolSearchExamples = "search_examples"
+	toolValidateCode   = "validate_code"
+	toolAnalyzeCode    = "analyze_code"
+)
+
+var (
+	queryToolSchema = json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"query": {"type": "string", "description": "what to search for, e.g. \"reverb and delay effects\""}
+		},
+		"required": ["query"]
+	}`)
+
+	codeToolSchema = json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"code": {"type": "string", "description": "complete strudel code"}
+		},
+		"required": ["code"]
+	}`)
+)
+
+// input of the search tools
+type searchToolInput struct {
+	Query string `json:"query"`
+}
+
+// input of the code tools
+type codeToolInput struct {
+	Code string `json:"code"`
+}
+
+// json view of strudel.CodeAnalysis returned by analyze_code
+type codeAnalysisResult struct {
+	SoundTags      []string `json:"sound_tags"`
+	EffectTags     []string `json:"effect_tags"`
+	MusicalTags    []string `json:"musical_tags"`
+	ComplexityTags []string `json:"complexity_tags"`
+	Complexity     int      `json:"complexity"`
+	LineCount      int      `json:"line_count"`
+	FunctionCount  int      `json:"function_count"`
+	VariableCount  int      `json:"variable_count"`
+}
+
+// validate_code tool result, lint warnings point at likely musical mistakes in valid code
+type validationToolResult struct {
+	*strudel.ValidationResult
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// runs tool calls for one generation and collects everything retrieved,
+// so references can be reported like in the fixed pipeline
+type toolExecutor struct {
+	retriever    Retriever
+	validator    strudel.CodeValidator
+	editorState  string
+	docs         []retriever.SearchResult
+	examples     []retriever.ExampleResult
+	seenDocs     map[string]bool
+	seenExamples map[string]bool
+}
+
+// returns the tools offered to the model. validate_code is only offered
+// when a validator is configured.
+func (a *Agent) availableTools() []llm.Tool {
+	tools := []llm.Tool{
+		{
+			Name:        toolSearchDocs,
+			Description: "Search the Strudel documentation. Use it when you are unsure about a function, its arguments or mini-notation syntax.",
+			InputSchema: queryToolSchema,
+		},
+		{
+			Name:        toolSearchExamples,
+			Description: "Search public Strudel patterns shared by the community for examples of a style or technique.",
+			InputSchema: queryToolSchema,
+		},
+		{
+			Name:        toolAnalyzeCode,
+			Description: "Analyze Strudel code and return its sounds, effects, musical elements and complexity.",
+			InputSchema: codeToolSchema,
+		},
+	}
+
+	if a.validator != nil {
+		tools = append(tools, llm.Tool{
+			Name:        toolValidateCode,
+			Description: "Check Strudel code for syntax errors before answering. Returns the error and its location if the code is invalid, and warnings about likely musical mistakes such as unknown sounds or out of range effect values.",
+			InputSchema: codeToolSchema,
+		})
+	}
+
+	return tools
+}
+
+// generates a response by letting the model call tools in a loop. once the
+// step budget is spent the model is asked for a final answer without tools.
+func (a *Agent) generateWithTools(
+	ctx context.Context,
+	req GenerateRequest,
+	textGenerator llm.TextGenerator,
+	toolCaller llm.ToolCaller,
+) (*GenerateResponse, error) {
+	maxSteps := req.MaxToolSteps
+	if maxSteps <= 0 {
+		maxSteps = defaultMaxToolSteps
+	}
+
+	executor := &toolExecutor{
+		retriever:    a.retriever,
+		validator:    a.validator,
+		editorState:  req.EditorState,
+		seenDocs:     make(map[string]bool),
+		seenExamples: make(map[string]bool),
+	}
+
+	systemPrompt := buildSystemPrompt(SystemPromptContext{
+		Cheatsheet:    getCheatsheet(),
+		EditorState:   req.EditorState,
+		Conversations: req.ConversationHistory,
+		ToolsEnabled:  true,
+		PatchMode:     usePatchMode(req),
+	})
+
+	messages := make([]llm.ToolMessage, 0, len(req.ConversationHistory)+1)
+	for _, msg := range req.ConversationHistory {
+		messages = append(messages, llm.ToolMessage{
+			Role:    msg.Role,
+			Content: msg.Content,
+		})
+	}
+
+	messages = append(messages, llm.ToolMessage{
+		Role:    "user",
+		Content: req.UserQuery,
+	})
+
+	tools := a.availableTools()
+	totalInputTokens := 0
+	totalOutputTokens := 0
+	steps := 0
+
+	var response *llm.ToolGenerationResponse
+
+	for {
+		budgetSpent := steps >= maxSteps
+
+		resp, err := toolCaller.GenerateWithTools(ctx, llm.ToolGenerationRequest{
+			SystemPrompt: systemPrompt,
+			Messages:     messages,
+			Tools:        tools,
+			DisableTools: budgetSpent,
+			MaxTokens:    4096,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate code: %w", err)
+		}
+
+		totalInputTokens += resp.Usage.InputTokens
+		totalOutputTokens += resp.Usage.OutputTokens
+
+		if len(resp.ToolCalls) == 0 || budgetSpent {
+			response = resp
+			break
+		}
+
+		steps++
+
+		messages = append(messages,
+			llm.ToolMessage{
+				Role:      "assistant",
+				Content:   resp.Text,
+				ToolCalls: resp.ToolCalls,
+			},
+			llm.ToolMessage{
+				Role:        "user",
+				ToolResults: executor.run(ctx, resp.ToolCalls),
+			},
+		)
+	}
+
+	var validationError string
+	var edits []Edit
+	didRetry := false
+
+	content, isCode := analyzeResponse(response.Text)
+
+	patched := false
+	if usePatchMode(req) {
+		if patch, ok := a.resolvePatch(ctx, textGenerator, systemPrompt, req, response.Text); ok {
+			patched = true
+			edits = patch.Edits
+			content = patch.Code
+			isCode = patch.Code != ""
+			didRetry = patch.DidRetry
+			validationError = patch.ValidationError
+			totalInputTokens += patch.Usage.InputTokens
+			totalOutputTokens += patch.Usage.OutputTokens
+		}
+	}
+
+	// the model may skip validate_code, so check the final answer anyway
+	if a.validator != nil && !patched && isCode && content != "" {
+		result, err := a.validator.Validate(ctx, content)
+		if err == nil && !result.Valid {
+			validationError = result.Error
+		}
+	}
+
+	if req.FormatCode && !patched && isCode {
+		content = formatGeneratedCode(content)
+	}
+
+	strudelRefs, docRefs := buildReferences(executor.docs, executor.examples)
+	provider, model := servedBy(textGenerator, response.Provider, response.Model)
+
+	return &GenerateResponse{
+		Code:              content,
+		DocsRetrieved:     len(executor.docs),
+		ExamplesRetrieved: len(executor.examples),
+		Examples:          executor.examples,
+		Docs:              executor.docs,
+		StrudelReferences: strudelRefs,
+		DocReferences:     docRefs,
+		Model:             model,
+		Provider:          provider,
+		IsActionable:      true,
+		IsCodeResponse:    isCode,
+		InputTokens:       totalInputTokens,
+		OutputTokens:      totalOutputTokens,
+		DidRetry:          didRetry,
+		ValidationError:   validationError,
+		ToolSteps:         steps,
+		Edits:             edits,
+	}, nil
+}
+
+// executes tool calls in order. failures are reported to the model as error
+// results rather than aborting the generation.
+func (e *toolExecutor) run(ctx context.Context, calls []llm.ToolCall) []llm.ToolResult {
+	results := make([]llm.ToolResult, 0, len(calls))
+
+	for _, call := range calls {
+		content, err := e.execute(ctx, call)
+		if err != nil {
+			log.Printf("agent tool %s failed: %v", call.Name, err)
+			results = append(results, llm.ToolResult{
+				ToolCallID: call.ID,
+				Content:    err.Error(),
+				IsError:    true,
+			})
+			continue
+		}
+
+		results = append(results, llm.ToolResult{
+			ToolCallID: call.ID,
+			Content:    content,
+		})
+	}
+
+	return results
+}
+
+// executes a single tool call and returns its result text
+func (e *toolExecutor) execute(ctx context.Context, call llm.ToolCall) (string, error) {
+	switch call.Name {
+	case toolSearchDocs:
+		var input searchToolInput
+		if err := decodeToolInput(call.Input, &input); err != nil || input.Query == "" {
+			return "", fmt.Errorf("query is required")
+		}
+
+		docs, err := e.retriever.HybridSearchDocs(ctx, input.Query, e.editorState, 3)
+		if err != nil {
+			return "", fmt.Errorf("failed to search docs: %w", err)
+		}
+
+		e.addDocs(docs)
+		return formatDocsResult(docs), nil
+
+	case toolSearchExamples:
+		var input searchToolInput
+		if err := decodeToolInput(call.Input, &input); err != nil || input.Query == "" {
+			return "", fmt.Errorf("query is required")
+		}
+
+		examples, err := e.retriever.HybridSearchExamples(ctx, input.Query, e.editorState, 2)
+		if err != nil {
+			return "", fmt.Errorf("failed to search examples: %w", err)
+		}
+
+		e.addExamples(examples)
+		return formatExamplesResult(examples), nil
+
+	case toolAnalyzeCode:
+		var input codeToolInput
+		if err := decodeToolInput(call.Input, &input); err != nil || input.Code == "" {
+			return "", fmt.Errorf("code is required")
+		}
+
+		analysis := strudel.AnalyzeCode(input.Code)
+
+		return marshalToolResult(codeAnalysisResult{
+			SoundTags:      analysis.SoundTags,
+			EffectTags:     analysis.EffectTags,
+			MusicalTags:    analysis.MusicalTags,
+			ComplexityTags: analysis.ComplexityTags,
+			Complexity:     analysis.Complexity,
+			LineCount:      analysis.LineCount,
+			FunctionCount:  analysis.FunctionCount,
+			VariableCount:  analysis.VariableCount,
+		})
+
+	case toolValidateCode:
+		if e.validator == nil {
+			return "", fmt.Errorf("validation is not available")
+		}
+
+		var input codeToolInput
+		if err := decodeToolInput(call.Input, &input); err != nil || input.Code == "" {
+			return "", fmt.Errorf("code is required")
+		}
+
+		result, err := e.validator.Validate(ctx, input.Code)
+		if err != nil {
+			return "", fmt.Errorf("failed to validate code: %w", err)
+		}
+
+		return marshalToolResult(validationToolResult{
+			ValidationResult: result,
+			Warnings:         lintWarnings(strudel.Lint(input.Code)),
+		})
+
+	default:
+		return "", fmt.Errorf("unknown tool: %s", call.Name)
+	}
+}
+
+// records docs not seen earlier in this generation
+func (e *toolExecutor) addDocs(docs []retriever.SearchResult) {
+	for _, doc := range docs {
+		key := doc.ID
+		if key == "" {
+			key = doc.PageURL + "#" + doc.SectionTitle
+		}
+
+		if e.seenDocs[key] {
+			continue
+		}
+
+		e.seenDocs[key] = true
+		e.docs = append(e.docs, doc)
+	}
+}
+
+// records examples not seen earlier in this generation
+func (e *toolExecutor) addExamples(examples []retriever.ExampleResult) {
+	for _, example := range examples {
+		if e.seenExamples[example.ID] {
+			continue
+		}
+
+		e.seenExamples[example.ID] = true
+		e.examples = append(e.examples, example)
+	}
+}
+
+// decodes tool input, treating a missing input as an empty object
+func decodeToolInput(raw json.RawMessage, v any) error {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(raw, v)
+}
+
+func marshalToolResult(v any) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal tool result: %w", err)
+	}
+
+	return string(data), nil
+}
+
+// formats doc search results as a tool result
+func formatDocsResult(docs []retriever.SearchResult) string {
+	if len(docs) == 0 {
+		return "no documentation found"
+	}
+
+	var builder strings.Builder
+
+	for _, doc := range docs {
+		builder.WriteString(fmt.Sprintf("[%s", doc.PageName))
+		if doc.SectionTitle != "" {
+			builder.WriteString(fmt.Sprintf(" - %s", doc.SectionTitle))
+		}
+		builder.WriteString("]\n")
+		builder.WriteString(doc.Content)
+		builder.WriteString("\n\n")
+	}
+
+	return strings.TrimSpace(builder.String())
+}
+
+// formats example search results as a tool result
+func formatExamplesResult(examples []retriever.ExampleResult) string {
+	if len(examples) == 0 {
+		return "no examples found"
+	}
+
+	var builder strings.Builder
+
+	for _, example := range examples {
+		builder.WriteString(fmt.Sprintf("[%s]", example.Title))
+		if example.AuthorName != "" {
+			builder.WriteString(fmt.Sprintf(" by %s", example.AuthorName))
+		}
+		builder.WriteString("\n")
+		if example.Description != "" {
+			builder.WriteString(example.Description)
+			builder.WriteString("\n")
+		}
+		builder.WriteString(example.Code)
+		builder.WriteString("\n\n")
+	}
+
+	return strings.TrimSpace(builder.String())
+}
@@ -26,7 +26,7 @@ type RAGCache interface {
 type Agent struct {
 	retriever Retriever
 	generator llm.LLM
-	validator *strudel.Validator
+	validator strudel.CodeValidator
 }
 
 // all inputs for code generation
@@ -37,6 +37,25 @@ type GenerateRequest struct {
 	CustomGenerator     llm.TextGenerator // optional byok generator
 	SessionID           string            // optional: enables rag caching for follow-up messages
 	RAGCache            RAGCache          // optional: cache for rag results
+	Agentic             bool              // optional: let the model call tools mid-generation (needs native tool use)
+	MaxToolSteps        int               // optional: tool-calling rounds for agentic mode (default 6)
+	PatchMode           bool              // optional: ask for line-anchored edits against EditorState instead of full code
+	FormatCode          bool              // optional: run generated code through the strudel formatter (not applied to patches)
+}
+
+// edit operations for patch mode
+const (
+	EditReplace = "replace"
+	EditInsert  = "insert"
+	EditDelete  = "delete"
+)
+
+// a line-anchored change against the numbered editor state
+type Edit struct {
+	Op        string `json:"op"`                 // "replace", "insert" or "delete"
+	StartLine int    `json:"start_line"`         // first line (1-based); for insert, the line to insert after (0 = top)
+	EndLine   int    `json:"end_line,omitempty"` // last replaced or deleted line, defaults to start_line
+	Content   string `json:"content,omitempty"`  // new lines for replace and insert
 }
 
 // reference to a strudel used as context
@@ -64,6 +83,7 @@ type GenerateResponse struct {
 	StrudelReferences   []StrudelReference        `json:"strudel_references,omitempty"`
 	DocReferences       []DocReference            `json:"doc_references,omitempty"`
 	Model               string                    `json:"model"`
+	Provider            string                    `json:"provider,omitempty"` // provider that served the request, if known
 	IsActionable        bool                      `json:"is_actionable"`
 	IsCodeResponse      bool                      `json:"is_code_response"` // true if response should update editor
 	ClarifyingQuestions []string                  `json:"clarifying_questions,omitempty"`
@@ -71,6 +91,8 @@ type GenerateResponse struct {
 	OutputTokens        int                       `json:"output_tokens"`
 	DidRetry            bool                      `json:"did_retry,omitempty"`
 	ValidationError     string                    `json:"validation_error,omitempty"`
+	ToolSteps           int                       `json:"tool_steps,omitempty"` // tool-calling rounds used in agentic mode
+	Edits               []Edit                    `json:"edits,omitempty"`      // applied patch in patch mode, Code holds the merged result
 }
 
 // chunk of a streaming response
@@ -83,6 +105,7 @@ type StreamEvent struct {
 	StrudelReferences []StrudelReference `json:"strudel_references,omitempty"`
 	DocReferences     []DocReference     `json:"doc_references,omitempty"`
 	Model             string             `json:"model,omitempty"`
+	Provider          string             `json:"provider,omitempty"`
 	IsCodeResponse    bool               `json:"is_code_response,omitempty"`
 	InputTokens       int                `json:"input_tokens,omitempty"`
 	OutputTokens      int                `json:"output_tokens,omitempty"`
@@ -3,6 +3,7 @@ package agent
 import (
 	"context"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
 	"strings"
@@ -184,15 +185,7 @@ func (a *Agent) retryWithValidationError(
 	numberedCode := addLineNumbers(invalidCode)
 
 	// build error message with location info if available
-	errorMsg := validationResult.Error
-
-	if validationResult.Line != nil {
-		if validationResult.Column != nil {
-			errorMsg = fmt.Sprintf("%s (line %d, column %d)", validationResult.Error, *validationResult.Line, *validationResult.Column)
-		} else {
-			errorMsg = fmt.Sprintf("%s (line %d)", validationResult.Error, *validationResult.Line)
-		}
-	}
+	errorMsg := formatValidationError(validationResult)
 
 	retryPrompt := fmt.Sprintf(`
 	the code you generated has a syntax error and will not run: %s.
@@ -200,9 +193,100 @@ func (a *Agent) retryWithValidationError(
 	please fix the error and return only the corrected strudel code.
 	do not include any explanation or line numbers.`, numberedCode, errorMsg)
 
+	// lint findings point at musical mistakes worth fixing in the same pass
+	if warnings := lintWarnings(strudel.Lint(invalidCode)); len(warnings) > 0 {
+		retryPrompt += fmt.Sprintf(`
+	also fix these likely mistakes:
+	- %s`, strings.Join(warnings, "\n\t- "))
+	}
+
 	return a.callGeneratorWithClient(ctx, generator, systemPrompt, retryPrompt, retryHistory)
 }
 
+// returns the provider and model that served a response, falling back to the
+// generator's model when the response doesn't say (e.g. byok generators)
+func servedBy(generator llm.TextGenerator, provider llm.Provider, model string) (string, string) {
+	if model == "" {
+		model = generator.Model()
+	}
+
+	return string(provider), model
+}
+
+// builds strudel and doc references for frontend display, deduping docs by page URL
+func buildReferences(docs []retriever.SearchResult, examples []retriever.ExampleResult) ([]StrudelReference, []DocReference) {
+	strudelRefs := make([]StrudelReference, 0, len(examples))
+	for _, ex := range examples {
+		strudelRefs = append(strudelRefs, StrudelReference{
+			ID:         ex.ID,
+			Title:      ex.Title,
+			AuthorName: ex.AuthorName,
+			URL:        fmt.Sprintf("/strudel/%s", ex.ID),
+		})
+	}
+
+	docRefs := make([]DocReference, 0, len(docs))
+	seen := make(map[string]bool)
+	for _, doc := range docs {
+		if seen[doc.PageURL] {
+			continue
+		}
+		seen[doc.PageURL] = true
+		docRefs = append(docRefs, DocReference{
+			PageName:     doc.PageName,
+			SectionTitle: doc.SectionTitle,
+			URL:          doc.PageURL,
+		})
+	}
+
+	return strudelRefs, docRefs
+}
+
+// formats generated code, keeping it unchanged if the formatter rejects it
+func formatGeneratedCode(code string) string {
+	formatted, err := strudel.Format(code)
+	if err != nil {
+		log.Printf("failed to format generated code: %v", err)
+		return code
+	}
+
+	// extracted code has no trailing newline, keep it that way
+	return strings.TrimSuffix(formatted, "\n")
+}
+
+// formats lint findings of warning or error severity with their location and fix
+func lintWarnings(findings []strudel.Finding) []string {
+	var warnings []string
+
+	for _, finding := range findings {
+		if finding.Severity == strudel.SeverityInfo {
+			continue
+		}
+
+		warning := fmt.Sprintf("line %d: %s", finding.Range.Start.Line, finding.Message)
+		if finding.Fix != nil {
+			warning += fmt.Sprintf(" (fix: %s)", finding.Fix.Description)
+		}
+
+		warnings = append(warnings, warning)
+	}
+
+	return warnings
+}
+
+// formats a validation error with its location if available
+func formatValidationError(result *strudel.ValidationResult) string {
+	if result.Line == nil {
+		return result.Error
+	}
+
+	if result.Column != nil {
+		return fmt.Sprintf("%s (line %d, column %d)", result.Error, *result.Line, *result.Column)
+	}
+
+	return fmt.Sprintf("%s (line %d)", result.Error, *result.Line)
+}
+
 // converts retriever.SearchResult slice to buffer.CachedDoc slice
 func docsToCache(docs []retriever.SearchResult) []buffer.CachedDoc {
 	cached := make([]buffer.CachedDoc, len(docs))
@@ -0,0 +1,213 @@
+package attribution
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"codeberg.org/algopatterns/server/algopatterns/strudels"
+	"github.com/jackc/pgx/v5"
+)
+
+// fork ancestors credited at most, deeper chains are cut off
+const maxUpstreamCredits = 100
+
+// collects the credits of a public strudel: the strudels it was forked from,
+// the example strudels the AI assistant used and the docs it cited
+func (s *Service) GetStrudelCredits(ctx context.Context, strudelID string) (*StrudelCredits, error) {
+	strudel, err := scanCreditedStrudel(s.db.QueryRow(ctx, queryGetCreditedStrudel, strudelID))
+	if err != nil {
+		return nil, err
+	}
+
+	upstream, err := s.listCreditedStrudels(ctx, queryListUpstreamStrudels, strudelID, maxUpstreamCredits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list upstream strudels: %w", err)
+	}
+
+	examples, err := s.listCreditedStrudels(ctx, queryListExampleStrudels, strudelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list example strudels: %w", err)
+	}
+
+	docs, err := s.listCitedDocs(ctx, strudelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cited docs: %w", err)
+	}
+
+	return &StrudelCredits{
+		Strudel:  *strudel,
+		Upstream: upstream,
+		Examples: examples,
+		Docs:     docs,
+	}, nil
+}
+
+func (s *Service) listCreditedStrudels(ctx context.Context, query string, args ...any) ([]CreditedStrudel, error) {
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	credited := make([]CreditedStrudel, 0)
+
+	for rows.Next() {
+		strudel, err := scanCreditedStrudel(rows)
+		if err != nil {
+			return nil, err
+		}
+		credited = append(credited, *strudel)
+	}
+
+	return credited, rows.Err()
+}
+
+func (s *Service) listCitedDocs(ctx context.Context, strudelID string) ([]CreditedDoc, error) {
+	rows, err := s.db.Query(ctx, queryListCitedDocs, strudelID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	docs := make([]CreditedDoc, 0)
+
+	for rows.Next() {
+		var doc CreditedDoc
+
+		if err := rows.Scan(&doc.PageName, &doc.SectionTitle, &doc.URL); err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+
+	return docs, rows.Err()
+}
+
+func scanCreditedStrudel(row pgx.Row) (*CreditedStrudel, error) {
+	var strudel CreditedStrudel
+	var isPublic bool
+
+	err := row.Scan(&strudel.ID, &strudel.Title, &strudel.AuthorName, &strudel.License, &isPublic, &strudel.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isPublic {
+		return &CreditedStrudel{Private: true, CreatedAt: strudel.CreatedAt}, nil
+	}
+
+	strudel.URL = fmt.Sprintf("/strudel/%s", strudel.ID)
+
+	return &strudel, nil
+}
+
+// formats credits as plain text, one line per credited work
+func FormatCreditsText(credits *StrudelCredits) string {
+	var b strings.Builder
+
+	b.WriteString(describeStrudel(credits.Strudel) + "\n")
+
+	writeSection := func(title string, lines []string) {
+		if len(lines) == 0 {
+			return
+		}
+
+		b.WriteString("\n" + title + "\n")
+		for _, line := range lines {
+			b.WriteString("- " + line + "\n")
+		}
+	}
+
+	writeSection("Forked from:", describeStrudels(credits.Upstream))
+	writeSection("AI assistant examples:", describeStrudels(credits.Examples))
+	writeSection("Documentation:", describeDocs(credits.Docs))
+
+	return b.String()
+}
+
+// formats credits in the REUSE style: SPDX tags for the copyright holders of
+// the strudel and its upstream forks and for its license, with everything
+// credited listed in comments
+func FormatCreditsSPDX(credits *StrudelCredits) string {
+	var b strings.Builder
+	seen := make(map[string]bool)
+
+	for _, strudel := range append([]CreditedStrudel{credits.Strudel}, credits.Upstream...) {
+		if strudel.Private {
+			continue
+		}
+
+		holder := fmt.Sprintf("%d %s", strudel.CreatedAt.Year(), strudel.AuthorName)
+		if !seen[holder] {
+			seen[holder] = true
+			b.WriteString("SPDX-FileCopyrightText: " + holder + "\n")
+		}
+	}
+
+	license := ""
+	if credits.Strudel.License != nil {
+		license = *credits.Strudel.License
+	}
+
+	b.WriteString("SPDX-License-Identifier: " + strudels.SPDXLicenseID(license) + "\n")
+
+	comments := func(label string, lines []string) {
+		for _, line := range lines {
+			b.WriteString("# " + label + ": " + line + "\n")
+		}
+	}
+
+	if len(credits.Upstream)+len(credits.Examples)+len(credits.Docs) > 0 {
+		b.WriteString("\n")
+	}
+
+	comments("Forked from", describeStrudels(credits.Upstream))
+	comments("AI assistant example", describeStrudels(credits.Examples))
+	comments("Documentation", describeDocs(credits.Docs))
+
+	return b.String()
+}
+
+// describes a credited strudel: "Title" by Author, CC BY 4.0 (/strudel/id)
+func describeStrudel(strudel CreditedStrudel) string {
+	if strudel.Private {
+		return "a private strudel"
+	}
+
+	license := "no license"
+	if strudel.License != nil && *strudel.License != "" {
+		license = *strudel.License
+	}
+
+	return fmt.Sprintf("%q by %s, %s (%s)", strudel.Title, strudel.AuthorName, license, strudel.URL)
+}
+
+func describeStrudels(credited []CreditedStrudel) []string {
+	lines := make([]string, 0, len(credited))
+	for _, strudel := range credited {
+		lines = append(lines, describeStrudel(strudel))
+	}
+
+	return lines
+}
+
+// describes a cited doc: Page - Section (url)
+func describeDocs(docs []CreditedDoc) []string {
+	lines := make([]string, 0, len(docs))
+
+	for _, doc := range docs {
+		line := doc.PageName
+		if doc.SectionTitle != "" {
+			line += " - " + doc.SectionTitle
+		}
+
+		if doc.URL != "" {
+			line += " (" + doc.URL + ")"
+		}
+
+		lines = append(lines, line)
+	}
+
+	return lines
+}
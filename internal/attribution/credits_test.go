@@ -0,0 +1,88 @@
+package attribution
+
+import (
+	"testing"
+	"time"
+)
+
+func testCredits() *StrudelCredits {
+	license := "CC BY-NC-SA 4.0"
+	upstreamLicense := "CC BY-NC 4.0"
+
+	return &StrudelCredits{
+		Strudel: CreditedStrudel{
+			ID:         "s1",
+			Title:      "night bus",
+			AuthorName: "ada",
+			License:    &license,
+			URL:        "/strudel/s1",
+			CreatedAt:  time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		},
+		Upstream: []CreditedStrudel{
+			{Private: true, CreatedAt: time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)},
+			{
+				ID:         "s0",
+				Title:      "bus stop",
+				AuthorName: "lin",
+				License:    &upstreamLicense,
+				URL:        "/strudel/s0",
+				CreatedAt:  time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		Examples: []CreditedStrudel{
+			{ID: "e1", Title: "dub", AuthorName: "kai", URL: "/strudel/e1"},
+		},
+		Docs: []CreditedDoc{
+			{PageName: "Effects", SectionTitle: "room", URL: "https://strudel.cc/learn/effects"},
+			{PageName: "Mini-Notation"},
+		},
+	}
+}
+
+func TestFormatCreditsText(t *testing.T) {
+	want := `"night bus" by ada, CC BY-NC-SA 4.0 (/strudel/s1)
+
+Forked from:
+- a private strudel
+- "bus stop" by lin, CC BY-NC 4.0 (/strudel/s0)
+
+AI assistant examples:
+- "dub" by kai, no license (/strudel/e1)
+
+Documentation:
+- Effects - room (https://strudel.cc/learn/effects)
+- Mini-Notation
+`
+
+	if got := FormatCreditsText(testCredits()); got != want {
+		t.Errorf("FormatCreditsText() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFormatCreditsSPDX(t *testing.T) {
+	want := `SPDX-FileCopyrightText: 2026 ada
+SPDX-FileCopyrightText: 2025 lin
+SPDX-License-Identifier: CC-BY-NC-SA-4.0
+
+# Forked from: a private strudel
+# Forked from: "bus stop" by lin, CC BY-NC 4.0 (/strudel/s0)
+# AI assistant example: "dub" by kai, no license (/strudel/e1)
+# Documentation: Effects - room (https://strudel.cc/learn/effects)
+# Documentation: Mini-Notation
+`
+
+	if got := FormatCreditsSPDX(testCredits()); got != want {
+		t.Errorf("FormatCreditsSPDX() =\n%s\nwant\n%s", got, want)
+	}
+
+	credits := &StrudelCredits{Strudel: CreditedStrudel{
+		Title:      "solo",
+		AuthorName: "ada",
+		CreatedAt:  time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+	}}
+	want = "SPDX-FileCopyrightText: 2026 ada\nSPDX-License-Identifier: NOASSERTION\n"
+
+	if got := FormatCreditsSPDX(credits); got != want {
+		t.Errorf("FormatCreditsSPDX() = %q, want %q", got, want)
+	}
+}
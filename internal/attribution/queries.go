@@ -110,4 +110,71 @@ const (
 	queryGetStrudelForkCount = `
 		SELECT COUNT(*) FROM user_strudels WHERE forked_from = $1
 	`
+
+	queryGetCreditedStrudel = `
+		SELECT s.id, s.title, COALESCE(u.name, 'Anonymous'), s.license, s.is_public, s.created_at
+		FROM user_strudels s
+		LEFT JOIN users u ON s.user_id = u.id
+		WHERE s.id = $1 AND s.is_public = true
+	`
+
+	// walks forked_from up from the strudel. the path stops the walk at a cycle
+	queryListUpstreamStrudels = `
+		WITH RECURSIVE upstream AS (
+			SELECT s.forked_from AS id, 1 AS depth, ARRAY[s.id] AS path
+			FROM user_strudels s
+			WHERE s.id = $1 AND s.forked_from IS NOT NULL
+			UNION ALL
+			SELECT p.forked_from, up.depth + 1, up.path || p.id
+			FROM upstream up
+			INNER JOIN user_strudels p ON p.id = up.id
+			WHERE p.forked_from IS NOT NULL
+			  AND NOT p.forked_from = ANY(up.path || p.id)
+			  AND up.depth < $2
+		)
+		SELECT s.id, s.title, COALESCE(u.name, 'Anonymous'), s.license, s.is_public, s.created_at
+		FROM upstream up
+		INNER JOIN user_strudels s ON s.id = up.id
+		LEFT JOIN users u ON s.user_id = u.id
+		ORDER BY up.depth
+	`
+
+	// strudels the agent used as examples, recorded as rag attributions or as
+	// references on the strudel's saved messages
+	queryListExampleStrudels = `
+		WITH referenced AS (
+			SELECT ra.source_strudel_id AS id
+			FROM rag_attributions ra
+			WHERE ra.target_strudel_id = $1
+			UNION
+			SELECT CASE
+				WHEN ref->>'id' ~* '^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$'
+				THEN (ref->>'id')::uuid
+			END
+			FROM strudel_messages m
+			CROSS JOIN LATERAL jsonb_array_elements(
+				CASE WHEN jsonb_typeof(m.strudel_references) = 'array' THEN m.strudel_references ELSE '[]'::jsonb END
+			) ref
+			WHERE m.strudel_id = $1
+		)
+		SELECT s.id, s.title, COALESCE(u.name, 'Anonymous'), s.license, s.is_public, s.created_at
+		FROM referenced r
+		INNER JOIN user_strudels s ON s.id = r.id
+		LEFT JOIN users u ON s.user_id = u.id
+		WHERE s.id <> $1
+		ORDER BY s.title, s.id
+	`
+
+	queryListCitedDocs = `
+		SELECT DISTINCT
+			COALESCE(ref->>'page_name', ''),
+			COALESCE(ref->>'section_title', ''),
+			COALESCE(ref->>'url', '')
+		FROM strudel_messages m
+		CROSS JOIN LATERAL jsonb_array_elements(
+			CASE WHEN jsonb_typeof(m.doc_references) = 'array' THEN m.doc_references ELSE '[]'::jsonb END
+		) ref
+		WHERE m.strudel_id = $1
+		ORDER BY 1, 2, 3
+	`
 )
@@ -40,3 +40,29 @@ type StrudelStatsResponse struct {
 	Stats      StrudelStats `json:"stats"`
 	RecentUses []StrudelUse `json:"recent_uses"`
 }
+
+// a strudel named in credits. private strudels are credited without details.
+type CreditedStrudel struct {
+	ID         string    `json:"id,omitempty"`
+	Title      string    `json:"title,omitempty"`
+	AuthorName string    `json:"author_name,omitempty"`
+	License    *string   `json:"license,omitempty"`
+	URL        string    `json:"url,omitempty"`
+	Private    bool      `json:"private,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// a documentation page the agent cited
+type CreditedDoc struct {
+	PageName     string `json:"page_name"`
+	SectionTitle string `json:"section_title,omitempty"`
+	URL          string `json:"url,omitempty"`
+}
+
+// everything a strudel builds on, as its license requires crediting
+type StrudelCredits struct {
+	Strudel  CreditedStrudel   `json:"strudel"`
+	Upstream []CreditedStrudel `json:"upstream"` // fork ancestors, parent first
+	Examples []CreditedStrudel `json:"examples"` // strudels the AI assistant used as examples
+	Docs     []CreditedDoc     `json:"docs"`
+}
@@ -129,6 +129,30 @@ func (d *Defense) Middleware() gin.HandlerFunc {
 	}
 }
 
+// returns a Gin middleware that limits requests per IP to a costly route,
+// on top of the global rate limit. route names the counter, limit is the
+// number of requests allowed per rate limit window.
+func (d *Defense) RouteRateLimit(route string, limit int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !d.config.Enabled {
+			c.Next()
+			return
+		}
+
+		ip := c.ClientIP()
+
+		count, err := d.store.IncrementRouteRate(c.Request.Context(), route, ip)
+		if err != nil {
+			logger.ErrorErr(err, "failed to increment route rate", "ip", ip, "route", route)
+		} else if count > int64(limit) {
+			d.handleRateLimited(c, ip)
+			return
+		}
+
+		c.Next()
+	}
+}
+
 func (d *Defense) handleHoneypot(ctx context.Context, c *gin.Context, ip, path string) {
 	logger.Warn("honeypot triggered", "ip", ip, "path", path)
 
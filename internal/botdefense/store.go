@@ -11,6 +11,7 @@ import (
 const (
 	keyTrappedIP  = "botdefense:trapped:%s"
 	keyRateLimit  = "botdefense:rate:%s"
+	keyRouteRate  = "botdefense:rate:%s:%s"
 	keyTrapReason = "botdefense:reason:%s"
 )
 
@@ -103,3 +104,19 @@ func (s *Store) IncrementRate(ctx context.Context, ip string) (int64, error) {
 
 	return incrCmd.Val(), nil
 }
+
+// increments the request count for an IP on one route and returns the new count
+func (s *Store) IncrementRouteRate(ctx context.Context, route, ip string) (int64, error) {
+	key := fmt.Sprintf(keyRouteRate, route, ip)
+
+	pipe := s.client.Pipeline()
+	incrCmd := pipe.Incr(ctx, key)
+	pipe.Expire(ctx, key, s.config.RateLimitWindow)
+
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return incrCmd.Val(), nil
+}
@@ -225,3 +225,21 @@ func (r *BufferedRepository) ListStaleSessions(ctx context.Context, threshold ti
 func (r *BufferedRepository) CountActiveParticipants(ctx context.Context, sessionID string) (int, error) {
 	return r.db.CountActiveParticipants(ctx, sessionID)
 }
+
+// === SNAPSHOT OPERATIONS ===
+
+func (r *BufferedRepository) CreateSnapshot(ctx context.Context, req *sessions.CreateSnapshotRequest) (*sessions.Snapshot, error) {
+	return r.db.CreateSnapshot(ctx, req)
+}
+
+func (r *BufferedRepository) ListSnapshots(ctx context.Context, sessionID string, limit, offset int) ([]*sessions.Snapshot, int, error) {
+	return r.db.ListSnapshots(ctx, sessionID, limit, offset)
+}
+
+func (r *BufferedRepository) GetSnapshot(ctx context.Context, sessionID, snapshotID string) (*sessions.Snapshot, error) {
+	return r.db.GetSnapshot(ctx, sessionID, snapshotID)
+}
+
+func (r *BufferedRepository) GetLatestSnapshot(ctx context.Context, sessionID string) (*sessions.Snapshot, error) {
+	return r.db.GetLatestSnapshot(ctx, sessionID)
+}
@@ -0,0 +1,186 @@
+package ccsignals
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"codeberg.org/algopatterns/server/internal/strudel"
+)
+
+// letters from other scripts that render like ASCII letters
+var confusables = map[rune]rune{
+	// cyrillic
+	'а': 'a', 'в': 'b', 'с': 'c', 'ԁ': 'd', 'е': 'e', 'һ': 'h', 'і': 'i', 'ј': 'j', 'к': 'k',
+	'м': 'm', 'н': 'h', 'о': 'o', 'р': 'p', 'ԛ': 'q', 'ѕ': 's', 'т': 't', 'у': 'y', 'ԝ': 'w',
+	'х': 'x', 'ү': 'y', 'ѵ': 'v',
+	'А': 'A', 'В': 'B', 'С': 'C', 'Е': 'E', 'Н': 'H', 'І': 'I', 'Ј': 'J', 'К': 'K', 'М': 'M',
+	'О': 'O', 'Р': 'P', 'Ѕ': 'S', 'Т': 'T', 'У': 'Y', 'Х': 'X', 'Ү': 'Y',
+	// greek
+	'α': 'a', 'ε': 'e', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o', 'ρ': 'p', 'τ': 't', 'υ': 'u',
+	'χ': 'x',
+	'Α': 'A', 'Β': 'B', 'Ε': 'E', 'Ζ': 'Z', 'Η': 'H', 'Ι': 'I', 'Κ': 'K', 'Μ': 'M', 'Ν': 'N',
+	'Ο': 'O', 'Ρ': 'P', 'Τ': 'T', 'Υ': 'Y', 'Χ': 'X',
+	// latin
+	'ı': 'i', 'ȷ': 'j', 'ℓ': 'l', 'ɑ': 'a', 'ɡ': 'g',
+	// quotes and dashes
+	'‘': '\'', '’': '\'', '‚': '\'', '′': '\'', '“': '"', '”': '"', '„': '"', '″': '"',
+	'‐': '-', '‑': '-', '‒': '-', '–': '-', '—': '-', '−': '-',
+}
+
+// returns code in a canonical form so that edits which keep the code the same
+// for a reader give the same fingerprint: confusable unicode is folded to
+// ASCII, comments are dropped, whitespace is collapsed, strings use double
+// quotes and variables declared in the code are renamed v0, v1, ... in order
+// of declaration. property names are kept, .fast(2) stays .fast(2).
+func canonicalize(code string) string {
+	code = foldConfusables(code)
+	tokens, _ := strudel.Tokenize(code)
+
+	renames := make(map[string]string)
+	for _, name := range declaredNames(tokens) {
+		if _, ok := renames[name]; !ok {
+			renames[name] = fmt.Sprintf("v%d", len(renames))
+		}
+	}
+
+	parts := make([]string, 0, len(tokens))
+	previous := ""
+
+	for _, token := range tokens {
+		switch token.Kind {
+		case strudel.TokenEOF, strudel.TokenComment:
+			continue
+
+		case strudel.TokenIdent:
+			name, ok := renames[token.Text]
+			if !ok || previous == "." || previous == "?." {
+				name = token.Text
+			}
+
+			parts = append(parts, name)
+
+		case strudel.TokenString:
+			parts = append(parts, quoteString(token.Value))
+
+		case strudel.TokenTemplate:
+			// substitutions make the template code, not a string
+			if strings.Contains(token.Value, "${") {
+				parts = append(parts, strings.Join(strings.Fields(token.Text), " "))
+			} else {
+				parts = append(parts, quoteString(token.Value))
+			}
+
+		default:
+			parts = append(parts, token.Text)
+		}
+
+		previous = token.Text
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// returns the names declared with let, const or var in order, including
+// later declarators of a list like let a = 1, b = 2. works on the tokens
+// alone, fingerprinting runs on every edit and must not pay for a parse.
+func declaredNames(tokens []strudel.Token) []string {
+	var names []string
+	declaring, expectName := false, false
+	depth := 0
+
+	for _, token := range tokens {
+		if token.Kind == strudel.TokenComment {
+			continue
+		}
+
+		if declaring && depth == 0 && (token.NewlineBefore || token.Text == ";") && !expectName {
+			declaring = false
+		}
+
+		switch {
+		case token.Kind == strudel.TokenIdent && (token.Text == "let" || token.Text == "const" || token.Text == "var"):
+			declaring, expectName = true, true
+			depth = 0
+
+		case expectName:
+			if token.Kind == strudel.TokenIdent {
+				names = append(names, token.Text)
+			}
+
+			expectName = false
+
+		case !declaring || token.Kind != strudel.TokenPunct:
+
+		case token.Text == "(" || token.Text == "[" || token.Text == "{":
+			depth++
+
+		case token.Text == ")" || token.Text == "]" || token.Text == "}":
+			depth--
+
+			// the closing bracket of e.g. for (let i = 0; ...) ends the declaration
+			if depth < 0 {
+				declaring = false
+			}
+
+		case token.Text == "," && depth == 0:
+			expectName = true
+		}
+	}
+
+	return names
+}
+
+// replaces confusable letters, fullwidth forms and mathematical letters with
+// their ASCII look-alikes, turns unicode spaces into plain spaces and drops
+// invisible characters and combining marks
+func foldConfusables(text string) string {
+	var builder strings.Builder
+	builder.Grow(len(text))
+
+	for _, r := range text {
+		switch {
+		case r == '\u200b' || r == '\u200c' || r == '\u200d' || r == '\u2060' || r == '\ufeff' || r == '\u00ad':
+			continue
+
+		case unicode.Is(unicode.Mn, r):
+			continue
+
+		case r > unicode.MaxASCII && unicode.IsSpace(r):
+			builder.WriteRune(' ')
+
+		case r >= '\uff01' && r <= '\uff5e':
+			builder.WriteRune(r - 0xfee0)
+
+		case r >= 0x1d400 && r <= 0x1d6a3:
+			// 13 styles of A-Z a-z, bold, italic, script, fraktur, ...
+			offset := (r - 0x1d400) % 52
+			if offset < 26 {
+				builder.WriteRune('A' + offset)
+			} else {
+				builder.WriteRune('a' + offset - 26)
+			}
+
+		case r >= 0x1d7ce && r <= 0x1d7ff:
+			builder.WriteRune('0' + (r-0x1d7ce)%10)
+
+		default:
+			if folded, ok := confusables[r]; ok {
+				r = folded
+			}
+
+			builder.WriteRune(r)
+		}
+	}
+
+	return builder.String()
+}
+
+// quotes a string value with double quotes. whitespace is collapsed, it is
+// not significant in mini-notation.
+func quoteString(value string) string {
+	value = strings.Join(strings.Fields(value), " ")
+	value = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value)
+
+	return `"` + value + `"`
+}
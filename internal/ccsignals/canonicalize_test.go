@@ -0,0 +1,330 @@
+package ccsignals
+
+import (
+	"slices"
+	"testing"
+
+	"codeberg.org/algopatterns/server/internal/strudel"
+)
+
+func TestCanonicalize(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want string
+	}{
+		{
+			name: "comments are dropped",
+			code: "// my own work\ns(\"bd sd\") /* kick */.fast(2) // faster",
+			want: `s ( "bd sd" ) . fast ( 2 )`,
+		},
+		{
+			name: "whitespace is collapsed",
+			code: "s(  \"bd   sd\"\n\n\t)\n  .fast( 2 )",
+			want: `s ( "bd sd" ) . fast ( 2 )`,
+		},
+		{
+			name: "quotes are canonicalized",
+			code: "s('bd sd').bank(`RolandTR909`).vowel('it\\'s \"a\"')",
+			want: `s ( "bd sd" ) . bank ( "RolandTR909" ) . vowel ( "it's \"a\"" )`,
+		},
+		{
+			name: "templates with substitutions are kept",
+			code: "note(`c ${root}`)",
+			want: "note ( `c ${root}` )",
+		},
+		{
+			name: "variables are renamed in order of declaration",
+			code: "const drums = s(\"bd\")\nlet bass = note(\"c2\")\nstack(drums, bass)",
+			want: `const v0 = s ( "bd" ) let v1 = note ( "c2" ) stack ( v0 , v1 )`,
+		},
+		{
+			name: "properties named like variables are kept",
+			code: "let fast = 2\ns(\"bd\").fast(fast)",
+			want: `let v0 = 2 s ( "bd" ) . fast ( v0 )`,
+		},
+		{
+			name: "homoglyphs are folded",
+			code: "ѕоund(\"bd\")",
+			want: `sound ( "bd" )`,
+		},
+		{
+			name: "empty code",
+			code: "  \n",
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := canonicalize(tt.code); got != tt.want {
+				t.Errorf("canonicalize(%q) = %q, want %q", tt.code, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeclaredNames(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want []string
+	}{
+		{name: "declarations", code: "const a = 1\nlet b = 2\nvar c", want: []string{"a", "b", "c"}},
+		{name: "declarator list", code: "let a = 1, b = f(x, y), c = [d, e]", want: []string{"a", "b", "c"}},
+		{name: "statement ends the list", code: "let a = 1; f(b, c)\nlet d = 2\ng(e, f)", want: []string{"a", "d"}},
+		{name: "for loop", code: "for (let i = 0; i < 4; i++) { s(\"bd\") }", want: []string{"i"}},
+		{name: "comments are skipped", code: "let /* kick */ drums = s(\"bd\")", want: []string{"drums"}},
+		{name: "no declarations", code: "s(\"bd sd\").fast(2)", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, _ := strudel.Tokenize(tt.code)
+			if got := declaredNames(tokens); !slices.Equal(got, tt.want) {
+				t.Errorf("declaredNames(%q) = %v, want %v", tt.code, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFoldConfusables(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"ascii is unchanged", `note("c e g")`, `note("c e g")`},
+		{"cyrillic", "nоtе", "note"},
+		{"greek", "Αρο", "Apo"},
+		{"fullwidth", "ｓ（＂ｂｄ＂）", `s("bd")`},
+		{"mathematical letters", "𝐬𝐨𝐮𝐧𝐝 𝟐", "sound 2"},
+		{"zero width characters", "so\u200bu\ufeffnd", "sound"},
+		{"combining marks", "no\u0301te", "note"},
+		{"unicode spaces", "s\u00a0(\u3000)", "s ( )"},
+		{"smart quotes", "s(“bd”)", `s("bd")`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := foldConfusables(tt.text); got != tt.want {
+				t.Errorf("foldConfusables(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+// protected strudels and edits meant to evade fingerprinting them
+var evasionCorpus = []struct {
+	original string
+	evasions map[string][]string
+}{
+	{
+		original: `setcps(0.5)
+const drums = s("bd sd [~ bd] sd, hh*8").bank("RolandTR909").gain(0.8)
+const bass = note("<c2 c2 eb2 g1>").s("sawtooth").lpf(600).decay(0.2)
+const keys = note("<[c4,eb4,g4] [ab3,c4,eb4]>").s("piano").room(0.4)
+stack(drums, bass, keys.slow(2))`,
+		evasions: map[string][]string{
+			"comments": {
+				`// original composition, all rights mine
+setcps(0.5)
+// drums
+const drums = s("bd sd [~ bd] sd, hh*8").bank("RolandTR909").gain(0.8) // punchy
+/* a deep bass line
+   written from scratch */
+const bass = note("<c2 c2 eb2 g1>").s("sawtooth").lpf(600).decay(0.2)
+const keys = note("<[c4,eb4,g4] [ab3,c4,eb4]>").s("piano").room(0.4) // chords
+// play it all
+stack(drums, bass, keys.slow(2))`,
+				`setcps(0.5) /* tempo */ /* tempo */ /* tempo */
+const drums = s("bd sd [~ bd] sd, hh*8") /* a */ .bank("RolandTR909") /* b */ .gain(0.8)
+const bass = note("<c2 c2 eb2 g1>") /* c */ .s("sawtooth") /* d */ .lpf(600).decay(0.2)
+const keys = note("<[c4,eb4,g4] [ab3,c4,eb4]>") /* e */ .s("piano").room(0.4)
+stack(drums, bass, keys.slow(2)) // lorem ipsum dolor sit amet consectetur adipiscing elit`,
+			},
+			"whitespace": {
+				`setcps( 0.5 )
+
+
+const   drums =   s( "bd  sd  [~ bd]  sd,  hh*8" )
+    .bank( "RolandTR909" )
+    .gain( 0.8 )
+const   bass  = note( "<c2  c2  eb2  g1>" )
+    .s( "sawtooth" )
+    .lpf( 600 )
+    .decay( 0.2 )
+const   keys  = note( "<[c4,eb4,g4]  [ab3,c4,eb4]>" ).s( "piano" ).room( 0.4 )
+
+
+stack( drums,  bass,  keys.slow( 2 ) )`,
+				"setcps(0.5)\n\tconst drums = s(\"bd sd [~ bd] sd, hh*8\").bank(\"RolandTR909\").gain(0.8)\n\t\tconst bass = note(\"<c2 c2 eb2 g1>\").s(\"sawtooth\").lpf(600).decay(0.2)\n\t\t\tconst keys = note(\"<[c4,eb4,g4] [ab3,c4,eb4]>\").s(\"piano\").room(0.4)\n\t\t\t\tstack(drums, bass, keys.slow(2))",
+			},
+			"renamed variables": {
+				`setcps(0.5)
+const beat = s("bd sd [~ bd] sd, hh*8").bank("RolandTR909").gain(0.8)
+const low = note("<c2 c2 eb2 g1>").s("sawtooth").lpf(600).decay(0.2)
+const harmony = note("<[c4,eb4,g4] [ab3,c4,eb4]>").s("piano").room(0.4)
+stack(beat, low, harmony.slow(2))`,
+				`setcps(0.5)
+const a = s("bd sd [~ bd] sd, hh*8").bank("RolandTR909").gain(0.8)
+const b = note("<c2 c2 eb2 g1>").s("sawtooth").lpf(600).decay(0.2)
+const c = note("<[c4,eb4,g4] [ab3,c4,eb4]>").s("piano").room(0.4)
+stack(a, b, c.slow(2))`,
+			},
+			"homoglyphs": {
+				`setcps(0.5)
+const drums = ѕ("bd ѕd [~ bd] ѕd, hh*8").bаnk("RоlаndTR909").gаin(0.8)
+const bass = nоtе("<с2 с2 еb2 g1>").ѕ("ѕаwtооth").lpf(600).dесаy(0.2)
+const keys = nоtе("<[с4,еb4,g4] [аb3,с4,еb4]>").ѕ("рiаnо").rооm(0.4)
+stack(drums, bass, keys.slow(2))`,
+				`ｓｅｔｃｐｓ(0.5)
+const drums = 𝐬("bd sd [~ bd] sd, hh*8").bank("RolandTR909").gain(0.8)
+const bass = note("<c2 c2 eb2 g1>").s("sawtooth").lpf(600).decay(0.2)
+const keys = ` + "n\u200bo\u200bt\u200be" + `("<[c4,eb4,g4] [ab3,c4,eb4]>").s("piano").room(0.4)
+stack(drums, bass, keys.slow(2))`,
+			},
+			"quotes": {
+				`setcps(0.5)
+const drums = s('bd sd [~ bd] sd, hh*8').bank('RolandTR909').gain(0.8)
+const bass = note('<c2 c2 eb2 g1>').s('sawtooth').lpf(600).decay(0.2)
+const keys = note('<[c4,eb4,g4] [ab3,c4,eb4]>').s('piano').room(0.4)
+stack(drums, bass, keys.slow(2))`,
+				"setcps(0.5)\nconst drums = s(`bd sd [~ bd] sd, hh*8`).bank(`RolandTR909`).gain(0.8)\nconst bass = note(`<c2 c2 eb2 g1>`).s(`sawtooth`).lpf(600).decay(0.2)\nconst keys = note(`<[c4,eb4,g4] [ab3,c4,eb4]>`).s(`piano`).room(0.4)\nstack(drums, bass, keys.slow(2))",
+			},
+			"combined": {
+				`// my track
+setcps( 0.5 )
+let x1 = s('bd  sd [~ bd] sd,  hh*8')  // drums
+  .bаnk('RolandTR909').gain( 0.8 )
+let x2 = nоte('<c2 c2 eb2 g1>').s(` + "`sawtooth`" + `)  /* bass */
+  .lpf(600).decay(0.2)
+let x3 = note('<[c4,eb4,g4] [ab3,c4,eb4]>').s('piаno').room(0.4)
+stack(x1, x2, x3.slow(2))`,
+			},
+		},
+	},
+	{
+		original: `samples('github:tidalcycles/dirt-samples')
+let melody = n("0 2 4 <7 9> 4 2").scale("D:dorian").s("triangle").delay(0.25)
+let hats = s("hh*16").gain(perlin.range(0.3, 0.7)).pan(sine.slow(4))
+let kick = s("bd*4").shape(0.3)
+stack(melody.jux(rev), hats, kick).cpm(120)`,
+		evasions: map[string][]string{
+			"comments": {
+				`// written by me
+samples('github:tidalcycles/dirt-samples') // samples
+let melody = n("0 2 4 <7 9> 4 2").scale("D:dorian").s("triangle").delay(0.25) // lead
+let hats = s("hh*16").gain(perlin.range(0.3, 0.7)).pan(sine.slow(4)) // hats
+/* four on the floor */
+let kick = s("bd*4").shape(0.3)
+stack(melody.jux(rev), hats, kick).cpm(120) // done`,
+			},
+			"whitespace": {
+				`samples( 'github:tidalcycles/dirt-samples' )
+
+let melody = n( "0  2  4  <7 9>  4  2" )
+  .scale( "D:dorian" )
+  .s( "triangle" )
+  .delay( 0.25 )
+
+let hats = s( "hh*16" )
+  .gain( perlin.range( 0.3, 0.7 ) )
+  .pan( sine.slow( 4 ) )
+
+let kick = s( "bd*4" ).shape( 0.3 )
+
+stack( melody.jux( rev ), hats, kick ).cpm( 120 )`,
+			},
+			"renamed variables": {
+				`samples('github:tidalcycles/dirt-samples')
+let tune = n("0 2 4 <7 9> 4 2").scale("D:dorian").s("triangle").delay(0.25)
+let top = s("hh*16").gain(perlin.range(0.3, 0.7)).pan(sine.slow(4))
+let low = s("bd*4").shape(0.3)
+stack(tune.jux(rev), top, low).cpm(120)`,
+			},
+			"homoglyphs": {
+				`sаmplеs('github:tidalcycles/dirt-samples')
+let melody = n("0 2 4 <7 9> 4 2").ѕcаlе("D:dorian").ѕ("triаnglе").dеlаy(0.25)
+let hats = ѕ("hh*16").gаin(реrlin.rаngе(0.3, 0.7)).раn(ѕinе.ѕlоw(4))
+let kick = ѕ("bd*4").ѕhаре(0.3)
+ѕtасk(melody.jux(rеv), hats, kick).срm(120)`,
+			},
+			"quotes": {
+				`samples("github:tidalcycles/dirt-samples")
+let melody = n('0 2 4 <7 9> 4 2').scale('D:dorian').s('triangle').delay(0.25)
+let hats = s('hh*16').gain(perlin.range(0.3, 0.7)).pan(sine.slow(4))
+let kick = s('bd*4').shape(0.3)
+stack(melody.jux(rev), hats, kick).cpm(120)`,
+			},
+		},
+	},
+}
+
+// code unrelated to the corpus, which must not match it
+var unrelatedCorpus = []string{
+	`setcpm(90)
+$: note("c3 [e3 g3] a3 <b3 d4>").s("gm_electric_guitar_muted").clip(0.5).hpf(300)
+$: s("<bd cp> [~ bd] <sd rim> ~").bank("AkaiLinn").velocity("0.9 0.6")
+$: n(irand(8).segment(16)).scale("A:minor:pentatonic").s("sine").release(0.1)`,
+	`let chords = chord("<Am7 Dm7 G7 Cmaj7>").voicing().s("gm_epiano1").attack(0.05)
+let arp = n("0 1 2 3 4 5 6 7").arp("up").s("square").lpf(sine.range(400, 4000).slow(8))
+let drums = s("[bd ~ ~ bd] [~ cp] [bd bd ~ ~] [~ cp ~ ~]").room(0.2).orbit(2)
+stack(chords, arp.gain(0.4), drums).late("[0 .01]*4")`,
+}
+
+func TestCanonicalize_EvasionCorpus(t *testing.T) {
+	hasher := NewSimHasher(DefaultShingleSize)
+
+	// the share of evasions of each kind that must stay within the match threshold
+	wantMatchRates := map[string]float64{
+		"comments":          1,
+		"whitespace":        1,
+		"renamed variables": 1,
+		"homoglyphs":        1,
+		"quotes":            1,
+		"combined":          1,
+	}
+
+	total := make(map[string]int)
+	matched := make(map[string]int)
+
+	for _, entry := range evasionCorpus {
+		original := hasher.Hash(entry.original)
+
+		for kind, evasions := range entry.evasions {
+			for _, evasion := range evasions {
+				distance := HammingDistance(original, hasher.Hash(evasion))
+				total[kind]++
+
+				if distance <= DefaultSimilarityThreshold {
+					matched[kind]++
+				} else {
+					t.Logf("%s evasion at distance %d:\n%s", kind, distance, evasion)
+				}
+			}
+		}
+	}
+
+	for kind, want := range wantMatchRates {
+		if total[kind] == 0 {
+			t.Errorf("no %s evasions in the corpus", kind)
+			continue
+		}
+
+		rate := float64(matched[kind]) / float64(total[kind])
+		if rate < want {
+			t.Errorf("%s match rate = %.2f (%d/%d), want at least %.2f", kind, rate, matched[kind], total[kind], want)
+		}
+	}
+
+	for _, code := range unrelatedCorpus {
+		fingerprint := hasher.Hash(code)
+
+		for _, entry := range evasionCorpus {
+			if distance := HammingDistance(fingerprint, hasher.Hash(entry.original)); distance <= DefaultSimilarityThreshold {
+				t.Errorf("unrelated code matches a protected strudel at distance %d:\n%s", distance, code)
+			}
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 var (
@@ -112,13 +113,86 @@ func (d *Detector) DetectPaste(ctx context.Context, _, userID, previousCode, new
 	}, nil
 }
 
+// records the text an update inserted in the user's rolling window for the
+// session and checks the accumulated insertions against protected
+// fingerprints every RollingWindowStep inserted characters. catches protected
+// code pasted in chunks that each stay below PasteDeltaThreshold.
+func (d *Detector) DetectChunkedPaste(ctx context.Context, sessionID, userID, previousCode, newCode string) (*DetectionResult, error) {
+	if d.store == nil {
+		return nil, ErrNilStore
+	}
+
+	if d.fingerprints == nil || d.config.RollingWindowSize <= 0 || d.config.RollingWindowTTL <= 0 {
+		return &DetectionResult{
+			ShouldLock: false,
+			Reason:     "rolling window disabled",
+		}, nil
+	}
+
+	inserted := insertedText(previousCode, newCode)
+	if inserted == "" {
+		return &DetectionResult{
+			ShouldLock: false,
+			Reason:     "no inserted text",
+		}, nil
+	}
+
+	window, due, err := d.store.AppendInserted(ctx, sessionID, userID, inserted, d.config.RollingWindowSize, d.config.RollingWindowStep, d.config.RollingWindowTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	if !due {
+		return &DetectionResult{
+			ShouldLock: false,
+			Reason:     "too little inserted since the last check",
+		}, nil
+	}
+
+	if len(window) < d.config.PasteDeltaThreshold {
+		return &DetectionResult{
+			ShouldLock: false,
+			Reason:     "accumulated insertions below threshold",
+		}, nil
+	}
+
+	fpMatch := d.fingerprints.FindBestMatch(window)
+
+	// users may paste their own protected work in any size
+	if fpMatch == nil || fpMatch.Record.CCSignal.AllowsAI() || (userID != "" && fpMatch.Record.CreatorID == userID) {
+		return &DetectionResult{
+			ShouldLock:       false,
+			Reason:           "accumulated insertions do not match protected work",
+			FingerprintMatch: fpMatch,
+		}, nil
+	}
+
+	// start a fresh window, otherwise every later edit would lock again and
+	// reset the baseline the unlock check compares against
+	if err := d.store.ClearInserted(ctx, sessionID, userID); err != nil {
+		return nil, err
+	}
+
+	return &DetectionResult{
+		ShouldLock:       true,
+		Reason:           "accumulated insertions are similar to protected work with no-ai restriction",
+		FingerprintMatch: fpMatch,
+	}, nil
+}
+
 // handles a code update event, managing locks as needed
 func (d *Detector) ProcessCodeUpdate(ctx context.Context, sessionID, userID, previousCode, newCode string) error {
 	if d.store == nil {
 		return ErrNilStore
 	}
 
-	result, err := d.DetectPaste(ctx, sessionID, userID, previousCode, newCode)
+	detect := d.DetectPaste
+	if !d.IsLargeDelta(previousCode, newCode) {
+		// small updates may still add up to protected code pasted in chunks
+		detect = d.DetectChunkedPaste
+	}
+
+	result, err := detect(ctx, sessionID, userID, previousCode, newCode)
 	if err != nil {
 		return err
 	}
@@ -259,6 +333,47 @@ func (d *Detector) IsSignificantEdit(baseline, current string) bool {
 	return normalized >= d.config.UnlockThreshold
 }
 
+// returns the text an update inserted: what replaced the span between the
+// common prefix and suffix of the previous and new code
+func insertedText(previousCode, newCode string) string {
+	prefix := 0
+	for prefix < len(previousCode) && prefix < len(newCode) && previousCode[prefix] == newCode[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(previousCode)-prefix && suffix < len(newCode)-prefix &&
+		previousCode[len(previousCode)-1-suffix] == newCode[len(newCode)-1-suffix] {
+		suffix++
+	}
+
+	// keep multi-byte characters whole
+	for prefix > 0 && prefix < len(newCode) && !utf8.RuneStart(newCode[prefix]) {
+		prefix--
+	}
+
+	end := len(newCode) - suffix
+	for end < len(newCode) && !utf8.RuneStart(newCode[end]) {
+		end++
+	}
+
+	return newCode[prefix:end]
+}
+
+// keeps the last maxLen bytes of a rolling window, starting at a whole character
+func trimWindow(window string, maxLen int) string {
+	if len(window) <= maxLen {
+		return window
+	}
+
+	start := len(window) - maxLen
+	for start < len(window) && !utf8.RuneStart(window[start]) {
+		start++
+	}
+
+	return window[start:]
+}
+
 func abs(x int) int {
 	if x < 0 {
 		return -x
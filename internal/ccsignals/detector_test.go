@@ -3,7 +3,9 @@ package ccsignals
 import (
 	"context"
 	"errors"
+	"slices"
 	"testing"
+	"time"
 )
 
 func TestDetector_IsLargeDelta(t *testing.T) {
@@ -356,6 +358,225 @@ func TestDetector_WithFingerprints(t *testing.T) {
 	})
 }
 
+func TestDetector_DetectChunkedPaste(t *testing.T) {
+	ctx := context.Background()
+	config := DefaultConfig()
+
+	protected := "note(\"c3 eb3 g3 bb3\").s(\"sawtooth\").lpf(sine.range(300, 2000).slow(8)).room(.6)\n" +
+		"stack(s(\"bd*2 [~ bd] sd ~\").bank(\"RolandTR909\"), s(\"hh*8\").gain(perlin.range(.3, .7)))\n" +
+		"n(\"0 2 4 <6 7> 9 7 4 2\").scale(\"C4:dorian\").s(\"piano\").delay(.4).delayfeedback(.5)\n" +
+		"s(\"bass:3*4\").note(\"<c2 f1 g1 eb2>\").clip(.8).shape(.4).jux(rev).every(4, x => x.fast(2))"
+
+	// pastes code in 150 character chunks, returning whether the session ends up locked
+	pasteInChunks := func(t *testing.T, d *Detector, sessionID, userID, code string) bool {
+		t.Helper()
+		current := ""
+
+		for start := 0; start < len(code); start += 150 {
+			next := code[:min(start+150, len(code))]
+			if err := d.ProcessCodeUpdate(ctx, sessionID, userID, current, next); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			current = next
+		}
+
+		locked, err := d.IsLocked(ctx, sessionID)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return locked
+	}
+
+	newDetector := func(signal CCSignal) (*Detector, *MemoryLockStore) {
+		indexed := NewInMemoryIndexedStore(4, 10, 3)
+		indexed.AddFromStrudel("work1", "creator1", signal, protected)
+		store := NewMemoryLockStore()
+
+		return NewDetector(config, store, nil).WithFingerprints(indexed), store
+	}
+
+	t.Run("protected code pasted in chunks locks", func(t *testing.T) {
+		d, store := newDetector(SignalNoAI)
+		defer func() { _ = store.Close() }() //nolint:errcheck // test cleanup
+
+		if !pasteInChunks(t, d, "session1", "user1", protected) {
+			t.Error("expected session to be locked after chunked paste")
+		}
+
+		// the window starts over once the session is locked, at most the last chunk is left
+		window, _, err := store.AppendInserted(ctx, "session1", "user1", "", config.RollingWindowSize, 0, config.RollingWindowTTL)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(window) > 150 {
+			t.Errorf("expected the window to start over after locking, got %d bytes", len(window))
+		}
+	})
+
+	t.Run("single chunk below threshold does not lock", func(t *testing.T) {
+		d, store := newDetector(SignalNoAI)
+		defer func() { _ = store.Close() }() //nolint:errcheck // test cleanup
+
+		result, err := d.DetectChunkedPaste(ctx, "session1", "user1", "", protected[:150])
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.ShouldLock {
+			t.Error("expected no lock below the threshold")
+		}
+	})
+
+	t.Run("own protected work does not lock", func(t *testing.T) {
+		d, store := newDetector(SignalNoAI)
+		defer func() { _ = store.Close() }() //nolint:errcheck // test cleanup
+
+		if pasteInChunks(t, d, "session1", "creator1", protected) {
+			t.Error("expected no lock when pasting own work")
+		}
+	})
+
+	t.Run("work that allows AI does not lock", func(t *testing.T) {
+		d, store := newDetector(SignalCredit)
+		defer func() { _ = store.Close() }() //nolint:errcheck // test cleanup
+
+		if pasteInChunks(t, d, "session1", "user1", protected) {
+			t.Error("expected no lock for work that allows AI")
+		}
+	})
+
+	t.Run("unrelated code does not lock", func(t *testing.T) {
+		d, store := newDetector(SignalNoAI)
+		defer func() { _ = store.Close() }() //nolint:errcheck // test cleanup
+
+		unrelated := "samples('github:tidalcycles/dirt-samples')\n" +
+			"$: s(\"<breaks165 breaks152>\").fit().chop(16).cut(1).sometimesBy(.3, x => x.speed(-1))\n" +
+			"$: note(\"[e5 b4 d5 c5]!2 a4 ~\").s(\"triangle\").decay(.2).sustain(0).pan(saw.range(.2, .8))\n" +
+			"$: s(\"cp\").struct(\"~ x ~ x\").orbit(2).room(.9).size(.8).lpf(1200).velocity(.7)"
+
+		if pasteInChunks(t, d, "session1", "user1", unrelated) {
+			t.Error("expected no lock for unrelated code")
+		}
+	})
+
+	t.Run("disabled without fingerprints", func(t *testing.T) {
+		store := NewMemoryLockStore()
+		defer func() { _ = store.Close() }() //nolint:errcheck // test cleanup
+		d := NewDetector(config, store, nil)
+
+		result, err := d.DetectChunkedPaste(ctx, "session1", "user1", "", protected)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.ShouldLock {
+			t.Error("expected no lock without fingerprints")
+		}
+	})
+}
+
+func TestMemoryLockStore_RollingWindow(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryLockStore()
+	defer func() { _ = store.Close() }() //nolint:errcheck // test cleanup
+
+	t.Run("keeps the most recent insertions", func(t *testing.T) {
+		for _, text := range []string{"abc", "def", "ghi"} {
+			if _, _, err := store.AppendInserted(ctx, "session1", "user1", text, 5, 0, time.Minute); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+
+		window, _, _ := store.AppendInserted(ctx, "session1", "user1", "", 5, 0, time.Minute) //nolint:errcheck // memory store never fails
+		if window != "efghi" {
+			t.Errorf("window = %q, want %q", window, "efghi")
+		}
+	})
+
+	t.Run("sessions have separate windows", func(t *testing.T) {
+		window, _, _ := store.AppendInserted(ctx, "session2", "user1", "xyz", 5, 0, time.Minute) //nolint:errcheck // memory store never fails
+		if window != "xyz" {
+			t.Errorf("window = %q, want %q", window, "xyz")
+		}
+	})
+
+	t.Run("users have separate windows", func(t *testing.T) {
+		window, _, _ := store.AppendInserted(ctx, "session1", "user2", "uvw", 5, 0, time.Minute) //nolint:errcheck // memory store never fails
+		if window != "uvw" {
+			t.Errorf("window = %q, want %q", window, "uvw")
+		}
+	})
+
+	t.Run("window is returned every checkEvery bytes", func(t *testing.T) {
+		var due []bool
+		for _, text := range []string{"ab", "cd", "ef", "gh"} {
+			_, ok, _ := store.AppendInserted(ctx, "session4", "user1", text, 10, 4, time.Minute) //nolint:errcheck // memory store never fails
+			due = append(due, ok)
+		}
+
+		if want := []bool{false, true, false, true}; !slices.Equal(due, want) {
+			t.Errorf("due = %v, want %v", due, want)
+		}
+	})
+
+	t.Run("window expires when idle", func(t *testing.T) {
+		if _, _, err := store.AppendInserted(ctx, "session3", "user1", "old", 10, 0, time.Millisecond); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		time.Sleep(5 * time.Millisecond)
+
+		window, _, _ := store.AppendInserted(ctx, "session3", "user1", "new", 10, 0, time.Minute) //nolint:errcheck // memory store never fails
+		if window != "new" {
+			t.Errorf("window = %q, want %q", window, "new")
+		}
+	})
+
+	t.Run("clear drops the window", func(t *testing.T) {
+		if err := store.ClearInserted(ctx, "session1", "user1"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		window, _, _ := store.AppendInserted(ctx, "session1", "user1", "", 5, 0, time.Minute) //nolint:errcheck // memory store never fails
+		if window != "" {
+			t.Errorf("window = %q, want empty", window)
+		}
+	})
+}
+
+func TestInsertedText(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous string
+		new      string
+		want     string
+	}{
+		{"append", "s(\"bd\")", "s(\"bd\").fast(2)", ".fast(2)"},
+		{"insert in the middle", "s(\"bd sd\")", "s(\"bd hh sd\")", "hh "},
+		{"replace", "note(\"c e g\")", "note(\"a d f\")", "a d f"},
+		{"deletion inserts nothing", "s(\"bd sd\")", "s(\"bd\")", ""},
+		{"unchanged", "s(\"bd\")", "s(\"bd\")", ""},
+		{"multi-byte characters stay whole", "// é", "// è", "è"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := insertedText(tt.previous, tt.new); got != tt.want {
+				t.Errorf("insertedText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrimWindow(t *testing.T) {
+	if got := trimWindow("abcdef", 4); got != "cdef" {
+		t.Errorf("trimWindow() = %q, want %q", got, "cdef")
+	}
+
+	// the cut falls inside "é" and moves to the next whole character
+	if got := trimWindow("aébc", 3); got != "bc" {
+		t.Errorf("trimWindow() = %q, want %q", got, "bc")
+	}
+}
+
 // helpers
 
 func generateLines(n int) string {
@@ -8,10 +8,11 @@ import (
 
 // MemoryLockStore implements LockStore using in-memory storage
 type MemoryLockStore struct {
-	mu     sync.RWMutex
-	locks  map[string]*memoryLock
-	done   chan struct{}
-	closed bool
+	mu      sync.RWMutex
+	locks   map[string]*memoryLock
+	windows map[memoryWindowKey]*memoryWindow
+	done    chan struct{}
+	closed  bool
 }
 
 type memoryLock struct {
@@ -20,11 +21,23 @@ type memoryLock struct {
 	expiresAt time.Time
 }
 
+type memoryWindowKey struct {
+	sessionID string
+	userID    string
+}
+
+type memoryWindow struct {
+	text      string
+	unchecked int // bytes appended since the window was last returned
+	expiresAt time.Time
+}
+
 // creates a new in-memory lock store
 func NewMemoryLockStore() *MemoryLockStore {
 	store := &MemoryLockStore{
-		locks: make(map[string]*memoryLock),
-		done:  make(chan struct{}),
+		locks:   make(map[string]*memoryLock),
+		windows: make(map[memoryWindowKey]*memoryWindow),
+		done:    make(chan struct{}),
 	}
 
 	go store.cleanupLoop()
@@ -90,6 +103,42 @@ func (s *MemoryLockStore) RefreshTTL(_ context.Context, sessionID string, ttl ti
 	return nil
 }
 
+// appends inserted text to a user's rolling window in a session and returns
+// the window once checkEvery bytes were appended since it was last returned
+func (s *MemoryLockStore) AppendInserted(_ context.Context, sessionID, userID, text string, maxLen, checkEvery int, ttl time.Duration) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	key := memoryWindowKey{sessionID: sessionID, userID: userID}
+
+	window, exists := s.windows[key]
+	if !exists || now.After(window.expiresAt) {
+		window = &memoryWindow{}
+		s.windows[key] = window
+	}
+
+	window.text = trimWindow(window.text+text, maxLen)
+	window.unchecked += len(text)
+	window.expiresAt = now.Add(ttl)
+
+	if window.unchecked < checkEvery {
+		return "", false, nil
+	}
+
+	window.unchecked = 0
+
+	return window.text, true, nil
+}
+
+// drops a user's rolling window in a session
+func (s *MemoryLockStore) ClearInserted(_ context.Context, sessionID, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.windows, memoryWindowKey{sessionID: sessionID, userID: userID})
+	return nil
+}
+
 // stops the cleanup goroutine
 func (s *MemoryLockStore) Close() error {
 	s.mu.Lock()
@@ -128,4 +177,10 @@ func (s *MemoryLockStore) cleanup() {
 			delete(s.locks, sessionID)
 		}
 	}
+
+	for key, window := range s.windows {
+		if now.After(window.expiresAt) {
+			delete(s.windows, key)
+		}
+	}
 }
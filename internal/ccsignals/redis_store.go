@@ -10,8 +10,10 @@ import (
 )
 
 const (
-	keyPasteLock     = "ccsignals:paste_lock:%s"
-	keyPasteBaseline = "ccsignals:paste_baseline:%s"
+	keyPasteLock      = "ccsignals:paste_lock:%s"
+	keyPasteBaseline  = "ccsignals:paste_baseline:%s"
+	keyPasteWindow    = "ccsignals:paste_window:%s:%s"
+	keyPasteUnchecked = "ccsignals:paste_window_unchecked:%s:%s"
 )
 
 // implements LockStore using Redis
@@ -133,6 +135,52 @@ func (s *RedisLockStore) RefreshTTL(ctx context.Context, sessionID string, ttl t
 	return err
 }
 
+// appends inserted text to a user's rolling window in a session and returns
+// the window once checkEvery bytes were appended since it was last returned
+func (s *RedisLockStore) AppendInserted(ctx context.Context, sessionID, userID, text string, maxLen, checkEvery int, ttl time.Duration) (string, bool, error) {
+	windowKey := fmt.Sprintf(keyPasteWindow, sessionID, userID)
+	uncheckedKey := fmt.Sprintf(keyPasteUnchecked, sessionID, userID)
+
+	pipe := s.client.TxPipeline()
+	pipe.Append(ctx, windowKey, text)
+	pipe.Expire(ctx, windowKey, ttl)
+	uncheckedCmd := pipe.IncrBy(ctx, uncheckedKey, int64(len(text)))
+	pipe.Expire(ctx, uncheckedKey, ttl)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", false, err
+	}
+
+	if uncheckedCmd.Val() < int64(checkEvery) {
+		return "", false, nil
+	}
+
+	pipe = s.client.TxPipeline()
+	windowCmd := pipe.Get(ctx, windowKey)
+	pipe.Set(ctx, uncheckedKey, 0, ttl)
+
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return "", false, err
+	}
+
+	window := windowCmd.Val()
+
+	// an append racing the trim can be lost, updates of one user arrive in order
+	if trimmed := trimWindow(window, maxLen); len(trimmed) < len(window) {
+		if err := s.client.Set(ctx, windowKey, trimmed, ttl).Err(); err != nil {
+			return "", false, err
+		}
+		window = trimmed
+	}
+
+	return window, true, nil
+}
+
+// drops a user's rolling window in a session
+func (s *RedisLockStore) ClearInserted(ctx context.Context, sessionID, userID string) error {
+	return s.client.Del(ctx, fmt.Sprintf(keyPasteWindow, sessionID, userID), fmt.Sprintf(keyPasteUnchecked, sessionID, userID)).Err()
+}
+
 // closes the redis connection
 func (s *RedisLockStore) Close() error {
 	return s.client.Close()
@@ -29,9 +29,11 @@ func NewSimHasher(shingleSize int) *SimHasher {
 	return &SimHasher{shingleSize: shingleSize}
 }
 
-// generates a SimHash fingerprint from text content
+// generates a SimHash fingerprint from text content. the content is
+// canonicalized first so comments, whitespace, homoglyphs and renamed
+// variables don't change the fingerprint.
 func (s *SimHasher) Hash(content string) Fingerprint {
-	normalized := normalizeText(content)
+	normalized := normalizeText(canonicalize(content))
 
 	words := tokenize(normalized)
 	if len(words) == 0 {
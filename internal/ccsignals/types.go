@@ -47,6 +47,9 @@ type Config struct {
 	PasteLineThreshold  int
 	UnlockThreshold     float64
 	LockTTL             time.Duration
+	RollingWindowSize   int           // inserted characters kept per session and user to catch chunked pastes, 0 disables
+	RollingWindowTTL    time.Duration // a window is dropped after this long without insertions
+	RollingWindowStep   int           // inserted characters between two checks of a window
 }
 
 // returns sensible defaults for the detection system
@@ -56,6 +59,9 @@ func DefaultConfig() Config {
 		PasteLineThreshold:  50,
 		UnlockThreshold:     0.30,
 		LockTTL:             1 * time.Hour,
+		RollingWindowSize:   4000,
+		RollingWindowTTL:    10 * time.Minute,
+		RollingWindowStep:   100,
 	}
 }
 
@@ -81,6 +87,12 @@ type LockStore interface {
 	GetLock(ctx context.Context, sessionID string) (*LockState, error)
 	RemoveLock(ctx context.Context, sessionID string) error
 	RefreshTTL(ctx context.Context, sessionID string, ttl time.Duration) error
+
+	// appends inserted text to a user's rolling window in a session, keeping
+	// its last maxLen bytes. once checkEvery bytes were appended since the
+	// window was last returned, it returns the window and true.
+	AppendInserted(ctx context.Context, sessionID, userID, text string, maxLen, checkEvery int, ttl time.Duration) (string, bool, error)
+	ClearInserted(ctx context.Context, sessionID, userID string) error
 }
 
 // defines the interface for validating content ownership
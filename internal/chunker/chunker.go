@@ -99,6 +99,8 @@ func ChunkDocument(content, sourcePathname string, pageName string, opts ChunkOp
 		}
 	}
 
+	assignKeys(chunks)
+
 	return chunks, nil
 }
 
@@ -147,7 +149,7 @@ func ChunkDocuments(docsPath string) ([]Chunk, []error) {
 			pageName = filepath.Base(path)
 		}
 
-		chunks, err := ChunkDocument(string(content), strings.TrimPrefix(docsPath, "./"), pageName, opts)
+		chunks, err := ChunkDocument(string(content), sourceName(docsPath), pageName, opts)
 		if err != nil {
 			logger.Warn("failed to chunk document",
 				"path", path,
@@ -3,6 +3,7 @@ package chunker
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -232,3 +233,60 @@ func TestRealDocumentWithExamples(t *testing.T) {
 		t.Logf("  %d. Section: %s (%d chars)", i+1, chunk.SectionTitle, len(chunk.Content))
 	}
 }
+
+func TestChunkKeysAndHash(t *testing.T) {
+	content := "# Intro\n\nFirst.\n\n## Sounds\n\nsound('bd')\n\n## Notes\n\nnote('c e g')\n"
+
+	chunks, err := ChunkDocument(content, "docs/strudel", "keys.mdx", DefaultOptions())
+	if err != nil {
+		t.Fatalf("ChunkDocument failed: %v", err)
+	}
+
+	// keys must be unique within a page
+	seen := make(map[string]bool)
+
+	for _, chunk := range chunks {
+		if chunk.Key == "" {
+			t.Errorf("chunk %q has no key", chunk.SectionTitle)
+		}
+
+		if seen[chunk.Key] {
+			t.Errorf("duplicate key %q", chunk.Key)
+		}
+
+		seen[chunk.Key] = true
+	}
+
+	// chunking the same content again must produce the same keys and hashes
+	again, err := ChunkDocument(content, "docs/strudel", "keys.mdx", DefaultOptions())
+	if err != nil {
+		t.Fatalf("ChunkDocument failed: %v", err)
+	}
+
+	for i := range chunks {
+		if chunks[i].Key != again[i].Key || chunks[i].Hash() != again[i].Hash() {
+			t.Errorf("chunk %d is not stable across runs", i)
+		}
+	}
+
+	// a content change changes the hash but keeps the key
+	changed, err := ChunkDocument(strings.Replace(content, "sound('bd')", "sound('hh')", 1), "docs/strudel", "keys.mdx", DefaultOptions())
+	if err != nil {
+		t.Fatalf("ChunkDocument failed: %v", err)
+	}
+
+	for i := range chunks {
+		if chunks[i].Key != changed[i].Key {
+			t.Errorf("chunk %d key changed from %q to %q", i, chunks[i].Key, changed[i].Key)
+		}
+
+		sameHash := chunks[i].Hash() == changed[i].Hash()
+		if edited := chunks[i].SectionTitle == "Sounds"; edited == sameHash {
+			t.Errorf("chunk %q: hash changed = %v, want %v", chunks[i].SectionTitle, !sameHash, edited)
+		}
+	}
+
+	if prefix := SourceURLPrefix("./docs/strudel"); !strings.HasPrefix(chunks[0].PageURL, prefix) {
+		t.Errorf("page url %q does not start with source prefix %q", chunks[0].PageURL, prefix)
+	}
+}
@@ -6,6 +6,7 @@ type Chunk struct {
 	SectionTitle string
 	Content      string
 	Metadata     map[string]interface{}
+	Key          string // section title and position, unique within the page
 }
 
 type ChunkOptions struct {
@@ -1,6 +1,9 @@
 package chunker
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"regexp"
 	"strings"
@@ -126,6 +129,39 @@ func stripMDXComponents(content string) string {
 	return mdxComponentRegex.ReplaceAllString(content, "")
 }
 
+// returns the url prefix shared by every chunk ChunkDocuments produces for docsPath
+func SourceURLPrefix(docsPath string) string {
+	return fmt.Sprintf("/%s/", sourceName(docsPath))
+}
+
+func sourceName(docsPath string) string {
+	return strings.TrimPrefix(docsPath, "./")
+}
+
+// numbers chunks per section title so split sections get distinct keys
+func assignKeys(chunks []Chunk) {
+	positions := make(map[string]int)
+
+	for i := range chunks {
+		title := chunks[i].SectionTitle
+		chunks[i].Key = fmt.Sprintf("%s#%d", title, positions[title])
+		positions[title]++
+	}
+}
+
+// returns a hash of everything stored for the chunk, to detect changes between ingestions
+func (c Chunk) Hash() string {
+	metadata, _ := json.Marshal(c.Metadata) //nolint:errcheck // frontmatter values are always marshalable
+
+	h := sha256.New()
+	for _, field := range []string{c.PageURL, c.SectionTitle, c.Content, string(metadata)} {
+		h.Write([]byte(field))
+		h.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
 func generateURL(urlPrefix string, pageName string) string {
 	name := strings.TrimSuffix(pageName, ".mdx")
 
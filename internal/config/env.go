@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
 
 	"github.com/joho/godotenv"
 )
@@ -19,13 +20,30 @@ func LoadEnvironmentVariables() (*Config, error) {
 	redisURL := os.Getenv("REDIS_URL")
 	jwtSecret := os.Getenv("JWT_SECRET")
 	environment := os.Getenv("ENVIRONMENT")
+	wsBackplane := os.Getenv("WS_BACKPLANE")
+	localLLMBaseURL := os.Getenv("LOCAL_LLM_BASE_URL")
+	localLLMAPIKey := os.Getenv("LOCAL_LLM_API_KEY")
+	localLLMModel := os.Getenv("LOCAL_LLM_MODEL")
+	retrievalReranker := os.Getenv("RETRIEVAL_RERANKER")
+	strudelValidator := os.Getenv("STRUDEL_VALIDATOR")
 
-	if openaiKey == "" {
-		return nil, fmt.Errorf("OPENAI_API_KEY environment variable is required")
+	validatorWorkers := 0 // default, see strudel.PoolOptions
+	if workersStr := os.Getenv("STRUDEL_VALIDATOR_WORKERS"); workersStr != "" {
+		workers, err := strconv.Atoi(workersStr)
+		if err != nil || workers < 1 {
+			return nil, fmt.Errorf("STRUDEL_VALIDATOR_WORKERS must be a positive number, got %q", workersStr)
+		}
+
+		validatorWorkers = workers
+	}
+
+	// hosted provider keys are optional when running against a local model server
+	if openaiKey == "" && localLLMBaseURL == "" {
+		return nil, fmt.Errorf("OPENAI_API_KEY environment variable is required (or set LOCAL_LLM_BASE_URL)")
 	}
 
-	if anthropicKey == "" {
-		return nil, fmt.Errorf("ANTHROPIC_API_KEY environment variable is required")
+	if anthropicKey == "" && localLLMBaseURL == "" {
+		return nil, fmt.Errorf("ANTHROPIC_API_KEY environment variable is required (or set LOCAL_LLM_BASE_URL)")
 	}
 
 	if supabaseConnStr == "" {
@@ -44,11 +62,30 @@ func LoadEnvironmentVariables() (*Config, error) {
 		environment = "development"
 	}
 
+	if wsBackplane != "" && wsBackplane != "redis" {
+		return nil, fmt.Errorf("WS_BACKPLANE must be empty or \"redis\", got %q", wsBackplane)
+	}
+
+	if retrievalReranker != "" && retrievalReranker != "llm" && retrievalReranker != "lexical" {
+		return nil, fmt.Errorf("RETRIEVAL_RERANKER must be empty, \"llm\" or \"lexical\", got %q", retrievalReranker)
+	}
+
+	if strudelValidator != "" && strudelValidator != "node" && strudelValidator != "static" {
+		return nil, fmt.Errorf("STRUDEL_VALIDATOR must be empty, \"node\" or \"static\", got %q", strudelValidator)
+	}
+
 	return &Config{
 		OpenAIKey:          openaiKey,
 		AnthropicKey:       anthropicKey,
 		SupabaseConnString: supabaseConnStr,
 		RedisURL:           redisURL,
 		Environment:        environment,
+		WSBackplane:        wsBackplane,
+		LocalLLMBaseURL:    localLLMBaseURL,
+		LocalLLMAPIKey:     localLLMAPIKey,
+		LocalLLMModel:      localLLMModel,
+		RetrievalReranker:  retrievalReranker,
+		StrudelValidator:   strudelValidator,
+		ValidatorWorkers:   validatorWorkers,
 	}, nil
 }
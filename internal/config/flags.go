@@ -3,6 +3,7 @@ package config
 import (
 	"flag"
 	"os"
+	"strings"
 )
 
 // parses CLI flags for the docs subcommand
@@ -29,6 +30,43 @@ func ParseConceptsFlags() Flags {
 	return Flags{Path: *path, Clear: *clearFlag}
 }
 
+// parses CLI flags for the reembed subcommand
+func ParseReembedFlags() ReembedFlags {
+	args := os.Args[2:]
+
+	fs := flag.NewFlagSet("reembed", flag.ExitOnError)
+	model := fs.String("model", "", "embedding model to build (required)")
+	provider := fs.String("provider", "openai", "embedding provider (openai, local)")
+	batchSize := fs.Int("batch", 100, "texts embedded per request")
+	cutover := fs.Bool("cutover", false, "activate the model after embedding")
+	fs.Parse(args) //nolint:errcheck,gosec // G104: ExitOnError flag set handles errors
+
+	return ReembedFlags{Model: *model, Provider: *provider, BatchSize: *batchSize, Cutover: *cutover}
+}
+
+// parses CLI flags for the retrieval evaluation command
+func ParseEvalFlags() EvalFlags {
+	args := os.Args[1:]
+
+	fs := flag.NewFlagSet("evalretrieval", flag.ExitOnError)
+	golden := fs.String("golden", "./cmd/evalretrieval/testdata/golden.yaml", "golden query set (.json, .yaml or .yml)")
+	k := fs.Int("k", 5, "number of results scored per query")
+	methods := fs.String("methods", "vector,bm25,hybrid", "comma-separated search methods to evaluate")
+	seed := fs.String("seed", "", "docs directory to ingest first (local databases only)")
+	jsonOut := fs.Bool("json", false, "print metrics as JSON")
+	verbose := fs.Bool("verbose", false, "print per-query scores")
+	fs.Parse(args) //nolint:errcheck,gosec // G104: ExitOnError flag set handles errors
+
+	var methodList []string
+	for _, method := range strings.Split(*methods, ",") {
+		if method = strings.TrimSpace(method); method != "" {
+			methodList = append(methodList, method)
+		}
+	}
+
+	return EvalFlags{Golden: *golden, K: *k, Methods: methodList, Seed: *seed, JSON: *jsonOut, Verbose: *verbose}
+}
+
 // returns default flags for docs ingestion
 func DefaultDocsFlags() Flags {
 	return Flags{Path: "./docs/strudel", Clear: false}
@@ -6,9 +6,34 @@ type Config struct {
 	SupabaseConnString string
 	RedisURL           string
 	Environment        string
+	WSBackplane        string
+	LocalLLMBaseURL    string // OpenAI-compatible server for the "local" provider
+	LocalLLMAPIKey     string
+	LocalLLMModel      string
+	RetrievalReranker  string // "llm", "lexical" or empty to keep the merged order
+	StrudelValidator   string // "static", "node" for the Node subprocess, or empty for node with static fallback
+	ValidatorWorkers   int    // node validator processes, 0 for the default
 }
 
 type Flags struct {
 	Path  string
 	Clear bool
 }
+
+// flags for re-embedding stored vectors with a new model
+type ReembedFlags struct {
+	Model     string
+	Provider  string
+	BatchSize int
+	Cutover   bool // activate the model once every chunk is embedded
+}
+
+// flags for the retrieval evaluation command
+type EvalFlags struct {
+	Golden  string
+	K       int
+	Methods []string // vector, bm25, hybrid
+	Seed    string   // docs directory ingested into a local database before evaluating
+	JSON    bool
+	Verbose bool // print per-query scores
+}
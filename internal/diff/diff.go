@@ -0,0 +1,171 @@
+package diff
+
+import "strings"
+
+// computes a line diff that turns a into b using the Myers algorithm.
+// common leading and trailing lines are matched first so small edits to long
+// programs stay cheap.
+func Lines(a, b string) []Line {
+	return myers(splitLines(a), splitLines(b))
+}
+
+// computes a diff between two lists of lines, which may keep their line endings
+func Sequences(a, b []string) []Line {
+	return myers(a, b)
+}
+
+// reports whether a or b has more than MaxLines lines
+func TooLarge(a, b string) bool {
+	return strings.Count(a, "\n") > MaxLines || strings.Count(b, "\n") > MaxLines
+}
+
+// counts added and removed lines
+func Summarize(lines []Line) Stats {
+	var stats Stats
+
+	for _, line := range lines {
+		switch line.Op {
+		case OpInsert:
+			stats.Added++
+		case OpDelete:
+			stats.Removed++
+		}
+	}
+
+	return stats
+}
+
+// splits text into lines without their trailing newline. empty text has no lines.
+func splitLines(text string) []string {
+	if text == "" {
+		return []string{}
+	}
+
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// finds a shortest edit script between a and b (line numbers are 1-based within the slices).
+// uses the linear space variant of Myers: each step splits the problem at the
+// middle snake of a shortest path, so memory stays O(n+m) however far apart a and b are.
+func myers(a, b []string) []Line {
+	size := 2*((len(a)+len(b)+1)/2) + 3
+
+	d := &differ{
+		a:        a,
+		b:        b,
+		forward:  make([]int, size),
+		backward: make([]int, size),
+		lines:    make([]Line, 0, len(a)+len(b)),
+	}
+
+	d.compare(0, len(a), 0, len(b))
+
+	return d.lines
+}
+
+// state shared by the recursive steps of a diff
+type differ struct {
+	a, b []string
+
+	// furthest x reached on each diagonal, from the start and from the end
+	forward, backward []int
+
+	lines []Line
+}
+
+// appends the edit script turning a[aLo:aHi] into b[bLo:bHi]
+func (d *differ) compare(aLo, aHi, bLo, bHi int) {
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		d.lines = append(d.lines, Line{Op: OpEqual, Text: d.a[aLo], OldLine: aLo + 1, NewLine: bLo + 1})
+		aLo++
+		bLo++
+	}
+
+	suffix := 0
+	for aLo < aHi-suffix && bLo < bHi-suffix && d.a[aHi-1-suffix] == d.b[bHi-1-suffix] {
+		suffix++
+	}
+
+	switch {
+	case aLo == aHi-suffix:
+		for y := bLo; y < bHi-suffix; y++ {
+			d.lines = append(d.lines, Line{Op: OpInsert, Text: d.b[y], NewLine: y + 1})
+		}
+
+	case bLo == bHi-suffix:
+		for x := aLo; x < aHi-suffix; x++ {
+			d.lines = append(d.lines, Line{Op: OpDelete, Text: d.a[x], OldLine: x + 1})
+		}
+
+	default:
+		x, y := d.middleSnake(aLo, aHi-suffix, bLo, bHi-suffix)
+		d.compare(aLo, x, bLo, y)
+		d.compare(x, aHi-suffix, y, bHi-suffix)
+	}
+
+	for i := suffix; i > 0; i-- {
+		d.lines = append(d.lines, Line{Op: OpEqual, Text: d.a[aHi-i], OldLine: aHi - i + 1, NewLine: bHi - i + 1})
+	}
+}
+
+// returns a point on a shortest edit path between a[aLo:aHi] and b[bLo:bHi],
+// found where the searches from both ends meet. the ranges differ in their
+// first and last lines, so the point is never one of the ends.
+func (d *differ) middleSnake(aLo, aHi, bLo, bHi int) (int, int) {
+	n, m := aHi-aLo, bHi-bLo
+	delta := n - m
+	odd := delta%2 != 0
+	offset := (n+m+1)/2 + 1
+
+	d.forward[offset+1] = 0
+	d.backward[offset+1] = 0
+
+	for step := 0; step <= (n+m+1)/2; step++ {
+		for k := -step; k <= step; k += 2 {
+			var x int
+			if k == -step || (k != step && d.forward[offset+k-1] < d.forward[offset+k+1]) {
+				x = d.forward[offset+k+1] // step down: insert from b
+			} else {
+				x = d.forward[offset+k-1] + 1 // step right: delete from a
+			}
+
+			y := x - k
+			for x < n && y < m && d.a[aLo+x] == d.b[bLo+y] {
+				x++
+				y++
+			}
+
+			d.forward[offset+k] = x
+
+			// the backward search has taken step-1 steps on the diagonals around delta
+			if odd && k >= delta-(step-1) && k <= delta+(step-1) && x+d.backward[offset+delta-k] >= n {
+				return aLo + x, bLo + y
+			}
+		}
+
+		// the same walk from the end, x and y count lines back from aHi and bHi
+		for k := -step; k <= step; k += 2 {
+			var x int
+			if k == -step || (k != step && d.backward[offset+k-1] < d.backward[offset+k+1]) {
+				x = d.backward[offset+k+1]
+			} else {
+				x = d.backward[offset+k-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && d.a[aHi-1-x] == d.b[bHi-1-y] {
+				x++
+				y++
+			}
+
+			d.backward[offset+k] = x
+
+			if !odd && delta-k >= -step && delta-k <= step && x+d.forward[offset+delta-k] >= n {
+				return aHi - x, bHi - y
+			}
+		}
+	}
+
+	// unreachable, the searches meet within (n+m+1)/2 steps
+	return aHi, bHi
+}
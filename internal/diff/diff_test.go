@@ -0,0 +1,181 @@
+package diff
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		a        string
+		b        string
+		expected []Line
+	}{
+		{
+			name:     "both empty",
+			a:        "",
+			b:        "",
+			expected: []Line{},
+		},
+		{
+			name: "identical",
+			a:    "s(\"bd sd\")\n.fast(2)",
+			b:    "s(\"bd sd\")\n.fast(2)",
+			expected: []Line{
+				{Op: OpEqual, Text: `s("bd sd")`, OldLine: 1, NewLine: 1},
+				{Op: OpEqual, Text: ".fast(2)", OldLine: 2, NewLine: 2},
+			},
+		},
+		{
+			name: "insert into empty",
+			a:    "",
+			b:    "note(\"c e g\")",
+			expected: []Line{
+				{Op: OpInsert, Text: `note("c e g")`, NewLine: 1},
+			},
+		},
+		{
+			name: "changed middle line",
+			a:    "stack(\n  s(\"bd\"),\n  s(\"hh*8\")\n)",
+			b:    "stack(\n  s(\"bd*2\"),\n  s(\"hh*8\")\n)",
+			expected: []Line{
+				{Op: OpEqual, Text: "stack(", OldLine: 1, NewLine: 1},
+				{Op: OpDelete, Text: `  s("bd"),`, OldLine: 2},
+				{Op: OpInsert, Text: `  s("bd*2"),`, NewLine: 2},
+				{Op: OpEqual, Text: `  s("hh*8")`, OldLine: 3, NewLine: 3},
+				{Op: OpEqual, Text: ")", OldLine: 4, NewLine: 4},
+			},
+		},
+		{
+			name: "appended line",
+			a:    "s(\"bd\")",
+			b:    "s(\"bd\")\n.gain(0.8)",
+			expected: []Line{
+				{Op: OpEqual, Text: `s("bd")`, OldLine: 1, NewLine: 1},
+				{Op: OpInsert, Text: ".gain(0.8)", NewLine: 2},
+			},
+		},
+		{
+			name: "trailing newline is ignored",
+			a:    "s(\"bd\")\n",
+			b:    "s(\"bd\")",
+			expected: []Line{
+				{Op: OpEqual, Text: `s("bd")`, OldLine: 1, NewLine: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Lines(tt.a, tt.b))
+		})
+	}
+}
+
+func TestLinesReconstructsBothSides(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	vocabulary := []string{`s("bd")`, `s("sd")`, ".fast(2)", ".slow(2)", ")", "stack(", ""}
+
+	randomProgram := func() string {
+		lines := make([]string, rng.Intn(12))
+		for i := range lines {
+			lines[i] = vocabulary[rng.Intn(len(vocabulary))]
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	for range 300 {
+		a, b := randomProgram(), randomProgram()
+		lines := Lines(a, b)
+
+		var oldSide, newSide []string
+		for _, line := range lines {
+			if line.Op != OpInsert {
+				oldSide = append(oldSide, line.Text)
+			}
+			if line.Op != OpDelete {
+				newSide = append(newSide, line.Text)
+			}
+		}
+
+		assert.Equal(t, splitLines(a), append([]string{}, oldSide...))
+		assert.Equal(t, splitLines(b), append([]string{}, newSide...))
+	}
+}
+
+func TestLinesIsMinimal(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	vocabulary := []string{`s("bd")`, `s("sd")`, ".fast(2)", ")", "stack("}
+
+	randomLines := func() []string {
+		lines := make([]string, rng.Intn(20))
+		for i := range lines {
+			lines[i] = vocabulary[rng.Intn(len(vocabulary))]
+		}
+		return lines
+	}
+
+	// longest common subsequence by dynamic programming
+	lcs := func(a, b []string) int {
+		table := make([][]int, len(a)+1)
+		for i := range table {
+			table[i] = make([]int, len(b)+1)
+		}
+
+		for i := len(a) - 1; i >= 0; i-- {
+			for j := len(b) - 1; j >= 0; j-- {
+				if a[i] == b[j] {
+					table[i][j] = table[i+1][j+1] + 1
+				} else {
+					table[i][j] = max(table[i+1][j], table[i][j+1])
+				}
+			}
+		}
+
+		return table[0][0]
+	}
+
+	for range 500 {
+		a, b := randomLines(), randomLines()
+		lines := Lines(strings.Join(a, "\n"), strings.Join(b, "\n"))
+
+		stats := Summarize(lines)
+		common := lcs(a, b)
+		assert.Equal(t, len(a)-common, stats.Removed, "a=%q b=%q", a, b)
+		assert.Equal(t, len(b)-common, stats.Added, "a=%q b=%q", a, b)
+
+		oldLine, newLine := 0, 0
+		for _, line := range lines {
+			if line.Op != OpInsert {
+				oldLine++
+				assert.Equal(t, oldLine, line.OldLine)
+			}
+			if line.Op != OpDelete {
+				newLine++
+				assert.Equal(t, newLine, line.NewLine)
+			}
+		}
+	}
+}
+
+func TestLinesDissimilarInputs(t *testing.T) {
+	a := make([]string, 5000)
+	b := make([]string, 5000)
+	for i := range a {
+		a[i] = "a" + strings.Repeat("x", i%7)
+		b[i] = "b" + strings.Repeat("y", i%5)
+	}
+
+	stats := Summarize(Lines(strings.Join(a, "\n"), strings.Join(b, "\n")))
+	assert.Equal(t, Stats{Added: 5000, Removed: 5000}, stats)
+}
+
+func TestSummarize(t *testing.T) {
+	stats := Summarize(Lines("a\nb\nc", "a\nx\nc\nd"))
+
+	assert.Equal(t, Stats{Added: 2, Removed: 1}, stats)
+}
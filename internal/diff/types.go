@@ -0,0 +1,29 @@
+package diff
+
+// the most lines a side may have to be diffed on request. Lines runs in linear
+// space but its time grows with the number of differences.
+const MaxLines = 10000
+
+// the kind of change a diff line represents
+type Op string
+
+const (
+	OpEqual  Op = "equal"
+	OpInsert Op = "insert"
+	OpDelete Op = "delete"
+)
+
+// a single line of a diff. line numbers are 1-based, and 0 when the line
+// does not exist on that side.
+type Line struct {
+	Op      Op     `json:"op"`
+	Text    string `json:"text"`
+	OldLine int    `json:"old_line,omitempty"`
+	NewLine int    `json:"new_line,omitempty"`
+}
+
+// added and removed line counts of a diff
+type Stats struct {
+	Added   int `json:"added"`
+	Removed int `json:"removed"`
+}
@@ -199,6 +199,16 @@ func ParticipantNotFound(c *gin.Context) {
 	})
 }
 
+// IncompatibleLicense returns a 400 error for a license a fork cannot use,
+// with the licenses it may use instead
+func IncompatibleLicense(c *gin.Context, message string, allowed []string) {
+	c.JSON(http.StatusBadRequest, ErrorResponse{
+		Error:   CodeIncompatibleLicense,
+		Message: message,
+		Allowed: allowed,
+	})
+}
+
 // IsValidUUID validates a UUID string format
 func IsValidUUID(id string) bool {
 	if id == "" {
@@ -2,10 +2,11 @@ package errors
 
 // ErrorResponse represents a standardized error response
 type ErrorResponse struct {
-	Error     string  `json:"error"`                // error code (e.g., "unauthorized", "not_found")
-	Message   string  `json:"message"`              // user-friendly message
-	Details   string  `json:"details,omitempty"`    // optional details (sanitized in production)
-	RequestID *string `json:"request_id,omitempty"` // echoed from request for correlation
+	Error     string   `json:"error"`                // error code (e.g., "unauthorized", "not_found")
+	Message   string   `json:"message"`              // user-friendly message
+	Details   string   `json:"details,omitempty"`    // optional details (sanitized in production)
+	Allowed   []string `json:"allowed,omitempty"`    // values the request may use instead
+	RequestID *string  `json:"request_id,omitempty"` // echoed from request for correlation
 }
 
 type ErrorInfo struct {
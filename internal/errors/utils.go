@@ -28,6 +28,7 @@ const (
 	CodeSessionNotFound     = "session_not_found"
 	CodeInvalidInvite       = "invalid_invite"
 	CodeParticipantNotFound = "participant_not_found"
+	CodeIncompatibleLicense = "incompatible_license"
 )
 
 // error categories for classification
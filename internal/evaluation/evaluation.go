@@ -0,0 +1,130 @@
+package evaluation
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// reads a golden set from a .json, .yaml or .yml file
+func LoadGoldenSet(path string) (*GoldenSet, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // G304: path comes from the operator
+	if err != nil {
+		return nil, fmt.Errorf("failed to read golden set: %w", err)
+	}
+
+	var set GoldenSet
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &set)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &set)
+	default:
+		return nil, fmt.Errorf("unsupported golden set format %q (use .json, .yaml or .yml)", filepath.Ext(path))
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse golden set: %w", err)
+	}
+
+	if len(set.Queries) == 0 {
+		return nil, fmt.Errorf("golden set has no queries")
+	}
+
+	for i, query := range set.Queries {
+		if strings.TrimSpace(query.Query) == "" {
+			return nil, fmt.Errorf("golden query %d has no query text", i+1)
+		}
+
+		if len(query.Expected) == 0 {
+			return nil, fmt.Errorf("golden query %q has no expected docs", query.Query)
+		}
+	}
+
+	return &set, nil
+}
+
+// scores the top k of ranked against the expected docs. each expected doc is
+// credited once, at the first result that matches it.
+func Evaluate(query GoldenQuery, ranked []RankedDoc, k int) QueryResult {
+	if len(ranked) > k {
+		ranked = ranked[:k]
+	}
+
+	result := QueryResult{Query: query.Query}
+	matched := make([]bool, len(query.Expected))
+	found := 0
+	dcg := 0.0
+
+	for i, doc := range ranked {
+		for j, expected := range query.Expected {
+			if matched[j] || !matches(expected, doc) {
+				continue
+			}
+
+			matched[j] = true
+			found++
+			dcg += 1 / math.Log2(float64(i+2))
+
+			if result.FirstRank == 0 {
+				result.FirstRank = i + 1
+			}
+
+			break
+		}
+	}
+
+	idealHits := min(len(query.Expected), k)
+	idcg := 0.0
+
+	for i := range idealHits {
+		idcg += 1 / math.Log2(float64(i+2))
+	}
+
+	result.Recall = float64(found) / float64(len(query.Expected))
+
+	if idcg > 0 {
+		result.NDCG = dcg / idcg
+	}
+
+	return result
+}
+
+// averages query results into recall@k, MRR and nDCG@k
+func Summarize(results []QueryResult, k int) Metrics {
+	metrics := Metrics{K: k, Queries: results}
+
+	if len(results) == 0 {
+		return metrics
+	}
+
+	for _, result := range results {
+		metrics.Recall += result.Recall
+		metrics.NDCG += result.NDCG
+
+		if result.FirstRank > 0 {
+			metrics.MRR += 1 / float64(result.FirstRank)
+		}
+	}
+
+	n := float64(len(results))
+	metrics.Recall /= n
+	metrics.MRR /= n
+	metrics.NDCG /= n
+
+	return metrics
+}
+
+func matches(expected ExpectedDoc, doc RankedDoc) bool {
+	if expected.Page != doc.Page {
+		return false
+	}
+
+	return expected.Section == "" || strings.EqualFold(expected.Section, doc.Section)
+}
@@ -0,0 +1,149 @@
+package evaluation
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluate(t *testing.T) {
+	query := GoldenQuery{
+		Query: "low pass filter",
+		Expected: []ExpectedDoc{
+			{Page: "effects.mdx", Section: "lpf"},
+			{Page: "tonal.mdx"},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		ranked    []RankedDoc
+		k         int
+		firstRank int
+		recall    float64
+		ndcg      float64
+	}{
+		{
+			name:      "perfect ranking",
+			ranked:    []RankedDoc{{Page: "effects.mdx", Section: "LPF"}, {Page: "tonal.mdx", Section: "scale"}},
+			k:         5,
+			firstRank: 1,
+			recall:    1,
+			ndcg:      1,
+		},
+		{
+			name:      "relevant at rank 2 and 3",
+			ranked:    []RankedDoc{{Page: "samples.mdx"}, {Page: "tonal.mdx"}, {Page: "effects.mdx", Section: "lpf"}},
+			k:         5,
+			firstRank: 2,
+			recall:    1,
+			ndcg:      (1/math.Log2(3) + 1/math.Log2(4)) / (1 + 1/math.Log2(3)),
+		},
+		{
+			name:      "duplicate page credited once",
+			ranked:    []RankedDoc{{Page: "tonal.mdx", Section: "scale"}, {Page: "tonal.mdx", Section: "mode"}},
+			k:         5,
+			firstRank: 1,
+			recall:    0.5,
+			ndcg:      1 / (1 + 1/math.Log2(3)),
+		},
+		{
+			name:      "wrong section does not match",
+			ranked:    []RankedDoc{{Page: "effects.mdx", Section: "room"}},
+			k:         5,
+			firstRank: 0,
+			recall:    0,
+			ndcg:      0,
+		},
+		{
+			name:      "results past k are ignored",
+			ranked:    []RankedDoc{{Page: "samples.mdx"}, {Page: "tonal.mdx"}},
+			k:         1,
+			firstRank: 0,
+			recall:    0,
+			ndcg:      0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Evaluate(query, tt.ranked, tt.k)
+
+			assert.Equal(t, tt.firstRank, result.FirstRank)
+			assert.InDelta(t, tt.recall, result.Recall, 1e-9)
+			assert.InDelta(t, tt.ndcg, result.NDCG, 1e-9)
+		})
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	metrics := Summarize([]QueryResult{
+		{FirstRank: 1, Recall: 1, NDCG: 1},
+		{FirstRank: 4, Recall: 0.5, NDCG: 0.4},
+		{FirstRank: 0, Recall: 0, NDCG: 0},
+	}, 5)
+
+	assert.Equal(t, 5, metrics.K)
+	assert.InDelta(t, 0.5, metrics.Recall, 1e-9)
+	assert.InDelta(t, (1+0.25)/3, metrics.MRR, 1e-9)
+	assert.InDelta(t, 1.4/3, metrics.NDCG, 1e-9)
+
+	empty := Summarize(nil, 5)
+	assert.Zero(t, empty.MRR)
+}
+
+func TestLoadGoldenSet(t *testing.T) {
+	dir := t.TempDir()
+
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		return path
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr string
+	}{
+		{
+			name: "yaml",
+			path: write("golden.yaml", "queries:\n  - query: add reverb\n    expected:\n      - page: effects.mdx\n        section: room\n"),
+		},
+		{
+			name: "json",
+			path: write("golden.json", `{"queries":[{"query":"add reverb","expected":[{"page":"effects.mdx","section":"room"}]}]}`),
+		},
+		{
+			name:    "unknown format",
+			path:    write("golden.txt", "add reverb"),
+			wantErr: "unsupported golden set format",
+		},
+		{
+			name:    "query without expected docs",
+			path:    write("missing.yaml", "queries:\n  - query: add reverb\n"),
+			wantErr: "has no expected docs",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set, err := LoadGoldenSet(tt.path)
+
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Len(t, set.Queries, 1)
+			assert.Equal(t, "add reverb", set.Queries[0].Query)
+			assert.Equal(t, ExpectedDoc{Page: "effects.mdx", Section: "room"}, set.Queries[0].Expected[0])
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package evaluation
+
+// a set of queries with the docs a good retriever should return for them
+type GoldenSet struct {
+	Queries []GoldenQuery `json:"queries" yaml:"queries"`
+}
+
+type GoldenQuery struct {
+	Query       string        `json:"query" yaml:"query"`
+	EditorState string        `json:"editor_state,omitempty" yaml:"editor_state,omitempty"` // passed to hybrid search
+	Expected    []ExpectedDoc `json:"expected" yaml:"expected"`
+}
+
+// a relevant doc. an empty section matches any chunk of the page.
+type ExpectedDoc struct {
+	Page    string `json:"page" yaml:"page"`
+	Section string `json:"section,omitempty" yaml:"section,omitempty"`
+}
+
+// a retrieved chunk, in rank order
+type RankedDoc struct {
+	Page    string
+	Section string
+}
+
+// scores for one query
+type QueryResult struct {
+	Query     string  `json:"query"`
+	FirstRank int     `json:"first_rank"` // 1-based rank of the first relevant result, 0 if none
+	Recall    float64 `json:"recall"`
+	NDCG      float64 `json:"ndcg"`
+}
+
+// scores averaged over a golden set
+type Metrics struct {
+	K       int           `json:"k"`
+	Recall  float64       `json:"recall_at_k"`
+	MRR     float64       `json:"mrr"`
+	NDCG    float64       `json:"ndcg_at_k"`
+	Queries []QueryResult `json:"queries"`
+}
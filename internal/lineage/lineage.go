@@ -0,0 +1,208 @@
+package lineage
+
+import (
+	"context"
+	"fmt"
+
+	"codeberg.org/algopatterns/server/algopatterns/strudels"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func New(db *pgxpool.Pool) *Service {
+	return NewWithStore(&dbStore{db: db})
+}
+
+// creates a service reading strudels from store
+func NewWithStore(store Store) *Service {
+	return &Service{store: store}
+}
+
+// builds the lineage of a public strudel: its ancestors up to the original and
+// the tree of forks below it. signals are inherited along each fork edge, a fork
+// is bound by the most restrictive signal of everything it was forked from.
+func (s *Service) Get(ctx context.Context, strudelID string, opts Options) (*Lineage, error) {
+	opts = opts.clamped()
+
+	root, err := s.store.GetNode(ctx, strudelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get strudel: %w", err)
+	}
+
+	if root == nil || !root.IsPublic {
+		return nil, ErrStrudelNotFound
+	}
+
+	// the whole chain is walked so signals from hidden ancestors still count
+	chain, truncated, err := s.walkAncestors(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+
+	ancestors := make([]LineageNode, len(chain))
+	inherited := strudels.CCSignal("")
+
+	for i := len(chain) - 1; i >= 0; i-- {
+		ancestors[i] = newLineageNode(chain[i], i+1, inherited)
+		inherited = ancestors[i].EffectiveSignal
+	}
+
+	strudel := newLineageNode(*root, 0, inherited)
+
+	lineage := &Lineage{
+		Strudel:            &strudel,
+		Ancestors:          ancestors,
+		AncestorsTruncated: truncated,
+	}
+
+	if len(ancestors) > opts.AncestorDepth {
+		lineage.Ancestors = ancestors[:opts.AncestorDepth]
+		lineage.AncestorsTruncated = true
+	}
+
+	// ancestors are never listed again as descendants when forks form a cycle
+	visited := map[string]bool{root.ID: true}
+	for _, ancestor := range chain {
+		visited[ancestor.ID] = true
+	}
+
+	count, truncated, err := s.walkDescendants(ctx, root.ID, &strudel, opts.DescendantDepth, visited)
+	if err != nil {
+		return nil, err
+	}
+
+	lineage.DescendantCount = count
+	lineage.DescendantsTruncated = truncated
+
+	return lineage, nil
+}
+
+// follows forked_from up from a strudel, parent first. stops at the original,
+// at a cycle, or after maxAncestorWalk ancestors, which reports truncation.
+func (s *Service) walkAncestors(ctx context.Context, node *Node) ([]Node, bool, error) {
+	visited := map[string]bool{node.ID: true}
+	var ancestors []Node
+
+	for node.ForkedFrom != nil && !visited[*node.ForkedFrom] {
+		if len(ancestors) == maxAncestorWalk {
+			return ancestors, true, nil
+		}
+
+		parent, err := s.store.GetNode(ctx, *node.ForkedFrom)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to get ancestor: %w", err)
+		}
+
+		if parent == nil {
+			break
+		}
+
+		visited[parent.ID] = true
+		ancestors = append(ancestors, *parent)
+		node = parent
+	}
+
+	return ancestors, false, nil
+}
+
+// adds forks below root level by level, up to depth levels and maxDescendants
+// forks. returns the number of forks added and whether any were left out.
+func (s *Service) walkDescendants(ctx context.Context, rootID string, root *LineageNode, depth int, visited map[string]bool) (int, bool, error) {
+	level := map[string]*LineageNode{rootID: root}
+	count := 0
+
+	for d := 1; len(level) > 0; d++ {
+		ids := make([]string, 0, len(level))
+		for id := range level {
+			ids = append(ids, id)
+		}
+
+		// one extra fork tells whether the limits cut the tree short
+		remaining := maxDescendants - count
+		if d > depth {
+			remaining = 0
+		}
+
+		forks, err := s.store.ListForks(ctx, ids, remaining+1)
+		if err != nil {
+			return 0, false, fmt.Errorf("failed to list forks: %w", err)
+		}
+
+		truncated := len(forks) > remaining
+		if truncated {
+			forks = forks[:remaining]
+		}
+
+		next := make(map[string]*LineageNode)
+
+		for _, fork := range forks {
+			if visited[fork.ID] || fork.ForkedFrom == nil {
+				continue
+			}
+
+			parent, ok := level[*fork.ForkedFrom]
+			if !ok {
+				continue
+			}
+
+			visited[fork.ID] = true
+			child := newLineageNode(fork, d, parent.EffectiveSignal)
+			parent.Children = append(parent.Children, &child)
+			next[fork.ID] = &child
+			count++
+		}
+
+		if truncated {
+			return count, true, nil
+		}
+
+		level = next
+	}
+
+	return count, false, nil
+}
+
+func newLineageNode(node Node, depth int, inherited strudels.CCSignal) LineageNode {
+	var own strudels.CCSignal
+	if node.CCSignal != nil {
+		own = *node.CCSignal
+	}
+
+	effective := own
+	if inherited.MoreRestrictiveThan(own) {
+		effective = inherited
+	}
+
+	lineageNode := LineageNode{
+		Private:         !node.IsPublic,
+		Depth:           depth,
+		CCSignal:        own,
+		InheritedSignal: inherited,
+		EffectiveSignal: effective,
+		CreatedAt:       node.CreatedAt,
+	}
+
+	if node.IsPublic {
+		lineageNode.ID = node.ID
+		lineageNode.Title = node.Title
+		lineageNode.AuthorID = node.AuthorID
+		lineageNode.AuthorName = node.AuthorName
+	}
+
+	return lineageNode
+}
+
+// keeps depths within 0 and MaxDepth, negative depths fall back to DefaultDepth
+func (o Options) clamped() Options {
+	clamp := func(depth int) int {
+		switch {
+		case depth < 0:
+			return DefaultDepth
+		case depth > MaxDepth:
+			return MaxDepth
+		default:
+			return depth
+		}
+	}
+
+	return Options{AncestorDepth: clamp(o.AncestorDepth), DescendantDepth: clamp(o.DescendantDepth)}
+}
@@ -0,0 +1,269 @@
+package lineage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"codeberg.org/algopatterns/server/algopatterns/strudels"
+)
+
+// in-memory store over a synthetic fork graph
+type memStore struct {
+	nodes map[string]Node
+	order []string
+}
+
+// builds a store from "id<parent:signal" specs, e.g. "b<a:cc-op". a "!" suffix
+// on the id marks the strudel private.
+func newMemStore(specs ...string) *memStore {
+	store := &memStore{nodes: make(map[string]Node)}
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i, spec := range specs {
+		id, rest, _ := strings.Cut(spec, "<")
+		parent, signal, _ := strings.Cut(rest, ":")
+
+		node := Node{
+			ID:        strings.TrimSuffix(id, "!"),
+			Title:     "title " + strings.TrimSuffix(id, "!"),
+			AuthorID:  "user-" + strings.TrimSuffix(id, "!"),
+			IsPublic:  !strings.HasSuffix(id, "!"),
+			CreatedAt: base.Add(time.Duration(i) * time.Minute),
+		}
+
+		if parent != "" {
+			node.ForkedFrom = &parent
+		}
+
+		if signal != "" {
+			ccSignal := strudels.CCSignal(signal)
+			node.CCSignal = &ccSignal
+		}
+
+		store.nodes[node.ID] = node
+		store.order = append(store.order, node.ID)
+	}
+
+	return store
+}
+
+func (s *memStore) GetNode(_ context.Context, strudelID string) (*Node, error) {
+	node, ok := s.nodes[strudelID]
+	if !ok {
+		return nil, nil
+	}
+
+	return &node, nil
+}
+
+func (s *memStore) ListForks(_ context.Context, parentIDs []string, limit int) ([]Node, error) {
+	var forks []Node
+
+	for _, id := range s.order {
+		node := s.nodes[id]
+		if node.ForkedFrom != nil && slices.Contains(parentIDs, *node.ForkedFrom) && len(forks) < limit {
+			forks = append(forks, node)
+		}
+	}
+
+	return forks, nil
+}
+
+func allDepths() Options {
+	return Options{AncestorDepth: MaxDepth, DescendantDepth: MaxDepth}
+}
+
+// lists node ids depth first as "id:effective signal"
+func flatten(node *LineageNode) []string {
+	id := node.ID
+	if node.Private {
+		id = "private"
+	}
+
+	out := []string{id + ":" + string(node.EffectiveSignal)}
+	for _, child := range node.Children {
+		out = append(out, flatten(child)...)
+	}
+
+	return out
+}
+
+func TestGet_InheritsSignalsAlongEdges(t *testing.T) {
+	store := newMemStore(
+		"a<:cc-cr",
+		"b<a",
+		"c<b:cc-op",
+		"d<c:cc-cr",
+		"e<d",
+		"f<d:no-ai",
+		"g<a:cc-dc",
+	)
+
+	lineage, err := NewWithStore(store).Get(context.Background(), "d", allDepths())
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	var ancestors []string
+	for _, ancestor := range lineage.Ancestors {
+		ancestors = append(ancestors, ancestor.ID+":"+string(ancestor.EffectiveSignal))
+	}
+
+	if want := []string{"c:cc-op", "b:cc-cr", "a:cc-cr"}; !reflect.DeepEqual(ancestors, want) {
+		t.Errorf("ancestors = %v, want %v", ancestors, want)
+	}
+
+	if want := []string{"d:cc-op", "e:cc-op", "f:no-ai"}; !reflect.DeepEqual(flatten(lineage.Strudel), want) {
+		t.Errorf("descendants = %v, want %v", flatten(lineage.Strudel), want)
+	}
+
+	strudel := lineage.Strudel
+	if strudel.CCSignal != strudels.CCSignalCredit || strudel.InheritedSignal != strudels.CCSignalOpen {
+		t.Errorf("strudel signals = %q inherited %q, want %q inherited %q",
+			strudel.CCSignal, strudel.InheritedSignal, strudels.CCSignalCredit, strudels.CCSignalOpen)
+	}
+
+	if lineage.DescendantCount != 2 || lineage.AncestorsTruncated || lineage.DescendantsTruncated {
+		t.Errorf("count = %d, truncated = %v/%v, want 2, false/false",
+			lineage.DescendantCount, lineage.AncestorsTruncated, lineage.DescendantsTruncated)
+	}
+}
+
+func TestGet_DepthLimits(t *testing.T) {
+	store := newMemStore("a<:no-ai", "b<a", "c<b", "d<c", "e<d", "f<e", "g<e")
+
+	lineage, err := NewWithStore(store).Get(context.Background(), "c", Options{AncestorDepth: 1, DescendantDepth: 1})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	if len(lineage.Ancestors) != 1 || lineage.Ancestors[0].ID != "b" || !lineage.AncestorsTruncated {
+		t.Errorf("ancestors = %+v, truncated = %v, want only b, truncated", lineage.Ancestors, lineage.AncestorsTruncated)
+	}
+
+	// the hidden original still restricts everything below it
+	if want := []string{"c:no-ai", "d:no-ai"}; !reflect.DeepEqual(flatten(lineage.Strudel), want) {
+		t.Errorf("descendants = %v, want %v", flatten(lineage.Strudel), want)
+	}
+
+	if !lineage.DescendantsTruncated {
+		t.Error("expected descendants to be truncated")
+	}
+
+	lineage, err = NewWithStore(store).Get(context.Background(), "e", Options{AncestorDepth: 0, DescendantDepth: 1})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	if len(lineage.Ancestors) != 0 || lineage.DescendantCount != 2 || lineage.DescendantsTruncated {
+		t.Errorf("ancestors = %d, count = %d, truncated = %v, want 0, 2, false",
+			len(lineage.Ancestors), lineage.DescendantCount, lineage.DescendantsTruncated)
+	}
+}
+
+func TestGet_Cycles(t *testing.T) {
+	tests := []struct {
+		name        string
+		specs       []string
+		id          string
+		ancestors   int
+		descendants []string
+	}{
+		{
+			name:        "self fork",
+			specs:       []string{"a<a:cc-cr"},
+			id:          "a",
+			descendants: []string{"a:cc-cr"},
+		},
+		{
+			name:        "two strudels forked from each other",
+			specs:       []string{"a<b", "b<a:cc-ec"},
+			id:          "a",
+			ancestors:   1,
+			descendants: []string{"a:cc-ec"},
+		},
+		{
+			name:        "cycle below the strudel",
+			specs:       []string{"a<", "b<a", "c<d", "d<c", "e<b"},
+			id:          "a",
+			descendants: []string{"a:", "b:", "e:"},
+		},
+		{
+			name:        "cycle through a fork",
+			specs:       []string{"a<c", "b<a", "c<b", "d<b"},
+			id:          "b",
+			ancestors:   2,
+			descendants: []string{"b:", "d:"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lineage, err := NewWithStore(newMemStore(tt.specs...)).Get(context.Background(), tt.id, allDepths())
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+
+			if len(lineage.Ancestors) != tt.ancestors {
+				t.Errorf("ancestors = %d, want %d", len(lineage.Ancestors), tt.ancestors)
+			}
+
+			if got := flatten(lineage.Strudel); !reflect.DeepEqual(got, tt.descendants) {
+				t.Errorf("descendants = %v, want %v", got, tt.descendants)
+			}
+		})
+	}
+}
+
+func TestGet_PrivateStrudels(t *testing.T) {
+	store := newMemStore("a<:cc-op", "b!<a", "c<b", "d!<c:no-ai", "e<d")
+
+	lineage, err := NewWithStore(store).Get(context.Background(), "c", allDepths())
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	private := lineage.Ancestors[0]
+	if !private.Private || private.ID != "" || private.Title != "" || private.AuthorID != "" {
+		t.Errorf("private ancestor = %+v, want it redacted", private)
+	}
+
+	if want := []string{"c:cc-op", "private:no-ai", "e:no-ai"}; !reflect.DeepEqual(flatten(lineage.Strudel), want) {
+		t.Errorf("descendants = %v, want %v", flatten(lineage.Strudel), want)
+	}
+
+	for _, id := range []string{"b", "d", "missing"} {
+		if _, err := NewWithStore(store).Get(context.Background(), id, allDepths()); !errors.Is(err, ErrStrudelNotFound) {
+			t.Errorf("Get(%q) error = %v, want ErrStrudelNotFound", id, err)
+		}
+	}
+}
+
+func TestGet_DescendantLimit(t *testing.T) {
+	specs := []string{"root<"}
+	for i := range maxDescendants + 1 {
+		specs = append(specs, fmt.Sprintf("fork-%d<root", i))
+	}
+
+	lineage, err := NewWithStore(newMemStore(specs...)).Get(context.Background(), "root", allDepths())
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	if lineage.DescendantCount != maxDescendants || !lineage.DescendantsTruncated {
+		t.Errorf("count = %d, truncated = %v, want %d, true", lineage.DescendantCount, lineage.DescendantsTruncated, maxDescendants)
+	}
+}
+
+func TestOptions_Clamped(t *testing.T) {
+	got := Options{AncestorDepth: -1, DescendantDepth: MaxDepth + 1}.clamped()
+	if want := (Options{AncestorDepth: DefaultDepth, DescendantDepth: MaxDepth}); got != want {
+		t.Errorf("clamped() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,19 @@
+package lineage
+
+const (
+	queryGetLineageNode = `
+		SELECT s.id, s.title, s.user_id, COALESCE(u.name, ''), s.cc_signal, s.forked_from, s.is_public, s.created_at
+		FROM user_strudels s
+		LEFT JOIN users u ON s.user_id = u.id
+		WHERE s.id = $1
+	`
+
+	queryListForks = `
+		SELECT s.id, s.title, s.user_id, COALESCE(u.name, ''), s.cc_signal, s.forked_from, s.is_public, s.created_at
+		FROM user_strudels s
+		LEFT JOIN users u ON s.user_id = u.id
+		WHERE s.forked_from = ANY($1::uuid[])
+		ORDER BY s.created_at, s.id
+		LIMIT $2
+	`
+)
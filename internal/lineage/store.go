@@ -0,0 +1,63 @@
+package lineage
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// reads lineage nodes from user_strudels
+type dbStore struct {
+	db *pgxpool.Pool
+}
+
+func (s *dbStore) GetNode(ctx context.Context, strudelID string) (*Node, error) {
+	node, err := scanNode(s.db.QueryRow(ctx, queryGetLineageNode, strudelID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+
+	return node, err
+}
+
+func (s *dbStore) ListForks(ctx context.Context, parentIDs []string, limit int) ([]Node, error) {
+	rows, err := s.db.Query(ctx, queryListForks, parentIDs, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	var forks []Node
+
+	for rows.Next() {
+		node, err := scanNode(rows)
+		if err != nil {
+			return nil, err
+		}
+		forks = append(forks, *node)
+	}
+
+	return forks, rows.Err()
+}
+
+func scanNode(row pgx.Row) (*Node, error) {
+	var node Node
+
+	err := row.Scan(
+		&node.ID,
+		&node.Title,
+		&node.AuthorID,
+		&node.AuthorName,
+		&node.CCSignal,
+		&node.ForkedFrom,
+		&node.IsPublic,
+		&node.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &node, nil
+}
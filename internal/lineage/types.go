@@ -0,0 +1,75 @@
+package lineage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"codeberg.org/algopatterns/server/algopatterns/strudels"
+)
+
+const (
+	DefaultDepth = 5
+	MaxDepth     = 20
+
+	// descendants listed at most, a popular strudel can have many forks
+	maxDescendants = 500
+
+	// ancestors walked at most to work out inherited signals, beyond the depth shown
+	maxAncestorWalk = 100
+)
+
+var ErrStrudelNotFound = errors.New("strudel not found")
+
+// a strudel as stored, with its fork parent
+type Node struct {
+	ID         string
+	Title      string
+	AuthorID   string
+	AuthorName string
+	CCSignal   *strudels.CCSignal
+	ForkedFrom *string
+	IsPublic   bool
+	CreatedAt  time.Time
+}
+
+// reads strudels and their forks for lineage walks
+type Store interface {
+	GetNode(ctx context.Context, strudelID string) (*Node, error) // nil when the strudel does not exist
+	ListForks(ctx context.Context, parentIDs []string, limit int) ([]Node, error)
+}
+
+type Service struct {
+	store Store
+}
+
+// how far a lineage walk goes from the strudel, in fork edges
+type Options struct {
+	AncestorDepth   int
+	DescendantDepth int
+}
+
+// a strudel in a lineage. private strudels keep their place in the graph and
+// their signals, but not their ID, title or author.
+type LineageNode struct {
+	ID              string            `json:"id,omitempty"`
+	Title           string            `json:"title,omitempty"`
+	AuthorID        string            `json:"author_id,omitempty"`
+	AuthorName      string            `json:"author_name,omitempty"`
+	Private         bool              `json:"private,omitempty"`
+	Depth           int               `json:"depth"`                      // fork edges away from the requested strudel
+	CCSignal        strudels.CCSignal `json:"cc_signal,omitempty"`        // set on the strudel itself
+	InheritedSignal strudels.CCSignal `json:"inherited_signal,omitempty"` // carried along the edge from the parent
+	EffectiveSignal strudels.CCSignal `json:"effective_signal,omitempty"` // the more restrictive of the two
+	CreatedAt       time.Time         `json:"created_at"`
+	Children        []*LineageNode    `json:"children,omitempty"`
+}
+
+// the ancestor chain and descendant tree of a strudel
+type Lineage struct {
+	Strudel              *LineageNode  `json:"strudel"`   // descendants are its children
+	Ancestors            []LineageNode `json:"ancestors"` // parent first
+	DescendantCount      int           `json:"descendant_count"`
+	AncestorsTruncated   bool          `json:"ancestors_truncated"`
+	DescendantsTruncated bool          `json:"descendants_truncated"`
+}
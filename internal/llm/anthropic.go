@@ -160,7 +160,7 @@ func (t *AnthropicTransformer) AnalyzeQuery(ctx context.Context, userQuery strin
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body) //nolint:errcheck
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	var transformResp transformResponse
@@ -239,7 +239,7 @@ func (t *AnthropicTransformer) GenerateTextStream(ctx context.Context, req TextG
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body) //nolint:errcheck
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	var fullText strings.Builder
@@ -347,7 +347,7 @@ func (t *AnthropicTransformer) GenerateText(ctx context.Context, req TextGenerat
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body) //nolint:errcheck
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	var apiResp transformResponse
@@ -368,6 +368,191 @@ func (t *AnthropicTransformer) GenerateText(ctx context.Context, req TextGenerat
 	}, nil
 }
 
+// anthropic tool-use request types
+type anthropicToolRequest struct {
+	Model       string                 `json:"model"`
+	MaxTokens   int                    `json:"max_tokens"`
+	System      string                 `json:"system,omitempty"`
+	Messages    []anthropicToolMessage `json:"messages"`
+	Tools       []anthropicTool        `json:"tools,omitempty"`
+	ToolChoice  *anthropicToolChoice   `json:"tool_choice,omitempty"`
+	Temperature float32                `json:"temperature"`
+}
+
+type anthropicTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+type anthropicToolChoice struct {
+	Type string `json:"type"` // "auto", "any", "tool" or "none"
+}
+
+type anthropicToolMessage struct {
+	Role    string                  `json:"role"`
+	Content []anthropicContentBlock `json:"content"`
+}
+
+// a text, tool_use or tool_result block, fields are set per type
+type anthropicContentBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   string          `json:"content,omitempty"`
+	IsError   bool            `json:"is_error,omitempty"`
+}
+
+type anthropicToolResponse struct {
+	Content    []anthropicContentBlock `json:"content"`
+	StopReason string                  `json:"stop_reason"`
+	Usage      struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+// generates a response that may request tool calls
+func (t *AnthropicTransformer) GenerateWithTools(ctx context.Context, req ToolGenerationRequest) (*ToolGenerationResponse, error) {
+	maxTokens := req.MaxTokens
+	if maxTokens == 0 {
+		maxTokens = t.config.MaxTokens
+	}
+
+	reqBody := buildAnthropicToolRequest(t.config.Model, maxTokens, t.config.Temperature, req)
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", anthropicMessagesURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", t.config.APIKey)
+	httpReq.Header.Set("anthropic-version", anthropicVersion)
+
+	if err := anthropicRateLimiter.Wait(ctx); err != nil {
+		return nil, fmt.Errorf("rate limiter error: %w", err)
+	}
+
+	resp, err := t.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body) //nolint:errcheck
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var apiResp anthropicToolResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return parseAnthropicToolResponse(&apiResp), nil
+}
+
+// converts a provider-neutral tool request into the anthropic messages format
+func buildAnthropicToolRequest(model string, maxTokens int, temperature float32, req ToolGenerationRequest) anthropicToolRequest {
+	messages := make([]anthropicToolMessage, 0, len(req.Messages))
+
+	for _, msg := range req.Messages {
+		blocks := make([]anthropicContentBlock, 0, 1+len(msg.ToolCalls)+len(msg.ToolResults))
+
+		// tool results must come first in a user turn
+		for _, result := range msg.ToolResults {
+			blocks = append(blocks, anthropicContentBlock{
+				Type:      "tool_result",
+				ToolUseID: result.ToolCallID,
+				Content:   result.Content,
+				IsError:   result.IsError,
+			})
+		}
+
+		if msg.Content != "" {
+			blocks = append(blocks, anthropicContentBlock{Type: "text", Text: msg.Content})
+		}
+
+		for _, call := range msg.ToolCalls {
+			input := call.Input
+			if len(input) == 0 {
+				input = json.RawMessage("{}")
+			}
+
+			blocks = append(blocks, anthropicContentBlock{
+				Type:  "tool_use",
+				ID:    call.ID,
+				Name:  call.Name,
+				Input: input,
+			})
+		}
+
+		messages = append(messages, anthropicToolMessage{Role: msg.Role, Content: blocks})
+	}
+
+	tools := make([]anthropicTool, 0, len(req.Tools))
+	for _, tool := range req.Tools {
+		tools = append(tools, anthropicTool{
+			Name:        tool.Name,
+			Description: tool.Description,
+			InputSchema: tool.InputSchema,
+		})
+	}
+
+	reqBody := anthropicToolRequest{
+		Model:       model,
+		MaxTokens:   maxTokens,
+		System:      req.SystemPrompt,
+		Messages:    messages,
+		Tools:       tools,
+		Temperature: temperature,
+	}
+
+	if req.DisableTools && len(tools) > 0 {
+		reqBody.ToolChoice = &anthropicToolChoice{Type: "none"}
+	}
+
+	return reqBody
+}
+
+// collects text and tool_use blocks from an anthropic response
+func parseAnthropicToolResponse(apiResp *anthropicToolResponse) *ToolGenerationResponse {
+	var text strings.Builder
+	var toolCalls []ToolCall
+
+	for _, block := range apiResp.Content {
+		switch block.Type {
+		case "text":
+			text.WriteString(block.Text)
+		case "tool_use":
+			toolCalls = append(toolCalls, ToolCall{
+				ID:    block.ID,
+				Name:  block.Name,
+				Input: block.Input,
+			})
+		}
+	}
+
+	return &ToolGenerationResponse{
+		Text:      strings.TrimSpace(text.String()),
+		ToolCalls: toolCalls,
+		Usage: Usage{
+			InputTokens:  apiResp.Usage.InputTokens,
+			OutputTokens: apiResp.Usage.OutputTokens,
+		},
+	}
+}
+
 // returns system prompt for query transformation
 func buildTransformationPrompt() string {
 	const prompt = `You are a query analyzer for Strudel music code generation.
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"codeberg.org/algopatterns/server/internal/config"
 )
@@ -21,10 +22,13 @@ func loadConfig() (*Config, error) {
 	}
 
 	transformerAPIKey := getAPIKeyForProvider(transformerProvider, baseConfig)
+	if err := checkAPIKey("transformer", transformerProvider, transformerAPIKey); err != nil {
+		return nil, err
+	}
 
 	transformerModel := os.Getenv("TRANSFORMER_MODEL")
 	if transformerModel == "" {
-		transformerModel = "claude-3-haiku-20240307" // default
+		transformerModel = defaultModelForProvider(transformerProvider, "claude-3-haiku-20240307", baseConfig)
 	}
 
 	// generator config
@@ -34,10 +38,33 @@ func loadConfig() (*Config, error) {
 	}
 
 	generatorAPIKey := getAPIKeyForProvider(generatorProvider, baseConfig)
+	if err := checkAPIKey("generator", generatorProvider, generatorAPIKey); err != nil {
+		return nil, err
+	}
 
 	generatorModel := os.Getenv("GENERATOR_MODEL")
 	if generatorModel == "" {
-		generatorModel = "claude-sonnet-4-20250514" // default
+		generatorModel = defaultModelForProvider(generatorProvider, "claude-sonnet-4-20250514", baseConfig)
+	}
+
+	// generator fallbacks, e.g. GENERATOR_FALLBACK_PROVIDERS=openai,local
+	var generatorFallbacks []FallbackConfig
+	for _, name := range strings.Split(os.Getenv("GENERATOR_FALLBACK_PROVIDERS"), ",") {
+		provider := Provider(strings.TrimSpace(name))
+		if provider == "" {
+			continue
+		}
+
+		apiKey := getAPIKeyForProvider(provider, baseConfig)
+		if err := checkAPIKey("fallback generator", provider, apiKey); err != nil {
+			return nil, err
+		}
+
+		generatorFallbacks = append(generatorFallbacks, FallbackConfig{
+			Provider: provider,
+			APIKey:   apiKey,
+			Model:    defaultModelForProvider(provider, "claude-sonnet-4-20250514", baseConfig),
+		})
 	}
 
 	// embedder config
@@ -46,11 +73,14 @@ func loadConfig() (*Config, error) {
 		embedderProvider = ProviderOpenAI // default
 	}
 
-	// use API key from base config
-	embedderAPIKey := baseConfig.OpenAIKey
+	embedderAPIKey := getAPIKeyForProvider(embedderProvider, baseConfig)
+	if err := checkAPIKey("embedder", embedderProvider, embedderAPIKey); err != nil {
+		return nil, err
+	}
 
+	// empty for local servers, NewLocalEmbedder picks its default model
 	embedderModel := os.Getenv("EMBEDDER_MODEL")
-	if embedderModel == "" {
+	if embedderModel == "" && embedderProvider == ProviderOpenAI {
 		embedderModel = "text-embedding-3-small" // default
 	}
 
@@ -95,8 +125,10 @@ func loadConfig() (*Config, error) {
 		GeneratorModel:         generatorModel,
 		GeneratorMaxTokens:     generatorMaxTokens,
 		GeneratorTemperature:   generatorTemperature,
+		GeneratorFallbacks:     generatorFallbacks,
 		EmbedderProvider:       embedderProvider,
 		EmbedderAPIKey:         embedderAPIKey,
 		EmbedderModel:          embedderModel,
+		LocalBaseURL:           baseConfig.LocalLLMBaseURL,
 	}, nil
 }
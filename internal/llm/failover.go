@@ -0,0 +1,293 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultFailoverMaxRetries       = 2
+	defaultFailoverInitialBackoff   = 500 * time.Millisecond
+	defaultFailoverMaxBackoff       = 8 * time.Second
+	defaultFailoverFailureThreshold = 5
+	defaultFailoverCooldown         = 30 * time.Second
+)
+
+// tunes retries and circuit breaking for a FailoverGenerator. zero values use defaults.
+type FailoverConfig struct {
+	MaxRetries       int           // retries per provider after the first attempt (default 2)
+	InitialBackoff   time.Duration // wait before the first retry, doubled after each (default 500ms)
+	MaxBackoff       time.Duration // upper bound for a single wait (default 8s)
+	FailureThreshold int           // consecutive 5xx/429 failures that open a provider's circuit (default 5)
+	Cooldown         time.Duration // how long an open circuit moves the provider to the back (default 30s)
+}
+
+// a generator in a failover chain
+type FailoverProvider struct {
+	Name      Provider
+	Generator TextGenerator
+}
+
+// implements TextGenerator (and ToolCaller) over an ordered chain of providers.
+// each provider is retried with exponential backoff on 5xx/429 and network
+// errors before the next one is tried. providers that keep failing are
+// circuit-broken and tried last until their cooldown ends.
+type FailoverGenerator struct {
+	providers []*failoverEntry
+	config    FailoverConfig
+	now       func() time.Time
+	sleep     func(ctx context.Context, d time.Duration) error
+}
+
+type failoverEntry struct {
+	FailoverProvider
+	breaker circuitBreaker
+}
+
+// counts consecutive retryable failures of one provider
+type circuitBreaker struct {
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+}
+
+func NewFailoverGenerator(providers []FailoverProvider, config FailoverConfig) (*FailoverGenerator, error) {
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("failover needs at least one provider")
+	}
+
+	if config.MaxRetries == 0 {
+		config.MaxRetries = defaultFailoverMaxRetries
+	}
+
+	if config.InitialBackoff == 0 {
+		config.InitialBackoff = defaultFailoverInitialBackoff
+	}
+
+	if config.MaxBackoff == 0 {
+		config.MaxBackoff = defaultFailoverMaxBackoff
+	}
+
+	if config.FailureThreshold == 0 {
+		config.FailureThreshold = defaultFailoverFailureThreshold
+	}
+
+	if config.Cooldown == 0 {
+		config.Cooldown = defaultFailoverCooldown
+	}
+
+	entries := make([]*failoverEntry, 0, len(providers))
+	for _, provider := range providers {
+		entries = append(entries, &failoverEntry{FailoverProvider: provider})
+	}
+
+	return &FailoverGenerator{
+		providers: entries,
+		config:    config,
+		now:       time.Now,
+		sleep:     sleepContext,
+	}, nil
+}
+
+// returns the primary provider's model
+func (f *FailoverGenerator) Model() string {
+	return f.providers[0].Generator.Model()
+}
+
+func (f *FailoverGenerator) GenerateText(ctx context.Context, req TextGenerationRequest) (*TextGenerationResponse, error) {
+	var response *TextGenerationResponse
+
+	err := f.run(ctx, nil, func(entry *failoverEntry) error {
+		resp, err := entry.Generator.GenerateText(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		resp.Provider = entry.Name
+		resp.Model = entry.Generator.Model()
+		response = resp
+
+		return nil
+	})
+
+	return response, err
+}
+
+// streams from the first provider that answers. once a chunk has been
+// emitted the output cannot be taken back, so later errors are returned
+// instead of failing over.
+func (f *FailoverGenerator) GenerateTextStream(ctx context.Context, req TextGenerationRequest, onChunk func(chunk string) error) (*TextGenerationResponse, error) {
+	var response *TextGenerationResponse
+	emitted := false
+
+	err := f.run(ctx, &emitted, func(entry *failoverEntry) error {
+		resp, err := entry.Generator.GenerateTextStream(ctx, req, func(chunk string) error {
+			emitted = true
+			return onChunk(chunk)
+		})
+		if err != nil {
+			return err
+		}
+
+		resp.Provider = entry.Name
+		resp.Model = entry.Generator.Model()
+		response = resp
+
+		return nil
+	})
+
+	return response, err
+}
+
+// generates with tools on the first provider that supports native tool use
+func (f *FailoverGenerator) GenerateWithTools(ctx context.Context, req ToolGenerationRequest) (*ToolGenerationResponse, error) {
+	var response *ToolGenerationResponse
+
+	err := f.run(ctx, nil, func(entry *failoverEntry) error {
+		toolCaller, ok := entry.Generator.(ToolCaller)
+		if !ok {
+			return errToolsUnsupported
+		}
+
+		resp, err := toolCaller.GenerateWithTools(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		resp.Provider = entry.Name
+		resp.Model = entry.Generator.Model()
+		response = resp
+
+		return nil
+	})
+
+	return response, err
+}
+
+// reports whether any provider in the chain supports native tool use
+func (f *FailoverGenerator) supportsTools() bool {
+	for _, entry := range f.providers {
+		if _, ok := entry.Generator.(ToolCaller); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+var errToolsUnsupported = errors.New("provider does not support tool use")
+
+// calls attempt on each provider in order until one succeeds. when committed
+// is set and becomes true, the current error is returned without failover.
+func (f *FailoverGenerator) run(ctx context.Context, committed *bool, attempt func(entry *failoverEntry) error) error {
+	var lastErr error
+
+	for _, entry := range f.orderedProviders() {
+		backoff := f.config.InitialBackoff
+
+		for try := 0; try <= f.config.MaxRetries; try++ {
+			err := attempt(entry)
+			if err == nil {
+				entry.breaker.recordSuccess()
+				return nil
+			}
+
+			lastErr = fmt.Errorf("%s: %w", entry.Name, err)
+
+			if ctx.Err() != nil || (committed != nil && *committed) {
+				return lastErr
+			}
+
+			if !isRetryable(err) {
+				break // try the next provider
+			}
+
+			entry.breaker.recordFailure(f.now(), f.config.FailureThreshold, f.config.Cooldown)
+
+			if try == f.config.MaxRetries || entry.breaker.isOpen(f.now()) {
+				break
+			}
+
+			if err := f.sleep(ctx, backoff); err != nil {
+				return lastErr
+			}
+
+			backoff = min(backoff*2, f.config.MaxBackoff)
+		}
+	}
+
+	return fmt.Errorf("all providers failed: %w", lastErr)
+}
+
+// returns providers in chain order, with circuit-broken ones moved to the end
+func (f *FailoverGenerator) orderedProviders() []*failoverEntry {
+	now := f.now()
+	ordered := make([]*failoverEntry, 0, len(f.providers))
+	var open []*failoverEntry
+
+	for _, entry := range f.providers {
+		if entry.breaker.isOpen(now) {
+			open = append(open, entry)
+			continue
+		}
+		ordered = append(ordered, entry)
+	}
+
+	return append(ordered, open...)
+}
+
+func (b *circuitBreaker) recordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.openUntil = time.Time{}
+}
+
+func (b *circuitBreaker) recordFailure(now time.Time, threshold int, cooldown time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.failures >= threshold {
+		b.openUntil = now.Add(cooldown)
+	}
+}
+
+func (b *circuitBreaker) isOpen(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return now.Before(b.openUntil)
+}
+
+// reports whether an error is worth retrying: 429, 5xx (including anthropic's
+// 529 overloaded) and transport errors. other API errors fail over right away.
+func isRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, errToolsUnsupported) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= http.StatusInternalServerError
+	}
+
+	return true
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
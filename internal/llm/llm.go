@@ -3,6 +3,8 @@ package llm
 import (
 	"context"
 	"fmt"
+
+	"codeberg.org/algopatterns/server/internal/config"
 )
 
 // creates a new LLM with config from environment variables
@@ -37,44 +39,121 @@ func NewLLMWithConfig(_ context.Context, config *Config) (LLM, error) {
 			APIKey: config.TransformerAPIKey,
 			Model:  config.TransformerModel,
 		})
+	case ProviderLocal:
+		transformer = NewLocalGenerator(OpenAIConfig{
+			APIKey:  config.TransformerAPIKey,
+			Model:   config.TransformerModel,
+			BaseURL: config.LocalBaseURL,
+		})
 	default:
 		return nil, fmt.Errorf("unsupported transformer provider: %s", config.TransformerProvider)
 	}
 
-	var textGenerator TextGenerator
+	primary, err := newTextGenerator(config.GeneratorProvider, config.GeneratorAPIKey, config.GeneratorModel, config)
+	if err != nil {
+		return nil, err
+	}
+
+	// the primary is wrapped even without fallbacks so it gets retries and backoff
+	chain := []FailoverProvider{{Name: config.GeneratorProvider, Generator: primary}}
+
+	for _, fallback := range config.GeneratorFallbacks {
+		generator, err := newTextGenerator(fallback.Provider, fallback.APIKey, fallback.Model, config)
+		if err != nil {
+			return nil, err
+		}
+
+		chain = append(chain, FailoverProvider{Name: fallback.Provider, Generator: generator})
+	}
+
+	textGenerator, err := NewFailoverGenerator(chain, FailoverConfig{})
+	if err != nil {
+		return nil, err
+	}
+
+	embedder, err := newEmbedder(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CompositeLLM{
+		QueryTransformer: transformer,
+		Embedder:         embedder,
+		TextGenerator:    textGenerator,
+	}, nil
+}
+
+// creates the configured embedder from environment variables, for tools that
+// only need embeddings (e.g. ingestion)
+func NewEmbedder(_ context.Context) (Embedder, error) {
+	config, err := loadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load LLM config: %w", err)
+	}
+
+	return newEmbedder(config)
+}
+
+// creates an embedder for a specific provider and model, e.g. to re-embed
+// stored vectors or to embed queries after an embedding model cutover
+func NewEmbedderForModel(_ context.Context, provider Provider, model string) (Embedder, error) {
+	baseConfig, err := config.LoadEnvironmentVariables()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load base config: %w", err)
+	}
+
+	apiKey := getAPIKeyForProvider(provider, baseConfig)
+	if err := checkAPIKey("embedder", provider, apiKey); err != nil {
+		return nil, err
+	}
+
+	return newEmbedder(&Config{
+		EmbedderProvider: provider,
+		EmbedderAPIKey:   apiKey,
+		EmbedderModel:    model,
+		LocalBaseURL:     baseConfig.LocalLLMBaseURL,
+	})
+}
 
-	switch config.GeneratorProvider {
+func newTextGenerator(provider Provider, apiKey, model string, config *Config) (TextGenerator, error) {
+	switch provider {
 	case ProviderAnthropic:
-		textGenerator = NewAnthropicTransformer(AnthropicConfig{
-			APIKey:      config.GeneratorAPIKey,
-			Model:       config.GeneratorModel,
+		return NewAnthropicTransformer(AnthropicConfig{
+			APIKey:      apiKey,
+			Model:       model,
 			MaxTokens:   config.GeneratorMaxTokens,
 			Temperature: config.GeneratorTemperature,
-		})
+		}), nil
 	case ProviderOpenAI:
-		textGenerator = NewOpenAIGenerator(OpenAIConfig{
-			APIKey: config.GeneratorAPIKey,
-			Model:  config.GeneratorModel,
-		})
+		return NewOpenAIGenerator(OpenAIConfig{
+			APIKey: apiKey,
+			Model:  model,
+		}), nil
+	case ProviderLocal:
+		return NewLocalGenerator(OpenAIConfig{
+			APIKey:  apiKey,
+			Model:   model,
+			BaseURL: config.LocalBaseURL,
+		}), nil
 	default:
-		return nil, fmt.Errorf("unsupported generator provider: %s", config.GeneratorProvider)
+		return nil, fmt.Errorf("unsupported generator provider: %s", provider)
 	}
+}
 
-	var embedder Embedder
-
+func newEmbedder(config *Config) (Embedder, error) {
 	switch config.EmbedderProvider {
 	case ProviderOpenAI:
-		embedder = NewOpenAIEmbedder(OpenAIConfig{
+		return NewOpenAIEmbedder(OpenAIConfig{
 			APIKey: config.EmbedderAPIKey,
 			Model:  config.EmbedderModel,
-		})
+		}), nil
+	case ProviderLocal:
+		return NewLocalEmbedder(OpenAIConfig{
+			APIKey:  config.EmbedderAPIKey,
+			Model:   config.EmbedderModel,
+			BaseURL: config.LocalBaseURL,
+		}), nil
 	default:
 		return nil, fmt.Errorf("unsupported embedder provider: %s", config.EmbedderProvider)
 	}
-
-	return &CompositeLLM{
-		QueryTransformer: transformer,
-		Embedder:         embedder,
-		TextGenerator:    textGenerator,
-	}, nil
 }
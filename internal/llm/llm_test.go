@@ -2,6 +2,10 @@ package llm
 
 import (
 	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -63,6 +67,16 @@ func TestNewLLMWithConfig_ValidateProviders(t *testing.T) {
 			expectError:   true,
 			errorContains: "unsupported embedder provider",
 		},
+		{
+			name: "valid local config without api keys",
+			config: &Config{
+				TransformerProvider: ProviderLocal,
+				GeneratorProvider:   ProviderLocal,
+				EmbedderProvider:    ProviderLocal,
+				LocalBaseURL:        "http://localhost:11434/v1",
+			},
+			expectError: false,
+		},
 		{
 			name: "valid anthropic config",
 			config: &Config{
@@ -162,3 +176,354 @@ func TestEmbeddings_InputValidation(t *testing.T) {
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "no texts provided")
 }
+
+func TestToolRequestConversion(t *testing.T) {
+	req := ToolGenerationRequest{
+		SystemPrompt: "system",
+		Messages: []ToolMessage{
+			{Role: "user", Content: "make a beat"},
+			{
+				Role:      "assistant",
+				Content:   "let me check the docs",
+				ToolCalls: []ToolCall{{ID: "call_1", Name: "search_docs", Input: json.RawMessage(`{"query":"drums"}`)}},
+			},
+			{
+				Role:        "user",
+				ToolResults: []ToolResult{{ToolCallID: "call_1", Content: "no documentation found", IsError: true}},
+			},
+		},
+		Tools: []Tool{{Name: "search_docs", Description: "search", InputSchema: json.RawMessage(`{"type":"object"}`)}},
+	}
+
+	tests := []struct {
+		name         string
+		disableTools bool
+	}{
+		{name: "tools enabled", disableTools: false},
+		{name: "tools disabled", disableTools: true},
+	}
+
+	for _, tt := range tests {
+		t.Run("anthropic "+tt.name, func(t *testing.T) {
+			req.DisableTools = tt.disableTools
+			apiReq := buildAnthropicToolRequest("claude", 1024, 0.7, req)
+
+			assert.Equal(t, "system", apiReq.System)
+			require.Len(t, apiReq.Messages, 3)
+			require.Len(t, apiReq.Tools, 1)
+
+			assistant := apiReq.Messages[1]
+			require.Len(t, assistant.Content, 2)
+			assert.Equal(t, "text", assistant.Content[0].Type)
+			assert.Equal(t, "tool_use", assistant.Content[1].Type)
+			assert.Equal(t, "call_1", assistant.Content[1].ID)
+
+			result := apiReq.Messages[2].Content[0]
+			assert.Equal(t, "tool_result", result.Type)
+			assert.Equal(t, "call_1", result.ToolUseID)
+			assert.True(t, result.IsError)
+
+			if tt.disableTools {
+				require.NotNil(t, apiReq.ToolChoice)
+				assert.Equal(t, "none", apiReq.ToolChoice.Type)
+			} else {
+				assert.Nil(t, apiReq.ToolChoice)
+			}
+		})
+
+		t.Run("openai "+tt.name, func(t *testing.T) {
+			req.DisableTools = tt.disableTools
+			apiReq := buildOpenAIToolRequest("gpt", req)
+
+			require.Len(t, apiReq.Messages, 4)
+			assert.Equal(t, "system", apiReq.Messages[0].Role)
+			require.Len(t, apiReq.Tools, 1)
+			assert.Equal(t, "function", apiReq.Tools[0].Type)
+
+			assistant := apiReq.Messages[2]
+			require.Len(t, assistant.ToolCalls, 1)
+			assert.Equal(t, "call_1", assistant.ToolCalls[0].ID)
+			assert.JSONEq(t, `{"query":"drums"}`, assistant.ToolCalls[0].Function.Arguments)
+
+			result := apiReq.Messages[3]
+			assert.Equal(t, "tool", result.Role)
+			assert.Equal(t, "call_1", result.ToolCallID)
+			require.NotNil(t, result.Content)
+			assert.Contains(t, *result.Content, "error")
+
+			if tt.disableTools {
+				assert.Equal(t, "none", apiReq.ToolChoice)
+			} else {
+				assert.Empty(t, apiReq.ToolChoice)
+			}
+		})
+	}
+}
+
+func TestToolResponseParsing(t *testing.T) {
+	t.Run("anthropic", func(t *testing.T) {
+		var apiResp anthropicToolResponse
+		require.NoError(t, json.Unmarshal([]byte(`{
+			"content": [
+				{"type": "text", "text": "checking"},
+				{"type": "tool_use", "id": "toolu_1", "name": "validate_code", "input": {"code": "s(\"bd\")"}}
+			],
+			"stop_reason": "tool_use",
+			"usage": {"input_tokens": 10, "output_tokens": 5}
+		}`), &apiResp))
+
+		resp := parseAnthropicToolResponse(&apiResp)
+
+		assert.Equal(t, "checking", resp.Text)
+		require.Len(t, resp.ToolCalls, 1)
+		assert.Equal(t, "toolu_1", resp.ToolCalls[0].ID)
+		assert.Equal(t, "validate_code", resp.ToolCalls[0].Name)
+		assert.JSONEq(t, `{"code": "s(\"bd\")"}`, string(resp.ToolCalls[0].Input))
+		assert.Equal(t, 10, resp.Usage.InputTokens)
+		assert.Equal(t, 5, resp.Usage.OutputTokens)
+	})
+
+	t.Run("openai", func(t *testing.T) {
+		var apiResp openaiToolChatResponse
+		require.NoError(t, json.Unmarshal([]byte(`{
+			"choices": [{
+				"message": {
+					"content": null,
+					"tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "search_docs", "arguments": "{\"query\":\"reverb\"}"}}]
+				},
+				"finish_reason": "tool_calls"
+			}],
+			"usage": {"prompt_tokens": 12, "completion_tokens": 3}
+		}`), &apiResp))
+
+		resp, err := parseOpenAIToolResponse(&apiResp)
+		require.NoError(t, err)
+
+		assert.Empty(t, resp.Text)
+		require.Len(t, resp.ToolCalls, 1)
+		assert.Equal(t, "search_docs", resp.ToolCalls[0].Name)
+		assert.JSONEq(t, `{"query":"reverb"}`, string(resp.ToolCalls[0].Input))
+		assert.Equal(t, 12, resp.Usage.InputTokens)
+	})
+
+	t.Run("openai without choices", func(t *testing.T) {
+		_, err := parseOpenAIToolResponse(&openaiToolChatResponse{})
+		assert.Error(t, err)
+	})
+}
+
+func TestLocalProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// local servers run without auth when no key is configured
+		assert.Empty(t, r.Header.Get("Authorization"))
+
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "llama3.1", body["model"])
+
+		switch r.URL.Path {
+		case "/v1/chat/completions":
+			if body["stream"] == true {
+				w.Header().Set("Content-Type", "text/event-stream")
+				_, _ = io.WriteString(w, "data: {\"choices\":[{\"delta\":{\"content\":\"sound(\"}}]}\n\n")      //nolint:errcheck
+				_, _ = io.WriteString(w, "data: {\"choices\":[{\"delta\":{\"content\":\"\\\"bd\\\")\"}}]}\n\n") //nolint:errcheck
+				_, _ = io.WriteString(w, "data: [DONE]\n\n")                                                    //nolint:errcheck
+				return
+			}
+
+			_, _ = io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":"sound(\"bd\")"}}],"usage":{"prompt_tokens":5,"completion_tokens":3}}`) //nolint:errcheck
+		case "/v1/embeddings":
+			_, _ = io.WriteString(w, `{"data":[{"index":0,"embedding":[0.1,0.2]}]}`) //nolint:errcheck
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	config := OpenAIConfig{Model: "llama3.1", BaseURL: server.URL + "/v1/"}
+	ctx := context.Background()
+
+	t.Run("generate text", func(t *testing.T) {
+		resp, err := NewLocalGenerator(config).GenerateText(ctx, TextGenerationRequest{
+			Messages: []Message{{Role: "user", Content: "kick"}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, `sound("bd")`, resp.Text)
+		assert.Equal(t, 5, resp.Usage.InputTokens)
+	})
+
+	t.Run("stream text", func(t *testing.T) {
+		var chunks []string
+		resp, err := NewLocalGenerator(config).GenerateTextStream(ctx, TextGenerationRequest{
+			Messages: []Message{{Role: "user", Content: "kick"}},
+		}, func(chunk string) error {
+			chunks = append(chunks, chunk)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"sound(", `"bd")`}, chunks)
+		assert.Equal(t, `sound("bd")`, resp.Text)
+	})
+
+	t.Run("embeddings", func(t *testing.T) {
+		embedding, err := NewLocalEmbedder(config).GenerateEmbedding(ctx, "kick")
+		require.NoError(t, err)
+		assert.Equal(t, []float32{0.1, 0.2}, embedding)
+	})
+}
+
+// scripted generator for failover tests, returns errs in order and then succeeds
+type fakeGenerator struct {
+	model  string
+	errs   []error
+	chunks []string
+	calls  int
+}
+
+func (f *fakeGenerator) Model() string { return f.model }
+
+func (f *fakeGenerator) GenerateText(_ context.Context, _ TextGenerationRequest) (*TextGenerationResponse, error) {
+	f.calls++
+	if f.calls <= len(f.errs) {
+		return nil, f.errs[f.calls-1]
+	}
+	return &TextGenerationResponse{Text: f.model}, nil
+}
+
+func (f *fakeGenerator) GenerateTextStream(_ context.Context, _ TextGenerationRequest, onChunk func(chunk string) error) (*TextGenerationResponse, error) {
+	f.calls++
+	for _, chunk := range f.chunks {
+		if err := onChunk(chunk); err != nil {
+			return nil, err
+		}
+	}
+	if f.calls <= len(f.errs) {
+		return nil, f.errs[f.calls-1]
+	}
+	return &TextGenerationResponse{Text: f.model}, nil
+}
+
+func TestFailoverGenerator(t *testing.T) {
+	overloaded := &APIError{StatusCode: 529, Body: "overloaded"}
+	badRequest := &APIError{StatusCode: http.StatusBadRequest, Body: "bad request"}
+
+	newChain := func(t *testing.T, primary, fallback *fakeGenerator) (*FailoverGenerator, *[]time.Duration) {
+		t.Helper()
+
+		failover, err := NewFailoverGenerator([]FailoverProvider{
+			{Name: ProviderAnthropic, Generator: primary},
+			{Name: ProviderOpenAI, Generator: fallback},
+		}, FailoverConfig{FailureThreshold: 3})
+		require.NoError(t, err)
+
+		var sleeps []time.Duration
+		failover.sleep = func(_ context.Context, d time.Duration) error {
+			sleeps = append(sleeps, d)
+			return nil
+		}
+
+		return failover, &sleeps
+	}
+
+	ctx := context.Background()
+	req := TextGenerationRequest{Messages: []Message{{Role: "user", Content: "kick"}}}
+
+	t.Run("retries with backoff then succeeds", func(t *testing.T) {
+		primary := &fakeGenerator{model: "claude", errs: []error{overloaded, overloaded}}
+		fallback := &fakeGenerator{model: "gpt"}
+		failover, sleeps := newChain(t, primary, fallback)
+
+		resp, err := failover.GenerateText(ctx, req)
+		require.NoError(t, err)
+		assert.Equal(t, ProviderAnthropic, resp.Provider)
+		assert.Equal(t, "claude", resp.Model)
+		assert.Equal(t, 3, primary.calls)
+		assert.Equal(t, 0, fallback.calls)
+		assert.Equal(t, []time.Duration{500 * time.Millisecond, time.Second}, *sleeps)
+	})
+
+	t.Run("fails over after retries are exhausted", func(t *testing.T) {
+		primary := &fakeGenerator{model: "claude", errs: []error{overloaded, overloaded, overloaded}}
+		fallback := &fakeGenerator{model: "gpt"}
+		failover, _ := newChain(t, primary, fallback)
+
+		resp, err := failover.GenerateText(ctx, req)
+		require.NoError(t, err)
+		assert.Equal(t, ProviderOpenAI, resp.Provider)
+		assert.Equal(t, "gpt", resp.Model)
+		assert.Equal(t, 3, primary.calls)
+	})
+
+	t.Run("non-retryable error fails over without retry", func(t *testing.T) {
+		primary := &fakeGenerator{model: "claude", errs: []error{badRequest}}
+		fallback := &fakeGenerator{model: "gpt"}
+		failover, sleeps := newChain(t, primary, fallback)
+
+		resp, err := failover.GenerateText(ctx, req)
+		require.NoError(t, err)
+		assert.Equal(t, ProviderOpenAI, resp.Provider)
+		assert.Equal(t, 1, primary.calls)
+		assert.Empty(t, *sleeps)
+	})
+
+	t.Run("all providers failing returns the last error", func(t *testing.T) {
+		primary := &fakeGenerator{model: "claude", errs: []error{badRequest}}
+		fallback := &fakeGenerator{model: "gpt", errs: []error{badRequest}}
+		failover, _ := newChain(t, primary, fallback)
+
+		_, err := failover.GenerateText(ctx, req)
+		require.Error(t, err)
+
+		var apiErr *APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	})
+
+	t.Run("open circuit moves provider to the back", func(t *testing.T) {
+		primary := &fakeGenerator{model: "claude", errs: []error{overloaded, overloaded, overloaded}}
+		fallback := &fakeGenerator{model: "gpt"}
+		failover, _ := newChain(t, primary, fallback)
+
+		_, err := failover.GenerateText(ctx, req)
+		require.NoError(t, err)
+
+		// the primary has recovered, but its circuit is still open
+		resp, err := failover.GenerateText(ctx, req)
+		require.NoError(t, err)
+		assert.Equal(t, ProviderOpenAI, resp.Provider)
+		assert.Equal(t, 3, primary.calls)
+
+		// after the cooldown the primary is tried first again
+		failover.now = func() time.Time { return time.Now().Add(time.Minute) }
+		resp, err = failover.GenerateText(ctx, req)
+		require.NoError(t, err)
+		assert.Equal(t, ProviderAnthropic, resp.Provider)
+	})
+
+	t.Run("stream fails over before the first chunk", func(t *testing.T) {
+		primary := &fakeGenerator{model: "claude", errs: []error{badRequest}}
+		fallback := &fakeGenerator{model: "gpt", chunks: []string{"sound(", `"bd")`}}
+		failover, _ := newChain(t, primary, fallback)
+
+		var chunks []string
+		resp, err := failover.GenerateTextStream(ctx, req, func(chunk string) error {
+			chunks = append(chunks, chunk)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, ProviderOpenAI, resp.Provider)
+		assert.Equal(t, []string{"sound(", `"bd")`}, chunks)
+	})
+
+	t.Run("stream does not fail over after a chunk", func(t *testing.T) {
+		primary := &fakeGenerator{model: "claude", errs: []error{overloaded}, chunks: []string{"sound("}}
+		fallback := &fakeGenerator{model: "gpt"}
+		failover, sleeps := newChain(t, primary, fallback)
+
+		_, err := failover.GenerateTextStream(ctx, req, func(string) error { return nil })
+		require.Error(t, err)
+		assert.Equal(t, 1, primary.calls)
+		assert.Equal(t, 0, fallback.calls)
+		assert.Empty(t, *sleeps)
+	})
+}
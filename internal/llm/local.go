@@ -0,0 +1,60 @@
+package llm
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultLocalBaseURL        = "http://localhost:11434/v1" // ollama
+	defaultLocalChatModel      = "llama3.1"
+	defaultLocalEmbeddingModel = "nomic-embed-text"
+)
+
+// shared HTTP client for local model servers. local inference is slower than
+// hosted APIs, so requests get more time to complete.
+var localHTTPClient = &http.Client{
+	Timeout: 5 * time.Minute,
+	Transport: &http.Transport{
+		MaxIdleConns:        20,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     90 * time.Second,
+	},
+}
+
+// creates a generator for a self-hosted OpenAI-compatible server
+// (ollama, llama.cpp, vLLM, LM Studio). requests are not rate limited.
+func NewLocalGenerator(config OpenAIConfig) *OpenAIGenerator {
+	if config.BaseURL == "" {
+		config.BaseURL = defaultLocalBaseURL
+	}
+	config.BaseURL = strings.TrimSuffix(config.BaseURL, "/")
+
+	if config.Model == "" {
+		config.Model = defaultLocalChatModel
+	}
+
+	return &OpenAIGenerator{
+		config:     config,
+		httpClient: localHTTPClient,
+	}
+}
+
+// creates an embedder for a self-hosted OpenAI-compatible server.
+// the model's dimension must match the stored vectors.
+func NewLocalEmbedder(config OpenAIConfig) *OpenAIEmbedder {
+	if config.BaseURL == "" {
+		config.BaseURL = defaultLocalBaseURL
+	}
+	config.BaseURL = strings.TrimSuffix(config.BaseURL, "/")
+
+	if config.Model == "" {
+		config.Model = defaultLocalEmbeddingModel
+	}
+
+	return &OpenAIEmbedder{
+		config:     config,
+		httpClient: localHTTPClient,
+	}
+}
@@ -15,10 +15,9 @@ import (
 )
 
 const (
-	openaiEmbeddingsURL      = "https://api.openai.com/v1/embeddings"
-	openaiChatCompletionsURL = "https://api.openai.com/v1/chat/completions"
-	defaultOpenAIModel       = "text-embedding-3-small"
-	defaultOpenAIChatModel   = "gpt-4o"
+	openaiBaseURL          = "https://api.openai.com/v1"
+	defaultOpenAIModel     = "text-embedding-3-small"
+	defaultOpenAIChatModel = "gpt-4o"
 	// openaiEmbeddingDimension = 1536
 )
 
@@ -37,6 +36,47 @@ var openaiHTTPClient = &http.Client{
 // limits to 50 requests/second with burst capacity of 10
 var openaiRateLimiter = rate.NewLimiter(50, 10)
 
+// sends a json POST request to an OpenAI-compatible endpoint and checks the
+// status. the caller must close the body of the returned response.
+func postOpenAIJSON(ctx context.Context, client *http.Client, limiter *rate.Limiter, url, apiKey string, body any) (*http.Response, error) {
+	jsonData, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	// local servers usually run without auth
+	if apiKey != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
+	}
+
+	// apply rate limiting before making the request
+	if limiter != nil {
+		if err := limiter.Wait(ctx); err != nil {
+			return nil, fmt.Errorf("rate limiter error: %w", err)
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()          //nolint:errcheck
+		body, _ := io.ReadAll(resp.Body) //nolint:errcheck
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	return resp, nil
+}
+
 type embeddingRequest struct {
 	Input    []string `json:"input"`
 	Model    string   `json:"model"`
@@ -58,13 +98,15 @@ type embeddingResponse struct {
 }
 
 type OpenAIConfig struct {
-	APIKey string
-	Model  string // e.g., "text-embedding-3-small"
+	APIKey  string
+	Model   string // e.g., "text-embedding-3-small"
+	BaseURL string // e.g., "http://localhost:11434/v1" for OpenAI-compatible servers (default: OpenAI)
 }
 
 type OpenAIEmbedder struct {
 	config     OpenAIConfig
 	httpClient *http.Client
+	limiter    *rate.Limiter // nil for servers without rate limits
 }
 
 func NewOpenAIEmbedder(config OpenAIConfig) *OpenAIEmbedder {
@@ -72,12 +114,22 @@ func NewOpenAIEmbedder(config OpenAIConfig) *OpenAIEmbedder {
 		config.Model = defaultOpenAIModel
 	}
 
+	if config.BaseURL == "" {
+		config.BaseURL = openaiBaseURL
+	}
+
 	return &OpenAIEmbedder{
 		config:     config,
 		httpClient: openaiHTTPClient, // use shared client with proper timeouts and connection pooling
+		limiter:    openaiRateLimiter,
 	}
 }
 
+// returns the embedding model, used to tag stored vectors
+func (e *OpenAIEmbedder) EmbeddingModel() string {
+	return e.config.Model
+}
+
 func (e *OpenAIEmbedder) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
 	embeddings, err := e.GenerateEmbeddings(ctx, []string{text})
 	if err != nil {
@@ -102,36 +154,13 @@ func (e *OpenAIEmbedder) GenerateEmbeddings(ctx context.Context, texts []string)
 		Encoding: "float",
 	}
 
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "POST", openaiEmbeddingsURL, bytes.NewBuffer(jsonData))
+	resp, err := postOpenAIJSON(ctx, e.httpClient, e.limiter, e.config.BaseURL+"/embeddings", e.config.APIKey, reqBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", e.config.APIKey))
-
-	// apply rate limiting before making the request
-	if err := openaiRateLimiter.Wait(ctx); err != nil {
-		return nil, fmt.Errorf("rate limiter error: %w", err)
-	}
-
-	resp, err := e.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, err
 	}
 
 	defer resp.Body.Close() //nolint:errcheck
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body) //nolint:errcheck
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
-	}
-
 	var embResp embeddingResponse
 	if err := json.NewDecoder(resp.Body).Decode(&embResp); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
@@ -203,6 +232,7 @@ type openaiChatResponse struct {
 type OpenAIGenerator struct {
 	config     OpenAIConfig
 	httpClient *http.Client
+	limiter    *rate.Limiter // nil for servers without rate limits
 }
 
 func NewOpenAIGenerator(config OpenAIConfig) *OpenAIGenerator {
@@ -210,9 +240,14 @@ func NewOpenAIGenerator(config OpenAIConfig) *OpenAIGenerator {
 		config.Model = defaultOpenAIChatModel
 	}
 
+	if config.BaseURL == "" {
+		config.BaseURL = openaiBaseURL
+	}
+
 	return &OpenAIGenerator{
 		config:     config,
 		httpClient: openaiHTTPClient,
+		limiter:    openaiRateLimiter,
 	}
 }
 
@@ -243,35 +278,13 @@ func (g *OpenAIGenerator) GenerateText(ctx context.Context, req TextGenerationRe
 		Temperature: 0.7,
 	}
 
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", openaiChatCompletionsURL, bytes.NewBuffer(jsonData))
+	resp, err := postOpenAIJSON(ctx, g.httpClient, g.limiter, g.config.BaseURL+"/chat/completions", g.config.APIKey, reqBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", g.config.APIKey))
-
-	if err := openaiRateLimiter.Wait(ctx); err != nil {
-		return nil, fmt.Errorf("rate limiter error: %w", err)
-	}
-
-	resp, err := g.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, err
 	}
 
 	defer resp.Body.Close() //nolint:errcheck
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body) //nolint:errcheck
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
-	}
-
 	var chatResp openaiChatResponse
 	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
@@ -312,34 +325,13 @@ func (g *OpenAIGenerator) GenerateTextStream(ctx context.Context, req TextGenera
 		Stream:      true,
 	}
 
-	jsonData, err := json.Marshal(reqBody)
+	resp, err := postOpenAIJSON(ctx, g.httpClient, g.limiter, g.config.BaseURL+"/chat/completions", g.config.APIKey, reqBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", openaiChatCompletionsURL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", g.config.APIKey))
-
-	if err := openaiRateLimiter.Wait(ctx); err != nil {
-		return nil, fmt.Errorf("rate limiter error: %w", err)
+		return nil, err
 	}
 
-	resp, err := g.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
-	}
 	defer resp.Body.Close() //nolint:errcheck
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body) //nolint:errcheck
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
-	}
-
 	var fullText strings.Builder
 	var usage Usage
 
@@ -413,50 +405,204 @@ func (g *OpenAIGenerator) AnalyzeQuery(ctx context.Context, userQuery string) (*
 		MaxTokens:   200,
 	}
 
-	jsonData, err := json.Marshal(reqBody)
+	resp, err := postOpenAIJSON(ctx, g.httpClient, g.limiter, g.config.BaseURL+"/chat/completions", g.config.APIKey, reqBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		return nil, err
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", openaiChatCompletionsURL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+	defer resp.Body.Close() //nolint:errcheck
+
+	var chatResp openaiChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if len(chatResp.Choices) == 0 {
+		return nil, fmt.Errorf("no choices in response")
 	}
 
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", g.config.APIKey))
+	responseText := chatResp.Choices[0].Message.Content
 
-	if err := openaiRateLimiter.Wait(ctx); err != nil {
-		return nil, fmt.Errorf("rate limiter error: %w", err)
+	var analysis QueryAnalysis
+	if err := json.Unmarshal([]byte(responseText), &analysis); err != nil {
+		return nil, fmt.Errorf("failed to parse query analysis JSON: %w", err)
 	}
 
-	resp, err := g.httpClient.Do(httpReq)
+	return &analysis, nil
+}
+
+// openai tool-calling request types
+type openaiToolChatRequest struct {
+	Model       string                  `json:"model"`
+	Messages    []openaiToolChatMessage `json:"messages"`
+	Tools       []openaiTool            `json:"tools,omitempty"`
+	ToolChoice  string                  `json:"tool_choice,omitempty"` // "auto" or "none"
+	MaxTokens   int                     `json:"max_tokens,omitempty"`
+	Temperature float32                 `json:"temperature,omitempty"`
+}
+
+type openaiTool struct {
+	Type     string             `json:"type"` // always "function"
+	Function openaiToolFunction `json:"function"`
+}
+
+type openaiToolFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Parameters  json.RawMessage `json:"parameters"`
+}
+
+// a chat message that may carry tool calls (assistant) or answer one (role "tool")
+type openaiToolChatMessage struct {
+	Role       string           `json:"role"`
+	Content    *string          `json:"content"`
+	ToolCalls  []openaiToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type openaiToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"` // always "function"
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"` // JSON encoded as a string
+	} `json:"function"`
+}
+
+type openaiToolChatResponse struct {
+	Choices []struct {
+		Message struct {
+			Content   *string          `json:"content"`
+			ToolCalls []openaiToolCall `json:"tool_calls"`
+		} `json:"message"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+// generates a response that may request tool calls
+func (g *OpenAIGenerator) GenerateWithTools(ctx context.Context, req ToolGenerationRequest) (*ToolGenerationResponse, error) {
+	reqBody := buildOpenAIToolRequest(g.config.Model, req)
+
+	resp, err := postOpenAIJSON(ctx, g.httpClient, g.limiter, g.config.BaseURL+"/chat/completions", g.config.APIKey, reqBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, err
 	}
 
 	defer resp.Body.Close() //nolint:errcheck
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body) //nolint:errcheck
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
-	}
-
-	var chatResp openaiChatResponse
+	var chatResp openaiToolChatResponse
 	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
+	return parseOpenAIToolResponse(&chatResp)
+}
+
+// converts a provider-neutral tool request into the openai chat format.
+// tool results become one "tool" message per call.
+func buildOpenAIToolRequest(model string, req ToolGenerationRequest) openaiToolChatRequest {
+	messages := make([]openaiToolChatMessage, 0, len(req.Messages)+1)
+
+	if req.SystemPrompt != "" {
+		systemPrompt := req.SystemPrompt
+		messages = append(messages, openaiToolChatMessage{Role: "system", Content: &systemPrompt})
+	}
+
+	for _, msg := range req.Messages {
+		for _, result := range msg.ToolResults {
+			content := result.Content
+			if result.IsError {
+				content = "error: " + content
+			}
+
+			messages = append(messages, openaiToolChatMessage{
+				Role:       "tool",
+				Content:    &content,
+				ToolCallID: result.ToolCallID,
+			})
+		}
+
+		if msg.Content == "" && len(msg.ToolCalls) == 0 {
+			continue
+		}
+
+		chatMsg := openaiToolChatMessage{Role: msg.Role}
+		if msg.Content != "" {
+			content := msg.Content
+			chatMsg.Content = &content
+		}
+
+		for _, call := range msg.ToolCalls {
+			toolCall := openaiToolCall{ID: call.ID, Type: "function"}
+			toolCall.Function.Name = call.Name
+			toolCall.Function.Arguments = string(call.Input)
+
+			if toolCall.Function.Arguments == "" {
+				toolCall.Function.Arguments = "{}"
+			}
+
+			chatMsg.ToolCalls = append(chatMsg.ToolCalls, toolCall)
+		}
+
+		messages = append(messages, chatMsg)
+	}
+
+	tools := make([]openaiTool, 0, len(req.Tools))
+	for _, tool := range req.Tools {
+		tools = append(tools, openaiTool{
+			Type: "function",
+			Function: openaiToolFunction{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.InputSchema,
+			},
+		})
+	}
+
+	reqBody := openaiToolChatRequest{
+		Model:       model,
+		Messages:    messages,
+		Tools:       tools,
+		MaxTokens:   req.MaxTokens,
+		Temperature: 0.7,
+	}
+
+	if req.DisableTools && len(tools) > 0 {
+		reqBody.ToolChoice = "none"
+	}
+
+	return reqBody
+}
+
+// collects text and tool calls from an openai chat response
+func parseOpenAIToolResponse(chatResp *openaiToolChatResponse) (*ToolGenerationResponse, error) {
 	if len(chatResp.Choices) == 0 {
 		return nil, fmt.Errorf("no choices in response")
 	}
 
-	responseText := chatResp.Choices[0].Message.Content
+	choice := chatResp.Choices[0].Message
+	response := &ToolGenerationResponse{
+		Usage: Usage{
+			InputTokens:  chatResp.Usage.PromptTokens,
+			OutputTokens: chatResp.Usage.CompletionTokens,
+		},
+	}
 
-	var analysis QueryAnalysis
-	if err := json.Unmarshal([]byte(responseText), &analysis); err != nil {
-		return nil, fmt.Errorf("failed to parse query analysis JSON: %w", err)
+	if choice.Content != nil {
+		response.Text = strings.TrimSpace(*choice.Content)
 	}
 
-	return &analysis, nil
+	for _, call := range choice.ToolCalls {
+		response.ToolCalls = append(response.ToolCalls, ToolCall{
+			ID:    call.ID,
+			Name:  call.Function.Name,
+			Input: json.RawMessage(call.Function.Arguments),
+		})
+	}
+
+	return response, nil
 }
@@ -1,6 +1,10 @@
 package llm
 
-import "context"
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
 
 // combines query transformation, embedding generation, and text generation
 type LLM interface {
@@ -46,6 +50,60 @@ type TextGenerator interface {
 	Model() string
 }
 
+// generates text with native tool use. implemented by generators whose API
+// supports tool calling, see ToolCallerFor
+type ToolCaller interface {
+	GenerateWithTools(ctx context.Context, req ToolGenerationRequest) (*ToolGenerationResponse, error)
+}
+
+// contains inputs for tool-enabled generation
+type ToolGenerationRequest struct {
+	SystemPrompt string        // system-level instructions
+	Messages     []ToolMessage // conversation including earlier tool calls and results
+	Tools        []Tool        // tools the model may call
+	DisableTools bool          // forces a text answer while keeping tool history valid
+	MaxTokens    int           // max tokens to generate
+}
+
+// contains output from tool-enabled generation
+type ToolGenerationResponse struct {
+	Text      string     // generated text (may be empty when only tools are called)
+	ToolCalls []ToolCall // tools the model wants to run, in order
+	Usage     Usage      // token usage statistics
+	Provider  Provider   // provider that served the request (set by FailoverGenerator)
+	Model     string     // model that served the request (set by FailoverGenerator)
+}
+
+// describes a tool the model can call
+type Tool struct {
+	Name        string          // unique tool name, e.g. "search_docs"
+	Description string          // when and why to use the tool
+	InputSchema json.RawMessage // JSON schema of the tool input object
+}
+
+// a tool invocation requested by the model
+type ToolCall struct {
+	ID    string          // provider-assigned call ID, echoed back in the result
+	Name  string          // tool name
+	Input json.RawMessage // JSON object matching the tool's input schema
+}
+
+// the outcome of running a tool call
+type ToolResult struct {
+	ToolCallID string // ID of the call this answers
+	Content    string // result text shown to the model
+	IsError    bool   // true if the tool failed
+}
+
+// represents a conversation turn that may carry tool calls or tool results.
+// assistant turns carry ToolCalls, user turns carry ToolResults.
+type ToolMessage struct {
+	Role        string // "user" or "assistant"
+	Content     string // message text
+	ToolCalls   []ToolCall
+	ToolResults []ToolResult
+}
+
 // contains inputs for text generation
 type TextGenerationRequest struct {
 	SystemPrompt string    // system-level instructions
@@ -55,8 +113,20 @@ type TextGenerationRequest struct {
 
 // contains output from text generation
 type TextGenerationResponse struct {
-	Text  string // generated text
-	Usage Usage  // token usage statistics
+	Text     string   // generated text
+	Usage    Usage    // token usage statistics
+	Provider Provider // provider that served the request (set by FailoverGenerator)
+	Model    string   // model that served the request (set by FailoverGenerator)
+}
+
+// returned when a provider API answers with a non-200 status
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API request failed with status %d: %s", e.StatusCode, e.Body)
 }
 
 // contains token usage statistics from llm api calls
@@ -74,8 +144,16 @@ type Message struct {
 const (
 	ProviderAnthropic Provider = "anthropic"
 	ProviderOpenAI    Provider = "openai"
+	ProviderLocal     Provider = "local" // self-hosted OpenAI-compatible server
 )
 
+// a fallback generator in the failover chain
+type FallbackConfig struct {
+	Provider Provider
+	APIKey   string
+	Model    string // empty uses the provider's default
+}
+
 // holds configuration for llm initialization
 type Config struct {
 	// transformer configuration (query expansion)
@@ -88,12 +166,16 @@ type Config struct {
 	// generator configuration (code generation)
 	GeneratorProvider    Provider
 	GeneratorAPIKey      string
-	GeneratorModel       string  // e.g., "claude-sonnet-4-20250514"
-	GeneratorMaxTokens   int     // e.g., 4096
-	GeneratorTemperature float32 // e.g., 0.7
+	GeneratorModel       string           // e.g., "claude-sonnet-4-20250514"
+	GeneratorMaxTokens   int              // e.g., 4096
+	GeneratorTemperature float32          // e.g., 0.7
+	GeneratorFallbacks   []FallbackConfig // tried in order when the generator keeps failing
 
 	// embedder configuration
 	EmbedderProvider Provider
 	EmbedderAPIKey   string
 	EmbedderModel    string // e.g., "text-embedding-3-small"
+
+	// OpenAI-compatible server used by ProviderLocal
+	LocalBaseURL string // e.g., "http://localhost:11434/v1"
 }
@@ -1,13 +1,80 @@
 package llm
 
-import "codeberg.org/algopatterns/server/internal/config"
+import (
+	"fmt"
+
+	"codeberg.org/algopatterns/server/internal/config"
+)
 
 // returns the appropriate API key for the given provider
 func getAPIKeyForProvider(provider Provider, baseConfig *config.Config) string {
 	switch provider {
 	case ProviderOpenAI:
 		return baseConfig.OpenAIKey
+	case ProviderLocal:
+		return baseConfig.LocalLLMAPIKey
 	default:
 		return baseConfig.AnthropicKey
 	}
 }
+
+// hosted providers need an API key, local servers may run without one
+func checkAPIKey(role string, provider Provider, apiKey string) error {
+	if provider == ProviderLocal || apiKey != "" {
+		return nil
+	}
+
+	return fmt.Errorf("%s provider %s requires an API key", role, provider)
+}
+
+// returns the model to use when none is configured. openai keeps its own
+// constructor default, local servers use LOCAL_LLM_MODEL.
+func defaultModelForProvider(provider Provider, anthropicDefault string, baseConfig *config.Config) string {
+	switch provider {
+	case ProviderAnthropic:
+		return anthropicDefault
+	case ProviderLocal:
+		return baseConfig.LocalLLMModel
+	default:
+		return ""
+	}
+}
+
+// returns the tool-calling generator behind g, if its provider supports native tool use
+func ToolCallerFor(g TextGenerator) (ToolCaller, bool) {
+	if composite, ok := g.(*CompositeLLM); ok {
+		g = composite.TextGenerator
+	}
+
+	if failover, ok := g.(*FailoverGenerator); ok && !failover.supportsTools() {
+		return nil, false
+	}
+
+	toolCaller, ok := g.(ToolCaller)
+	return toolCaller, ok
+}
+
+// returns the model behind an embedder, or "" if it does not report one
+func EmbeddingModelOf(e Embedder) string {
+	if composite, ok := e.(*CompositeLLM); ok {
+		e = composite.Embedder
+	}
+
+	if named, ok := e.(interface{ EmbeddingModel() string }); ok {
+		return named.EmbeddingModel()
+	}
+
+	return ""
+}
+
+// returns the query transformer as a text generator, for small scoring tasks
+// that should not use the (larger) code generation model
+func TransformerGenerator(l LLM) (TextGenerator, bool) {
+	composite, ok := l.(*CompositeLLM)
+	if !ok {
+		return nil, false
+	}
+
+	generator, ok := composite.QueryTransformer.(TextGenerator)
+	return generator, ok
+}
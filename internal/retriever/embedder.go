@@ -0,0 +1,116 @@
+package retriever
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"codeberg.org/algopatterns/server/internal/llm"
+	"codeberg.org/algopatterns/server/internal/logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var errActiveModelUnknown = errors.New("failed to read active embedding model")
+
+// creates a resolver that reads the active embedding model from db. without a
+// database the configured embedder is always used.
+func NewActiveEmbedder(db *pgxpool.Pool, configured llm.Embedder) *ActiveEmbedder {
+	e := &ActiveEmbedder{
+		configured:  configured,
+		newEmbedder: llm.NewEmbedderForModel,
+	}
+
+	if db != nil {
+		e.lookup = func(ctx context.Context) (string, llm.Provider, error) {
+			var model, provider string
+
+			err := db.QueryRow(ctx, activeEmbeddingModelQuery).Scan(&model, &provider)
+			if errors.Is(err, pgx.ErrNoRows) {
+				return "", "", nil
+			}
+
+			return model, llm.Provider(provider), err
+		}
+	}
+
+	return e
+}
+
+// returns the embedder of the active embedding model and the model its vectors
+// must be stored with. fails when the active model cannot be read, so vectors
+// are never written with a model that is not active.
+func (e *ActiveEmbedder) Resolve(ctx context.Context) (llm.Embedder, string, error) {
+	configuredModel := llm.EmbeddingModelOf(e.configured)
+
+	if e.lookup == nil {
+		return e.configured, configuredModel, nil
+	}
+
+	model, provider, err := e.activeModel(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if model == "" || model == configuredModel {
+		return e.configured, configuredModel, nil
+	}
+
+	e.mu.Lock()
+	embedder, ok := e.embedders[model]
+	e.mu.Unlock()
+
+	if ok {
+		return embedder, model, nil
+	}
+
+	// created outside the lock, NewEmbedderForModel reads the environment
+	embedder, err = e.newEmbedder(ctx, provider, model)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create embedder for active model %s: %w", model, err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// a concurrent call may have created one first, keep the cached embedder
+	if cached, ok := e.embedders[model]; ok {
+		return cached, model, nil
+	}
+
+	if e.embedders == nil {
+		e.embedders = make(map[string]llm.Embedder)
+	}
+
+	e.embedders[model] = embedder
+	logger.Info("embedding with active model", "model", model, "provider", provider)
+
+	return embedder, model, nil
+}
+
+// returns the active model, read again once activeEmbeddingModelTTL has passed.
+// the lock is only held to read and swap the cached model.
+func (e *ActiveEmbedder) activeModel(ctx context.Context) (string, llm.Provider, error) {
+	e.mu.Lock()
+
+	if time.Since(e.checkedAt) <= activeEmbeddingModelTTL {
+		defer e.mu.Unlock()
+		return e.model, e.provider, nil
+	}
+
+	e.mu.Unlock()
+
+	model, provider, err := e.lookup(ctx)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %w", errActiveModelUnknown, err)
+	}
+
+	e.mu.Lock()
+	e.model = model
+	e.provider = provider
+	e.checkedAt = time.Now()
+	e.mu.Unlock()
+
+	return model, provider, nil
+}
@@ -0,0 +1,205 @@
+package retriever
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"codeberg.org/algopatterns/server/internal/llm"
+)
+
+// embedder reporting its model, like the openai and local embedders
+type namedEmbedder struct {
+	model string
+}
+
+func (e *namedEmbedder) EmbeddingModel() string { return e.model }
+
+func (e *namedEmbedder) GenerateEmbedding(_ context.Context, _ string) ([]float32, error) {
+	return []float32{1}, nil
+}
+
+func (e *namedEmbedder) GenerateEmbeddings(_ context.Context, texts []string) ([][]float32, error) {
+	return make([][]float32, len(texts)), nil
+}
+
+// creates a resolver reading the active model from lookup, counting lookups
+// and created embedders. lookups fail if the resolver's lock is held.
+func newTestActiveEmbedder(t *testing.T, configured llm.Embedder, lookup func() (string, llm.Provider, error)) (*ActiveEmbedder, *int, *int) {
+	t.Helper()
+
+	var lookups, created int
+	e := &ActiveEmbedder{configured: configured}
+
+	e.lookup = func(_ context.Context) (string, llm.Provider, error) {
+		if !e.mu.TryLock() {
+			t.Error("active model looked up while holding the lock")
+		} else {
+			e.mu.Unlock()
+		}
+
+		lookups++
+		return lookup()
+	}
+
+	e.newEmbedder = func(_ context.Context, _ llm.Provider, model string) (llm.Embedder, error) {
+		if !e.mu.TryLock() {
+			t.Error("embedder created while holding the lock")
+		} else {
+			e.mu.Unlock()
+		}
+
+		created++
+		return &namedEmbedder{model: model}, nil
+	}
+
+	return e, &lookups, &created
+}
+
+func TestActiveEmbedderResolve(t *testing.T) {
+	configured := &namedEmbedder{model: "text-embedding-3-small"}
+
+	tests := []struct {
+		name        string
+		active      string
+		wantModel   string
+		wantCreated int
+	}{
+		{"no model registered", "", "text-embedding-3-small", 0},
+		{"configured model is active", "text-embedding-3-small", "text-embedding-3-small", 0},
+		{"other model is active", "nomic-embed-text", "nomic-embed-text", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, lookups, created := newTestActiveEmbedder(t, configured, func() (string, llm.Provider, error) {
+				return tt.active, llm.ProviderLocal, nil
+			})
+
+			for range 3 {
+				embedder, model, err := e.Resolve(context.Background())
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				if model != tt.wantModel {
+					t.Errorf("expected model %q, got %q", tt.wantModel, model)
+				}
+
+				if got := llm.EmbeddingModelOf(embedder); got != tt.wantModel {
+					t.Errorf("expected an embedder for %q, got one for %q", tt.wantModel, got)
+				}
+
+				if tt.wantCreated == 0 && embedder != llm.Embedder(configured) {
+					t.Error("expected the configured embedder")
+				}
+			}
+
+			// the active model and its embedder are cached
+			if *lookups != 1 {
+				t.Errorf("expected 1 lookup, got %d", *lookups)
+			}
+
+			if *created != tt.wantCreated {
+				t.Errorf("expected %d embedders created, got %d", tt.wantCreated, *created)
+			}
+		})
+	}
+}
+
+func TestActiveEmbedderFollowsCutover(t *testing.T) {
+	active := "text-embedding-3-small"
+	e, lookups, _ := newTestActiveEmbedder(t, &namedEmbedder{model: "text-embedding-3-small"}, func() (string, llm.Provider, error) {
+		return active, llm.ProviderOpenAI, nil
+	})
+
+	if _, model, _ := e.Resolve(context.Background()); model != "text-embedding-3-small" {
+		t.Fatalf("expected the configured model, got %q", model)
+	}
+
+	active = "nomic-embed-text"
+
+	// still cached within the TTL
+	if _, model, _ := e.Resolve(context.Background()); model != "text-embedding-3-small" {
+		t.Errorf("expected the cached model, got %q", model)
+	}
+
+	e.checkedAt = time.Now().Add(-activeEmbeddingModelTTL - time.Second)
+
+	if _, model, _ := e.Resolve(context.Background()); model != "nomic-embed-text" {
+		t.Errorf("expected the new active model, got %q", model)
+	}
+
+	if *lookups != 2 {
+		t.Errorf("expected 2 lookups, got %d", *lookups)
+	}
+}
+
+func TestActiveEmbedderErrors(t *testing.T) {
+	configured := &namedEmbedder{model: "text-embedding-3-small"}
+
+	t.Run("lookup fails", func(t *testing.T) {
+		e, lookups, _ := newTestActiveEmbedder(t, configured, func() (string, llm.Provider, error) {
+			return "", "", errors.New("connection refused")
+		})
+
+		if _, _, err := e.Resolve(context.Background()); !errors.Is(err, errActiveModelUnknown) {
+			t.Errorf("expected errActiveModelUnknown, got %v", err)
+		}
+
+		// failures are not cached
+		_, _, _ = e.Resolve(context.Background())
+
+		if *lookups != 2 {
+			t.Errorf("expected 2 lookups, got %d", *lookups)
+		}
+	})
+
+	t.Run("embedder cannot be created", func(t *testing.T) {
+		e, _, _ := newTestActiveEmbedder(t, configured, func() (string, llm.Provider, error) {
+			return "nomic-embed-text", llm.ProviderLocal, nil
+		})
+		e.newEmbedder = func(_ context.Context, _ llm.Provider, _ string) (llm.Embedder, error) {
+			return nil, errors.New("missing api key")
+		}
+
+		_, _, err := e.Resolve(context.Background())
+		if err == nil || errors.Is(err, errActiveModelUnknown) {
+			t.Errorf("expected an embedder creation error, got %v", err)
+		}
+	})
+}
+
+func TestActiveEmbedderWithoutDatabase(t *testing.T) {
+	configured := &namedEmbedder{model: "text-embedding-3-small"}
+
+	embedder, model, err := NewActiveEmbedder(nil, configured).Resolve(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if embedder != llm.Embedder(configured) || model != "text-embedding-3-small" {
+		t.Errorf("expected the configured embedder and model, got %v and %q", embedder, model)
+	}
+}
+
+// queries keep working with the configured embedder when the active model
+// cannot be read
+func TestClientActiveEmbedderFallsBack(t *testing.T) {
+	configured := &llm.CompositeLLM{Embedder: &namedEmbedder{model: "text-embedding-3-small"}}
+	e, _, _ := newTestActiveEmbedder(t, configured, func() (string, llm.Provider, error) {
+		return "", "", errors.New("connection refused")
+	})
+
+	client := &Client{llm: configured, embedder: e}
+
+	embedder, err := client.activeEmbedder(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if embedder != llm.Embedder(configured) {
+		t.Error("expected the configured embedder")
+	}
+}
@@ -1,6 +1,12 @@
 package retriever
 
 const (
+	activeEmbeddingModelQuery = `
+		SELECT model, provider
+		FROM embedding_models
+		WHERE status = 'active'
+	`
+
 	vectorSearchQuery = `
 		SELECT
 			id::text,
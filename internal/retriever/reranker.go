@@ -0,0 +1,123 @@
+package retriever
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"codeberg.org/algopatterns/server/internal/llm"
+)
+
+const (
+	// candidate text sent to the LLM reranker is cut to keep the prompt small
+	maxRerankCandidateChars = 600
+	rerankMaxTokens         = 256
+)
+
+const llmRerankPrompt = `You rate how useful search results are for answering a question about Strudel, a live coding music library.
+For each numbered passage, give a relevance score from 0 (unrelated) to 10 (directly answers the question).
+Respond with only a JSON array of numbers, one per passage, in passage order. Example for three passages: [7, 0, 3]`
+
+// creates a reranker that scores candidates with generator, usually the query transformer
+func NewLLMReranker(generator llm.TextGenerator) *LLMReranker {
+	return &LLMReranker{generator: generator}
+}
+
+func (r *LLMReranker) Score(ctx context.Context, query string, candidates []string) ([]float32, error) {
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "Question: %s\n\n", query)
+
+	for i, candidate := range candidates {
+		if len(candidate) > maxRerankCandidateChars {
+			candidate = strings.ToValidUTF8(candidate[:maxRerankCandidateChars], "") + "..."
+		}
+
+		fmt.Fprintf(&prompt, "[%d]\n%s\n\n", i+1, candidate)
+	}
+
+	resp, err := r.generator.GenerateText(ctx, llm.TextGenerationRequest{
+		SystemPrompt: llmRerankPrompt,
+		Messages:     []llm.Message{{Role: "user", Content: prompt.String()}},
+		MaxTokens:    rerankMaxTokens,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate rerank scores: %w", err)
+	}
+
+	return parseRerankScores(resp.Text, len(candidates))
+}
+
+// extracts the json score array from an LLM response and scales it to 0-1
+func parseRerankScores(response string, count int) ([]float32, error) {
+	start := strings.Index(response, "[")
+	end := strings.LastIndex(response, "]")
+
+	if start == -1 || end <= start {
+		return nil, fmt.Errorf("no score array in rerank response")
+	}
+
+	var scores []float32
+	if err := json.Unmarshal([]byte(response[start:end+1]), &scores); err != nil {
+		return nil, fmt.Errorf("failed to parse rerank scores: %w", err)
+	}
+
+	if len(scores) != count {
+		return nil, fmt.Errorf("expected %d rerank scores, got %d", count, len(scores))
+	}
+
+	for i := range scores {
+		scores[i] = min(max(scores[i], 0), 10) / 10
+	}
+
+	return scores, nil
+}
+
+// creates a reranker that needs no model, e.g. for offline use
+func NewLexicalReranker() *LexicalReranker {
+	return &LexicalReranker{}
+}
+
+// scores each candidate by the share of distinct query terms it contains.
+// terms are lowercased words of two or more letters or digits.
+func (r *LexicalReranker) Score(_ context.Context, query string, candidates []string) ([]float32, error) {
+	queryTerms := termSet(query)
+	scores := make([]float32, len(candidates))
+
+	if len(queryTerms) == 0 {
+		return scores, nil
+	}
+
+	for i, candidate := range candidates {
+		candidateTerms := termSet(candidate)
+		matched := 0
+
+		for term := range queryTerms {
+			if candidateTerms[term] {
+				matched++
+			}
+		}
+
+		scores[i] = float32(matched) / float32(len(queryTerms))
+	}
+
+	return scores, nil
+}
+
+// splits text into distinct lowercase terms
+func termSet(text string) map[string]bool {
+	terms := make(map[string]bool)
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for _, word := range words {
+		if len(word) >= 2 {
+			terms[word] = true
+		}
+	}
+
+	return terms
+}
@@ -12,23 +12,44 @@ import (
 )
 
 func New(db *pgxpool.Pool, llm llm.LLM) *Client {
-	return &Client{
-		db:   db,
-		llm:  llm,
-		topK: defaultTopK,
-	}
+	return NewWithTopK(db, llm, defaultTopK)
 }
 
 func NewWithTopK(db *pgxpool.Pool, llm llm.LLM, topK int) *Client {
 	return &Client{
-		db:   db,
-		llm:  llm,
-		topK: topK,
+		db:       db,
+		llm:      llm,
+		topK:     topK,
+		embedder: NewActiveEmbedder(db, llm),
 	}
 }
 
+// creates a client whose hybrid searches rerank merged results by default
+func NewWithReranker(db *pgxpool.Pool, llm llm.LLM, reranker Reranker) *Client {
+	client := New(db, llm)
+	client.reranker = reranker
+
+	return client
+}
+
+// returns the resolver of the active embedding model, for callers storing vectors
+func (c *Client) ActiveEmbedder() *ActiveEmbedder {
+	return c.embedder
+}
+
+// embeds text with the active embedding model, for callers searching stored
+// vectors themselves
+func (c *Client) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	embedding, err := c.embedQuery(ctx, text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate query embedding: %w", err)
+	}
+
+	return embedding, nil
+}
+
 func (c *Client) VectorSearch(ctx context.Context, queryText string, topK int) ([]SearchResult, error) {
-	embedding, err := c.llm.GenerateEmbedding(ctx, queryText)
+	embedding, err := c.embedQuery(ctx, queryText)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate query embedding: %w", err)
 	}
@@ -68,7 +89,7 @@ func (c *Client) VectorSearch(ctx context.Context, queryText string, topK int) (
 }
 
 func (c *Client) SearchExamples(ctx context.Context, queryText string, topK int) ([]ExampleResult, error) {
-	embedding, err := c.llm.GenerateEmbedding(ctx, queryText)
+	embedding, err := c.embedQuery(ctx, queryText)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate query embedding: %w", err)
 	}
@@ -185,14 +206,146 @@ func (c *Client) BM25SearchExamples(ctx context.Context, queryText string, topK
 	return results, nil
 }
 
-func (c *Client) HybridSearchDocs(ctx context.Context, userQuery, _ string, topK int) ([]SearchResult, error) {
+// hybrid search (vector + BM25) for docs, reranked when the client has a reranker
+func (c *Client) HybridSearchDocs(ctx context.Context, userQuery, editorState string, topK int) ([]SearchResult, error) {
+	return c.HybridSearchDocsWithOptions(ctx, userQuery, editorState, topK, SearchOptions{Rerank: c.reranker != nil})
+}
+
+// hybrid search (vector + BM25) for docs with per-call settings, grouped by
+// page with page summaries and examples for the LLM context.
+func (c *Client) HybridSearchDocsWithOptions(ctx context.Context, userQuery, editorState string, topK int, opts SearchOptions) ([]SearchResult, error) {
+	merged, err := c.HybridRankDocs(ctx, userQuery, editorState, topK, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	organized, err := c.organizeByPage(ctx, merged)
+	if err != nil {
+		return nil, fmt.Errorf("failed to organize results: %w", err)
+	}
+
+	return organized, nil
+}
+
+// fused (and optionally reranked) hybrid ranking of doc chunks, before they
+// are grouped by page. with a non-empty editor an intent+context search runs
+// next to the intent search.
+func (c *Client) HybridRankDocs(ctx context.Context, userQuery, editorState string, topK int, opts SearchOptions) ([]SearchResult, error) {
 	searchQuery, err := c.llm.TransformQuery(ctx, userQuery)
 	if err != nil {
 		logger.Warn("query transformation failed, using original query", "error", err)
 		searchQuery = userQuery
 	}
 
-	// run vector and BM25 searches in parallel
+	rerank := opts.Rerank && c.reranker != nil
+
+	mergeK := topK
+	if rerank {
+		mergeK = rerankPoolSize(opts, topK)
+	}
+
+	keywords := extractEditorKeywords(editorState)
+
+	var intent, contextual []SearchResult
+	var intentErr, contextualErr error
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		intent, intentErr = c.hybridDocCandidates(ctx, searchQuery, userQuery, mergeK)
+	}()
+
+	if keywords != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			contextual, contextualErr = c.hybridDocCandidates(ctx, searchQuery+" "+keywords, userQuery, mergeK)
+		}()
+	}
+
+	wg.Wait()
+
+	if intentErr != nil {
+		return nil, intentErr
+	}
+
+	if contextualErr != nil {
+		logger.Warn("context search failed, using intent only", "error", contextualErr)
+	}
+
+	merged := mergeAndRankDocs(intent, guardContextDocs(intent, contextual, mergeK), mergeK)
+
+	if rerank {
+		merged = rerankDocs(ctx, c.reranker, userQuery, merged, topK)
+	}
+
+	return merged, nil
+}
+
+// hybrid search (vector + BM25) for strudel examples, reranked when the client has a reranker
+func (c *Client) HybridSearchExamples(ctx context.Context, userQuery, editorState string, topK int) ([]ExampleResult, error) {
+	return c.HybridSearchExamplesWithOptions(ctx, userQuery, editorState, topK, SearchOptions{Rerank: c.reranker != nil})
+}
+
+// hybrid search (vector + BM25) for strudel examples with per-call settings.
+// with a non-empty editor an intent+context search runs next to the intent search.
+func (c *Client) HybridSearchExamplesWithOptions(ctx context.Context, userQuery, editorState string, topK int, opts SearchOptions) ([]ExampleResult, error) {
+	searchQuery, err := c.llm.TransformQuery(ctx, userQuery)
+	if err != nil {
+		logger.Warn("query transformation failed, using original query", "error", err)
+		searchQuery = userQuery
+	}
+
+	rerank := opts.Rerank && c.reranker != nil
+
+	mergeK := topK
+	if rerank {
+		mergeK = rerankPoolSize(opts, topK)
+	}
+
+	keywords := extractEditorKeywords(editorState)
+
+	var intent, contextual []ExampleResult
+	var intentErr, contextualErr error
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		intent, intentErr = c.hybridExampleCandidates(ctx, searchQuery, userQuery, mergeK)
+	}()
+
+	if keywords != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			contextual, contextualErr = c.hybridExampleCandidates(ctx, searchQuery+" "+keywords, userQuery, mergeK)
+		}()
+	}
+
+	wg.Wait()
+
+	if intentErr != nil {
+		return nil, intentErr
+	}
+
+	if contextualErr != nil {
+		logger.Warn("context search failed, using intent only", "error", contextualErr)
+	}
+
+	merged := mergeAndRankExamples(intent, guardContextExamples(intent, contextual, mergeK), mergeK)
+
+	if rerank {
+		merged = rerankExamples(ctx, c.reranker, userQuery, merged, topK)
+	}
+
+	return merged, nil
+}
+
+// runs vector and BM25 doc searches in parallel and merges them with weighted scoring.
+// BM25 always uses the user's words, editor keywords only steer the vector search.
+func (c *Client) hybridDocCandidates(ctx context.Context, vectorQuery, bm25Query string, topK int) ([]SearchResult, error) {
 	searchK := topK + 5 // get extra results for better merging
 
 	var vectorResults, bm25Results []SearchResult
@@ -202,13 +355,13 @@ func (c *Client) HybridSearchDocs(ctx context.Context, userQuery, _ string, topK
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		vectorResults, vectorErr = c.VectorSearch(ctx, searchQuery, searchK)
+		vectorResults, vectorErr = c.VectorSearch(ctx, vectorQuery, searchK)
 	}()
 
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		bm25Results, bm25Err = c.BM25Search(ctx, userQuery, searchK)
+		bm25Results, bm25Err = c.BM25Search(ctx, bm25Query, searchK)
 	}()
 
 	wg.Wait()
@@ -222,24 +375,11 @@ func (c *Client) HybridSearchDocs(ctx context.Context, userQuery, _ string, topK
 		bm25Results = []SearchResult{}
 	}
 
-	merged := mergeVectorAndBM25Docs(vectorResults, bm25Results, topK)
-
-	organized, err := c.organizeByPage(ctx, merged)
-	if err != nil {
-		return nil, fmt.Errorf("failed to organize results: %w", err)
-	}
-
-	return organized, nil
+	return mergeVectorAndBM25Docs(vectorResults, bm25Results, topK), nil
 }
 
-// hybrid search (vector + BM25) for strudel examples
-func (c *Client) HybridSearchExamples(ctx context.Context, userQuery, _ string, topK int) ([]ExampleResult, error) {
-	searchQuery, err := c.llm.TransformQuery(ctx, userQuery)
-	if err != nil {
-		logger.Warn("query transformation failed, using original query", "error", err)
-		searchQuery = userQuery
-	}
-
+// runs vector and BM25 example searches in parallel and merges them with weighted scoring
+func (c *Client) hybridExampleCandidates(ctx context.Context, vectorQuery, bm25Query string, topK int) ([]ExampleResult, error) {
 	searchK := topK + 5 // get extra results for better merging
 
 	var vectorResults, bm25Results []ExampleResult
@@ -250,14 +390,14 @@ func (c *Client) HybridSearchExamples(ctx context.Context, userQuery, _ string,
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		vectorResults, vectorErr = c.SearchExamples(ctx, searchQuery, searchK)
+		vectorResults, vectorErr = c.SearchExamples(ctx, vectorQuery, searchK)
 	}()
 
 	// BM25 search (30% weight)
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		bm25Results, bm25Err = c.BM25SearchExamples(ctx, userQuery, searchK)
+		bm25Results, bm25Err = c.BM25SearchExamples(ctx, bm25Query, searchK)
 	}()
 
 	wg.Wait()
@@ -271,7 +411,5 @@ func (c *Client) HybridSearchExamples(ctx context.Context, userQuery, _ string,
 		bm25Results = []ExampleResult{}
 	}
 
-	merged := mergeVectorAndBM25Examples(vectorResults, bm25Results, topK)
-
-	return merged, nil
+	return mergeVectorAndBM25Examples(vectorResults, bm25Results, topK), nil
 }
@@ -1,11 +1,14 @@
 package retriever
 
 import (
+	"context"
+	"errors"
 	"log"
 	"slices"
 	"strings"
 	"testing"
 
+	"codeberg.org/algopatterns/server/internal/llm"
 	"codeberg.org/algopatterns/server/internal/strudel"
 	"github.com/joho/godotenv"
 )
@@ -195,3 +198,221 @@ func TestUniqueStrings(t *testing.T) {
 		}
 	}
 }
+
+// scores candidates with fixed values, or fails
+type fixedReranker struct {
+	scores []float32
+	err    error
+}
+
+func (r *fixedReranker) Score(_ context.Context, _ string, _ []string) ([]float32, error) {
+	return r.scores, r.err
+}
+
+// returns a canned response for the LLM reranker
+type cannedGenerator struct {
+	text string
+}
+
+func (g *cannedGenerator) GenerateText(_ context.Context, _ llm.TextGenerationRequest) (*llm.TextGenerationResponse, error) {
+	return &llm.TextGenerationResponse{Text: g.text}, nil
+}
+
+func (g *cannedGenerator) GenerateTextStream(_ context.Context, _ llm.TextGenerationRequest, _ func(chunk string) error) (*llm.TextGenerationResponse, error) {
+	return &llm.TextGenerationResponse{Text: g.text}, nil
+}
+
+func (g *cannedGenerator) Model() string { return "canned" }
+
+func resultIDs(results []SearchResult) []string {
+	ids := make([]string, len(results))
+	for i, result := range results {
+		ids[i] = result.ID
+	}
+	return ids
+}
+
+// verifies reranking reorders the merged hybrid candidates and keeps the top K
+func TestRerankMergedDocs(t *testing.T) {
+	vector := []SearchResult{
+		{ID: "1", SectionTitle: "Filters", Content: "lpf sets a low pass filter", Similarity: 0.9},
+		{ID: "2", SectionTitle: "Samples", Content: "sound plays a sample", Similarity: 0.8},
+		{ID: "3", SectionTitle: "Tempo", Content: "setcps changes the tempo", Similarity: 0.7},
+	}
+
+	bm25 := []SearchResult{
+		{ID: "2", SectionTitle: "Samples", Content: "sound plays a sample", Similarity: 0.5},
+		{ID: "4", SectionTitle: "Reverb", Content: "room adds reverb to a sound", Similarity: 0.9},
+	}
+
+	merged := mergeVectorAndBM25Docs(vector, bm25, 10)
+	if got := resultIDs(merged); !slices.Equal(got, []string{"2", "1", "3", "4"}) {
+		t.Fatalf("unexpected merge order %v", got)
+	}
+
+	ctx := context.Background()
+
+	t.Run("reorders by score", func(t *testing.T) {
+		reranked := rerankDocs(ctx, &fixedReranker{scores: []float32{0.1, 0.2, 0.9, 0.5}}, "tempo", merged, 3)
+
+		if got := resultIDs(reranked); !slices.Equal(got, []string{"3", "4", "1"}) {
+			t.Errorf("expected [3 4 1], got %v", got)
+		}
+
+		if reranked[0].Similarity != 0.9 {
+			t.Errorf("expected rerank score to replace similarity, got %f", reranked[0].Similarity)
+		}
+	})
+
+	t.Run("ties keep merged order", func(t *testing.T) {
+		reranked := rerankDocs(ctx, &fixedReranker{scores: []float32{0.5, 0.5, 0.5, 0.5}}, "tempo", merged, 4)
+
+		if got := resultIDs(reranked); !slices.Equal(got, []string{"2", "1", "3", "4"}) {
+			t.Errorf("expected merged order, got %v", got)
+		}
+	})
+
+	t.Run("failure keeps merged order", func(t *testing.T) {
+		for _, reranker := range []Reranker{
+			&fixedReranker{err: errors.New("unavailable")},
+			&fixedReranker{scores: []float32{1}},
+		} {
+			reranked := rerankDocs(ctx, reranker, "tempo", merged, 2)
+
+			if got := resultIDs(reranked); !slices.Equal(got, []string{"2", "1"}) {
+				t.Errorf("expected [2 1], got %v", got)
+			}
+		}
+	})
+
+	t.Run("lexical", func(t *testing.T) {
+		reranked := rerankDocs(ctx, NewLexicalReranker(), "add reverb to the drums", merged, 2)
+
+		if reranked[0].ID != "4" {
+			t.Errorf("expected the reverb chunk first, got %v", resultIDs(reranked))
+		}
+	})
+
+	t.Run("llm", func(t *testing.T) {
+		reranker := NewLLMReranker(&cannedGenerator{text: "Scores: [2, 0, 10, 4]"})
+		reranked := rerankDocs(ctx, reranker, "how do I change the tempo", merged, 2)
+
+		if got := resultIDs(reranked); !slices.Equal(got, []string{"3", "4"}) {
+			t.Errorf("expected [3 4], got %v", got)
+		}
+
+		if reranked[0].Similarity != 1 {
+			t.Errorf("expected scores scaled to 0-1, got %f", reranked[0].Similarity)
+		}
+	})
+}
+
+// verifies example reranking uses tags and code, not only the title
+func TestRerankMergedExamples(t *testing.T) {
+	vector := []ExampleResult{
+		{ID: "a", Title: "Ambient pad", Code: "note('c e g').s('sawtooth')", Similarity: 0.9},
+		{ID: "b", Title: "Groove", Tags: []string{"drums", "breakbeat"}, Code: "s('bd sd')", Similarity: 0.6},
+	}
+
+	merged := mergeVectorAndBM25Examples(vector, nil, 10)
+	reranked := rerankExamples(context.Background(), NewLexicalReranker(), "breakbeat drums", merged, 1)
+
+	if len(reranked) != 1 || reranked[0].ID != "b" {
+		t.Errorf("expected the tagged groove example, got %+v", reranked)
+	}
+}
+
+func TestParseRerankScores(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		count    int
+		want     []float32
+		wantErr  bool
+	}{
+		{name: "bare array", response: "[10, 5, 0]", count: 3, want: []float32{1, 0.5, 0}},
+		{name: "surrounding text", response: "Here you go:\n[3, 7]\nDone.", count: 2, want: []float32{0.3, 0.7}},
+		{name: "clamped", response: "[12, -1]", count: 2, want: []float32{1, 0}},
+		{name: "wrong count", response: "[1, 2]", count: 3, wantErr: true},
+		{name: "no array", response: "all relevant", count: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRerankScores(tt.response, tt.count)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %v", got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+// verifies an unrelated editor cannot push the intent results out
+func TestContextRelevanceGuard(t *testing.T) {
+	// "add a melody" with drum code in the editor
+	intent := []SearchResult{
+		{ID: "melody", Similarity: 0.60},
+		{ID: "note", Similarity: 0.55},
+		{ID: "scale", Similarity: 0.50},
+	}
+
+	contextual := []SearchResult{
+		{ID: "drums", Similarity: 0.90},
+		{ID: "note", Similarity: 0.88},
+		{ID: "samples", Similarity: 0.85},
+		{ID: "bank", Similarity: 0.80},
+	}
+
+	guarded := guardContextDocs(intent, contextual, 3)
+	if got := resultIDs(guarded); !slices.Equal(got, []string{"drums"}) {
+		t.Fatalf("expected only the best context-only result, got %v", got)
+	}
+
+	if guarded[0].Similarity >= 0.90 {
+		t.Errorf("expected context-only score to be down-weighted, got %f", guarded[0].Similarity)
+	}
+
+	merged := mergeAndRankDocs(intent, guarded, 3)
+	if got := resultIDs(merged); !slices.Equal(got, []string{"drums", "melody", "note"}) {
+		t.Errorf("expected intent results to keep two of three slots, got %v", got)
+	}
+
+	// a larger topK leaves room for more context
+	if got := guardContextDocs(intent, contextual, 5); len(got) != 2 {
+		t.Errorf("expected 2 context-only results for topK 5, got %v", resultIDs(got))
+	}
+
+	// an empty editor leaves the intent results untouched
+	if got := mergeAndRankDocs(intent, guardContextDocs(intent, nil, 3), 3); !slices.Equal(resultIDs(got), resultIDs(intent)) {
+		t.Errorf("expected intent order without context, got %v", resultIDs(got))
+	}
+}
+
+func TestContextRelevanceGuardExamples(t *testing.T) {
+	intent := []ExampleResult{{ID: "a", Similarity: 0.7}, {ID: "b", Similarity: 0.6}}
+	contextual := []ExampleResult{{ID: "a", Similarity: 0.9}, {ID: "c", Similarity: 0.8}, {ID: "d", Similarity: 0.75}}
+
+	merged := mergeAndRankExamples(intent, guardContextExamples(intent, contextual, 2), 2)
+
+	ids := make([]string, len(merged))
+	for i, example := range merged {
+		ids[i] = example.ID
+	}
+
+	// "a" keeps its intent score, "c" is capped to one slot and down-weighted below it
+	if !slices.Equal(ids, []string{"a", "c"}) {
+		t.Errorf("expected [a c], got %v", ids)
+	}
+}
@@ -1,15 +1,56 @@
 package retriever
 
 import (
+	"context"
+	"sync"
+	"time"
+
 	"codeberg.org/algopatterns/server/internal/llm"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // client performs vector similarity search on documentation and examples
 type Client struct {
-	db   *pgxpool.Pool
-	llm  llm.LLM
-	topK int
+	db       *pgxpool.Pool
+	llm      llm.LLM
+	topK     int
+	embedder *ActiveEmbedder
+	reranker Reranker // optional, reorders merged hybrid results
+}
+
+// scores search candidates against a query. implementations must return one
+// score per candidate, in order, where higher means more relevant.
+type Reranker interface {
+	Score(ctx context.Context, query string, candidates []string) ([]float32, error)
+}
+
+// per-call settings for hybrid search
+type SearchOptions struct {
+	Rerank           bool // reorder merged candidates with the client's reranker
+	RerankCandidates int  // merged candidates passed to the reranker (default 20)
+}
+
+// scores candidates with the transformer LLM
+type LLMReranker struct {
+	generator llm.TextGenerator
+}
+
+// scores candidates by how many query terms they contain, for offline use
+type LexicalReranker struct{}
+
+// resolves the embedder of the active embedding model, so an embedding model
+// cutover takes effect without a restart. the configured embedder is used when
+// it matches or when no model is registered.
+type ActiveEmbedder struct {
+	configured  llm.Embedder
+	lookup      func(ctx context.Context) (model string, provider llm.Provider, err error) // nil without a database
+	newEmbedder func(ctx context.Context, provider llm.Provider, model string) (llm.Embedder, error)
+
+	mu        sync.Mutex
+	provider  llm.Provider
+	model     string // active model, empty if none is registered
+	checkedAt time.Time
+	embedders map[string]llm.Embedder // model -> embedder, for models other than the configured one
 }
 
 // represents a document chunk from vector search
@@ -2,19 +2,59 @@ package retriever
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"slices"
 	"sort"
+	"strings"
 	"sync"
+	"time"
 
+	"codeberg.org/algopatterns/server/internal/llm"
 	"codeberg.org/algopatterns/server/internal/logger"
 	"codeberg.org/algopatterns/server/internal/strudel"
 )
 
 const (
 	defaultTopK = 5
+
+	// how long the active embedding model is cached before it is read again
+	activeEmbeddingModelTTL = time.Minute
+
+	// merged candidates passed to the reranker when SearchOptions leaves it unset
+	defaultRerankCandidates = 20
+
+	// relevance guard for intent+context results the intent search did not find:
+	// their score is scaled down and they may fill at most this share of topK
+	contextScoreWeight = 0.85
+	maxContextShare    = 0.4
 )
 
+// embeds a search query with the active embedding model. the configured
+// embedder is used when it matches or when the active model is unknown.
+func (c *Client) embedQuery(ctx context.Context, text string) ([]float32, error) {
+	embedder, err := c.activeEmbedder(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return embedder.GenerateEmbedding(ctx, text)
+}
+
+func (c *Client) activeEmbedder(ctx context.Context) (llm.Embedder, error) {
+	if c.embedder == nil {
+		return c.llm, nil
+	}
+
+	embedder, _, err := c.embedder.Resolve(ctx)
+	if errors.Is(err, errActiveModelUnknown) && ctx.Err() == nil {
+		logger.Warn("failed to read active embedding model, using configured embedder", "error", err)
+		return c.llm, nil
+	}
+
+	return embedder, err
+}
+
 // groups chunks by page and fetches special sections
 func (c *Client) organizeByPage(ctx context.Context, chunks []SearchResult) ([]SearchResult, error) {
 	pageSet := make(map[string]bool)
@@ -147,6 +187,53 @@ func extractEditorKeywords(editorState string) string {
 	return strudel.ExtractKeywords(editorState)
 }
 
+// keeps an unrelated editor from drowning out the intent. results the intent
+// search also found are dropped (the intent copy is kept by the merge), the
+// rest are down-weighted and capped at maxContextShare of topK.
+func guardContext[T any](intent, contextual []T, topK int, id func(T) string, scale func(*T, float32)) []T {
+	if len(contextual) == 0 {
+		return nil
+	}
+
+	found := make(map[string]bool, len(intent))
+	for _, result := range intent {
+		found[id(result)] = true
+	}
+
+	limit := max(1, int(float64(topK)*maxContextShare))
+	guarded := make([]T, 0, limit)
+
+	// contextual results arrive sorted by score, so the best ones are kept
+	for _, result := range contextual {
+		if found[id(result)] {
+			continue
+		}
+
+		if len(guarded) == limit {
+			break
+		}
+
+		scale(&result, contextScoreWeight)
+		guarded = append(guarded, result)
+	}
+
+	return guarded
+}
+
+func guardContextDocs(intent, contextual []SearchResult, topK int) []SearchResult {
+	return guardContext(intent, contextual, topK,
+		func(doc SearchResult) string { return doc.ID },
+		func(doc *SearchResult, weight float32) { doc.Similarity *= weight },
+	)
+}
+
+func guardContextExamples(intent, contextual []ExampleResult, topK int) []ExampleResult {
+	return guardContext(intent, contextual, topK,
+		func(example ExampleResult) string { return example.ID },
+		func(example *ExampleResult, weight float32) { example.Similarity *= weight },
+	)
+}
+
 // merges and deduplicates doc search results, ranking by similarity
 func mergeAndRankDocs(primary, contextual []SearchResult, topK int) []SearchResult {
 	seen := make(map[string]bool)
@@ -280,3 +367,93 @@ func mergeVectorAndBM25Examples(vectorResults, bm25Results []ExampleResult, topK
 
 	return merged
 }
+
+// reorders candidates by reranker score and keeps the top K. the score
+// replaces Similarity. on failure the merged order is kept.
+func rerank[T any](ctx context.Context, reranker Reranker, query string, candidates []T, text func(T) string, setScore func(*T, float32), topK int) []T {
+	if len(candidates) > 1 {
+		texts := make([]string, len(candidates))
+		for i, candidate := range candidates {
+			texts[i] = text(candidate)
+		}
+
+		scores, err := reranker.Score(ctx, query, texts)
+
+		switch {
+		case err != nil:
+			logger.Warn("reranking failed, keeping merged order", "error", err)
+		case len(scores) != len(candidates):
+			logger.Warn("reranker returned wrong number of scores, keeping merged order",
+				"candidates", len(candidates),
+				"scores", len(scores),
+			)
+		default:
+			reranked := make([]T, len(candidates))
+			copy(reranked, candidates)
+
+			order := make([]int, len(candidates))
+			for i := range order {
+				order[i] = i
+			}
+
+			// stable, so ties keep the merged order
+			sort.SliceStable(order, func(i, j int) bool {
+				return scores[order[i]] > scores[order[j]]
+			})
+
+			for i, idx := range order {
+				reranked[i] = candidates[idx]
+				setScore(&reranked[i], scores[idx])
+			}
+
+			candidates = reranked
+		}
+	}
+
+	if len(candidates) > topK {
+		return candidates[:topK]
+	}
+
+	return candidates
+}
+
+// reranks docs by section title and content
+func rerankDocs(ctx context.Context, reranker Reranker, query string, docs []SearchResult, topK int) []SearchResult {
+	return rerank(ctx, reranker, query, docs,
+		func(doc SearchResult) string {
+			return doc.SectionTitle + "\n" + doc.Content
+		},
+		func(doc *SearchResult, score float32) {
+			doc.Similarity = score
+		},
+		topK,
+	)
+}
+
+// reranks examples by title, description, tags and code
+func rerankExamples(ctx context.Context, reranker Reranker, query string, examples []ExampleResult, topK int) []ExampleResult {
+	return rerank(ctx, reranker, query, examples,
+		func(example ExampleResult) string {
+			return strings.Join([]string{
+				example.Title,
+				example.Description,
+				strings.Join(example.Tags, " "),
+				example.Code,
+			}, "\n")
+		},
+		func(example *ExampleResult, score float32) {
+			example.Similarity = score
+		},
+		topK,
+	)
+}
+
+// number of merged candidates to keep before reranking
+func rerankPoolSize(opts SearchOptions, topK int) int {
+	candidates := opts.RerankCandidates
+	if candidates <= 0 {
+		candidates = defaultRerankCandidates
+	}
+
+	return max(candidates, topK)
+}
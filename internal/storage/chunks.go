@@ -21,27 +21,32 @@ func (c *Client) ClearAllChunks(ctx context.Context) error {
 	return nil
 }
 
-// inserts a single chunk with its embedding into the database
-func (c *Client) InsertChunk(ctx context.Context, chunk chunker.Chunk, embedding []float32) error {
+// inserts or replaces a single chunk (matched by page url and key) with its
+// embedding, tagged with the embedding model
+func (c *Client) UpsertChunk(ctx context.Context, chunk chunker.Chunk, embedding []float32, model string) error {
 	_, err := c.pool.Exec(ctx,
-		insertChunkQuery,
+		upsertChunkQuery,
 		chunk.PageName,
 		chunk.PageURL,
 		chunk.SectionTitle,
 		chunk.Content,
 		pgvector.NewVector(embedding),
 		chunk.Metadata,
+		model,
+		len(embedding),
+		chunk.Key,
+		chunk.Hash(),
 	)
 
 	if err != nil {
-		return fmt.Errorf("failed to insert chunk: %w", err)
+		return fmt.Errorf("failed to upsert chunk: %w", err)
 	}
 
 	return nil
 }
 
-// multiple chunks in a single transaction
-func (c *Client) InsertChunksBatch(ctx context.Context, chunks []chunker.Chunk, embeddings [][]float32) error {
+// upserts multiple chunks in a single transaction
+func (c *Client) UpsertChunksBatch(ctx context.Context, chunks []chunker.Chunk, embeddings [][]float32, model string) error {
 	if len(chunks) != len(embeddings) {
 		return fmt.Errorf("chunks and embeddings length mismatch")
 	}
@@ -61,13 +66,17 @@ func (c *Client) InsertChunksBatch(ctx context.Context, chunks []chunker.Chunk,
 	batch := &pgx.Batch{}
 
 	for i, chunk := range chunks {
-		batch.Queue(insertChunkQuery,
+		batch.Queue(upsertChunkQuery,
 			chunk.PageName,
 			chunk.PageURL,
 			chunk.SectionTitle,
 			chunk.Content,
 			pgvector.NewVector(embeddings[i]),
 			chunk.Metadata,
+			model,
+			len(embeddings[i]),
+			chunk.Key,
+			chunk.Hash(),
 		)
 	}
 
@@ -77,7 +86,7 @@ func (c *Client) InsertChunksBatch(ctx context.Context, chunks []chunker.Chunk,
 		_, err := br.Exec()
 		if err != nil {
 			br.Close() //nolint:errcheck,gosec // G104: error path cleanup
-			return fmt.Errorf("failed to insert chunk %d: %w", i, err)
+			return fmt.Errorf("failed to upsert chunk %d: %w", i, err)
 		}
 	}
 
@@ -92,6 +101,48 @@ func (c *Client) InsertChunksBatch(ctx context.Context, chunks []chunker.Chunk,
 	return nil
 }
 
+// returns key and hash of every stored chunk whose page url starts with urlPrefix
+func (c *Client) ListChunkHashes(ctx context.Context, urlPrefix string) ([]ChunkHash, error) {
+	rows, err := c.pool.Query(ctx, listChunkHashesQuery, urlPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chunk hashes: %w", err)
+	}
+
+	defer rows.Close()
+	var hashes []ChunkHash
+
+	for rows.Next() {
+		var hash ChunkHash
+
+		if err := rows.Scan(&hash.PageName, &hash.PageURL, &hash.Key, &hash.ContentHash); err != nil {
+			return nil, fmt.Errorf("failed to scan chunk hash: %w", err)
+		}
+
+		hashes = append(hashes, hash)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating chunk hashes: %w", err)
+	}
+
+	return hashes, nil
+}
+
+// deletes a page's chunks except those whose key is in keep. an empty keep
+// removes the whole page. returns the number of deleted chunks.
+func (c *Client) DeleteChunksByPage(ctx context.Context, pageURL string, keep []string) (int64, error) {
+	if keep == nil {
+		keep = []string{}
+	}
+
+	result, err := c.pool.Exec(ctx, deleteChunksByPageQuery, pageURL, keep)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete chunks for page %s: %w", pageURL, err)
+	}
+
+	return result.RowsAffected(), nil
+}
+
 // returns the total number of chunks in the database
 func (c *Client) GetChunkCount(ctx context.Context) (int, error) {
 	var count int
@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"codeberg.org/algopatterns/server/internal/logger"
+	"github.com/jackc/pgx/v5"
+	"github.com/pgvector/pgvector-go"
+)
+
+// registers a model to build embeddings for. an existing model keeps its
+// status and dimension, a retired one is built again.
+func (c *Client) RegisterEmbeddingModel(ctx context.Context, model, provider string, dimension int) (*EmbeddingModel, error) {
+	var registered EmbeddingModel
+
+	err := c.pool.QueryRow(ctx, registerEmbeddingModelQuery, model, provider, dimension).Scan(
+		&registered.Model,
+		&registered.Provider,
+		&registered.Dimension,
+		&registered.Status,
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to register embedding model: %w", err)
+	}
+
+	return &registered, nil
+}
+
+// returns doc chunks that have no staged embedding for model yet
+func (c *Client) ListChunksMissingEmbedding(ctx context.Context, model string, limit int) ([]EmbeddingSource, error) {
+	return c.listEmbeddingSources(ctx, listChunksMissingEmbeddingQuery, model, limit)
+}
+
+// returns embedded strudels that have no staged embedding for model yet
+func (c *Client) ListStrudelsMissingEmbedding(ctx context.Context, model string, limit int) ([]EmbeddingSource, error) {
+	return c.listEmbeddingSources(ctx, listStrudelsMissingEmbeddingQuery, model, limit)
+}
+
+// stores embeddings for doc chunks without touching the live vectors
+func (c *Client) StageChunkEmbeddings(ctx context.Context, model string, sources []EmbeddingSource, embeddings [][]float32) error {
+	return c.stageEmbeddings(ctx, stageChunkEmbeddingQuery, model, sources, embeddings)
+}
+
+// stores embeddings for strudels without touching the live vectors
+func (c *Client) StageStrudelEmbeddings(ctx context.Context, model string, sources []EmbeddingSource, embeddings [][]float32) error {
+	return c.stageEmbeddings(ctx, stageStrudelEmbeddingQuery, model, sources, embeddings)
+}
+
+// swaps the staged embeddings of model into the live vectors and makes it the
+// active model, all in one transaction
+func (c *Client) ActivateEmbeddingModel(ctx context.Context, model string) error {
+	if _, err := c.pool.Exec(ctx, activateEmbeddingModelQuery, model); err != nil {
+		return fmt.Errorf("failed to activate embedding model: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Client) listEmbeddingSources(ctx context.Context, query, model string, limit int) ([]EmbeddingSource, error) {
+	rows, err := c.pool.Query(ctx, query, model, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list embedding sources: %w", err)
+	}
+
+	defer rows.Close()
+	var sources []EmbeddingSource
+
+	for rows.Next() {
+		var source EmbeddingSource
+
+		if err := rows.Scan(&source.ID, &source.Text); err != nil {
+			return nil, fmt.Errorf("failed to scan embedding source: %w", err)
+		}
+
+		sources = append(sources, source)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating embedding sources: %w", err)
+	}
+
+	return sources, nil
+}
+
+func (c *Client) stageEmbeddings(ctx context.Context, query, model string, sources []EmbeddingSource, embeddings [][]float32) error {
+	batch, err := newStageBatch(query, model, sources, embeddings)
+	if err != nil {
+		return err
+	}
+
+	tx, err := c.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	// defer rollback - will be no-op if commit succeeds
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			logger.Warn("failed to rollback transaction", "error", err)
+		}
+	}()
+
+	br := tx.SendBatch(ctx, batch)
+
+	for i := range len(sources) {
+		if _, err := br.Exec(); err != nil {
+			br.Close() //nolint:errcheck,gosec // G104: error path cleanup
+			return fmt.Errorf("failed to stage embedding %d: %w", i, err)
+		}
+	}
+
+	if err := br.Close(); err != nil {
+		return fmt.Errorf("failed to close batch: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// queues one staging insert per source, with its embedding and the model
+func newStageBatch(query, model string, sources []EmbeddingSource, embeddings [][]float32) (*pgx.Batch, error) {
+	if len(sources) != len(embeddings) {
+		return nil, fmt.Errorf("sources and embeddings length mismatch")
+	}
+
+	batch := &pgx.Batch{}
+
+	for i, source := range sources {
+		batch.Queue(query, source.ID, model, pgvector.NewVector(embeddings[i]))
+	}
+
+	return batch, nil
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/pgvector/pgvector-go"
+)
+
+func TestNewStageBatch(t *testing.T) {
+	sources := []EmbeddingSource{
+		{ID: "chunk-1", Text: "sound"},
+		{ID: "chunk-2", Text: "note"},
+	}
+	embeddings := [][]float32{{0.1, 0.2}, {0.3, 0.4}}
+
+	for _, query := range []string{stageChunkEmbeddingQuery, stageStrudelEmbeddingQuery} {
+		batch, err := newStageBatch(query, "nomic-embed-text", sources, embeddings)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if batch.Len() != len(sources) {
+			t.Fatalf("expected %d queued inserts, got %d", len(sources), batch.Len())
+		}
+
+		for i, queued := range batch.QueuedQueries {
+			if queued.SQL != query {
+				t.Errorf("insert %d: expected the staging query, got %q", i, queued.SQL)
+			}
+
+			if len(queued.Arguments) != 3 {
+				t.Fatalf("insert %d: expected 3 arguments, got %d", i, len(queued.Arguments))
+			}
+
+			if queued.Arguments[0] != sources[i].ID {
+				t.Errorf("insert %d: expected id %s, got %v", i, sources[i].ID, queued.Arguments[0])
+			}
+
+			if queued.Arguments[1] != "nomic-embed-text" {
+				t.Errorf("insert %d: expected the model, got %v", i, queued.Arguments[1])
+			}
+
+			vector, ok := queued.Arguments[2].(pgvector.Vector)
+			if !ok {
+				t.Fatalf("insert %d: expected a pgvector.Vector, got %T", i, queued.Arguments[2])
+			}
+
+			if want := pgvector.NewVector(embeddings[i]).String(); vector.String() != want {
+				t.Errorf("insert %d: expected embedding %s, got %s", i, want, vector.String())
+			}
+		}
+	}
+}
+
+func TestNewStageBatchLengthMismatch(t *testing.T) {
+	sources := []EmbeddingSource{{ID: "chunk-1"}, {ID: "chunk-2"}}
+
+	if _, err := newStageBatch(stageChunkEmbeddingQuery, "nomic-embed-text", sources, [][]float32{{0.1}}); err == nil {
+		t.Error("expected an error when embeddings are missing")
+	}
+}
+
+func TestNewStageBatchEmpty(t *testing.T) {
+	batch, err := newStageBatch(stageStrudelEmbeddingQuery, "nomic-embed-text", nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if batch.Len() != 0 {
+		t.Errorf("expected an empty batch, got %d inserts", batch.Len())
+	}
+}
@@ -4,8 +4,82 @@ const (
 	getChunkCountQuery   = "SELECT COUNT(*) FROM doc_embeddings"
 	deleteAllChunksQuery = "DELETE FROM doc_embeddings"
 
-	insertChunkQuery = `
-		INSERT INTO doc_embeddings (page_name, page_url, section_title, content, embedding, metadata)
-		VALUES ($1, $2, $3, $4, $5, $6)
+	// a changed chunk drops its staged vectors, they were made from the old content
+	upsertChunkQuery = `
+		WITH upserted AS (
+			INSERT INTO doc_embeddings (page_name, page_url, section_title, content, embedding, metadata, embedding_model, embedding_dim, chunk_key, content_hash)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			ON CONFLICT (page_url, chunk_key) DO UPDATE
+			SET page_name = EXCLUDED.page_name,
+				section_title = EXCLUDED.section_title,
+				content = EXCLUDED.content,
+				embedding = EXCLUDED.embedding,
+				metadata = EXCLUDED.metadata,
+				embedding_model = EXCLUDED.embedding_model,
+				embedding_dim = EXCLUDED.embedding_dim,
+				content_hash = EXCLUDED.content_hash
+			RETURNING id
+		)
+		DELETE FROM doc_embeddings_staged
+		WHERE doc_id IN (SELECT id FROM upserted)
 	`
+
+	listChunkHashesQuery = `
+		SELECT page_name, page_url, COALESCE(chunk_key, ''), COALESCE(content_hash, '')
+		FROM doc_embeddings
+		WHERE starts_with(page_url, $1)
+	`
+
+	deleteChunksByPageQuery = `
+		DELETE FROM doc_embeddings
+		WHERE page_url = $1
+		  AND (chunk_key IS NULL OR NOT (chunk_key = ANY($2)))
+	`
+
+	// a retired model can be built again, an active or building one is left as is
+	registerEmbeddingModelQuery = `
+		INSERT INTO embedding_models (model, provider, dimension)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (model) DO UPDATE
+		SET status = CASE WHEN embedding_models.status = 'retired' THEN 'building' ELSE embedding_models.status END
+		RETURNING model, provider, dimension, status
+	`
+
+	listChunksMissingEmbeddingQuery = `
+		SELECT d.id::text, d.content
+		FROM doc_embeddings d
+		WHERE NOT EXISTS (
+			SELECT 1 FROM doc_embeddings_staged s
+			WHERE s.doc_id = d.id AND s.model = $1
+		)
+		ORDER BY d.id
+		LIMIT $2
+	`
+
+	// re-embeds the strudels that are embedded today, with the same text
+	listStrudelsMissingEmbeddingQuery = `
+		SELECT us.id::text, concat_ws(E'\n', us.title, us.description, us.code)
+		FROM user_strudels us
+		WHERE us.embedding IS NOT NULL
+		  AND NOT EXISTS (
+			SELECT 1 FROM user_strudel_embeddings_staged s
+			WHERE s.strudel_id = us.id AND s.model = $1
+		  )
+		ORDER BY us.id
+		LIMIT $2
+	`
+
+	stageChunkEmbeddingQuery = `
+		INSERT INTO doc_embeddings_staged (doc_id, model, embedding)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (doc_id, model) DO UPDATE SET embedding = EXCLUDED.embedding
+	`
+
+	stageStrudelEmbeddingQuery = `
+		INSERT INTO user_strudel_embeddings_staged (strudel_id, model, embedding)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (strudel_id, model) DO UPDATE SET embedding = EXCLUDED.embedding
+	`
+
+	activateEmbeddingModelQuery = "SELECT activate_embedding_model($1)"
 )
@@ -0,0 +1,23 @@
+package storage
+
+// an embedding model known to the retriever
+type EmbeddingModel struct {
+	Model     string
+	Provider  string
+	Dimension int
+	Status    string // "building", "active" or "retired"
+}
+
+// a stored row and the text its embedding is generated from
+type EmbeddingSource struct {
+	ID   string
+	Text string
+}
+
+// identifies a stored chunk and the content it was embedded from
+type ChunkHash struct {
+	PageName    string
+	PageURL     string
+	Key         string // empty for chunks ingested before keys existed
+	ContentHash string
+}
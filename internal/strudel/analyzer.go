@@ -175,7 +175,7 @@ func AnalyzeCode(code string) CodeAnalysis {
 	analysis := CodeAnalysis{
 		SoundTags:      analyzeSounds(code, parsed),
 		EffectTags:     analyzeEffects(parsed),
-		MusicalTags:    analyzeMusicalElements(code, parsed),
+		MusicalTags:    analyzeMusicalElements(parsed),
 		ComplexityTags: analyzeComplexityTags(code, parsed),
 		Complexity:     calculateComplexity(code, parsed),
 		LineCount:      strings.Count(code, "\n") + 1,
@@ -228,7 +228,7 @@ func analyzeEffects(parsed ParsedCode) []string {
 }
 
 // analyzeMusicalElements identifies musical constructs
-func analyzeMusicalElements(code string, parsed ParsedCode) []string {
+func analyzeMusicalElements(parsed ParsedCode) []string {
 	tags := make(map[string]bool)
 
 	// note patterns indicate melody
@@ -243,10 +243,17 @@ func analyzeMusicalElements(code string, parsed ParsedCode) []string {
 		tags["melodic"] = true
 	}
 
-	// chord patterns (multiple notes at once indicated by comma)
-	if regexp.MustCompile(`note\s*\(\s*["'][^"']*,`).MatchString(code) {
-		tags["chords"] = true
-		tags["harmony"] = true
+	for _, pattern := range parsed.NotePatterns {
+		// chord patterns (multiple notes at once indicated by comma)
+		if pattern.HasStack() {
+			tags["chords"] = true
+			tags["harmony"] = true
+		}
+
+		// sequences (plain note patterns)
+		if isPlainSequence(pattern) {
+			tags["sequences"] = true
+		}
 	}
 
 	// rhythm patterns
@@ -254,11 +261,6 @@ func analyzeMusicalElements(code string, parsed ParsedCode) []string {
 		tags["rhythm"] = true
 	}
 
-	// sequences (note patterns)
-	if regexp.MustCompile(`note\s*\(\s*["'][a-g0-9\s]+["']`).MatchString(code) {
-		tags["sequences"] = true
-	}
-
 	return mapKeysToSlice(tags)
 }
 
@@ -382,6 +384,21 @@ func GenerateTags(analysis CodeAnalysis, category string, existingTags []string)
 	return mapKeysToSlice(tags)
 }
 
+// reports whether a pattern is a sequence of notes without grouping or modifiers
+func isPlainSequence(pattern *MiniNode) bool {
+	if pattern.Kind != MiniSequence || len(pattern.Children) == 0 {
+		return false
+	}
+
+	for _, step := range pattern.Children {
+		if step.Kind != MiniAtom || len(step.Modifiers) > 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
 func contains(slice []string, item string) bool {
 	return slices.Contains(slice, item)
 }
@@ -0,0 +1,891 @@
+package strudel
+
+import (
+	"fmt"
+	"slices"
+)
+
+// binary operator precedence, higher binds tighter
+var binaryPrecedence = map[string]int{
+	"??": 1, "||": 2, "&&": 3, "|": 4, "^": 5, "&": 6,
+	"==": 7, "!=": 7, "===": 7, "!==": 7,
+	"<": 8, ">": 8, "<=": 8, ">=": 8, "instanceof": 8, "in": 8,
+	"<<": 9, ">>": 9, ">>>": 9,
+	"+": 10, "-": 10,
+	"*": 11, "/": 11, "%": 11,
+	"**": 12,
+}
+
+var assignmentOperators = []string{
+	"=", "+=", "-=", "*=", "/=", "%=", "**=", "<<=", ">>=", ">>>=", "&=", "|=", "^=", "&&=", "||=", "??=",
+}
+
+var unaryOperators = []string{"!", "-", "+", "~", "++", "--", "typeof", "void", "delete", "await"}
+
+// keywords that cannot start an expression
+var statementKeywords = []string{
+	"break", "case", "catch", "class", "const", "continue", "default", "do", "else", "export",
+	"finally", "for", "if", "import", "let", "return", "switch", "throw", "try", "var", "while",
+}
+
+type jsParser struct {
+	src     string
+	tokens  []Token
+	pos     int
+	errors  []*SyntaxError
+	closers []int // index of the bracket closing each opener, -1 if unclosed
+}
+
+// aborts the current statement after a syntax error has been recorded
+type parseBailout struct{}
+
+// parses the JavaScript subset Strudel code uses: declarations, functions,
+// labeled pattern lines ($:), if and return, and expressions including arrow
+// functions and method chains. parsing is tolerant: a syntax error is recorded
+// in Program.Errors and only the part of the statement before it is kept.
+func ParseProgram(code string) *Program {
+	tokens, lexErrors := Tokenize(code)
+	program := &Program{Source: code, Errors: lexErrors}
+	p := &jsParser{src: code}
+
+	// comments are dropped from the token stream but keep their line breaks
+	newline := false
+	for _, token := range tokens {
+		if token.Kind == TokenComment {
+			program.Comments = append(program.Comments, token)
+			newline = newline || token.NewlineBefore
+
+			continue
+		}
+
+		token.NewlineBefore = token.NewlineBefore || newline
+		newline = false
+		p.tokens = append(p.tokens, token)
+	}
+
+	for !p.at(TokenEOF) {
+		if statement := p.topLevelStatement(); statement != nil {
+			program.Statements = append(program.Statements, statement)
+		}
+	}
+
+	program.Errors = append(program.Errors, p.errors...)
+	slices.SortStableFunc(program.Errors, func(a, b *SyntaxError) int {
+		return a.Pos.Offset - b.Pos.Offset
+	})
+
+	return program
+}
+
+// parses one statement, skipping to the next statement if it has a syntax error
+func (p *jsParser) topLevelStatement() (statement Statement) {
+	start := p.pos
+
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(parseBailout); !ok {
+				panic(r)
+			}
+
+			statement = p.salvage(start, p.pos)
+			p.synchronize(start)
+		}
+	}()
+
+	return p.statement()
+}
+
+// parses the tokens of a failed statement up to the error, with unclosed
+// brackets closed, so code that is still being typed keeps its complete part.
+// returns nil if that prefix does not parse either.
+func (p *jsParser) salvage(start, end int) (statement Statement) {
+	tokens := slices.Clone(p.tokens[start:min(end, len(p.tokens)-1)])
+
+	// drop trailing operators and openers, e.g. the "." of s("bd").
+	for len(tokens) > 0 && tokens[len(tokens)-1].Kind == TokenPunct && !isCloser(tokens[len(tokens)-1].Text) {
+		tokens = tokens[:len(tokens)-1]
+	}
+
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	var open []string
+	for _, token := range tokens {
+		switch {
+		case token.Kind != TokenPunct:
+		case token.Text == "(" || token.Text == "[" || token.Text == "{":
+			open = append(open, token.Text)
+		case isCloser(token.Text):
+			if len(open) == 0 || closerOf(open[len(open)-1]) != token.Text {
+				return nil
+			}
+
+			open = open[:len(open)-1]
+		}
+	}
+
+	eof := p.tokens[min(end, len(p.tokens)-1)].Range.Start
+	for i := len(open) - 1; i >= 0; i-- {
+		tokens = append(tokens, Token{Kind: TokenPunct, Text: closerOf(open[i]), Range: Range{Start: eof, End: eof}})
+	}
+
+	tokens = append(tokens, Token{Kind: TokenEOF, Range: Range{Start: eof, End: eof}})
+	sub := &jsParser{src: p.src, tokens: tokens}
+
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(parseBailout); !ok {
+				panic(r)
+			}
+
+			statement = nil
+		}
+	}()
+
+	statement = sub.statement()
+	if !sub.at(TokenEOF) {
+		return nil
+	}
+
+	return statement
+}
+
+// skips to the start of the next statement: past a semicolon or to a new line
+// that does not continue a method chain
+func (p *jsParser) synchronize(start int) {
+	if p.pos == start {
+		p.pos++
+	}
+
+	depth := 0
+
+	for !p.at(TokenEOF) {
+		token := p.peek()
+
+		switch {
+		case depth == 0 && token.is(";"):
+			p.pos++
+			return
+		case depth == 0 && token.NewlineBefore && !token.is(".") && !token.is("?."):
+			return
+		case token.is("(") || token.is("[") || token.is("{"):
+			depth++
+		case (token.is(")") || token.is("]") || token.is("}")) && depth > 0:
+			depth--
+		}
+
+		p.pos++
+	}
+}
+
+func (p *jsParser) statement() Statement {
+	token := p.peek()
+
+	switch {
+	case token.is(";"):
+		p.pos++
+		return nil
+
+	case token.is("{"):
+		return p.block()
+
+	case token.isIdent("let") || token.isIdent("const") || token.isIdent("var"):
+		decl := p.varDecl()
+		p.endStatement()
+
+		return decl
+
+	case token.isIdent("function"):
+		return p.funcDecl()
+
+	case token.isIdent("return"):
+		return p.returnStmt()
+
+	case token.isIdent("if"):
+		return p.ifStmt()
+
+	case token.Kind == TokenIdent && !slices.Contains(statementKeywords, token.Text) && p.peekAt(1).is(":"):
+		label := p.identifier()
+		p.pos++ // :
+
+		body := p.statement()
+		if body == nil {
+			p.fail(p.peek(), "expected statement after label %q", label.Name)
+		}
+
+		return &LabeledStmt{Range: p.rangeFrom(token.Range.Start), Label: label, Body: body}
+
+	case token.Kind == TokenIdent && slices.Contains(statementKeywords, token.Text):
+		p.fail(token, "unsupported statement %q", token.Text)
+	}
+
+	expr := p.expression()
+	p.endStatement()
+
+	return &ExprStmt{Range: p.rangeFrom(token.Range.Start), Expr: expr}
+}
+
+// requires a statement to end with a semicolon, a closing brace, the end of input or a line break
+func (p *jsParser) endStatement() {
+	token := p.peek()
+
+	switch {
+	case token.is(";"):
+		p.pos++
+	case token.is("}") || token.Kind == TokenEOF || token.NewlineBefore:
+	default:
+		p.fail(token, "unexpected %s", describeToken(token))
+	}
+}
+
+func (p *jsParser) block() *BlockStmt {
+	start := p.expect("{").Range.Start
+	block := &BlockStmt{}
+
+	for !p.peek().is("}") {
+		if p.at(TokenEOF) {
+			p.fail(p.peek(), "expected \"}\"")
+		}
+
+		if statement := p.statement(); statement != nil {
+			block.Statements = append(block.Statements, statement)
+		}
+	}
+
+	p.pos++
+	block.Range = p.rangeFrom(start)
+
+	return block
+}
+
+func (p *jsParser) varDecl() *VarDecl {
+	token := p.next()
+	decl := &VarDecl{Kind: token.Text}
+
+	for {
+		start := p.peek().Range.Start
+		declarator := &Declarator{Name: p.identifier()}
+
+		if p.peek().is("=") {
+			p.pos++
+			declarator.Value = p.assignment()
+		} else if token.Text == "const" {
+			p.fail(p.peek(), "missing initializer in const declaration")
+		}
+
+		declarator.Range = p.rangeFrom(start)
+		decl.Declarations = append(decl.Declarations, declarator)
+
+		if !p.peek().is(",") {
+			break
+		}
+
+		p.pos++
+	}
+
+	decl.Range = p.rangeFrom(token.Range.Start)
+
+	return decl
+}
+
+func (p *jsParser) funcDecl() *FuncDecl {
+	start := p.next().Range.Start
+	decl := &FuncDecl{Name: p.identifier()}
+
+	p.expect("(")
+	decl.Params = p.params(")")
+	decl.Body = p.block()
+	decl.Range = p.rangeFrom(start)
+
+	return decl
+}
+
+func (p *jsParser) returnStmt() *ReturnStmt {
+	start := p.next().Range.Start
+	stmt := &ReturnStmt{}
+
+	if next := p.peek(); !next.is(";") && !next.is("}") && next.Kind != TokenEOF && !next.NewlineBefore {
+		stmt.Value = p.expression()
+	}
+
+	p.endStatement()
+	stmt.Range = p.rangeFrom(start)
+
+	return stmt
+}
+
+func (p *jsParser) ifStmt() *IfStmt {
+	start := p.next().Range.Start
+	stmt := &IfStmt{}
+
+	p.expect("(")
+	stmt.Cond = p.expression()
+	p.expect(")")
+	stmt.Then = p.statement()
+
+	if p.peek().isIdent("else") {
+		p.pos++
+		stmt.Else = p.statement()
+	}
+
+	stmt.Range = p.rangeFrom(start)
+
+	return stmt
+}
+
+func (p *jsParser) expression() Expression {
+	return p.assignment()
+}
+
+func (p *jsParser) assignment() Expression {
+	if p.arrowAhead() {
+		return p.arrowFunction()
+	}
+
+	start := p.peek().Range.Start
+	target := p.conditional()
+
+	op := p.peek()
+	if op.Kind != TokenPunct || !slices.Contains(assignmentOperators, op.Text) {
+		return target
+	}
+
+	switch target.(type) {
+	case *Identifier, *MemberExpr, *IndexExpr:
+	default:
+		p.fail(op, "invalid assignment target")
+	}
+
+	p.pos++
+	value := p.assignment()
+
+	return &AssignExpr{Range: p.rangeFrom(start), Op: op.Text, Target: target, Value: value}
+}
+
+// reports whether the next tokens start an arrow function: x => or (...) =>
+func (p *jsParser) arrowAhead() bool {
+	token := p.peek()
+
+	if token.Kind == TokenIdent && p.peekAt(1).is("=>") {
+		return true
+	}
+
+	if !token.is("(") {
+		return false
+	}
+
+	if p.closers == nil {
+		p.closers = matchBrackets(p.tokens)
+	}
+
+	closer := p.closers[p.pos]
+
+	return closer != -1 && closer+1 < len(p.tokens) && p.tokens[closer+1].is("=>")
+}
+
+// returns, for each opening bracket in tokens, the index of the bracket that
+// closes it. any closer closes the innermost open bracket, mismatched or not.
+func matchBrackets(tokens []Token) []int {
+	closers := make([]int, len(tokens))
+	var open []int
+
+	for i, token := range tokens {
+		closers[i] = -1
+
+		switch {
+		case token.is("(") || token.is("[") || token.is("{"):
+			open = append(open, i)
+		case (token.is(")") || token.is("]") || token.is("}")) && len(open) > 0:
+			closers[open[len(open)-1]] = i
+			open = open[:len(open)-1]
+		}
+	}
+
+	return closers
+}
+
+func (p *jsParser) arrowFunction() *FuncExpr {
+	start := p.peek().Range.Start
+	fn := &FuncExpr{Arrow: true}
+
+	if p.peek().Kind == TokenIdent {
+		name := p.identifier()
+		fn.Params = []*Param{{Range: name.Range, Name: name}}
+	} else {
+		p.expect("(")
+		fn.Params = p.params(")")
+	}
+
+	p.expect("=>")
+
+	if p.peek().is("{") {
+		fn.Body = p.block()
+	} else {
+		fn.Body = p.assignment()
+	}
+
+	fn.Range = p.rangeFrom(start)
+
+	return fn
+}
+
+// parses parameters up to and including the closing token
+func (p *jsParser) params(closing string) []*Param {
+	var params []*Param
+
+	for !p.peek().is(closing) {
+		start := p.peek().Range.Start
+		param := &Param{}
+
+		if p.peek().is("...") {
+			p.pos++
+			param.Rest = true
+		}
+
+		param.Name = p.identifier()
+
+		if p.peek().is("=") {
+			p.pos++
+			param.Default = p.assignment()
+		}
+
+		param.Range = p.rangeFrom(start)
+		params = append(params, param)
+
+		if !p.peek().is(",") {
+			break
+		}
+
+		p.pos++
+	}
+
+	p.expect(closing)
+
+	return params
+}
+
+func (p *jsParser) conditional() Expression {
+	start := p.peek().Range.Start
+	cond := p.binary(1)
+
+	if !p.peek().is("?") {
+		return cond
+	}
+
+	p.pos++
+	then := p.assignment()
+	p.expect(":")
+	otherwise := p.assignment()
+
+	return &ConditionalExpr{Range: p.rangeFrom(start), Cond: cond, Then: then, Else: otherwise}
+}
+
+func (p *jsParser) binary(minPrecedence int) Expression {
+	start := p.peek().Range.Start
+	left := p.unary()
+
+	for {
+		op := p.peek()
+		if op.Kind != TokenPunct && !op.isIdent("instanceof") && !op.isIdent("in") {
+			return left
+		}
+
+		precedence, ok := binaryPrecedence[op.Text]
+		if !ok || precedence < minPrecedence {
+			return left
+		}
+
+		p.pos++
+
+		// ** is right associative
+		next := precedence + 1
+		if op.Text == "**" {
+			next = precedence
+		}
+
+		right := p.binary(next)
+		left = &BinaryExpr{Range: p.rangeFrom(start), Op: op.Text, Left: left, Right: right}
+	}
+}
+
+func (p *jsParser) unary() Expression {
+	token := p.peek()
+
+	if (token.Kind == TokenPunct || token.Kind == TokenIdent) && slices.Contains(unaryOperators, token.Text) {
+		p.pos++
+		operand := p.unary()
+
+		return &UnaryExpr{Range: p.rangeFrom(token.Range.Start), Op: token.Text, Operand: operand}
+	}
+
+	expr := p.callMember()
+
+	if next := p.peek(); (next.is("++") || next.is("--")) && !next.NewlineBefore {
+		p.pos++
+		return &UnaryExpr{Range: p.rangeFrom(token.Range.Start), Op: next.Text, Operand: expr, Postfix: true}
+	}
+
+	return expr
+}
+
+// parses a primary expression followed by calls, member accesses and indexing
+func (p *jsParser) callMember() Expression {
+	start := p.peek().Range.Start
+
+	var expr Expression
+	if p.peek().isIdent("new") {
+		expr = p.newExpr()
+	} else {
+		expr = p.primary()
+	}
+
+	for {
+		token := p.peek()
+
+		switch {
+		case token.is("."):
+			p.pos++
+			expr = &MemberExpr{Object: expr, Property: p.identifier()}
+
+		case token.is("?."):
+			p.pos++
+
+			switch {
+			case p.peek().is("("):
+				expr = &CallExpr{Callee: expr, Args: p.args()}
+			case p.peek().is("["):
+				p.pos++
+				index := p.expression()
+				p.expect("]")
+				expr = &IndexExpr{Object: expr, Index: index}
+			default:
+				expr = &MemberExpr{Object: expr, Property: p.identifier(), Optional: true}
+			}
+
+		case token.is("["):
+			p.pos++
+			index := p.expression()
+			p.expect("]")
+			expr = &IndexExpr{Object: expr, Index: index}
+
+		case token.is("("):
+			expr = &CallExpr{Callee: expr, Args: p.args()}
+
+		default:
+			return expr
+		}
+
+		setRange(expr, p.rangeFrom(start))
+	}
+}
+
+func (p *jsParser) newExpr() Expression {
+	start := p.next().Range.Start
+	callee := p.primary()
+
+	for p.peek().is(".") {
+		p.pos++
+		callee = &MemberExpr{Range: p.rangeFrom(start), Object: callee, Property: p.identifier()}
+	}
+
+	call := &CallExpr{Callee: callee, New: true}
+	if p.peek().is("(") {
+		call.Args = p.args()
+	}
+
+	call.Range = p.rangeFrom(start)
+
+	return call
+}
+
+func (p *jsParser) args() []Expression {
+	p.expect("(")
+
+	var args []Expression
+
+	for !p.peek().is(")") {
+		args = append(args, p.element())
+
+		if !p.peek().is(",") {
+			break
+		}
+
+		p.pos++
+	}
+
+	p.expect(")")
+
+	return args
+}
+
+// parses an argument or array element, which may be spread
+func (p *jsParser) element() Expression {
+	if !p.peek().is("...") {
+		return p.assignment()
+	}
+
+	start := p.next().Range.Start
+	value := p.assignment()
+
+	return &SpreadExpr{Range: p.rangeFrom(start), Value: value}
+}
+
+func (p *jsParser) primary() Expression {
+	token := p.peek()
+
+	switch token.Kind {
+	case TokenIdent:
+		if token.Text == "function" {
+			return p.funcExpr()
+		}
+
+		if slices.Contains(statementKeywords, token.Text) {
+			p.fail(token, "unexpected keyword %q", token.Text)
+		}
+
+		return p.identifier()
+
+	case TokenNumber:
+		p.pos++
+		return &NumberLiteral{Range: token.Range, Value: token.Text}
+
+	case TokenString, TokenTemplate:
+		p.pos++
+		return &StringLiteral{Range: token.Range, Value: token.Value, Quote: token.Text[0]}
+
+	case TokenPunct:
+		switch token.Text {
+		case "(":
+			p.pos++
+			expr := p.expression()
+			p.expect(")")
+
+			return expr
+
+		case "[":
+			return p.array()
+
+		case "{":
+			return p.object()
+		}
+	}
+
+	p.fail(token, "unexpected %s", describeToken(token))
+
+	return nil
+}
+
+func (p *jsParser) funcExpr() *FuncExpr {
+	start := p.next().Range.Start
+	fn := &FuncExpr{}
+
+	if p.peek().Kind == TokenIdent {
+		fn.Name = p.identifier()
+	}
+
+	p.expect("(")
+	fn.Params = p.params(")")
+	fn.Body = p.block()
+	fn.Range = p.rangeFrom(start)
+
+	return fn
+}
+
+func (p *jsParser) array() *ArrayLiteral {
+	start := p.expect("[").Range.Start
+	array := &ArrayLiteral{}
+
+	for !p.peek().is("]") {
+		array.Elements = append(array.Elements, p.element())
+
+		if !p.peek().is(",") {
+			break
+		}
+
+		p.pos++
+	}
+
+	p.expect("]")
+	array.Range = p.rangeFrom(start)
+
+	return array
+}
+
+func (p *jsParser) object() *ObjectLiteral {
+	start := p.expect("{").Range.Start
+	object := &ObjectLiteral{}
+
+	for !p.peek().is("}") {
+		object.Properties = append(object.Properties, p.property())
+
+		if !p.peek().is(",") {
+			break
+		}
+
+		p.pos++
+	}
+
+	p.expect("}")
+	object.Range = p.rangeFrom(start)
+
+	return object
+}
+
+func (p *jsParser) property() *Property {
+	token := p.peek()
+	property := &Property{}
+
+	switch {
+	case token.is("..."):
+		property.Value = p.element()
+		property.Range = p.rangeFrom(token.Range.Start)
+
+		return property
+
+	case token.Kind == TokenIdent || token.Kind == TokenNumber:
+		p.pos++
+		property.Key = token.Text
+
+	case token.Kind == TokenString:
+		p.pos++
+		property.Key = token.Value
+
+	case token.is("["):
+		p.pos++
+		key := p.expression()
+		p.expect("]")
+		property.Key = "[" + p.src[key.Span().Start.Offset:key.Span().End.Offset] + "]"
+
+	default:
+		p.fail(token, "unexpected %s in object", describeToken(token))
+	}
+
+	switch {
+	case p.peek().is(":"):
+		p.pos++
+		property.Value = p.assignment()
+
+	case p.peek().is("("):
+		// method shorthand: key(params) { body }
+		fn := &FuncExpr{}
+		p.pos++
+		fn.Params = p.params(")")
+		fn.Body = p.block()
+		fn.Range = p.rangeFrom(token.Range.Start)
+		property.Value = fn
+
+	case token.Kind == TokenIdent:
+		property.Value = &Identifier{Range: token.Range, Name: token.Text}
+
+	default:
+		p.fail(p.peek(), "expected \":\" after property key")
+	}
+
+	property.Range = p.rangeFrom(token.Range.Start)
+
+	return property
+}
+
+func (p *jsParser) identifier() *Identifier {
+	token := p.peek()
+	if token.Kind != TokenIdent {
+		p.fail(token, "expected identifier, got %s", describeToken(token))
+	}
+
+	p.pos++
+
+	return &Identifier{Range: token.Range, Name: token.Text}
+}
+
+func (p *jsParser) expect(text string) Token {
+	token := p.peek()
+	if !token.is(text) {
+		p.fail(token, "expected %q, got %s", text, describeToken(token))
+	}
+
+	p.pos++
+
+	return token
+}
+
+func (p *jsParser) peek() Token {
+	return p.peekAt(0)
+}
+
+func (p *jsParser) peekAt(n int) Token {
+	if p.pos+n >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+
+	return p.tokens[p.pos+n]
+}
+
+func (p *jsParser) next() Token {
+	token := p.peek()
+	p.pos++
+
+	return token
+}
+
+func (p *jsParser) at(kind TokenKind) bool {
+	return p.peek().Kind == kind
+}
+
+// returns the range from start to the end of the last consumed token
+func (p *jsParser) rangeFrom(start Pos) Range {
+	end := start
+	if p.pos > 0 {
+		end = p.tokens[min(p.pos, len(p.tokens))-1].Range.End
+	}
+
+	return Range{Start: start, End: end}
+}
+
+func (p *jsParser) fail(token Token, format string, args ...any) {
+	p.errors = append(p.errors, &SyntaxError{Message: fmt.Sprintf(format, args...), Pos: token.Range.Start})
+	panic(parseBailout{})
+}
+
+func (t Token) is(punct string) bool {
+	return t.Kind == TokenPunct && t.Text == punct
+}
+
+func (t Token) isIdent(name string) bool {
+	return t.Kind == TokenIdent && t.Text == name
+}
+
+func isCloser(text string) bool {
+	return text == ")" || text == "]" || text == "}"
+}
+
+func closerOf(opener string) string {
+	switch opener {
+	case "(":
+		return ")"
+	case "[":
+		return "]"
+	default:
+		return "}"
+	}
+}
+
+func describeToken(token Token) string {
+	if token.Kind == TokenEOF {
+		return "end of input"
+	}
+
+	return fmt.Sprintf("token %q", token.Text)
+}
+
+// sets the range of a call, member or index expression built in a chain
+func setRange(expr Expression, span Range) {
+	switch e := expr.(type) {
+	case *CallExpr:
+		e.Range = span
+	case *MemberExpr:
+		e.Range = span
+	case *IndexExpr:
+		e.Range = span
+	}
+}
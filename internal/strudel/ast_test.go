@@ -0,0 +1,151 @@
+package strudel
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseProgram(t *testing.T) {
+	code := `// drums
+let drums = "bd sd"
+$: s(drums)
+  .fast(2)
+  .room(.5)
+const lead = n => note(n).s("sawtooth")
+function pattern(x = 1, ...rest) { return x }
+if (true) { lead("c e") } else s("hh")
+samples({ bd: ['bd/1.wav'], ...extra })
+x?.foo[0] = -y ** 2 + (a ? b : c)`
+
+	program := ParseProgram(code)
+
+	if len(program.Errors) != 0 {
+		t.Fatalf("ParseProgram() errors = %v", program.Errors)
+	}
+
+	if len(program.Statements) != 7 {
+		t.Fatalf("ParseProgram() has %d statements, want 7", len(program.Statements))
+	}
+
+	if len(program.Comments) != 1 || program.Comments[0].Text != "// drums" {
+		t.Errorf("ParseProgram() comments = %v, want [// drums]", program.Comments)
+	}
+
+	labeled, ok := program.Statements[1].(*LabeledStmt)
+	if !ok || labeled.Label.Name != "$" {
+		t.Fatalf("statement 2 = %T, want $: labeled statement", program.Statements[1])
+	}
+
+	// the chain continues over lines: room(fast(s(drums)))
+	room, ok := labeled.Body.(*ExprStmt).Expr.(*CallExpr)
+	if !ok || room.Callee.(*MemberExpr).Property.Name != "room" {
+		t.Fatalf("labeled body = %#v, want .room() call", labeled.Body)
+	}
+
+	if room.Range.Start.Line != 3 || room.Range.End.Line != 5 {
+		t.Errorf("chain range lines = %d-%d, want 3-5", room.Range.Start.Line, room.Range.End.Line)
+	}
+
+	lead := program.Statements[2].(*VarDecl).Declarations[0].Value
+	if fn, ok := lead.(*FuncExpr); !ok || !fn.Arrow || len(fn.Params) != 1 {
+		t.Errorf("const lead = %T, want single parameter arrow function", lead)
+	}
+}
+
+func TestParseProgramErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		code   string
+		line   int
+		column int
+		substr string
+	}{
+		{
+			name:   "unclosed call",
+			code:   "s(\"bd sd\"",
+			line:   1,
+			column: 10,
+			substr: "end of input",
+		},
+		{
+			name:   "missing operator",
+			code:   "s(\"bd\")\nnote(\"c\" \"e\")",
+			line:   2,
+			column: 10,
+			substr: "expected \")\"",
+		},
+		{
+			name:   "unterminated string",
+			code:   "s(\"bd\n",
+			line:   1,
+			column: 3,
+			substr: "unterminated string",
+		},
+		{
+			name:   "unsupported statement",
+			code:   "for (let i = 0; i < 4; i++) {}",
+			line:   1,
+			column: 1,
+			substr: "unsupported statement",
+		},
+		{
+			name:   "unexpected character",
+			code:   "s(\"bd\") ¤",
+			line:   1,
+			column: 9,
+			substr: "unexpected character",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program := ParseProgram(tt.code)
+			if len(program.Errors) == 0 {
+				t.Fatalf("ParseProgram(%q) expected errors", tt.code)
+			}
+
+			err := program.Errors[0]
+			if err.Pos.Line != tt.line || err.Pos.Column != tt.column {
+				t.Errorf("error at %d:%d, want %d:%d (%v)", err.Pos.Line, err.Pos.Column, tt.line, tt.column, err)
+			}
+
+			if !strings.Contains(err.Message, tt.substr) {
+				t.Errorf("error %q does not contain %q", err.Message, tt.substr)
+			}
+		})
+	}
+}
+
+func TestParseProgramRecovery(t *testing.T) {
+	// the complete part of a statement being typed is kept, later statements still parse
+	code := "s(\"bd sd\").fast(\nnote(\"c e\")"
+
+	program := ParseProgram(code)
+
+	if len(program.Errors) == 0 {
+		t.Fatal("ParseProgram() expected an error for the unclosed call")
+	}
+
+	parsed := extract(program)
+
+	if !contains(parsed.Sounds, "bd") || !contains(parsed.Notes, "c") {
+		t.Errorf("recovered sounds %v and notes %v, want bd and c", parsed.Sounds, parsed.Notes)
+	}
+}
+
+func TestParseProgramDeepNesting(t *testing.T) {
+	// arrow lookahead at every "(" must not rescan to the matching bracket
+	for _, code := range []string{
+		strings.Repeat("(", 32*1024) + "1" + strings.Repeat(")", 32*1024),
+		strings.Repeat("(", 64*1024),
+		strings.Repeat("f(", 16*1024) + "x => x",
+	} {
+		start := time.Now()
+		ParseProgram(code)
+
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("ParseProgram() of %d bytes took %v", len(code), elapsed)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package strudel
+
+import (
+	"slices"
+	"strings"
+)
+
+// controls set a value on each event and take it as their only argument: .gain(.5)
+var controlFunctions = []string{
+	"s", "sound", "n", "note", "freq", "bank", "scale", "mode", "legato", "dur", "duration",
+	"octave", "voicing", "voicings", "dict", "anchor", "root", "rootNotes", "unit", "cps",
+	"cutoff", "resonance", "hcutoff", "hresonance", "bandf", "bandq", "lp", "hp", "bp",
+	"size", "sz", "dry", "att", "rel", "sus", "amp", "accelerate", "nudge", "color",
+	"midichan", "ccn", "ccv", "cc", "midi", "osc", "vowel", "velocity", "lpenv", "duckrelease", "tremdp",
+}
+
+// functions on patterns by the arguments they take as methods
+var transformFunctions = map[functionSpec][]string{
+	{0, 0}: {
+		"rev", "palindrome", "degrade", "undegrade", "brak", "press", "invert", "inv", "hush",
+		"fit", "mute",
+	},
+	{0, 1}: {
+		"log", "pianoroll", "punchcard", "scope", "spiral", "spectrum", "wordfall",
+		"_pianoroll", "_punchcard", "_scope", "_spiral", "_spectrum", "clip",
+	},
+	{1, 1}: {
+		"fast", "slow", "hurry", "early", "late", "ply", "segment", "seg", "iter", "iterBack",
+		"degradeBy", "undegradeBy", "mask", "struct", "add", "sub", "mul", "div", "mod", "set",
+		"keep", "keepif", "linger", "fastGap", "swing", "pressBy", "arp", "arpWith", "pick",
+		"inhabit", "squeeze", "fmap", "apply", "withValue", "transpose", "scaleTranspose",
+		"chop", "striate", "jux", "cpm", "color", "brakBy", "shuffle", "scramble", "velocity",
+		"chord", "p", "q", "sometimes", "often", "rarely", "almostNever", "almostAlways", "always", "never",
+		"someCycles",
+	},
+	{1, 2}: {
+		"range", "rangex", "range2", "slice", "splice", "every", "firstOf", "lastOf",
+		"sometimesBy", "someCyclesBy", "when", "juxBy", "off", "chunk", "chunkBack", "inside",
+		"outside", "compress", "zoom", "swingBy", "bite", "ribbon", "rib", "euclid",
+		"euclidLegato", "loopAt", "within",
+	},
+	{1, 3}: {
+		"euclidRot", "euclidLegatoRot", "echo", "stut", "stutWith", "echoWith", "whenmod",
+	},
+	{1, -1}: {
+		"superimpose", "layer",
+	},
+	{0, -1}: {
+		"stack", "cat", "seq", "sequence", "fastcat", "slowcat", "polymeter", "polymeterSteps",
+		"randcat", "wrandcat", "timeCat", "timecat", "stepcat", "arrange", "chooseCycles",
+		"choose", "wchoose", "wchooseCycles", "pure", "reify",
+	},
+}
+
+// functions only called globally, with the arguments they take
+var globalFunctions = map[string]functionSpec{
+	"samples":            {1, 3},
+	"soundAlias":         {2, 2},
+	"aliasBank":          {1, 2},
+	"setcps":             {1, 1},
+	"setcpm":             {1, 1},
+	"setCps":             {1, 1},
+	"setCpm":             {1, 1},
+	"slider":             {1, 4},
+	"irand":              {1, 1},
+	"run":                {1, 1},
+	"binary":             {1, 1},
+	"binaryN":            {1, 2},
+	"mini":               {1, 1},
+	"m":                  {1, 1},
+	"h":                  {1, 1},
+	"all":                {1, 1},
+	"each":               {1, 1},
+	"register":           {2, 2},
+	"brandBy":            {1, 1},
+	"initHydra":          {0, 1},
+	"H":                  {1, 1},
+	"midin":              {0, 1},
+	"loadOrc":            {1, 1},
+	"loadCsound":         {1, 1},
+	"setVoicingRange":    {2, 2},
+	"addVoicings":        {2, 3},
+	"setDefaultVoicings": {1, 1},
+}
+
+// continuous patterns used as values: .lpf(sine.range(200, 2000))
+var signalNames = []string{
+	"sine", "cosine", "saw", "square", "tri", "isaw", "itri", "isquare", "rand", "perlin", "brand",
+	"sine2", "cosine2", "saw2", "square2", "tri2", "isaw2", "itri2", "rand2", "time",
+	"mouseX", "mouseY", "silence",
+}
+
+// JavaScript globals Strudel code may call
+var javaScriptGlobals = []string{
+	"Math", "console", "JSON", "Number", "String", "Array", "Object", "Boolean", "Symbol", "Map",
+	"Set", "Date", "Promise", "Error", "parseInt", "parseFloat", "isNaN", "isFinite", "setTimeout",
+	"setInterval", "clearTimeout", "clearInterval", "fetch", "window", "document", "globalThis",
+	"undefined", "NaN", "Infinity", "require",
+}
+
+// functions whose string arguments are not mini-notation, e.g. sample urls
+var nonPatternFunctions = []string{
+	"samples", "soundAlias", "aliasBank", "loadOrc", "loadCsound", "initHydra", "H",
+	"addVoicings", "setDefaultVoicings", "register",
+}
+
+// builds the function catalog. effects and controls take a value, transforms
+// override them where an effect takes more, e.g. echo(times, time, feedback).
+func buildFunctionCatalog() map[string]functionSpec {
+	catalog := make(map[string]functionSpec)
+
+	effects := [][]string{
+		effectDefs.Filter, effectDefs.FilterEnvelope, effectDefs.Distortion, effectDefs.Dynamics,
+		effectDefs.Spatial, effectDefs.Delay, effectDefs.Reverb, effectDefs.Modulation,
+		effectDefs.Envelope, effectDefs.PitchEnvelope, effectDefs.FMSynthesis, effectDefs.Sampler,
+		effectDefs.Routing, effectDefs.Sidechain, effectDefs.Synthesis, effectDefs.ZZFX,
+		controlFunctions,
+	}
+
+	for _, names := range effects {
+		for _, name := range names {
+			catalog[name] = functionSpec{minArgs: 0, maxArgs: 1}
+		}
+	}
+
+	for spec, names := range transformFunctions {
+		for _, name := range names {
+			catalog[name] = spec
+		}
+	}
+
+	for name, spec := range globalFunctions {
+		catalog[name] = spec
+	}
+
+	return catalog
+}
+
+// known Strudel functions and the arguments they take
+var functionCatalog = buildFunctionCatalog()
+
+// returns the catalog function closest to name, or "" if none is close enough
+func suggestFunction(name string) string {
+	best, bestDistance := "", len(name)/2+1
+	if bestDistance > 3 {
+		bestDistance = 3
+	}
+
+	names := make([]string, 0, len(functionCatalog))
+	for candidate := range functionCatalog {
+		names = append(names, candidate)
+	}
+
+	slices.Sort(names)
+
+	for _, candidate := range names {
+		if distance := levenshtein(strings.ToLower(name), strings.ToLower(candidate)); distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+
+	return best
+}
+
+// returns the edit distance between two strings
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+
+		previous, current = current, previous
+	}
+
+	return previous[len(rb)]
+}
@@ -0,0 +1,1128 @@
+package strudel
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	formatWidth  = 80 // lines longer than this are broken where possible
+	formatIndent = "  "
+
+	// chains with this many method calls get one call per line at statement level
+	formatChainCalls = 3
+)
+
+type formatter struct {
+	source   string
+	comments []Token
+	next     int                     // index of the next comment to print
+	lost     bool                    // a comment was printed away from its place
+	mini     map[*StringLiteral]bool // strings that hold mini-notation
+}
+
+// formats Strudel code deterministically: one statement per line with two
+// space indentation, long method chains and stack() layers one per line,
+// double quoted mini-notation with normalized spacing and no semicolons.
+// comments are kept. code with syntax errors is returned as an error.
+func Format(code string) (string, error) {
+	program := ParseProgram(code)
+	if len(program.Errors) > 0 {
+		return "", fmt.Errorf("failed to parse code: %w", program.Errors[0])
+	}
+
+	f := &formatter{source: code, comments: program.Comments, mini: miniStrings(program)}
+
+	formatted := f.statements(program.Statements, "", 0, len(code))
+	if formatted != "" {
+		formatted += "\n"
+	}
+
+	// the formatter must never change what the code does
+	if dumpProgram(ParseProgram(formatted)) != dumpProgram(program) {
+		return "", errors.New("failed to format code: formatting would change the program")
+	}
+
+	return formatted, nil
+}
+
+// prints statements one per line with the comments between start and end
+func (f *formatter) statements(statements []Statement, indent string, start, end int) string {
+	w := &lineWriter{f: f, indent: indent, prev: start}
+
+	for i, statement := range statements {
+		span := statement.Span()
+		w.comments(span.Start.Offset)
+
+		next, lost := f.next, f.lost
+		f.lost = false
+
+		text := f.statement(statement, indent, utf8.RuneCountInString(indent))
+
+		// a comment inside the statement has no place in the formatted
+		// layout, the statement is kept as written
+		if f.lost || f.commentBefore(span.End.Offset) {
+			text = f.source[span.Start.Offset:span.End.Offset]
+			f.next = next
+
+			for f.next < len(f.comments) && f.comments[f.next].Range.Start.Offset < span.End.Offset {
+				f.next++
+			}
+		}
+
+		f.lost = lost
+
+		// without semicolons a line starting with these continues the previous statement
+		if i > 0 && text != "" && strings.ContainsAny(text[:1], "([`+-") {
+			text = ";" + text
+		}
+
+		w.item(text, span.Start.Offset, span.End.Offset)
+
+		limit := end
+		if i < len(statements)-1 {
+			limit = statements[i+1].Span().Start.Offset
+		}
+
+		w.trailing(span.End, limit)
+	}
+
+	w.comments(end)
+
+	return w.String()
+}
+
+func (f *formatter) statement(statement Statement, indent string, column int) string {
+	switch s := statement.(type) {
+	case *VarDecl:
+		text := s.Kind + " "
+
+		for i, declarator := range s.Declarations {
+			if i > 0 {
+				text += ", "
+			}
+
+			text += declarator.Name.Name
+
+			if declarator.Value != nil {
+				text += " = "
+				text += f.expr(declarator.Value, indent, endColumn(column, text), true)
+			}
+		}
+
+		return text
+
+	case *FuncDecl:
+		return "function " + s.Name.Name + "(" + f.params(s.Params) + ") " + f.block(s.Body, indent)
+
+	case *ExprStmt:
+		text := f.expr(s.Expr, indent, column, true)
+
+		// an expression statement cannot start like a block or function declaration
+		if strings.HasPrefix(text, "{") || strings.HasPrefix(text, "function ") || strings.HasPrefix(text, "function(") {
+			text = "(" + text + ")"
+		}
+
+		return text
+
+	case *LabeledStmt:
+		text := s.Label.Name + ": "
+		return text + f.statement(s.Body, indent, endColumn(column, text))
+
+	case *BlockStmt:
+		return f.block(s, indent)
+
+	case *ReturnStmt:
+		if s.Value == nil {
+			return "return"
+		}
+
+		return "return " + f.expr(s.Value, indent, column+len("return "), true)
+
+	case *IfStmt:
+		text := "if ("
+		text += f.expr(s.Cond, indent, endColumn(column, text), false) + ") "
+		text += f.statement(s.Then, indent, endColumn(column, text))
+
+		if s.Else != nil {
+			if _, ok := s.Then.(*BlockStmt); ok {
+				text += " else "
+			} else {
+				text += "\n" + indent + "else "
+			}
+
+			text += f.statement(s.Else, indent, endColumn(column, text))
+		}
+
+		return text
+	}
+
+	return ""
+}
+
+func (f *formatter) block(block *BlockStmt, indent string) string {
+	inner := f.statements(block.Statements, indent+formatIndent, block.Start.Offset+1, block.End.Offset-1)
+	if inner == "" {
+		return "{}"
+	}
+
+	return "{\n" + inner + "\n" + indent + "}"
+}
+
+// prints an expression on one line when it fits, otherwise over several.
+// top is set for expressions that make up a statement or a stack() layer,
+// where long method chains always get one call per line.
+func (f *formatter) expr(e Expression, indent string, column int, top bool) string {
+	if !f.mustBreak(e, top) && !f.looseComment([]Node{e}, e.Span().Start.Offset, e.Span().End.Offset) {
+		next, lost := f.next, f.lost
+
+		if text := f.print(e, indent, column, true); fits(column, text) {
+			return text
+		}
+
+		f.next, f.lost = next, lost
+	}
+
+	return f.print(e, indent, column, false)
+}
+
+// prints a subexpression, on one line when flat is set
+func (f *formatter) sub(e Expression, indent string, column int, flat bool) string {
+	if flat {
+		return f.print(e, indent, column, true)
+	}
+
+	return f.expr(e, indent, column, false)
+}
+
+func (f *formatter) print(e Expression, indent string, column int, flat bool) string {
+	switch x := e.(type) {
+	case *Identifier:
+		return x.Name
+
+	case *NumberLiteral:
+		return x.Value
+
+	case *StringLiteral:
+		return f.string(x)
+
+	case *ArrayLiteral:
+		items := make([]Node, len(x.Elements))
+		for i, element := range x.Elements {
+			items[i] = element
+		}
+
+		return f.list("[", "]", items, x.Start.Offset+1, x.End.Offset-1, indent, column, flat, false)
+
+	case *ObjectLiteral:
+		items := make([]Node, len(x.Properties))
+		for i, property := range x.Properties {
+			items[i] = property
+		}
+
+		return f.list("{", "}", items, x.Start.Offset+1, x.End.Offset-1, indent, column, flat, false)
+
+	case *SpreadExpr:
+		return "..." + f.sub(x.Value, indent, column+3, flat)
+
+	case *CallExpr:
+		if x.New {
+			return f.link(x, indent, column, flat)
+		}
+
+		return f.chain(e, indent, column, flat)
+
+	case *MemberExpr, *IndexExpr:
+		return f.chain(e, indent, column, flat)
+
+	case *UnaryExpr:
+		if x.Postfix {
+			return f.sub(x.Operand, indent, column, flat) + x.Op
+		}
+
+		text := x.Op
+		if len(x.Op) > 2 {
+			text += " " // typeof, void, delete and await
+		}
+
+		operand := f.operand(x.Operand, needsUnaryParens(x.Operand), indent, column+len(text), flat)
+
+		// - -x and + +x must not merge into -- and ++
+		if (x.Op[0] == '-' || x.Op[0] == '+') && strings.HasPrefix(operand, x.Op[:1]) {
+			text += " "
+		}
+
+		return text + operand
+
+	case *BinaryExpr:
+		text := f.operand(x.Left, needsBinaryParens(x.Left, x.Op, false), indent, column, flat)
+		text += " " + x.Op + " "
+
+		return text + f.operand(x.Right, needsBinaryParens(x.Right, x.Op, true), indent, endColumn(column, text), flat)
+
+	case *AssignExpr:
+		text := f.sub(x.Target, indent, column, flat) + " " + x.Op + " "
+		return text + f.sub(x.Value, indent, endColumn(column, text), flat)
+
+	case *ConditionalExpr:
+		_, nested := x.Cond.(*ConditionalExpr)
+		text := f.operand(x.Cond, nested || needsAssignParens(x.Cond), indent, column, flat) + " ? "
+		text += f.sub(x.Then, indent, endColumn(column, text), flat) + " : "
+
+		return text + f.sub(x.Else, indent, endColumn(column, text), flat)
+
+	case *FuncExpr:
+		return f.function(x, indent, column, flat)
+	}
+
+	return ""
+}
+
+// prints an operand, in parentheses when parens is set
+func (f *formatter) operand(e Expression, parens bool, indent string, column int, flat bool) string {
+	if !parens {
+		return f.sub(e, indent, column, flat)
+	}
+
+	return "(" + f.sub(e, indent, column+1, flat) + ")"
+}
+
+// prints a method chain. chains that do not fit get one link per line, a
+// property access stays with the call that follows it: .add.squeeze(...)
+func (f *formatter) chain(e Expression, indent string, column int, flat bool) string {
+	base, head, links := splitChain(e)
+
+	text := f.operand(base, needsObjectParens(base), indent, column, flat)
+	for _, op := range head {
+		text += f.link(op, indent, endColumn(column, text), flat)
+	}
+
+	inline := func() string {
+		line := text
+		for _, link := range links {
+			for _, op := range link {
+				line += f.link(op, indent, endColumn(column, line), true)
+			}
+		}
+
+		return line
+	}
+
+	if flat || len(links) == 0 {
+		return inline()
+	}
+
+	// a multi-line base like stack(...) keeps a few short links on its last line: ).fast(2)
+	if strings.Contains(text, "\n") && len(links) < formatChainCalls && !f.looseComment([]Node{e}, chainEnd(base, head), e.Span().End.Offset) {
+		next, lost := f.next, f.lost
+
+		if line := inline(); fits(column, line) {
+			return line
+		}
+
+		f.next, f.lost = next, lost
+	}
+
+	inner := indent + formatIndent
+	w := &lineWriter{f: f, indent: inner, prev: chainEnd(base, head)}
+
+	for i, link := range links {
+		w.comments(linkStart(link))
+
+		line := ""
+		for _, op := range link {
+			line += f.link(op, inner, endColumn(utf8.RuneCountInString(inner), line), false)
+		}
+
+		end := link[len(link)-1].Span().End
+		w.item(line, linkStart(link), end.Offset)
+
+		// a comment after the last link belongs to the enclosing code
+		if i < len(links)-1 {
+			w.trailing(end, linkStart(links[i+1]))
+		}
+	}
+
+	return text + "\n" + w.String()
+}
+
+// prints one operation of a chain without its object
+func (f *formatter) link(op Expression, indent string, column int, flat bool) string {
+	switch x := op.(type) {
+	case *MemberExpr:
+		if x.Optional {
+			return "?." + x.Property.Name
+		}
+
+		return "." + x.Property.Name
+
+	case *IndexExpr:
+		return "[" + f.sub(x.Index, indent, column+1, flat) + "]"
+
+	case *CallExpr:
+		if x.New {
+			text := "new " + f.operand(x.Callee, needsObjectParens(x.Callee), indent, column+4, flat)
+			return text + f.args(x, indent, endColumn(column, text), flat)
+		}
+
+		return f.args(x, indent, column, flat)
+	}
+
+	return ""
+}
+
+// prints the arguments of a call. stack() layers go one per line.
+func (f *formatter) args(call *CallExpr, indent string, column int, flat bool) string {
+	items := make([]Node, len(call.Args))
+	for i, arg := range call.Args {
+		items[i] = arg
+	}
+
+	return f.list("(", ")", items, call.Callee.Span().End.Offset, call.End.Offset-1, indent, column, flat, isLayeredStack(call))
+}
+
+// prints the items of a call, array or object between open and close: inline
+// when they fit, otherwise one per line with the comments between them
+func (f *formatter) list(open, close string, items []Node, start, end int, indent string, column int, flat, layers bool) string {
+	inline := !layers && !f.looseComment(items, start, end)
+	for _, item := range items {
+		inline = inline && !f.layered(item)
+	}
+
+	if flat || inline {
+		next, lost := f.next, f.lost
+
+		parts := make([]string, len(items))
+		for i, item := range items {
+			parts[i] = f.item(item, indent, 0, true, false)
+		}
+
+		text := open + strings.Join(parts, ", ") + close
+		if open == "{" && len(items) > 0 {
+			text = "{ " + strings.Join(parts, ", ") + " }"
+		}
+
+		if flat || fits(column, text) {
+			return text
+		}
+
+		f.next, f.lost = next, lost
+	}
+
+	inner := indent + formatIndent
+	w := &lineWriter{f: f, indent: inner, prev: start}
+
+	for i, item := range items {
+		span := item.Span()
+		w.comments(span.Start.Offset)
+
+		text := f.item(item, inner, utf8.RuneCountInString(inner), false, layers)
+		if i < len(items)-1 {
+			text += ","
+		}
+
+		w.item(text, span.Start.Offset, span.End.Offset)
+
+		limit := end
+		if i < len(items)-1 {
+			limit = items[i+1].Span().Start.Offset
+		}
+
+		w.trailing(span.End, limit)
+	}
+
+	w.comments(end)
+
+	if w.empty() {
+		return open + close
+	}
+
+	return open + "\n" + w.String() + "\n" + indent + close
+}
+
+// prints a list item: an expression or an object property
+func (f *formatter) item(item Node, indent string, column int, flat, top bool) string {
+	property, ok := item.(*Property)
+	if !ok {
+		if flat {
+			return f.print(item.(Expression), indent, column, true)
+		}
+
+		return f.expr(item.(Expression), indent, column, top)
+	}
+
+	if property.Key == "" {
+		return f.sub(property.Value, indent, column, flat) // ...spread
+	}
+
+	key := property.Key
+	if c := f.source[property.Start.Offset]; c == '"' || c == '\'' {
+		tokens, _ := Tokenize(f.source[property.Start.Offset:property.End.Offset])
+		key = tokens[0].Text
+	}
+
+	switch value := property.Value.(type) {
+	case *Identifier:
+		if value.Range == property.Range {
+			return key // shorthand
+		}
+
+	case *FuncExpr:
+		if !value.Arrow && value.Start == property.Start {
+			return key + "(" + f.params(value.Params) + ") " + f.block(value.Body.(*BlockStmt), indent)
+		}
+	}
+
+	text := key + ": "
+
+	return text + f.sub(property.Value, indent, endColumn(column, text), flat)
+}
+
+func (f *formatter) function(fn *FuncExpr, indent string, column int, flat bool) string {
+	var text string
+
+	switch {
+	case fn.Arrow && len(fn.Params) == 1 && fn.Params[0].Default == nil && !fn.Params[0].Rest:
+		text = fn.Params[0].Name.Name + " => "
+	case fn.Arrow:
+		text = "(" + f.params(fn.Params) + ") => "
+	case fn.Name != nil:
+		text = "function " + fn.Name.Name + "(" + f.params(fn.Params) + ") "
+	default:
+		text = "function(" + f.params(fn.Params) + ") "
+	}
+
+	switch body := fn.Body.(type) {
+	case *BlockStmt:
+		return text + f.block(body, indent)
+
+	case *ObjectLiteral:
+		// an object body needs parentheses, a brace would start a block
+		return text + "(" + f.sub(body, indent, endColumn(column, text)+1, flat) + ")"
+
+	case Expression:
+		return text + f.sub(body, indent, endColumn(column, text), flat)
+	}
+
+	return text
+}
+
+func (f *formatter) params(params []*Param) string {
+	parts := make([]string, len(params))
+
+	for i, param := range params {
+		if param.Rest {
+			parts[i] = "..."
+		}
+
+		parts[i] += param.Name.Name
+
+		if param.Default != nil {
+			parts[i] += " = " + f.print(param.Default, "", 0, true)
+		}
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// prints a string. mini-notation gets normalized spacing in double quotes,
+// other strings are kept as written.
+func (f *formatter) string(str *StringLiteral) string {
+	raw := f.source[str.Start.Offset:str.End.Offset]
+	if !f.mini[str] || raw[1:len(raw)-1] != str.Value || strings.ContainsAny(str.Value, "\n\\") {
+		return raw
+	}
+
+	formatted, ok := formatMini(str.Value)
+	if !ok {
+		return raw
+	}
+
+	return `"` + formatted + `"`
+}
+
+// reports whether e has to be printed over several lines
+func (f *formatter) mustBreak(e Expression, top bool) bool {
+	if f.layered(e) || !top {
+		return f.layered(e)
+	}
+
+	_, _, links := splitChain(e)
+
+	calls := 0
+	for _, link := range links {
+		if _, ok := link[len(link)-1].(*CallExpr); ok {
+			calls++
+		}
+	}
+
+	return calls >= formatChainCalls
+}
+
+// reports whether node contains a stack() that gets one layer per line
+func (f *formatter) layered(node Node) bool {
+	layered := false
+
+	Walk(node, func(node Node) bool {
+		switch n := node.(type) {
+		case *BlockStmt:
+			return false // statements are laid out on their own
+		case *CallExpr:
+			layered = layered || isLayeredStack(n)
+		}
+
+		return !layered
+	})
+
+	return layered
+}
+
+// reports whether a comment that has not been printed starts before offset
+func (f *formatter) commentBefore(offset int) bool {
+	return f.next < len(f.comments) && f.comments[f.next].Range.Start.Offset < offset
+}
+
+// reports whether a comment between start and end is outside the function
+// bodies of nodes. comments in function bodies stay with their statements
+// when the nodes are printed on one line.
+func (f *formatter) looseComment(nodes []Node, start, end int) bool {
+	var blocks []Range
+	for _, node := range nodes {
+		Walk(node, func(node Node) bool {
+			if block, ok := node.(*BlockStmt); ok {
+				blocks = append(blocks, block.Range)
+				return false
+			}
+
+			return true
+		})
+	}
+
+	for _, comment := range f.comments {
+		offset := comment.Range.Start.Offset
+		if offset < start || offset >= end {
+			continue
+		}
+
+		if !slices.ContainsFunc(blocks, func(block Range) bool {
+			return offset > block.Start.Offset && offset < block.End.Offset
+		}) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// writes the lines of a statement list, a broken argument list or a broken
+// method chain, with the comments between the items
+type lineWriter struct {
+	f      *formatter
+	indent string
+	prev   int // source offset where the last written item ends
+	b      strings.Builder
+}
+
+// writes the comments that start before offset on lines of their own
+func (w *lineWriter) comments(offset int) {
+	f := w.f
+
+	for f.commentBefore(offset) {
+		comment := f.comments[f.next]
+		if comment.Range.Start.Offset < w.prev {
+			f.lost = true
+		}
+
+		w.item(commentText(comment), comment.Range.Start.Offset, comment.Range.End.Offset)
+		f.next++
+	}
+}
+
+// writes an item on a new line, after a blank line if the source had one
+func (w *lineWriter) item(text string, start, end int) {
+	if !w.empty() {
+		w.b.WriteString("\n")
+
+		if start > w.prev && strings.Count(w.f.source[w.prev:start], "\n") > 1 {
+			w.b.WriteString("\n")
+		}
+	}
+
+	w.b.WriteString(w.indent + text)
+	w.prev = end
+}
+
+// appends the comments that follow the last item on its line, before limit
+func (w *lineWriter) trailing(end Pos, limit int) {
+	f := w.f
+
+	for f.commentBefore(limit) && f.comments[f.next].Range.Start.Line == end.Line {
+		comment := f.comments[f.next]
+		w.b.WriteString(" " + commentText(comment))
+		w.prev = comment.Range.End.Offset
+		f.next++
+	}
+}
+
+func (w *lineWriter) empty() bool {
+	return w.b.Len() == 0
+}
+
+func (w *lineWriter) String() string {
+	return w.b.String()
+}
+
+func commentText(comment Token) string {
+	return strings.TrimRight(comment.Text, " \t\r")
+}
+
+// splits a chain like note("c").s("piano").fast(2) into its base expression
+// (note), the operations printed with the base (("c")) and the links that
+// start with a property access (.s("piano") and .fast(2)). a variable base
+// keeps its first link: pat.fast(2).
+func splitChain(e Expression) (Expression, []Expression, [][]Expression) {
+	var ops []Expression
+
+loop:
+	for {
+		switch x := e.(type) {
+		case *CallExpr:
+			if x.New {
+				break loop
+			}
+
+			ops = append(ops, x)
+			e = x.Callee
+
+		case *MemberExpr:
+			ops = append(ops, x)
+			e = x.Object
+
+		case *IndexExpr:
+			ops = append(ops, x)
+			e = x.Object
+
+		default:
+			break loop
+		}
+	}
+
+	slices.Reverse(ops)
+
+	i := 0
+	for i < len(ops) && !isMemberOp(ops[i]) {
+		i++
+	}
+
+	head := ops[:i]
+
+	var links [][]Expression
+	for i < len(ops) {
+		j := i + 1
+		for j < len(ops) && (!isMemberOp(ops[j]) || !slices.ContainsFunc(ops[i:j], isAccessOp)) {
+			j++
+		}
+
+		links = append(links, ops[i:j])
+		i = j
+	}
+
+	if _, ok := e.(*Identifier); ok && len(head) == 0 && len(links) > 0 {
+		head, links = links[0], links[1:]
+	}
+
+	return e, head, links
+}
+
+func isMemberOp(op Expression) bool {
+	_, ok := op.(*MemberExpr)
+	return ok
+}
+
+// reports whether op is a call or index
+func isAccessOp(op Expression) bool {
+	return !isMemberOp(op)
+}
+
+// returns the offset of the property name a link starts with
+func linkStart(link []Expression) int {
+	return link[0].(*MemberExpr).Property.Start.Offset
+}
+
+// returns the source offset where the printed part of a chain before its links ends
+func chainEnd(base Expression, head []Expression) int {
+	if len(head) > 0 {
+		return head[len(head)-1].Span().End.Offset
+	}
+
+	return base.Span().End.Offset
+}
+
+// reports whether call is stack() with layers that are more than literals
+func isLayeredStack(call *CallExpr) bool {
+	callee, ok := call.Callee.(*Identifier)
+	if !ok || callee.Name != "stack" || len(call.Args) < 2 {
+		return false
+	}
+
+	for _, arg := range call.Args {
+		switch arg.(type) {
+		case *StringLiteral, *NumberLiteral, *Identifier:
+		default:
+			return true
+		}
+	}
+
+	return false
+}
+
+// reports whether e needs parentheses as the object of a call, property access or index
+func needsObjectParens(e Expression) bool {
+	switch e.(type) {
+	case *Identifier, *StringLiteral, *ArrayLiteral, *CallExpr, *MemberExpr, *IndexExpr:
+		return false
+	}
+
+	return true
+}
+
+// reports whether e needs parentheses as the operand of a unary operator
+func needsUnaryParens(e Expression) bool {
+	switch e.(type) {
+	case *BinaryExpr, *ConditionalExpr:
+		return true
+	}
+
+	return needsAssignParens(e)
+}
+
+// reports whether e is an assignment or arrow function, which bind looser than any operator
+func needsAssignParens(e Expression) bool {
+	switch x := e.(type) {
+	case *AssignExpr:
+		return true
+	case *FuncExpr:
+		return x.Arrow
+	}
+
+	return false
+}
+
+// reports whether e needs parentheses as the left or right operand of op
+func needsBinaryParens(e Expression, op string, right bool) bool {
+	switch x := e.(type) {
+	case *BinaryExpr:
+		// ?? cannot be mixed with || or && without parentheses
+		if (op == "??") != (x.Op == "??") && (isLogicalOp(op) || isLogicalOp(x.Op)) {
+			return true
+		}
+
+		outer, inner := binaryPrecedence[op], binaryPrecedence[x.Op]
+		if inner != outer {
+			return inner < outer
+		}
+
+		// ** is right associative, all others are left associative
+		return right != (op == "**")
+
+	case *UnaryExpr:
+		return op == "**" && !right && !x.Postfix
+	}
+
+	return needsUnaryParens(e)
+}
+
+func isLogicalOp(op string) bool {
+	return op == "||" || op == "&&" || op == "??"
+}
+
+// reports whether text starting at column stays within the line width. text
+// spans lines only for function bodies and template strings, whose lines are
+// laid out on their own.
+func fits(column int, text string) bool {
+	first, _, _ := strings.Cut(text, "\n")
+	return column+utf8.RuneCountInString(first) <= formatWidth && endColumn(column, text) <= formatWidth
+}
+
+// returns the column after text, which starts at column
+func endColumn(column int, text string) int {
+	if i := strings.LastIndexByte(text, '\n'); i >= 0 {
+		return utf8.RuneCountInString(text[i+1:])
+	}
+
+	return column + utf8.RuneCountInString(text)
+}
+
+// returns the strings of a program that hold mini-notation: double quoted and
+// template strings, except arguments of functions that take plain strings
+// like samples() and operands of operators like "a" + b
+func miniStrings(program *Program) map[*StringLiteral]bool {
+	mini := make(map[*StringLiteral]bool)
+	plain := make(map[*StringLiteral]bool)
+
+	skip := func(nodes ...Node) {
+		for _, node := range nodes {
+			Walk(node, func(node Node) bool {
+				if str, ok := node.(*StringLiteral); ok {
+					plain[str] = true
+				}
+
+				return true
+			})
+		}
+	}
+
+	program.Walk(func(node Node) bool {
+		switch n := node.(type) {
+		case *CallExpr:
+			switch callee := n.Callee.(type) {
+			case *Identifier:
+				if slices.Contains(nonPatternFunctions, callee.Name) || slices.Contains(javaScriptGlobals, callee.Name) {
+					for _, arg := range n.Args {
+						skip(arg)
+					}
+				}
+
+			case *MemberExpr:
+				if object, ok := callee.Object.(*Identifier); ok && slices.Contains(javaScriptGlobals, object.Name) {
+					for _, arg := range n.Args {
+						skip(arg)
+					}
+				}
+			}
+
+		case *BinaryExpr:
+			skip(n.Left, n.Right)
+
+		case *IndexExpr:
+			skip(n.Index)
+
+		case *StringLiteral:
+			if n.Quote != '\'' && !strings.Contains(n.Value, "${") {
+				mini[n] = true
+			}
+		}
+
+		return true
+	})
+
+	for str := range plain {
+		delete(mini, str)
+	}
+
+	return mini
+}
+
+// normalizes the spacing of mini-notation: single spaces between steps,
+// ", " between layers, " | " between choices and modifiers attached to
+// their step. reports false if the pattern does not parse or would change.
+func formatMini(src string) (string, bool) {
+	root, err := ParseMini(src)
+	if err != nil {
+		return "", false
+	}
+
+	formatted := printMini(root, src)
+
+	reparsed, err := ParseMini(formatted)
+	if err != nil || dumpMini(reparsed) != dumpMini(root) {
+		return "", false
+	}
+
+	return formatted, true
+}
+
+// prints a mini-notation node. src is the pattern the node was parsed from,
+// it tells bracketed subsequences from feet separated by " . ".
+func printMini(node *MiniNode, src string) string {
+	var text string
+
+	switch node.Kind {
+	case MiniSequence:
+		steps := make([]string, len(node.Children))
+		feet := len(node.Children) > 0
+
+		for i, child := range node.Children {
+			steps[i] = printMiniStep(child, src)
+			feet = feet && child.Kind == MiniSequence && !isBracketed(child, src)
+		}
+
+		if feet {
+			for i, child := range node.Children {
+				steps[i] = printMini(child, src)
+			}
+
+			text = strings.Join(steps, " . ")
+		} else {
+			text = strings.Join(steps, " ")
+		}
+
+	case MiniStack, MiniRandom:
+		separator := ", "
+		if node.Kind == MiniRandom {
+			separator = " | "
+		}
+
+		layers := make([]string, len(node.Children))
+		for i, child := range node.Children {
+			layers[i] = printMini(child, src)
+		}
+
+		text = strings.Join(layers, separator)
+
+	case MiniAlternate:
+		text = "<" + printMini(node.Children[0], src) + ">"
+
+	case MiniPolymeter:
+		text = "{" + printMini(node.Children[0], src) + "}"
+
+	case MiniRange:
+		text = printMiniStep(node.Children[0], src) + " .. " + printMiniStep(node.Children[1], src)
+
+	default:
+		text = node.Value
+	}
+
+	return text + printMiniModifiers(node, src)
+}
+
+// prints a step of a sequence, in brackets if it is a subsequence
+func printMiniStep(node *MiniNode, src string) string {
+	switch node.Kind {
+	case MiniSequence, MiniStack, MiniRandom:
+		if node.Kind != MiniSequence || isBracketed(node, src) {
+			bare := *node
+			bare.Modifiers = nil
+
+			return "[" + printMini(&bare, src) + "]" + printMiniModifiers(node, src)
+		}
+	}
+
+	return printMini(node, src)
+}
+
+func printMiniModifiers(node *MiniNode, src string) string {
+	var text string
+
+	for _, modifier := range node.Modifiers {
+		text += modifier.Op
+
+		if modifier.Op == "(" {
+			args := make([]string, len(modifier.Args))
+			for i, arg := range modifier.Args {
+				args[i] = printMini(arg, src)
+			}
+
+			text += strings.Join(args, ",") + ")"
+
+			continue
+		}
+
+		for _, arg := range modifier.Args {
+			text += printMiniStep(arg, src)
+		}
+	}
+
+	return text
+}
+
+// reports whether a sequence node was written in brackets
+func isBracketed(node *MiniNode, src string) bool {
+	start, end := node.Range.Start.Offset, node.Range.End.Offset
+	if len(node.Modifiers) > 0 {
+		end = node.Modifiers[0].Range.Start.Offset
+	}
+
+	return start < end && end <= len(src) && src[start] == '[' && src[end-1] == ']'
+}
+
+// returns a canonical form of a program for comparing what two programs do:
+// positions and quotes are left out, mini-notation is compared parsed
+func dumpProgram(program *Program) string {
+	var b strings.Builder
+
+	for _, statement := range program.Statements {
+		dumpValue(&b, reflect.ValueOf(statement))
+		b.WriteString("\n")
+	}
+
+	for _, comment := range program.Comments {
+		b.WriteString(commentText(comment) + "\n")
+	}
+
+	for _, err := range program.Errors {
+		b.WriteString(err.Message + "\n")
+	}
+
+	return b.String()
+}
+
+func dumpMini(node *MiniNode) string {
+	var b strings.Builder
+	dumpValue(&b, reflect.ValueOf(node))
+
+	return b.String()
+}
+
+func dumpValue(b *strings.Builder, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if v.IsNil() {
+			b.WriteString("nil")
+			return
+		}
+
+		if str, ok := v.Interface().(*StringLiteral); ok {
+			dumpString(b, str)
+			return
+		}
+
+		dumpValue(b, v.Elem())
+
+	case reflect.Struct:
+		b.WriteString(v.Type().Name() + "{")
+
+		for i := range v.NumField() {
+			if field := v.Type().Field(i); field.Type == reflect.TypeOf(Range{}) || field.Type == reflect.TypeOf(Pos{}) {
+				continue
+			}
+
+			dumpValue(b, v.Field(i))
+			b.WriteString(" ")
+		}
+
+		b.WriteString("}")
+
+	case reflect.Slice:
+		b.WriteString("[")
+
+		for i := range v.Len() {
+			dumpValue(b, v.Index(i))
+			b.WriteString(" ")
+		}
+
+		b.WriteString("]")
+
+	case reflect.String:
+		b.WriteString(strconv.Quote(v.String()))
+
+	default:
+		fmt.Fprint(b, v.Interface())
+	}
+}
+
+func dumpString(b *strings.Builder, str *StringLiteral) {
+	if str.Quote != '\'' {
+		if root, err := ParseMini(str.Value); err == nil {
+			b.WriteString("mini(" + dumpMini(root) + ")")
+			return
+		}
+	}
+
+	b.WriteString(strconv.Quote(str.Value))
+}
@@ -0,0 +1,157 @@
+package strudel
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want string
+	}{
+		{
+			name: "short chain stays on one line",
+			code: `note("c e g").s("piano");`,
+			want: "note(\"c e g\").s(\"piano\")\n",
+		},
+		{
+			name: "long chain gets one call per line",
+			code: `$: note("c e g").s("piano").lpf(800).room(.5)`,
+			want: "$: note(\"c e g\")\n  .s(\"piano\")\n  .lpf(800)\n  .room(.5)\n",
+		},
+		{
+			name: "mini-notation spacing",
+			code: "s(\"bd  sd [ ~ bd ]*2 , hh*4\")\nnote(`<[c3,eb3]  [f3,ab3]>`)\ns('bd  sd')",
+			want: "s(\"bd sd [~ bd]*2, hh*4\")\nnote(\"<[c3, eb3] [f3, ab3]>\")\ns('bd  sd')\n",
+		},
+		{
+			name: "stack layers one per line",
+			code: `stack(s("bd*2"), note("c2 eb2").s("sawtooth")).room(.3)`,
+			want: "stack(\n  s(\"bd*2\"),\n  note(\"c2 eb2\").s(\"sawtooth\")\n).room(.3)\n",
+		},
+		{
+			name: "stack of strings stays on one line",
+			code: `stack( "bd" , "hh*2" )`,
+			want: "stack(\"bd\", \"hh*2\")\n",
+		},
+		{
+			name: "comments are kept",
+			code: "// drums\nstack(s(\"bd\"), // kick\n  // hats\n  s(\"hh*8\"))\n\n\n\ns(\"cp\") // clap",
+			want: "// drums\nstack(\n  s(\"bd\"), // kick\n  // hats\n  s(\"hh*8\")\n)\n\ns(\"cp\") // clap\n",
+		},
+		{
+			name: "comments between chain links",
+			code: "s(\"bd\")\n// faster\n.fast(2)",
+			want: "s(\"bd\")\n  // faster\n  .fast(2)\n",
+		},
+		{
+			name: "statement with a comment inside an expression is kept as written",
+			code: "let a = /* one */ 1;\nlet  b=2",
+			want: "let a = /* one */ 1\nlet b = 2\n",
+		},
+		{
+			name: "functions and blocks",
+			code: "register('rlpf', (x, pat) => { return pat.lpf(x) })\nfunction half(pat){return pat.slow(2);}",
+			want: "register('rlpf', (x, pat) => {\n  return pat.lpf(x)\n})\nfunction half(pat) {\n  return pat.slow(2)\n}\n",
+		},
+		{
+			name: "operators keep their precedence",
+			code: "x = (a + b) * c - (d - e) + 2 ** (3 ** 4) + (2 ** 3) ** 4",
+			want: "x = (a + b) * c - (d - e) + 2 ** 3 ** 4 + (2 ** 3) ** 4\n",
+		},
+		{
+			name: "semicolon before a line that would continue the previous one",
+			code: "a;\n(b || c).fast(2)",
+			want: "a\n;(b || c).fast(2)\n",
+		},
+		{
+			name: "objects and arrays",
+			code: "let o = {a:1,'b c':[1,2], d}\nx => ({a:1})",
+			want: "let o = { a: 1, 'b c': [1, 2], d }\nx => ({ a: 1 })\n",
+		},
+		{
+			name: "empty code",
+			code: "  \n",
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Format(tt.code)
+			if err != nil {
+				t.Fatalf("Format() error = %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("Format() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormat_Idempotent(t *testing.T) {
+	codes := []string{
+		"setcps(.5)\n$: s(\"bd sd [~ bd] hh*2\").bank('RolandTR909').gain(.8).room(.3)",
+		"const chords = note(\"<[c3,eb3,g3] [f3,ab3,c4]>\").s(\"piano\")\nstack(chords, n(\"0 .. 3 . 4 5\").scale(\"C:minor\").every(4, x => x.rev()))",
+		"s(\"bd sd hh cp bd sd hh cp bd sd hh cp bd sd hh cp bd sd hh cp bd sd hh cp\").fast(2)",
+		"x.superimpose(y => stack(y.fast(2), y.slow(2))).gain(1)",
+		"if (a) b()\nelse c()\nif (x) { y() } else { z() }",
+		"note(`c e\n  g b`).s(\"piano\")",
+	}
+
+	for _, code := range codes {
+		once, err := Format(code)
+		if err != nil {
+			t.Fatalf("Format(%q) error = %v", code, err)
+		}
+
+		twice, err := Format(once)
+		if err != nil {
+			t.Fatalf("Format(%q) error = %v", once, err)
+		}
+
+		if twice != once {
+			t.Errorf("Format() is not idempotent:\n%s\nthen\n%s", once, twice)
+		}
+
+		for _, line := range strings.Split(once, "\n") {
+			if len(line) > formatWidth && !strings.Contains(line, `"`) {
+				t.Errorf("line longer than %d characters: %q", formatWidth, line)
+			}
+		}
+	}
+}
+
+func TestFormat_InvalidCode(t *testing.T) {
+	for _, code := range []string{`s("bd"`, `note("c e").s(`, `let = 1`} {
+		if _, err := Format(code); err == nil {
+			t.Errorf("Format(%q) expected an error", code)
+		}
+	}
+}
+
+func TestFormatMini(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"bd  sd", "bd sd"},
+		{" [bd sd]*2 ", "[bd sd]*2"},
+		{"bd(3,8,2) hh?0.2 sd@3", "bd(3,8,2) hh?0.2 sd@3"},
+		{"a b . c", "a b . c"},
+		{"0 .. 3", "0 .. 3"},
+		{"<a b>!2 {c d, e}%4", "<a b>!2 {c d, e}%4"},
+		{"bd|sd|hh", "bd | sd | hh"},
+		{"bd ! ~ _", "bd! ~ _"},
+	}
+
+	for _, tt := range tests {
+		got, ok := formatMini(tt.src)
+		if !ok || got != tt.want {
+			t.Errorf("formatMini(%q) = %q, %v, want %q", tt.src, got, ok, tt.want)
+		}
+	}
+}
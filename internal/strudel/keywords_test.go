@@ -31,6 +31,11 @@ func TestExtractKeywords(t *testing.T) {
 			code:     "",
 			contains: []string{},
 		},
+		{
+			name:     "code still being typed",
+			code:     `s("bd [sd").lpf(`,
+			contains: []string{"bd", "sd"},
+		},
 	}
 
 	for _, tt := range tests {
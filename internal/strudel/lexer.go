@@ -0,0 +1,294 @@
+package strudel
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// punctuators, longest first so the lexer takes the longest match
+var punctuators = []string{
+	">>>=", "...", "===", "!==", "**=", "<<=", ">>=", ">>>", "&&=", "||=", "??=",
+	"=>", "==", "!=", "<=", ">=", "&&", "||", "??", "?.", "++", "--", "+=", "-=", "*=", "/=", "%=",
+	"&=", "|=", "^=", "**", "<<", ">>",
+	"{", "}", "(", ")", "[", "]", ";", ",", ".", "<", ">", "+", "-", "*", "/", "%",
+	"&", "|", "^", "!", "~", "?", ":", "=", "@", "#",
+}
+
+type lexer struct {
+	src    string
+	pos    Pos
+	tokens []Token
+	errors []*SyntaxError
+}
+
+// splits JavaScript source into tokens, including comments. lexing continues
+// after errors such as unterminated strings so callers still get every token.
+func Tokenize(src string) ([]Token, []*SyntaxError) {
+	l := &lexer{src: src, pos: Pos{Line: 1, Column: 1}}
+	newline := false
+
+	for {
+		newline = l.skipSpace() || newline
+
+		if l.pos.Offset >= len(l.src) {
+			l.tokens = append(l.tokens, Token{Kind: TokenEOF, Range: Range{Start: l.pos, End: l.pos}, NewlineBefore: newline})
+			return l.tokens, l.errors
+		}
+
+		token := l.next()
+		token.NewlineBefore = newline
+		l.tokens = append(l.tokens, token)
+
+		// a line comment ends its line and a block comment spanning lines counts as a line break
+		newline = token.Kind == TokenComment && (strings.HasPrefix(token.Text, "//") || strings.Contains(token.Text, "\n"))
+	}
+}
+
+// skips whitespace and reports whether it contained a line break
+func (l *lexer) skipSpace() bool {
+	newline := false
+
+	for l.pos.Offset < len(l.src) {
+		r, _ := utf8.DecodeRuneInString(l.src[l.pos.Offset:])
+		if !unicode.IsSpace(r) {
+			break
+		}
+
+		if r == '\n' {
+			newline = true
+		}
+
+		l.advance()
+	}
+
+	return newline
+}
+
+func (l *lexer) next() Token {
+	start := l.pos
+	c := l.src[start.Offset]
+
+	switch {
+	case strings.HasPrefix(l.src[start.Offset:], "//"):
+		for l.pos.Offset < len(l.src) && l.src[l.pos.Offset] != '\n' {
+			l.advance()
+		}
+
+		return l.token(TokenComment, start)
+
+	case strings.HasPrefix(l.src[start.Offset:], "/*"):
+		end := strings.Index(l.src[start.Offset+2:], "*/")
+		if end == -1 {
+			l.errorf(start, "unterminated comment")
+			l.advanceTo(len(l.src))
+		} else {
+			l.advanceTo(start.Offset + 2 + end + 2)
+		}
+
+		return l.token(TokenComment, start)
+
+	case c == '"' || c == '\'' || c == '`':
+		return l.string(c)
+
+	case isDigit(c) || (c == '.' && l.pos.Offset+1 < len(l.src) && isDigit(l.src[l.pos.Offset+1])):
+		return l.number()
+	}
+
+	r, _ := utf8.DecodeRuneInString(l.src[start.Offset:])
+	if isIdentStart(r) {
+		for l.pos.Offset < len(l.src) {
+			r, _ := utf8.DecodeRuneInString(l.src[l.pos.Offset:])
+			if !isIdentStart(r) && !unicode.IsDigit(r) {
+				break
+			}
+
+			l.advance()
+		}
+
+		return l.token(TokenIdent, start)
+	}
+
+	for _, punct := range punctuators {
+		if strings.HasPrefix(l.src[start.Offset:], punct) {
+			l.advanceTo(start.Offset + len(punct))
+			return l.token(TokenPunct, start)
+		}
+	}
+
+	l.advance()
+	l.errorf(start, "unexpected character %q", r)
+
+	return l.token(TokenPunct, start)
+}
+
+// lexes a quoted string or template literal. template substitutions are kept
+// verbatim in the value, tracking nested braces to find the closing backtick.
+func (l *lexer) string(quote byte) Token {
+	start := l.pos
+	l.advance()
+
+	var value strings.Builder
+	depth := 0
+
+	for {
+		if l.pos.Offset >= len(l.src) || (quote != '`' && l.src[l.pos.Offset] == '\n') {
+			l.errorf(start, "unterminated string")
+			break
+		}
+
+		c := l.src[l.pos.Offset]
+
+		if c == quote && depth == 0 {
+			l.advance()
+			break
+		}
+
+		if c == '\\' && l.pos.Offset+1 < len(l.src) {
+			l.advance()
+			value.WriteString(unescape(l.src[l.pos.Offset]))
+			l.advance()
+
+			continue
+		}
+
+		if quote == '`' {
+			if depth == 0 && strings.HasPrefix(l.src[l.pos.Offset:], "${") {
+				depth++
+				value.WriteString("${")
+				l.advanceTo(l.pos.Offset + 2)
+
+				continue
+			}
+
+			if depth > 0 && c == '{' {
+				depth++
+			} else if depth > 0 && c == '}' {
+				depth--
+			}
+		}
+
+		_, size := utf8.DecodeRuneInString(l.src[l.pos.Offset:])
+		value.WriteString(l.src[l.pos.Offset : l.pos.Offset+size])
+		l.advance()
+	}
+
+	kind := TokenString
+	if quote == '`' {
+		kind = TokenTemplate
+	}
+
+	token := l.token(kind, start)
+	token.Value = value.String()
+
+	return token
+}
+
+func (l *lexer) number() Token {
+	start := l.pos
+
+	if strings.HasPrefix(l.src[start.Offset:], "0x") || strings.HasPrefix(l.src[start.Offset:], "0X") {
+		l.advanceTo(start.Offset + 2)
+
+		for l.pos.Offset < len(l.src) && isHexDigit(l.src[l.pos.Offset]) {
+			l.advance()
+		}
+
+		return l.token(TokenNumber, start)
+	}
+
+	seenDot, seenExp := false, false
+
+	for l.pos.Offset < len(l.src) {
+		c := l.src[l.pos.Offset]
+
+		switch {
+		case isDigit(c) || c == '_':
+		case c == '.' && !seenDot && !seenExp:
+			seenDot = true
+		case (c == 'e' || c == 'E') && !seenExp:
+			seenExp = true
+
+			if next := l.pos.Offset + 1; next < len(l.src) && (l.src[next] == '+' || l.src[next] == '-') {
+				l.advance()
+			}
+		default:
+			return l.token(TokenNumber, start)
+		}
+
+		l.advance()
+	}
+
+	return l.token(TokenNumber, start)
+}
+
+func (l *lexer) token(kind TokenKind, start Pos) Token {
+	text := l.src[start.Offset:l.pos.Offset]
+	return Token{Kind: kind, Text: text, Value: text, Range: Range{Start: start, End: l.pos}}
+}
+
+// moves past one character
+func (l *lexer) advance() {
+	_, size := utf8.DecodeRuneInString(l.src[l.pos.Offset:])
+	l.pos = advancePos(l.pos, l.src[l.pos.Offset:l.pos.Offset+size])
+}
+
+func (l *lexer) advanceTo(offset int) {
+	for l.pos.Offset < offset {
+		l.advance()
+	}
+}
+
+func (l *lexer) errorf(pos Pos, format string, args ...any) {
+	l.errors = append(l.errors, &SyntaxError{Message: fmt.Sprintf(format, args...), Pos: pos})
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s (line %d, column %d)", e.Message, e.Pos.Line, e.Pos.Column)
+}
+
+// returns the position after text, which starts at pos
+func advancePos(pos Pos, text string) Pos {
+	for _, r := range text {
+		if r == '\n' {
+			pos.Line++
+			pos.Column = 1
+		} else {
+			pos.Column++
+		}
+	}
+
+	pos.Offset += len(text)
+
+	return pos
+}
+
+// returns the character an escape sequence stands for. unicode and hex
+// escapes are kept verbatim, they never carry mini-notation syntax.
+func unescape(c byte) string {
+	switch c {
+	case 'n':
+		return "\n"
+	case 't':
+		return "\t"
+	case 'r':
+		return "\r"
+	case 'u', 'x':
+		return "\\" + string(c)
+	default:
+		return string(c)
+	}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || r == '$' || unicode.IsLetter(r)
+}
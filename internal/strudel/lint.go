@@ -0,0 +1,690 @@
+package strudel
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// events per cycle above which speeding a pattern up is flagged
+const maxEventsPerCycle = 32
+
+// sounds available without samples(), besides those in soundDefs. names
+// ending in _ are prefixes, like the general midi soundfonts gm_*.
+var defaultSounds = []string{
+	"piano", "supersaw", "pulse", "sbd", "bytebeat", "gm_", "casio", "jazz", "metal", "east",
+	"space", "wind", "insect", "crow", "numbers", "num", "folkharp", "harp", "marimba",
+	"glockenspiel", "vibraphone", "xylophone", "kalimba", "ocarina", "recorder", "sax",
+	"steinway", "bass", "bass1", "bass2", "bass3", "clap", "kick", "snare", "hat", "tom",
+	"ride", "crash", "shaker", "tabla", "sitar", "bell", "gong", "superpiano",
+}
+
+// usable values of effect arguments, higher values clip, distort or feed back
+var effectRanges = map[string]valueRange{
+	"lpf": {20, 20000}, "hpf": {20, 20000}, "bpf": {20, 20000}, "cutoff": {20, 20000},
+	"hcutoff": {20, 20000}, "bandf": {20, 20000}, "lp": {20, 20000}, "hp": {20, 20000},
+	"bp": {20, 20000}, "lpq": {0, 50}, "hpq": {0, 50}, "bpq": {0, 50}, "resonance": {0, 50},
+	"gain": {0, 2}, "postgain": {0, 2}, "velocity": {0, 1}, "pan": {0, 1}, "delay": {0, 1},
+	"delayfeedback": {0, 1}, "room": {0, 1}, "shape": {0, 1}, "crush": {1, 16},
+}
+
+// intervals of scales notes are checked against, by name as in "C:minor"
+var scaleIntervals = map[string][]int{
+	"major":            {0, 2, 4, 5, 7, 9, 11},
+	"ionian":           {0, 2, 4, 5, 7, 9, 11},
+	"minor":            {0, 2, 3, 5, 7, 8, 10},
+	"aeolian":          {0, 2, 3, 5, 7, 8, 10},
+	"dorian":           {0, 2, 3, 5, 7, 9, 10},
+	"phrygian":         {0, 1, 3, 5, 7, 8, 10},
+	"lydian":           {0, 2, 4, 6, 7, 9, 11},
+	"mixolydian":       {0, 2, 4, 5, 7, 9, 10},
+	"locrian":          {0, 1, 3, 5, 6, 8, 10},
+	"harmonic minor":   {0, 2, 3, 5, 7, 8, 11},
+	"melodic minor":    {0, 2, 3, 5, 7, 9, 11},
+	"major pentatonic": {0, 2, 4, 7, 9},
+	"minor pentatonic": {0, 3, 5, 7, 10},
+	"blues":            {0, 3, 5, 6, 7, 10},
+}
+
+var (
+	sharpNames = []string{"c", "c#", "d", "d#", "e", "f", "f#", "g", "g#", "a", "a#", "b"}
+	flatNames  = []string{"c", "db", "d", "eb", "e", "f", "gb", "g", "ab", "a", "bb", "b"}
+)
+
+type linter struct {
+	program  *Program
+	e        *extractor
+	findings []Finding
+}
+
+// flags likely musical mistakes in Strudel code: unknown sounds, notes outside
+// the declared scale, effect values out of range, speeding up dense patterns
+// and unused variables. findings are ordered by position.
+func Lint(code string) []Finding {
+	program := ParseProgram(code)
+
+	l := &linter{
+		program:  program,
+		e:        newExtractor(program),
+		findings: []Finding{},
+	}
+
+	l.unknownSounds()
+	l.notesOutsideScale()
+	l.effectValues()
+	l.denseFast()
+	l.unusedVariables()
+
+	slices.SortStableFunc(l.findings, func(a, b Finding) int {
+		return a.Range.Start.Offset - b.Range.Start.Offset
+	})
+
+	return l.findings
+}
+
+// flags sounds that are neither built in nor default samples. code loading
+// its own samples can use any name and is skipped.
+func (l *linter) unknownSounds() {
+	if CountPattern(l.program.Source, "samples") > 0 {
+		return
+	}
+
+	l.eachPatternAtom([]string{"sound", "s"}, func(str *StringLiteral, atom *MiniNode) {
+		name := atom.Name()
+		if name == "" || isNumeric(name) || isKnownSound(name) {
+			return
+		}
+
+		r := l.atomRange(str, atom, len(name))
+		finding := Finding{
+			Rule:     "unknown-sound",
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("unknown sound %q", name),
+			Range:    r,
+		}
+
+		if suggestion := suggestSound(name); suggestion != "" {
+			finding.Message += fmt.Sprintf(", did you mean %q?", suggestion)
+			finding.Fix = &Fix{Description: fmt.Sprintf("use %q", suggestion), Range: r, Replacement: suggestion}
+		}
+
+		l.findings = append(l.findings, finding)
+	})
+}
+
+// flags note names outside the scale when the code declares a single
+// scale with a root, e.g. scale("C:minor")
+func (l *linter) notesOutsideScale() {
+	var scales []string
+
+	l.eachPatternAtom([]string{"scale"}, func(_ *StringLiteral, atom *MiniNode) {
+		if !slices.Contains(scales, atom.Value) {
+			scales = append(scales, atom.Value)
+		}
+	})
+
+	if len(scales) != 1 {
+		return
+	}
+
+	rootName, scaleName, ok := strings.Cut(scales[0], ":")
+	if !ok {
+		return
+	}
+
+	root, _, _, ok := parseNoteName(rootName)
+	intervals, known := scaleIntervals[strings.ReplaceAll(strings.ToLower(scaleName), ":", " ")]
+	if !ok || !known {
+		return
+	}
+
+	inScale := make(map[int]bool, len(intervals))
+	for _, interval := range intervals {
+		inScale[(root+interval)%12] = true
+	}
+
+	label := fmt.Sprintf("%s %s", rootName, strings.ReplaceAll(scaleName, ":", " "))
+
+	l.eachPatternAtom([]string{"note"}, func(str *StringLiteral, atom *MiniNode) {
+		pitch, nameLen, octave, ok := parseNoteName(atom.Value)
+		if !ok || inScale[pitch] {
+			return
+		}
+
+		r := l.atomRange(str, atom, len(atom.Value))
+		finding := Finding{
+			Rule:     "note-outside-scale",
+			Severity: SeverityInfo,
+			Message:  fmt.Sprintf("note %s is outside the scale %s", atom.Value, label),
+			Range:    r,
+		}
+
+		if replacement := nearestScaleNote(atom.Value, pitch, nameLen, octave, inScale); replacement != "" {
+			finding.Fix = &Fix{Description: fmt.Sprintf("use %s", replacement), Range: r, Replacement: replacement}
+		}
+
+		l.findings = append(l.findings, finding)
+	})
+}
+
+// flags effect values outside their usable range, as numbers or in patterns
+func (l *linter) effectValues() {
+	l.program.Walk(func(node Node) bool {
+		call, ok := node.(*CallExpr)
+		if !ok || len(call.Args) == 0 {
+			return true
+		}
+
+		name := calleeName(call)
+		limits, ok := effectRanges[name]
+		if !ok {
+			return true
+		}
+
+		switch arg := call.Args[0].(type) {
+		case *NumberLiteral, *UnaryExpr:
+			if value, ok := numberValue(arg); ok {
+				l.checkEffectValue(name, limits, value, arg.Span())
+			}
+
+		case *StringLiteral:
+			mini, err := ParseMini(arg.Value)
+			if err != nil {
+				return true
+			}
+
+			for _, atom := range mini.Atoms() {
+				if value, err := strconv.ParseFloat(atom.Value, 64); err == nil {
+					l.checkEffectValue(name, limits, value, l.atomRange(arg, atom, len(atom.Value)))
+				}
+			}
+		}
+
+		return true
+	})
+}
+
+func (l *linter) checkEffectValue(name string, limits valueRange, value float64, r Range) {
+	if value >= limits.min && value <= limits.max {
+		return
+	}
+
+	clamped := formatFloat(math.Max(limits.min, math.Min(limits.max, value)))
+
+	l.findings = append(l.findings, Finding{
+		Rule:     "effect-range",
+		Severity: SeverityWarning,
+		Message: fmt.Sprintf("%s(%s) is outside the usable range %s to %s", name,
+			formatFloat(value), formatFloat(limits.min), formatFloat(limits.max)),
+		Range: r,
+		Fix:   &Fix{Description: fmt.Sprintf("use %s", clamped), Range: r, Replacement: clamped},
+	})
+}
+
+// flags .fast() on patterns already playing many events per cycle
+func (l *linter) denseFast() {
+	l.program.Walk(func(node Node) bool {
+		call, ok := node.(*CallExpr)
+		if !ok || len(call.Args) != 1 {
+			return true
+		}
+
+		member, ok := call.Callee.(*MemberExpr)
+		if !ok || (member.Property.Name != "fast" && member.Property.Name != "hurry") {
+			return true
+		}
+
+		factor, ok := numberValue(call.Args[0])
+		if !ok || factor <= 1 {
+			return true
+		}
+
+		events := l.events(member.Object, 0)
+		if events == 0 || events*factor <= maxEventsPerCycle {
+			return true
+		}
+
+		l.findings = append(l.findings, Finding{
+			Rule:     "dense-fast",
+			Severity: SeverityWarning,
+			Message: fmt.Sprintf("%s(%s) on a pattern with %s events per cycle plays %s events per cycle",
+				member.Property.Name, formatFloat(factor), formatFloat(events), formatFloat(events*factor)),
+			Range: Range{Start: member.Property.Start, End: call.End},
+		})
+
+		return true
+	})
+}
+
+// estimates the events per cycle of a pattern expression, 0 when unknown
+func (l *linter) events(expr Expression, depth int) float64 {
+	if depth > maxBindingDepth {
+		return 0
+	}
+
+	switch x := expr.(type) {
+	case *StringLiteral:
+		mini, err := ParseMini(x.Value)
+		if err != nil {
+			return 0
+		}
+
+		return miniEvents(mini)
+
+	case *Identifier:
+		if value, ok := l.e.bindings[x.Name]; ok {
+			return l.events(value, depth+1)
+		}
+
+	case *CallExpr:
+		switch callee := x.Callee.(type) {
+		case *Identifier:
+			var events []float64
+			for _, arg := range x.Args {
+				events = append(events, l.events(arg, depth+1))
+			}
+
+			if len(events) == 0 {
+				return 0
+			}
+
+			switch callee.Name {
+			case "seq", "sequence", "fastcat":
+				total := 0.0
+				for _, e := range events {
+					total += e
+				}
+
+				return total
+			case "sound", "s", "note", "n", "stack", "cat", "slowcat":
+				return slices.Max(events)
+			}
+
+		case *MemberExpr:
+			// s("bd").note("c e") keeps the structure of the receiver
+			events := l.events(callee.Object, depth+1)
+			if events == 0 {
+				return 0
+			}
+
+			factor := 1.0
+			if len(x.Args) == 1 {
+				if value, ok := numberValue(x.Args[0]); ok && value > 0 {
+					factor = value
+				}
+			}
+
+			switch callee.Property.Name {
+			case "fast", "hurry", "ply":
+				return events * factor
+			case "slow":
+				return events / factor
+			}
+
+			return events
+		}
+	}
+
+	return 0
+}
+
+// estimates the events per cycle of a mini-notation pattern
+func miniEvents(n *MiniNode) float64 {
+	var events float64
+
+	switch n.Kind {
+	case MiniAtom:
+		events = 1
+
+	case MiniSequence:
+		for _, child := range n.Children {
+			events += miniEvents(child) * repeats(child)
+		}
+
+	case MiniStack, MiniRandom, MiniPolymeter:
+		for _, child := range n.Children {
+			events = math.Max(events, miniEvents(child))
+		}
+
+	case MiniAlternate:
+		// one step of the content plays per cycle
+		if content := n.Children[0]; content.Kind == MiniSequence {
+			for _, step := range content.Children {
+				events = math.Max(events, miniEvents(step))
+			}
+		} else {
+			events = miniEvents(content)
+		}
+
+	case MiniRange:
+		from, errFrom := strconv.Atoi(n.Children[0].Value)
+		to, errTo := strconv.Atoi(n.Children[1].Value)
+		events = 2
+
+		if errFrom == nil && errTo == nil {
+			events = math.Abs(float64(to-from)) + 1
+		}
+	}
+
+	for _, modifier := range n.Modifiers {
+		if len(modifier.Args) == 0 {
+			continue
+		}
+
+		value := miniMax(modifier.Args[0])
+
+		switch modifier.Op {
+		case "*":
+			events *= value
+		case "/":
+			if value > 0 {
+				events /= value
+			}
+		case "(":
+			events *= value
+		}
+	}
+
+	return events
+}
+
+// returns how many steps a sequence child takes with ! replication
+func repeats(n *MiniNode) float64 {
+	count := 1.0
+
+	for _, modifier := range n.Modifiers {
+		if modifier.Op != "!" {
+			continue
+		}
+
+		if len(modifier.Args) > 0 {
+			count = miniMax(modifier.Args[0])
+		} else {
+			count++
+		}
+	}
+
+	return count
+}
+
+// returns the largest number in a modifier argument, so *<2 4> counts as *4
+func miniMax(n *MiniNode) float64 {
+	largest := 0.0
+
+	for _, atom := range n.Atoms() {
+		if value, err := strconv.ParseFloat(atom.Value, 64); err == nil {
+			largest = math.Max(largest, value)
+		}
+	}
+
+	if n.Kind == MiniAtom && largest == 0 {
+		return 1
+	}
+
+	return largest
+}
+
+// flags variables that are declared but never used. a pattern held in an
+// unused variable does not play.
+func (l *linter) unusedVariables() {
+	declaring := make(map[*Identifier]bool)
+	used := make(map[string]bool)
+
+	l.program.Walk(func(node Node) bool {
+		switch n := node.(type) {
+		case *Declarator:
+			declaring[n.Name] = true
+		case *FuncDecl:
+			declaring[n.Name] = true
+		case *FuncExpr:
+			declaring[n.Name] = true
+		case *Param:
+			declaring[n.Name] = true
+		case *MemberExpr:
+			declaring[n.Property] = true
+		case *Identifier:
+			if !declaring[n] {
+				used[n.Name] = true
+			}
+		}
+
+		return true
+	})
+
+	l.program.Walk(func(node Node) bool {
+		decl, ok := node.(*VarDecl)
+		if !ok {
+			return true
+		}
+
+		for _, declarator := range decl.Declarations {
+			if used[declarator.Name.Name] {
+				continue
+			}
+
+			finding := Finding{
+				Rule:     "unused-variable",
+				Severity: SeverityInfo,
+				Message:  fmt.Sprintf("variable %s is declared but never used", declarator.Name.Name),
+				Range:    declarator.Name.Range,
+			}
+
+			if len(decl.Declarations) == 1 {
+				finding.Fix = &Fix{
+					Description: "remove the declaration",
+					Range:       l.lineRange(decl.Range),
+				}
+			}
+
+			l.findings = append(l.findings, finding)
+		}
+
+		return true
+	})
+}
+
+// extends a statement's range over trailing blanks and its line break
+func (l *linter) lineRange(r Range) Range {
+	src := l.program.Source
+	end := r.End
+
+	rest := src[end.Offset:]
+	trimmed := strings.TrimLeft(rest, " \t;")
+
+	if strings.HasPrefix(trimmed, "\n") {
+		end = advancePos(end, rest[:len(rest)-len(trimmed)+1])
+	}
+
+	return Range{Start: r.Start, End: end}
+}
+
+// calls visit with every atom of the patterns passed to the named functions
+func (l *linter) eachPatternAtom(names []string, visit func(*StringLiteral, *MiniNode)) {
+	l.program.Walk(func(node Node) bool {
+		call, ok := node.(*CallExpr)
+		if !ok {
+			return true
+		}
+
+		name := calleeName(call)
+		if !slices.Contains(names, name) {
+			return true
+		}
+
+		for _, str := range l.e.patternArgs(call, name) {
+			mini, err := ParseMini(str.Value)
+			if err != nil {
+				continue
+			}
+
+			for _, atom := range mini.Atoms() {
+				visit(str, atom)
+			}
+		}
+
+		return true
+	})
+}
+
+// returns the source range of the first length bytes of an atom
+func (l *linter) atomRange(str *StringLiteral, atom *MiniNode, length int) Range {
+	start := stringPos(l.program.Source, str, atom.Range.Start)
+	end := atom.Range.Start
+	end.Offset += length
+
+	return Range{Start: start, End: stringPos(l.program.Source, str, end)}
+}
+
+// returns the called function or method name
+func calleeName(call *CallExpr) string {
+	switch callee := call.Callee.(type) {
+	case *Identifier:
+		return callee.Name
+	case *MemberExpr:
+		return callee.Property.Name
+	}
+
+	return ""
+}
+
+// returns the value of a number literal, or a negated one
+func numberValue(expr Expression) (float64, bool) {
+	sign := 1.0
+
+	if unary, ok := expr.(*UnaryExpr); ok && unary.Op == "-" && !unary.Postfix {
+		sign = -1
+		expr = unary.Operand
+	}
+
+	number, ok := expr.(*NumberLiteral)
+	if !ok {
+		return 0, false
+	}
+
+	value, err := strconv.ParseFloat(strings.ReplaceAll(number.Value, "_", ""), 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return sign * value, true
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func isKnownSound(name string) bool {
+	for _, sounds := range soundCategories {
+		if matchesSound(sounds, name) {
+			return true
+		}
+	}
+
+	return matchesSound(defaultSounds, name)
+}
+
+// reports whether name is in sounds, where entries ending in _ match as prefix
+func matchesSound(sounds []string, name string) bool {
+	for _, sound := range sounds {
+		if sound == name || (strings.HasSuffix(sound, "_") && strings.HasPrefix(name, sound)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// returns the known sound closest to name, or "" if none is close enough.
+// built in sounds win ties over default samples.
+func suggestSound(name string) string {
+	var builtIn []string
+	for _, sounds := range soundCategories {
+		builtIn = append(builtIn, sounds...)
+	}
+
+	slices.Sort(builtIn)
+	defaults := slices.Sorted(slices.Values(defaultSounds))
+
+	best, bestDistance := "", min(3, len(name)/2+1)
+
+	for _, candidate := range append(builtIn, defaults...) {
+		if strings.HasSuffix(candidate, "_") {
+			continue
+		}
+
+		if distance := levenshtein(name, candidate); distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+
+	return best
+}
+
+// parses a note name like c, Eb3 or f#4 into its pitch class, the length
+// of the name without octave and the octave (-1 when absent)
+func parseNoteName(s string) (pitch, nameLen, octave int, ok bool) {
+	if s == "" {
+		return 0, 0, 0, false
+	}
+
+	pitch = strings.IndexByte("c d ef g a b", byte(unicode.ToLower(rune(s[0]))))
+	if pitch < 0 || s[0] == ' ' {
+		return 0, 0, 0, false
+	}
+
+	i := 1
+	for ; i < len(s) && (s[i] == '#' || s[i] == 's' || s[i] == 'b'); i++ {
+		if s[i] == 'b' {
+			pitch--
+		} else {
+			pitch++
+		}
+	}
+
+	nameLen, octave = i, -1
+
+	if i < len(s) {
+		value, err := strconv.Atoi(s[i:])
+		if err != nil {
+			return 0, 0, 0, false
+		}
+
+		octave = value
+	}
+
+	return (pitch%12 + 12) % 12, nameLen, octave, true
+}
+
+// returns the note closest to an out-of-scale note, lower on ties, spelled
+// like the original: case, flats and octave are kept
+func nearestScaleNote(note string, pitch, nameLen, octave int, inScale map[int]bool) string {
+	names := sharpNames
+	if strings.Contains(note[1:nameLen], "b") {
+		names = flatNames
+	}
+
+	for distance := 1; distance < 12; distance++ {
+		for _, delta := range []int{-distance, distance} {
+			target := pitch + delta
+			if !inScale[(target%12+12)%12] {
+				continue
+			}
+
+			name := names[(target%12+12)%12]
+			if unicode.IsUpper(rune(note[0])) {
+				name = strings.ToUpper(name[:1]) + name[1:]
+			}
+
+			if octave >= 0 {
+				name += strconv.Itoa(octave + int(math.Floor(float64(target)/12)))
+			}
+
+			return name
+		}
+	}
+
+	return ""
+}
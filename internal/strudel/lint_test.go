@@ -0,0 +1,116 @@
+package strudel
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name  string
+		code  string
+		rules []string
+	}{
+		{
+			name:  "clean pattern",
+			code:  `s("bd sd, hh*8").bank("RolandTR909").lpf(2000).gain(.8)`,
+			rules: []string{},
+		},
+		{
+			name:  "unknown sound",
+			code:  `s("bd sdd")`,
+			rules: []string{"unknown-sound"},
+		},
+		{
+			name:  "custom samples allow any sound",
+			code:  "samples('github:user/repo')\ns(\"mysample\")",
+			rules: []string{},
+		},
+		{
+			name:  "effect values out of range",
+			code:  `s("bd").lpf(50000).gain("<1 5>")`,
+			rules: []string{"effect-range", "effect-range"},
+		},
+		{
+			name:  "note outside the scale",
+			code:  `note("c eb f#").scale("C:minor")`,
+			rules: []string{"note-outside-scale"},
+		},
+		{
+			name:  "alternating scales are not checked",
+			code:  `note("c f#").scale("<C:minor D:major>")`,
+			rules: []string{},
+		},
+		{
+			name:  "fast on a dense pattern",
+			code:  `s("hh*16").fast(4)`,
+			rules: []string{"dense-fast"},
+		},
+		{
+			name:  "density followed through variables",
+			code:  "const hats = s(\"hh*8\").ply(2)\nhats.fast(4)",
+			rules: []string{"dense-fast"},
+		},
+		{
+			name:  "unused variable",
+			code:  "const drums = s(\"bd\")\nlet bass = note(\"c2\")\nbass.s(\"sawtooth\")",
+			rules: []string{"unused-variable"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := []string{}
+			for _, finding := range Lint(tt.code) {
+				rules = append(rules, finding.Rule)
+			}
+
+			if !reflect.DeepEqual(rules, tt.rules) {
+				t.Errorf("Lint() rules = %v, want %v", rules, tt.rules)
+			}
+		})
+	}
+}
+
+func TestLint_Fixes(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		expected string
+	}{
+		{"closest sound", `s("bd sdd")`, `s("bd sd")`},
+		{"clamped effect value", `s("bd").lpf(50000)`, `s("bd").lpf(20000)`},
+		{"clamped value in a pattern", `s("bd").gain("<1 5>")`, `s("bd").gain("<1 2>")`},
+		{"nearest scale note keeps octave", `note("c3 f#3").scale("C:minor")`, `note("c3 f3").scale("C:minor")`},
+		{"nearest scale note keeps flats", `note("Db4").scale("C:major")`, `note("C4").scale("C:major")`},
+		{"removed declaration", "const drums = s(\"bd\")\ns(\"hh\")", `s("hh")`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := Lint(tt.code)
+			if len(findings) != 1 || findings[0].Fix == nil {
+				t.Fatalf("expected one finding with a fix, got %+v", findings)
+			}
+
+			fix := findings[0].Fix
+			result := tt.code[:fix.Range.Start.Offset] + fix.Replacement + tt.code[fix.Range.End.Offset:]
+
+			if result != tt.expected {
+				t.Errorf("fixed code = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLint_Range(t *testing.T) {
+	findings := Lint("s(\"bd\")\n  .lpf(50000)")
+	if len(findings) != 1 {
+		t.Fatalf("expected one finding, got %+v", findings)
+	}
+
+	start, end := findings[0].Range.Start, findings[0].Range.End
+	if start.Line != 2 || start.Column != 8 || end.Column != 13 {
+		t.Errorf("range = %d:%d-%d:%d, want 2:8-2:13", start.Line, start.Column, end.Line, end.Column)
+	}
+}
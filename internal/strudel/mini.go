@@ -0,0 +1,358 @@
+package strudel
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type miniParser struct {
+	src string
+	pos Pos
+	err *SyntaxError
+}
+
+// parses a mini-notation string such as "<bd [sd sd]>*2, hh(3,8)". the root
+// is a sequence, or a stack or random choice of sequences. brackets parse to
+// their content, <...> and {...} to a MiniAlternate or MiniPolymeter node whose
+// only child is their content.
+func ParseMini(src string) (root *MiniNode, err error) {
+	p := &miniParser{src: src, pos: Pos{Line: 1, Column: 1}}
+
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(parseBailout); !ok {
+				panic(r)
+			}
+
+			root, err = nil, p.err
+		}
+	}()
+
+	root = p.layers(0)
+	if p.more() {
+		p.fail("unexpected %q", p.peek())
+	}
+
+	return root, nil
+}
+
+// parses sequences separated by , (stack) or | (random choice) up to closing
+func (p *miniParser) layers(closing byte) *MiniNode {
+	start := p.pos
+	layers := []*MiniNode{p.sequence(closing)}
+	kind := MiniSequence
+
+	for p.more() && (p.peek() == ',' || p.peek() == '|') {
+		next := MiniStack
+		if p.peek() == '|' {
+			next = MiniRandom
+		}
+
+		if kind != MiniSequence && kind != next {
+			p.fail("cannot mix \",\" and \"|\" in one group")
+		}
+
+		kind = next
+		p.advance(1)
+		layers = append(layers, p.sequence(closing))
+	}
+
+	if len(layers) == 1 {
+		return layers[0]
+	}
+
+	return &MiniNode{Kind: kind, Children: layers, Range: Range{Start: start, End: p.pos}}
+}
+
+// parses whitespace separated steps. feet separated by " . " become subsequences.
+func (p *miniParser) sequence(closing byte) *MiniNode {
+	start := p.pos
+	var steps, feet []*MiniNode
+	footStart := start
+
+	for {
+		p.skipSpace()
+
+		if !p.more() || p.peek() == closing || p.peek() == ',' || p.peek() == '|' {
+			break
+		}
+
+		switch {
+		case p.peek() == '!' && len(steps) > 0:
+			// a standalone ! repeats the previous step
+			p.modifiers(steps[len(steps)-1])
+			continue
+
+		case p.word() == "..":
+			if len(steps) == 0 {
+				p.fail("range without start")
+			}
+
+			p.advance(2)
+			p.skipSpace()
+
+			from := steps[len(steps)-1]
+			to := p.step()
+			steps[len(steps)-1] = &MiniNode{Kind: MiniRange, Children: []*MiniNode{from, to}, Range: Range{Start: from.Range.Start, End: to.Range.End}}
+
+			continue
+
+		case p.word() == ".":
+			feet = append(feet, &MiniNode{Kind: MiniSequence, Children: steps, Range: Range{Start: footStart, End: p.pos}})
+			steps = nil
+			p.advance(1)
+			footStart = p.pos
+
+			continue
+		}
+
+		steps = append(steps, p.step())
+	}
+
+	if feet != nil {
+		feet = append(feet, &MiniNode{Kind: MiniSequence, Children: steps, Range: Range{Start: footStart, End: p.pos}})
+		steps = feet
+	}
+
+	return &MiniNode{Kind: MiniSequence, Children: steps, Range: Range{Start: start, End: p.pos}}
+}
+
+// parses a term and the modifiers attached to it
+func (p *miniParser) step() *MiniNode {
+	node := p.term()
+	p.modifiers(node)
+
+	return node
+}
+
+func (p *miniParser) term() *MiniNode {
+	start := p.pos
+
+	if !p.more() {
+		p.fail("unexpected end of pattern")
+	}
+
+	switch c := p.peek(); c {
+	case '[':
+		p.advance(1)
+		node := p.layers(']')
+		p.expect(']')
+		node.Range = Range{Start: start, End: p.pos}
+
+		return node
+
+	case '<', '{':
+		close := byte('>')
+		kind := MiniAlternate
+
+		if c == '{' {
+			close = '}'
+			kind = MiniPolymeter
+		}
+
+		p.advance(1)
+		content := p.layers(close)
+		p.expect(close)
+
+		return &MiniNode{Kind: kind, Children: []*MiniNode{content}, Range: Range{Start: start, End: p.pos}}
+
+	case '~':
+		p.advance(1)
+		return &MiniNode{Kind: MiniRest, Value: "~", Range: Range{Start: start, End: p.pos}}
+	}
+
+	word := p.word()
+	if word == "" {
+		p.fail("unexpected %q", p.peek())
+	}
+
+	p.advance(len(word))
+	node := &MiniNode{Kind: MiniAtom, Value: word, Range: Range{Start: start, End: p.pos}}
+
+	switch word {
+	case "-":
+		node.Kind = MiniRest
+	case "_":
+		node.Kind = MiniHold
+	}
+
+	return node
+}
+
+// parses modifiers directly following a step and attaches them to node
+func (p *miniParser) modifiers(node *MiniNode) {
+	for p.more() {
+		start := p.pos
+		op := p.peek()
+		modifier := &MiniModifier{Op: string(op)}
+
+		switch op {
+		case '*', '/', '%':
+			p.advance(1)
+			modifier.Args = []*MiniNode{p.term()}
+
+		case '@':
+			p.advance(1)
+			modifier.Args = []*MiniNode{p.number()}
+
+		case '!', '?':
+			p.advance(1)
+
+			if p.more() && isDigit(p.peek()) {
+				modifier.Args = []*MiniNode{p.number()}
+			}
+
+		case '(':
+			p.advance(1)
+
+			for {
+				modifier.Args = append(modifier.Args, p.sequence(')'))
+
+				if !p.more() || p.peek() != ',' {
+					break
+				}
+
+				p.advance(1)
+			}
+
+			p.expect(')')
+
+			if len(modifier.Args) < 2 || len(modifier.Args) > 3 {
+				p.failAt(start, "euclidean rhythm needs 2 or 3 arguments, got %d", len(modifier.Args))
+			}
+
+		default:
+			return
+		}
+
+		modifier.Range = Range{Start: start, End: p.pos}
+		node.Modifiers = append(node.Modifiers, modifier)
+		node.Range.End = p.pos
+	}
+}
+
+func (p *miniParser) number() *MiniNode {
+	start := p.pos
+	word := p.word()
+
+	if word == "" || !isNumeric(strings.TrimPrefix(word, "-")) {
+		p.fail("expected number")
+	}
+
+	p.advance(len(word))
+
+	return &MiniNode{Kind: MiniAtom, Value: word, Range: Range{Start: start, End: p.pos}}
+}
+
+// returns the atom characters at the current position
+func (p *miniParser) word() string {
+	end := p.pos.Offset
+
+	for end < len(p.src) {
+		r, size := utf8.DecodeRuneInString(p.src[end:])
+		if !isMiniWordRune(r) {
+			break
+		}
+
+		end += size
+	}
+
+	return p.src[p.pos.Offset:end]
+}
+
+func (p *miniParser) skipSpace() {
+	for p.more() {
+		r, size := utf8.DecodeRuneInString(p.src[p.pos.Offset:])
+		if !unicode.IsSpace(r) {
+			return
+		}
+
+		p.advance(size)
+	}
+}
+
+func (p *miniParser) expect(c byte) {
+	p.skipSpace()
+
+	if !p.more() {
+		p.fail("expected %q", c)
+	}
+
+	if p.peek() != c {
+		p.fail("expected %q, got %q", c, p.peek())
+	}
+
+	p.advance(1)
+}
+
+func (p *miniParser) more() bool {
+	return p.pos.Offset < len(p.src)
+}
+
+func (p *miniParser) peek() byte {
+	return p.src[p.pos.Offset]
+}
+
+// moves past n bytes
+func (p *miniParser) advance(n int) {
+	p.pos = advancePos(p.pos, p.src[p.pos.Offset:p.pos.Offset+n])
+}
+
+func (p *miniParser) fail(format string, args ...any) {
+	p.failAt(p.pos, format, args...)
+}
+
+func (p *miniParser) failAt(pos Pos, format string, args ...any) {
+	p.err = &SyntaxError{Message: "mini-notation: " + fmt.Sprintf(format, args...), Pos: pos}
+	panic(parseBailout{})
+}
+
+// returns the atoms played by the pattern in source order. atoms inside
+// modifiers, like the 2 of bd*2, are not played and are skipped.
+func (n *MiniNode) Atoms() []*MiniNode {
+	if n == nil {
+		return nil
+	}
+
+	if n.Kind == MiniAtom {
+		return []*MiniNode{n}
+	}
+
+	var atoms []*MiniNode
+	for _, child := range n.Children {
+		atoms = append(atoms, child.Atoms()...)
+	}
+
+	return atoms
+}
+
+// returns the atom value without a sample index or variant: bd:3 → bd
+func (n *MiniNode) Name() string {
+	name, _, _ := strings.Cut(n.Value, ":")
+	return name
+}
+
+// reports whether the pattern plays several layers at once
+func (n *MiniNode) HasStack() bool {
+	if n == nil {
+		return false
+	}
+
+	if n.Kind == MiniStack {
+		return true
+	}
+
+	for _, child := range n.Children {
+		if child.HasStack() {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isMiniWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("#.-_^:", r)
+}
@@ -0,0 +1,198 @@
+package strudel
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseMini(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		kind  MiniKind
+		steps int
+		atoms []string
+	}{
+		{
+			name:  "sequence",
+			src:   "bd sd hh",
+			kind:  MiniSequence,
+			steps: 3,
+			atoms: []string{"bd", "sd", "hh"},
+		},
+		{
+			name:  "nested alternation with subsequence",
+			src:   "<bd [sd sd]>*2",
+			kind:  MiniSequence,
+			steps: 1,
+			atoms: []string{"bd", "sd", "sd"},
+		},
+		{
+			name:  "stack",
+			src:   "bd sd, hh*16",
+			kind:  MiniStack,
+			steps: 2,
+			atoms: []string{"bd", "sd", "hh"},
+		},
+		{
+			name:  "random choice",
+			src:   "bd | sd | cp",
+			kind:  MiniRandom,
+			steps: 3,
+			atoms: []string{"bd", "sd", "cp"},
+		},
+		{
+			name:  "euclidean rhythm arguments are not played",
+			src:   "bd(3,8,<0 2>) ~ sd",
+			kind:  MiniSequence,
+			steps: 3,
+			atoms: []string{"bd", "sd"},
+		},
+		{
+			name:  "polymeter with steps",
+			src:   "{bd sd, hh hh hh}%4",
+			kind:  MiniSequence,
+			steps: 1,
+			atoms: []string{"bd", "sd", "hh", "hh", "hh"},
+		},
+		{
+			name:  "replicate, elongate and degrade",
+			src:   "bd ! sd!2 hh@3 cp? _",
+			kind:  MiniSequence,
+			steps: 5,
+			atoms: []string{"bd", "sd", "hh", "cp"},
+		},
+		{
+			name:  "feet",
+			src:   "bd . hh hh",
+			kind:  MiniSequence,
+			steps: 2,
+			atoms: []string{"bd", "hh", "hh"},
+		},
+		{
+			name:  "notes and sample indices",
+			src:   "c#4 eb2 bd:3 C^7 -1.5",
+			kind:  MiniSequence,
+			steps: 5,
+			atoms: []string{"c#4", "eb2", "bd:3", "C^7", "-1.5"},
+		},
+		{
+			name:  "empty",
+			src:   "",
+			kind:  MiniSequence,
+			steps: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, err := ParseMini(tt.src)
+			if err != nil {
+				t.Fatalf("ParseMini(%q) error = %v", tt.src, err)
+			}
+
+			if root.Kind != tt.kind {
+				t.Errorf("root kind = %d, want %d", root.Kind, tt.kind)
+			}
+
+			if len(root.Children) != tt.steps {
+				t.Errorf("root has %d children, want %d", len(root.Children), tt.steps)
+			}
+
+			var atoms []string
+			for _, atom := range root.Atoms() {
+				atoms = append(atoms, atom.Value)
+			}
+
+			if !reflect.DeepEqual(atoms, tt.atoms) {
+				t.Errorf("atoms = %v, want %v", atoms, tt.atoms)
+			}
+		})
+	}
+}
+
+func TestParseMiniModifiers(t *testing.T) {
+	root, err := ParseMini("<bd [sd sd]>*2 hh(3,8)")
+	if err != nil {
+		t.Fatalf("ParseMini() error = %v", err)
+	}
+
+	alternate := root.Children[0]
+	if alternate.Kind != MiniAlternate {
+		t.Fatalf("first step kind = %d, want MiniAlternate", alternate.Kind)
+	}
+
+	if len(alternate.Modifiers) != 1 || alternate.Modifiers[0].Op != "*" || alternate.Modifiers[0].Args[0].Value != "2" {
+		t.Errorf("alternate modifiers = %+v, want *2", alternate.Modifiers)
+	}
+
+	if alternate.Range.Start.Offset != 0 || alternate.Range.End.Offset != 14 {
+		t.Errorf("alternate range = %d-%d, want 0-14", alternate.Range.Start.Offset, alternate.Range.End.Offset)
+	}
+
+	euclid := root.Children[1].Modifiers
+	if len(euclid) != 1 || euclid[0].Op != "(" || len(euclid[0].Args) != 2 {
+		t.Errorf("euclid modifiers = %+v, want (3,8)", euclid)
+	}
+}
+
+func TestParseMiniErrors(t *testing.T) {
+	tests := []struct {
+		src    string
+		column int
+		substr string
+	}{
+		{src: "bd [sd", column: 7, substr: "expected ']'"},
+		{src: "<bd sd", column: 7, substr: "expected '>'"},
+		{src: "bd ] sd", column: 4, substr: "unexpected ']'"},
+		{src: "[bd | sd, hh]", column: 9, substr: "cannot mix"},
+		{src: "bd(3)", column: 3, substr: "2 or 3 arguments"},
+		{src: "bd@x", column: 4, substr: "expected number"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := ParseMini(tt.src)
+			if err == nil {
+				t.Fatalf("ParseMini(%q) expected error", tt.src)
+			}
+
+			syntaxErr, ok := err.(*SyntaxError)
+			if !ok {
+				t.Fatalf("error type = %T, want *SyntaxError", err)
+			}
+
+			if syntaxErr.Pos.Column != tt.column {
+				t.Errorf("error column = %d, want %d (%v)", syntaxErr.Pos.Column, tt.column, err)
+			}
+
+			if !strings.Contains(err.Error(), tt.substr) {
+				t.Errorf("error %q does not contain %q", err.Error(), tt.substr)
+			}
+		})
+	}
+}
+
+func TestMiniHasStack(t *testing.T) {
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{src: "c e g", want: false},
+		{src: "[c,e,g] f", want: true},
+		{src: "<[g3,b3,e4]!2 [a3,c3,e4]>", want: true},
+		{src: "36 43, 52 59", want: true},
+	}
+
+	for _, tt := range tests {
+		root, err := ParseMini(tt.src)
+		if err != nil {
+			t.Fatalf("ParseMini(%q) error = %v", tt.src, err)
+		}
+
+		if got := root.HasStack(); got != tt.want {
+			t.Errorf("HasStack(%q) = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+}
@@ -2,71 +2,284 @@ package strudel
 
 import (
 	"regexp"
+	"slices"
 	"strings"
 )
 
-var (
-	// sound extraction: sound("bd") or s("bd")
-	// supports both quotes and backticks: s("bd") or s(`bd`)
-	soundPattern = regexp.MustCompile("(?:sound|s)\\s*\\(\\s*[\"'`]([^\"'`]+)[\"'`]")
+// calls counted by CountPatterns (extracted from Strudel docs)
+var countedPatterns = []string{
+	// structure/layering
+	"stack", "layer",
 
-	// note extraction: note("c e g")
-	// supports both quotes and backticks: note("c e g") or note(`c e g`)
-	notePattern = regexp.MustCompile("note\\s*\\(\\s*[\"'`]([^\"'`]+)[\"'`]")
+	// time modifiers
+	"slow", "fast", "early", "late", "euclid", "rev", "iter", "ply", "segment",
 
-	// backtick template literals followed by methods: `c e g`.note()
-	// extracts content from backticks when followed by a method call
-	backtickPattern = regexp.MustCompile("`([^`]+)`\\s*\\.\\w+\\s*\\(")
+	// conditional modifiers
+	"every", "sometimes", "often", "rarely", "almostNever", "almostAlways", "never", "always",
 
-	// function calls: .fast(2), .slow(4), .stack()
-	functionPattern = regexp.MustCompile(`\.(\w+)\s*\(`)
+	// arrangement
+	"arrange",
 
-	// variable declarations: let x = ..., const y = ...
-	variablePattern = regexp.MustCompile(`(?:let|const|var)\s+(\w+)\s*=`)
+	// interactive
+	"slider",
 
-	// scale/mode: scale("minor"), mode("dorian")
-	scalePattern = regexp.MustCompile(`(?:scale|mode)\s*\(\s*["'](\w+)["']`)
-)
+	// sampler effects
+	"chop", "striate", "slice",
+}
+
+// functions whose arguments are patterns combined into one, e.g. note(cat("c e", "g b"))
+var combinatorFunctions = []string{
+	"cat", "seq", "sequence", "fastcat", "slowcat", "stack", "polymeter", "randcat", "wchoose", "arrange", "timeCat",
+}
+
+// how deep variables are followed when resolving the pattern passed to a function
+const maxBindingDepth = 8
+
+type extractor struct {
+	bindings map[string]Expression // variable values, to resolve patterns held in variables
+	methods  map[*Identifier]bool  // property identifiers of method calls
+	parsed   ParsedCode
+}
 
 // extracts all elements from Strudel code
 func Parse(code string) ParsedCode {
-	return ParsedCode{
-		Sounds:    ExtractSounds(code),
-		Notes:     ExtractNotes(code),
-		Functions: ExtractFunctions(code),
-		Variables: ExtractVariables(code),
-		Scales:    ExtractScales(code),
-		Patterns:  CountPatterns(code),
+	return extract(ParseProgram(code))
+}
+
+// creates an extractor with the variable bindings of a program. bindings are
+// collected first so patterns can use variables declared later.
+func newExtractor(program *Program) *extractor {
+	e := &extractor{
+		bindings: make(map[string]Expression),
+		methods:  make(map[*Identifier]bool),
+		parsed: ParsedCode{
+			Sounds:    []string{},
+			Notes:     []string{},
+			Functions: []string{},
+			Variables: []string{},
+			Scales:    []string{},
+			Patterns:  make(map[string]int, len(countedPatterns)),
+		},
+	}
+
+	for _, name := range countedPatterns {
+		e.parsed.Patterns[name] = 0
+	}
+
+	program.Walk(func(node Node) bool {
+		if declarator, ok := node.(*Declarator); ok && declarator.Value != nil {
+			e.bindings[declarator.Name.Name] = declarator.Value
+		}
+
+		return true
+	})
+
+	return e
+}
+
+// extracts all elements from a parsed program
+func extract(program *Program) ParsedCode {
+	e := newExtractor(program)
+	seenSounds := make(map[string]bool)
+
+	program.Walk(func(node Node) bool {
+		switch n := node.(type) {
+		case *Declarator:
+			e.parsed.Variables = append(e.parsed.Variables, n.Name.Name)
+
+		case *Identifier:
+			if e.methods[n] {
+				e.parsed.Functions = append(e.parsed.Functions, n.Name)
+			}
+
+		case *CallExpr:
+			name := e.call(n)
+
+			for _, pattern := range e.patternArgs(n, name) {
+				switch name {
+				case "sound", "s":
+					for _, sound := range soundNames(pattern) {
+						if !seenSounds[sound] {
+							e.parsed.Sounds = append(e.parsed.Sounds, sound)
+							seenSounds[sound] = true
+						}
+					}
+
+				case "note":
+					notes, mini := patternAtoms(pattern)
+					e.parsed.Notes = append(e.parsed.Notes, notes...)
+
+					if mini != nil {
+						e.parsed.NotePatterns = append(e.parsed.NotePatterns, mini)
+					}
+
+				case "scale", "mode":
+					e.parsed.Scales = append(e.parsed.Scales, scaleNames(pattern)...)
+				}
+			}
+		}
+
+		return true
+	})
+
+	return e.parsed
+}
+
+// records a call and returns the called function or method name
+func (e *extractor) call(call *CallExpr) string {
+	var name string
+
+	switch callee := call.Callee.(type) {
+	case *Identifier:
+		name = callee.Name
+	case *MemberExpr:
+		name = callee.Property.Name
+		e.methods[callee.Property] = true
+	default:
+		return ""
+	}
+
+	if _, counted := e.parsed.Patterns[name]; counted {
+		e.parsed.Patterns[name]++
+	}
+
+	return name
+}
+
+// returns the strings passed as pattern to a sound, note or scale call:
+// the first argument, or the receiver for argument-less methods like "c e g".note()
+func (e *extractor) patternArgs(call *CallExpr, name string) []*StringLiteral {
+	switch name {
+	case "sound", "s", "note", "scale", "mode":
+	default:
+		return nil
+	}
+
+	if len(call.Args) > 0 {
+		return e.patternStrings(call.Args[0], 0)
+	}
+
+	if member, ok := call.Callee.(*MemberExpr); ok {
+		return e.patternStrings(member.Object, 0)
+	}
+
+	return nil
+}
+
+// resolves an expression to the pattern strings it is built from, following
+// variables, method chains on strings and pattern combinators like cat()
+func (e *extractor) patternStrings(expr Expression, depth int) []*StringLiteral {
+	if depth > maxBindingDepth {
+		return nil
+	}
+
+	switch x := expr.(type) {
+	case *StringLiteral:
+		if x.Quote == '`' && strings.Contains(x.Value, "${") {
+			return nil
+		}
+
+		return []*StringLiteral{x}
+
+	case *Identifier:
+		if value, ok := e.bindings[x.Name]; ok {
+			return e.patternStrings(value, depth+1)
+		}
+
+	case *ArrayLiteral:
+		var patterns []*StringLiteral
+		for _, element := range x.Elements {
+			patterns = append(patterns, e.patternStrings(element, depth+1)...)
+		}
+
+		return patterns
+
+	case *CallExpr:
+		switch callee := x.Callee.(type) {
+		case *MemberExpr:
+			// "c e".fast(2) is still the pattern "c e"
+			return e.patternStrings(callee.Object, depth+1)
+
+		case *Identifier:
+			if !slices.Contains(combinatorFunctions, callee.Name) {
+				return nil
+			}
+
+			var patterns []*StringLiteral
+			for _, arg := range x.Args {
+				patterns = append(patterns, e.patternStrings(arg, depth+1)...)
+			}
+
+			return patterns
+		}
 	}
+
+	return nil
 }
 
 // extracts sound sample names from sound() calls
 // example: sound("bd hh sd") → ["bd", "hh", "sd"]
 // handles complex patterns: s("bd:0") → ["bd"], s("[~ sd:3]*2") → ["sd"]
 func ExtractSounds(code string) []string {
-	sounds := []string{}
-	seen := make(map[string]bool)
-
-	matches := soundPattern.FindAllStringSubmatch(code, -1)
-
-	for _, match := range matches {
-		if len(match) > 1 {
-			// parse pattern string to extract sound names
-			parsed := parsePatternString(match[1])
-			for _, s := range parsed {
-				if !seen[s] {
-					sounds = append(sounds, s)
-					seen[s] = true
-				}
-			}
+	return Parse(code).Sounds
+}
+
+// returns the sound names a pattern plays, skipping rests, numbers and sample indices
+func soundNames(pattern *StringLiteral) []string {
+	var names []string
+
+	atoms, _ := patternAtoms(pattern)
+
+	for _, name := range atoms {
+		name, _, _ = strings.Cut(name, ":")
+
+		// skip empty, numeric-only tokens, and common pattern markers
+		if name == "" || isNumeric(name) || name == "x" {
+			continue
+		}
+
+		names = append(names, name)
+	}
+
+	return names
+}
+
+// returns the scale names of a scale pattern: "C:minor" → ["minor"]
+func scaleNames(pattern *StringLiteral) []string {
+	var names []string
+
+	atoms, _ := patternAtoms(pattern)
+
+	for _, atom := range atoms {
+		if _, scale, ok := strings.Cut(atom, ":"); ok {
+			atom = strings.ReplaceAll(scale, ":", " ")
 		}
+
+		names = append(names, atom)
 	}
 
-	return sounds
+	return names
 }
 
-// parsePatternString extracts sound names from Strudel pattern syntax
-// handles: "bd:0" → ["bd"], "[~ sd hh]*2" → ["sd", "hh"], "bd, hh" → ["bd", "hh"]
+// returns the atoms of a mini-notation string and its parsed form. patterns that
+// do not parse, e.g. while they are being typed, fall back to splitting on
+// pattern syntax and return a nil node.
+func patternAtoms(pattern *StringLiteral) ([]string, *MiniNode) {
+	mini, err := ParseMini(pattern.Value)
+	if err != nil {
+		return parsePatternString(pattern.Value), nil
+	}
+
+	var atoms []string
+	for _, atom := range mini.Atoms() {
+		atoms = append(atoms, atom.Value)
+	}
+
+	return atoms, mini
+}
+
+// parsePatternString splits a pattern into atoms by removing pattern syntax
+// handles: "bd:0" → ["bd:0"], "[~ sd hh]*2" → ["sd", "hh", "2"], "bd, hh" → ["bd", "hh"]
 func parsePatternString(pattern string) []string {
 	// remove common pattern syntax characters
 	cleaners := []string{"[", "]", "<", ">", "(", ")", "{", "}", "*", "@", "!", "/", "|", "?"}
@@ -79,26 +292,17 @@ func parsePatternString(pattern string) []string {
 	cleaned = strings.ReplaceAll(cleaned, ",", " ")
 	tokens := strings.Fields(cleaned)
 
-	sounds := []string{}
+	atoms := []string{}
 	for _, token := range tokens {
 		// skip rests and silences
-		if token == "~" || token == "-" || token == "" {
-			continue
-		}
-
-		// remove sample number suffix (e.g., "bd:0" → "bd")
-		parts := strings.Split(token, ":")
-		soundName := parts[0]
-
-		// skip empty, numeric-only tokens, and common pattern markers
-		if soundName == "" || isNumeric(soundName) || soundName == "x" {
+		if token == "~" || token == "-" || token == "_" {
 			continue
 		}
 
-		sounds = append(sounds, soundName)
+		atoms = append(atoms, token)
 	}
 
-	return sounds
+	return atoms
 }
 
 // isNumeric checks if a string is purely numeric
@@ -109,133 +313,57 @@ func isNumeric(s string) bool {
 
 // extractNotes extracts note names from note() calls
 // example: note("c e g") → ["c", "e", "g"]
-// also supports backtick strings: `c e g`.note() → ["c", "e", "g"]
+// also supports strings with a note method: `c e g`.note() → ["c", "e", "g"]
 func ExtractNotes(code string) []string {
-	notes := []string{}
-
-	// extract from note("...") or note(`...`)
-	matches := notePattern.FindAllStringSubmatch(code, -1)
-	for _, match := range matches {
-		if len(match) > 1 {
-			// split space-separated notes
-			noteList := strings.Fields(match[1])
-			notes = append(notes, noteList...)
-		}
-	}
-
-	// extract from backtick template literals: `c e g`.note()
-	backtickMatches := backtickPattern.FindAllStringSubmatch(code, -1)
-	for _, match := range backtickMatches {
-		if len(match) > 1 {
-			// split space-separated notes/patterns
-			noteList := strings.Fields(match[1])
-			notes = append(notes, noteList...)
-		}
-	}
-
-	return notes
+	return Parse(code).Notes
 }
 
-// extractFunctions extracts function/method names from .func() calls
+// extractFunctions extracts method names from .func() calls
 // example: .fast(2).slow(4) → ["fast", "slow"]
 func ExtractFunctions(code string) []string {
-	functions := []string{}
-	matches := functionPattern.FindAllStringSubmatch(code, -1)
-
-	for _, match := range matches {
-		if len(match) > 1 {
-			functions = append(functions, match[1])
-		}
-	}
-
-	return functions
+	return Parse(code).Functions
 }
 
 // extractVariables extracts variable names from declarations
 // example: let pat1 = sound("bd") → ["pat1"]
 func ExtractVariables(code string) []string {
-	variables := []string{}
-	matches := variablePattern.FindAllStringSubmatch(code, -1)
-
-	for _, match := range matches {
-		if len(match) > 1 {
-			variables = append(variables, match[1])
-		}
-	}
-
-	return variables
+	return Parse(code).Variables
 }
 
 // extractScales extracts scale/mode names
-// example: scale("minor") → ["minor"]
+// example: scale("minor") → ["minor"], scale("C:minor") → ["minor"]
 func ExtractScales(code string) []string {
-	scales := []string{}
-	matches := scalePattern.FindAllStringSubmatch(code, -1)
-
-	for _, match := range matches {
-		if len(match) > 1 {
-			scales = append(scales, match[1])
-		}
-	}
-
-	return scales
+	return Parse(code).Scales
 }
 
-// counts occurrences of specific patterns
+// counts calls of common pattern functions, as function or method
 func CountPatterns(code string) map[string]int {
-	patterns := make(map[string]int)
-
-	// common patterns to count (extracted from Strudel docs)
-	patternRegexes := map[string]*regexp.Regexp{
-		// structure/layering (match both .stack() and standalone stack())
-		"stack": regexp.MustCompile(`(?:^|\W)stack\s*\(`),
-		"layer": regexp.MustCompile(`(?:^|\W)layer\s*\(`),
-
-		// time modifiers
-		"slow":    regexp.MustCompile(`\.slow\s*\(`),
-		"fast":    regexp.MustCompile(`\.fast\s*\(`),
-		"early":   regexp.MustCompile(`\.early\s*\(`),
-		"late":    regexp.MustCompile(`\.late\s*\(`),
-		"euclid":  regexp.MustCompile(`\.euclid\s*\(`),
-		"rev":     regexp.MustCompile(`\.rev\s*\(`),
-		"iter":    regexp.MustCompile(`\.iter\s*\(`),
-		"ply":     regexp.MustCompile(`\.ply\s*\(`),
-		"segment": regexp.MustCompile(`\.segment\s*\(`),
-
-		// conditional modifiers
-		"every":        regexp.MustCompile(`\.every\s*\(`),
-		"sometimes":    regexp.MustCompile(`\.sometimes\s*\(`),
-		"often":        regexp.MustCompile(`\.often\s*\(`),
-		"rarely":       regexp.MustCompile(`\.rarely\s*\(`),
-		"almostNever":  regexp.MustCompile(`\.almostNever\s*\(`),
-		"almostAlways": regexp.MustCompile(`\.almostAlways\s*\(`),
-		"never":        regexp.MustCompile(`\.never\s*\(`),
-		"always":       regexp.MustCompile(`\.always\s*\(`),
-
-		// arrangement
-		"arrange": regexp.MustCompile(`(?:^|\W)arrange\s*\(`), // match arrange() as function or method
-
-		// interactive
-		"slider": regexp.MustCompile(`slider\s*\(`),
-
-		// sampler effects
-		"chop":    regexp.MustCompile(`\.chop\s*\(`),
-		"striate": regexp.MustCompile(`\.striate\s*\(`),
-		"slice":   regexp.MustCompile(`\.slice\s*\(`),
-	}
-
-	for name, regex := range patternRegexes {
-		matches := regex.FindAllString(code, -1)
-		patterns[name] = len(matches)
-	}
-
-	return patterns
+	return Parse(code).Patterns
 }
 
-// counts occurrences of a specific pattern
+// counts calls of a specific function, as function or method
 func CountPattern(code string, pattern string) int {
-	regex := regexp.MustCompile(`\.` + pattern + `\s*\(`)
-	matches := regex.FindAllString(code, -1)
+	count := 0
+
+	ParseProgram(code).Walk(func(node Node) bool {
+		call, ok := node.(*CallExpr)
+		if !ok {
+			return true
+		}
+
+		switch callee := call.Callee.(type) {
+		case *Identifier:
+			if callee.Name == pattern {
+				count++
+			}
+		case *MemberExpr:
+			if callee.Property.Name == pattern {
+				count++
+			}
+		}
+
+		return true
+	})
 
-	return len(matches)
+	return count
 }
@@ -36,6 +36,31 @@ func TestExtractSounds(t *testing.T) {
 			code:     `note("c e g")`,
 			expected: []string{},
 		},
+		{
+			name:     "nested mini-notation",
+			code:     `s("<bd [sd sd]>*2, ~ hh:3(3,8)")`,
+			expected: []string{"bd", "sd", "hh"},
+		},
+		{
+			name:     "pattern held in a variable",
+			code:     "let drums = \"bd cp\"\n$: s(drums).fast(2)",
+			expected: []string{"bd", "cp"},
+		},
+		{
+			name:     "stack arguments and chained sound",
+			code:     `stack(s("bd*4"), note("c e").sound("<sawtooth square>"))`,
+			expected: []string{"bd", "sawtooth", "square"},
+		},
+		{
+			name:     "combinator argument",
+			code:     `s(cat("bd sd", "hh"))`,
+			expected: []string{"bd", "sd", "hh"},
+		},
+		{
+			name:     "sound names inside a string are not calls",
+			code:     `note("c").lpf("s(1)")`,
+			expected: []string{},
+		},
 	}
 
 	for _, tt := range tests {
@@ -74,6 +99,16 @@ func TestExtractNotes(t *testing.T) {
 			code:     `sound("bd")`,
 			expected: []string{},
 		},
+		{
+			name:     "string with note method",
+			code:     "`<c3 [e3 g3]>`.note()",
+			expected: []string{"c3", "e3", "g3"},
+		},
+		{
+			name:     "chord and method chain on the pattern",
+			code:     `note("[c,e,g] ~ b".fast(2))`,
+			expected: []string{"c", "e", "g", "b"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -107,6 +142,16 @@ func TestExtractFunctions(t *testing.T) {
 			code:     `sound("bd")`,
 			expected: []string{},
 		},
+		{
+			name:     "chain across lines and inside arrow functions",
+			code:     "s(\"bd\")\n  .often(x => x.ply(2))\n  .room(.5)",
+			expected: []string{"often", "ply", "room"},
+		},
+		{
+			name:     "calls inside strings are ignored",
+			code:     `s("bd.fast(2)")`,
+			expected: []string{},
+		},
 	}
 
 	for _, tt := range tests {
@@ -157,6 +202,39 @@ func TestExtractVariables(t *testing.T) {
 	}
 }
 
+func TestExtractScales(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     string
+		expected []string
+	}{
+		{
+			name:     "scale name",
+			code:     `n("0 2 4").scale("minor")`,
+			expected: []string{"minor"},
+		},
+		{
+			name:     "root and alternating scales",
+			code:     `n("0 2").scale("<C:major A:minor:pentatonic>")`,
+			expected: []string{"major", "minor pentatonic"},
+		},
+		{
+			name:     "no scales",
+			code:     `s("bd")`,
+			expected: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ExtractScales(tt.code)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("ExtractScales() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
 func TestCountPatterns(t *testing.T) {
 	code := `sound("bd").stack(sound("hh")).every(4).stack(note("c"))`
 
@@ -0,0 +1,323 @@
+package strudel
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// creates a validator that checks code in process. it needs no Node install
+// and validates concurrently, but only knows the functions in the catalog.
+func NewStaticValidator() *StaticValidator {
+	return &StaticValidator{}
+}
+
+// checks JavaScript syntax, mini-notation strings, calls to unknown functions
+// and argument counts. the first problem in the code is reported, with a
+// 1-based line and 0-based column like the Node validator.
+func (v *StaticValidator) Validate(ctx context.Context, code string) (*ValidationResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(code) == "" {
+		return &ValidationResult{Valid: false, Error: "Empty or invalid code"}, nil
+	}
+
+	problems := CheckProgram(ParseProgram(code))
+	if len(problems) == 0 {
+		return &ValidationResult{Valid: true}, nil
+	}
+
+	first := problems[0]
+	line, column := first.Pos.Line, first.Pos.Column-1
+
+	return &ValidationResult{
+		Valid:  false,
+		Error:  first.Message,
+		Line:   &line,
+		Column: &column,
+	}, nil
+}
+
+// nothing to release, the validator runs in process
+func (v *StaticValidator) Close() error {
+	return nil
+}
+
+// always true, there is no process to wait for
+func (v *StaticValidator) IsReady() bool {
+	return true
+}
+
+type programChecker struct {
+	program    *Program
+	declared   map[string]bool       // functions, variables and parameters declared in the code
+	registered map[string]bool       // pattern methods defined with register('name', ...)
+	bindings   map[string]Expression // variable values, to tell which receivers are patterns
+	skipped    map[*StringLiteral]bool
+	problems   []*SyntaxError
+}
+
+// returns the problems in a parsed program ordered by position: syntax errors,
+// invalid mini-notation, unknown functions and wrong argument counts
+func CheckProgram(program *Program) []*SyntaxError {
+	c := &programChecker{
+		program:    program,
+		declared:   make(map[string]bool),
+		registered: make(map[string]bool),
+		bindings:   make(map[string]Expression),
+		skipped:    make(map[*StringLiteral]bool),
+	}
+
+	c.problems = append(c.problems, program.Errors...)
+	c.collectDeclarations()
+
+	program.Walk(func(node Node) bool {
+		switch n := node.(type) {
+		case *CallExpr:
+			c.checkCall(n)
+		case *StringLiteral:
+			c.checkMini(n)
+		}
+
+		return true
+	})
+
+	slices.SortStableFunc(c.problems, func(a, b *SyntaxError) int {
+		return a.Pos.Offset - b.Pos.Offset
+	})
+
+	return c.problems
+}
+
+// records declared names and their values. scopes are ignored, a parameter
+// named like a function anywhere in the code makes calls to it valid.
+// register('name', fn) declares a function that also works as a method.
+func (c *programChecker) collectDeclarations() {
+	c.program.Walk(func(node Node) bool {
+		switch n := node.(type) {
+		case *Declarator:
+			c.declared[n.Name.Name] = true
+
+			if n.Value != nil {
+				c.bindings[n.Name.Name] = n.Value
+			}
+
+		case *FuncDecl:
+			c.declared[n.Name.Name] = true
+
+		case *FuncExpr:
+			if n.Name != nil {
+				c.declared[n.Name.Name] = true
+			}
+
+		case *Param:
+			c.declared[n.Name.Name] = true
+
+		case *AssignExpr:
+			if target, ok := n.Target.(*Identifier); ok {
+				c.declared[target.Name] = true
+			}
+
+		case *CallExpr:
+			callee, ok := n.Callee.(*Identifier)
+			if !ok || callee.Name != "register" || len(n.Args) == 0 {
+				break
+			}
+
+			if name, ok := n.Args[0].(*StringLiteral); ok {
+				c.declared[name.Value] = true
+				c.registered[name.Value] = true
+			}
+		}
+
+		return true
+	})
+}
+
+func (c *programChecker) checkCall(call *CallExpr) {
+	switch callee := call.Callee.(type) {
+	case *Identifier:
+		name := callee.Name
+
+		if slices.Contains(nonPatternFunctions, name) {
+			c.skipStrings(call)
+		}
+
+		if c.declared[name] || call.New || slices.Contains(javaScriptGlobals, name) {
+			return
+		}
+
+		if spec, ok := globalFunctions[name]; ok {
+			c.checkArgs(call, callee, spec)
+			return
+		}
+
+		spec, ok := functionCatalog[name]
+		if !ok {
+			c.unknownFunction(callee)
+			return
+		}
+
+		// pattern functions may take the pattern as an extra argument: fast(2, "bd sd")
+		if spec.maxArgs >= 0 {
+			spec.maxArgs++
+		}
+
+		c.checkArgs(call, callee, functionSpec{minArgs: 0, maxArgs: spec.maxArgs})
+
+	case *MemberExpr:
+		if object, ok := callee.Object.(*Identifier); ok && slices.Contains(javaScriptGlobals, object.Name) {
+			c.skipStrings(call)
+			return
+		}
+
+		if !c.isPattern(callee.Object, 0) || c.registered[callee.Property.Name] {
+			return
+		}
+
+		spec, ok := functionCatalog[callee.Property.Name]
+		if !ok {
+			c.unknownFunction(callee.Property)
+			return
+		}
+
+		c.checkArgs(call, callee.Property, spec)
+	}
+}
+
+// reports whether an expression evaluates to a pattern, following variables
+func (c *programChecker) isPattern(expr Expression, depth int) bool {
+	if depth > maxBindingDepth {
+		return false
+	}
+
+	switch x := expr.(type) {
+	case *StringLiteral:
+		return true
+
+	case *Identifier:
+		if value, ok := c.bindings[x.Name]; ok {
+			return c.isPattern(value, depth+1)
+		}
+
+		return !c.declared[x.Name] && slices.Contains(signalNames, x.Name)
+
+	case *CallExpr:
+		switch callee := x.Callee.(type) {
+		case *Identifier:
+			_, known := functionCatalog[callee.Name]
+			return known && !c.declared[callee.Name] && !slices.Contains(nonPatternFunctions, callee.Name)
+
+		case *MemberExpr:
+			_, known := functionCatalog[callee.Property.Name]
+			return known && c.isPattern(callee.Object, depth+1)
+		}
+	}
+
+	return false
+}
+
+// checks the mini-notation of a double quoted or template string
+func (c *programChecker) checkMini(str *StringLiteral) {
+	if c.skipped[str] || str.Quote == '\'' || (str.Quote == '`' && strings.Contains(str.Value, "${")) {
+		return
+	}
+
+	_, err := ParseMini(str.Value)
+	if err == nil {
+		return
+	}
+
+	syntaxErr, ok := err.(*SyntaxError)
+	if !ok {
+		return
+	}
+
+	c.problems = append(c.problems, &SyntaxError{
+		Message: syntaxErr.Message,
+		Pos:     stringPos(c.program.Source, str, syntaxErr.Pos),
+	})
+}
+
+// maps a position in a string's value to the source. strings with escape
+// sequences map to the opening quote, their value and source differ.
+func stringPos(source string, str *StringLiteral, pos Pos) Pos {
+	start, end := str.Start.Offset+1, str.End.Offset-1
+	if end < start || end > len(source) || source[start:end] != str.Value {
+		return str.Start
+	}
+
+	return advancePos(advancePos(str.Start, source[str.Start.Offset:start]), str.Value[:pos.Offset])
+}
+
+// excludes the strings passed to a call from mini-notation checks
+func (c *programChecker) skipStrings(call *CallExpr) {
+	for _, arg := range call.Args {
+		Walk(arg, func(node Node) bool {
+			if str, ok := node.(*StringLiteral); ok {
+				c.skipped[str] = true
+			}
+
+			return true
+		})
+	}
+}
+
+func (c *programChecker) unknownFunction(name *Identifier) {
+	message := fmt.Sprintf("unknown function %q", name.Name)
+
+	if suggestion := suggestFunction(name.Name); suggestion != "" {
+		message += fmt.Sprintf(", did you mean %q?", suggestion)
+	}
+
+	c.problems = append(c.problems, &SyntaxError{Message: message, Pos: name.Start})
+}
+
+// reports a call whose argument count is outside spec
+func (c *programChecker) checkArgs(call *CallExpr, name *Identifier, spec functionSpec) {
+	args := countArgs(call)
+	if args < 0 || (args >= spec.minArgs && (spec.maxArgs < 0 || args <= spec.maxArgs)) {
+		return
+	}
+
+	c.problems = append(c.problems, &SyntaxError{
+		Message: fmt.Sprintf("%s() takes %s, got %d", name.Name, describeArgs(spec.minArgs, spec.maxArgs), args),
+		Pos:     name.Start,
+	})
+}
+
+// returns the number of arguments of a call, or -1 when a spread makes it unknown
+func countArgs(call *CallExpr) int {
+	for _, arg := range call.Args {
+		if _, ok := arg.(*SpreadExpr); ok {
+			return -1
+		}
+	}
+
+	return len(call.Args)
+}
+
+// describes an argument count range: "1 argument", "1 to 2 arguments", "at least 1 argument"
+func describeArgs(minArgs, maxArgs int) string {
+	plural := func(n int) string {
+		if n == 1 {
+			return "1 argument"
+		}
+
+		return fmt.Sprintf("%d arguments", n)
+	}
+
+	switch {
+	case maxArgs < 0:
+		return "at least " + plural(minArgs)
+	case minArgs == maxArgs:
+		return plural(maxArgs)
+	case minArgs == 0:
+		return "at most " + plural(maxArgs)
+	default:
+		return fmt.Sprintf("%d to %s", minArgs, plural(maxArgs))
+	}
+}
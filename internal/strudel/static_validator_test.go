@@ -0,0 +1,113 @@
+package strudel
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestStaticValidator_ValidCode(t *testing.T) {
+	v := NewStaticValidator()
+
+	tests := []struct {
+		name string
+		code string
+	}{
+		{"simple sound", `sound("bd sd")`},
+		{"sound with effect", `sound("bd sd").fast(2)`},
+		{"note pattern", `note("c3 e3 g3").sound("piano")`},
+		{"stack pattern", `stack(sound("bd*4"), sound("hh*8"))`},
+		{"with dollar prefix", `$: sound("bd*4")`},
+		{"signals and arrow functions", `note("c e").s("sine").lpf(sine.range(300, 2000).slow(8)).often(x => x.ply(2))`},
+		{"pattern in a variable", "const drums = s(\"bd sd\")\ndrums.jux(rev).room(.5)"},
+		{"pattern passed first", `stack(s("bd"), s("hh")).every(4, fast(2))`},
+		{"sample urls are not mini-notation", `samples('github:tidalcycles/dirt-samples', "https://example.com/[")`},
+		{"user functions", "function swap(pat) { return pat.rev() }\nswap(s(\"bd sd\")).fast(2)"},
+		{"methods on other objects", `s("bd").gain(Math.floor(1.5)).color(colors.pick(2))`},
+		{"registered functions", "register('wobble', (rate, pat) => pat.lpf(sine.range(200, 2000).fast(rate)))\ns(\"bd sd\").wobble(2)\nwobble(4, note(\"c e\"))"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := v.Validate(context.Background(), tt.code)
+			if err != nil {
+				t.Fatalf("validation error: %v", err)
+			}
+
+			if !result.Valid {
+				t.Errorf("expected valid, got error: %s", result.Error)
+			}
+		})
+	}
+}
+
+func TestStaticValidator_InvalidCode(t *testing.T) {
+	v := NewStaticValidator()
+
+	tests := []struct {
+		name    string
+		code    string
+		message string
+		line    int
+		column  int
+	}{
+		{"missing closing paren", `sound("bd sd"`, "expected", 1, 13},
+		{"unclosed mini-notation bracket", `sound("[bd sd")`, "mini-notation: expected ']'", 1, 13},
+		{"mini-notation on a later line", "s(\"bd\")\n  .note(\"c <e g\")", "mini-notation", 2, 15},
+		{"unknown method", `s("bd sd").fastt(2)`, `unknown function "fastt", did you mean "fast"?`, 1, 11},
+		{"unknown function", `stak(s("bd"), s("hh"))`, `unknown function "stak", did you mean "stack"?`, 1, 0},
+		{"missing argument", `s("bd").fast()`, "fast() takes 1 argument, got 0", 1, 8},
+		{"too many arguments", `s("bd").lpf(1000, 2)`, "lpf() takes at most 1 argument, got 2", 1, 8},
+		{"first problem wins", `s("bd").gian(1).fast()`, `unknown function "gian"`, 1, 8},
+		{"empty code", "  ", "Empty or invalid code", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := v.Validate(context.Background(), tt.code)
+			if err != nil {
+				t.Fatalf("validation error: %v", err)
+			}
+
+			if result.Valid {
+				t.Fatal("expected invalid, got valid")
+			}
+
+			if !strings.Contains(result.Error, tt.message) {
+				t.Errorf("error = %q, want it to contain %q", result.Error, tt.message)
+			}
+
+			if tt.line == 0 {
+				return
+			}
+
+			if result.Line == nil || result.Column == nil {
+				t.Fatalf("expected a position, got line %v column %v", result.Line, result.Column)
+			}
+
+			if *result.Line != tt.line || *result.Column != tt.column {
+				t.Errorf("position = %d:%d, want %d:%d", *result.Line, *result.Column, tt.line, tt.column)
+			}
+		})
+	}
+}
+
+func TestSuggestFunction(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"fastt", "fast"},
+		{"colour", "color"},
+		{"Stack", "stack"},
+		{"xyzzyplugh", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := suggestFunction(tt.name); got != tt.expected {
+				t.Errorf("suggestFunction(%q) = %q, want %q", tt.name, got, tt.expected)
+			}
+		})
+	}
+}
@@ -1,5 +1,12 @@
 package strudel
 
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
 type CodeAnalysis struct {
 	SoundTags      []string // ["drums", "synth", "bass"]
 	EffectTags     []string // ["delay", "reverb", "filter"]
@@ -50,6 +57,8 @@ type ParsedCode struct {
 	Variables []string       // variable names: ["pat1", "rhythm"]
 	Scales    []string       // scale/mode names: ["minor", "dorian"]
 	Patterns  map[string]int // pattern counts: {"stack": 2, "arrange": 1}
+
+	NotePatterns []*MiniNode // parsed note() patterns
 }
 
 type KeywordOptions struct {
@@ -60,3 +69,331 @@ type KeywordOptions struct {
 	IncludeScales    bool // include scale names (default: true)
 	Deduplicate      bool // remove duplicates (default: true)
 }
+
+// a location in source code. line and column are 1-based, column counts characters.
+type Pos struct {
+	Offset int // byte offset
+	Line   int
+	Column int
+}
+
+// the source span of a token or node, end exclusive
+type Range struct {
+	Start Pos
+	End   Pos
+}
+
+type TokenKind int
+
+const (
+	TokenEOF TokenKind = iota
+	TokenIdent
+	TokenNumber
+	TokenString   // single or double quoted
+	TokenTemplate // backtick quoted
+	TokenPunct
+	TokenComment
+)
+
+type Token struct {
+	Kind          TokenKind
+	Text          string // source text, including quotes and comment markers
+	Value         string // unescaped content for strings and templates, otherwise Text
+	Range         Range
+	NewlineBefore bool // a line break precedes the token, used for automatic semicolons
+}
+
+// a syntax error in JavaScript or mini-notation
+type SyntaxError struct {
+	Message string
+	Pos     Pos
+}
+
+// parsed JavaScript of the subset Strudel code uses
+type Program struct {
+	Statements []Statement
+	Comments   []Token
+	Errors     []*SyntaxError // parsing continues after an error with the next statement
+	Source     string
+}
+
+type Node interface {
+	Span() Range
+}
+
+type Statement interface {
+	Node
+	statementNode()
+}
+
+type Expression interface {
+	Node
+	expressionNode()
+}
+
+// let, const or var with one or more declarators
+type VarDecl struct {
+	Range
+	Kind         string
+	Declarations []*Declarator
+}
+
+type Declarator struct {
+	Range
+	Name  *Identifier
+	Value Expression // nil without initializer
+}
+
+type FuncDecl struct {
+	Range
+	Name   *Identifier
+	Params []*Param
+	Body   *BlockStmt
+}
+
+type ExprStmt struct {
+	Range
+	Expr Expression
+}
+
+// a labeled statement, e.g. a $: pattern line
+type LabeledStmt struct {
+	Range
+	Label *Identifier
+	Body  Statement
+}
+
+type BlockStmt struct {
+	Range
+	Statements []Statement
+}
+
+type ReturnStmt struct {
+	Range
+	Value Expression // nil for a bare return
+}
+
+type IfStmt struct {
+	Range
+	Cond Expression
+	Then Statement
+	Else Statement // nil without else
+}
+
+type Identifier struct {
+	Range
+	Name string
+}
+
+type NumberLiteral struct {
+	Range
+	Value string
+}
+
+// a quoted or template string. double quoted and template strings are mini-notation in Strudel.
+type StringLiteral struct {
+	Range
+	Value string
+	Quote byte // '"', '\'' or '`'
+}
+
+type ArrayLiteral struct {
+	Range
+	Elements []Expression
+}
+
+type ObjectLiteral struct {
+	Range
+	Properties []*Property
+}
+
+type Property struct {
+	Range
+	Key   string
+	Value Expression // the key identifier for shorthand properties
+}
+
+// a spread element: ...args
+type SpreadExpr struct {
+	Range
+	Value Expression
+}
+
+type CallExpr struct {
+	Range
+	Callee Expression
+	Args   []Expression
+	New    bool // called with new
+}
+
+// property access: object.name or object?.name
+type MemberExpr struct {
+	Range
+	Object   Expression
+	Property *Identifier
+	Optional bool
+}
+
+type IndexExpr struct {
+	Range
+	Object Expression
+	Index  Expression
+}
+
+type UnaryExpr struct {
+	Range
+	Op      string
+	Operand Expression
+	Postfix bool // x++ and x--
+}
+
+type BinaryExpr struct {
+	Range
+	Op    string
+	Left  Expression
+	Right Expression
+}
+
+type AssignExpr struct {
+	Range
+	Op     string
+	Target Expression
+	Value  Expression
+}
+
+type ConditionalExpr struct {
+	Range
+	Cond Expression
+	Then Expression
+	Else Expression
+}
+
+type Param struct {
+	Range
+	Name    *Identifier
+	Default Expression // nil without default
+	Rest    bool
+}
+
+// an arrow function or function expression. Body is a *BlockStmt or an Expression.
+type FuncExpr struct {
+	Range
+	Name   *Identifier // nil for anonymous functions
+	Params []*Param
+	Body   Node
+	Arrow  bool
+}
+
+type MiniKind int
+
+const (
+	MiniSequence  MiniKind = iota // space separated steps: bd sd
+	MiniStack                     // comma separated layers: bd, hh
+	MiniRandom                    // | separated choices: bd | sd
+	MiniAlternate                 // one step per cycle: <bd sd>
+	MiniPolymeter                 // aligned steps: {bd sd, hh hh hh}
+	MiniAtom                      // a word, number or note: bd:3, eb4, 0.5
+	MiniRest                      // ~ or -
+	MiniHold                      // _ extends the previous step
+	MiniRange                     // 0 .. 3
+)
+
+// a node of parsed mini-notation. ranges are relative to the mini-notation source.
+type MiniNode struct {
+	Kind      MiniKind
+	Value     string // atom text
+	Children  []*MiniNode
+	Modifiers []*MiniModifier
+	Range     Range
+}
+
+// an operator applied to a mini-notation step, e.g. *2, /4, !, @3, ?, %4 or (3,8)
+type MiniModifier struct {
+	Op    string // "*", "/", "!", "@", "?", "%" or "(" for euclidean rhythms
+	Args  []*MiniNode
+	Range Range
+}
+
+// checks Strudel code before it reaches the user. implemented by the Node
+// subprocess Validator and the in-process StaticValidator.
+type CodeValidator interface {
+	Validate(ctx context.Context, code string) (*ValidationResult, error)
+	Close() error
+}
+
+// validates Strudel code in process, without Node
+type StaticValidator struct{}
+
+type PoolOptions struct {
+	Workers        int           // validator processes, default 2
+	RequestTimeout time.Duration // per validation, a worker that takes longer is respawned. default 5s
+	HealthInterval time.Duration // between pings of idle workers, default 30s
+}
+
+// a snapshot of validator pool activity
+type PoolStats struct {
+	Workers     int    `json:"workers"`
+	Ready       int    `json:"ready"`
+	Idle        int    `json:"idle"`
+	QueueDepth  int64  `json:"queue_depth"` // requests waiting for a free worker
+	InFlight    int64  `json:"in_flight"`
+	Validations uint64 `json:"validations"`
+	Timeouts    uint64 `json:"timeouts"`
+	Restarts    uint64 `json:"restarts"`
+}
+
+// dispatches validations to several Node validator processes, replacing
+// workers that crash, hang or stop answering health checks
+type ValidatorPool struct {
+	workers []*Validator
+	idle    chan *Validator
+	options PoolOptions
+
+	queued      atomic.Int64
+	inFlight    atomic.Int64
+	validations atomic.Uint64
+	timeouts    atomic.Uint64
+	restarts    atomic.Uint64
+
+	mu     sync.Mutex // serializes restarts with Close
+	closed bool
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// the arguments a function takes when called as a method on a pattern.
+// called as a global function the pattern comes first: s("bd") or fast(2, "bd").
+type functionSpec struct {
+	minArgs int
+	maxArgs int // -1 for any number
+}
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+)
+
+// a likely musical mistake found by Lint
+type Finding struct {
+	Rule     string // "unknown-sound", "note-outside-scale", "effect-range", "dense-fast" or "unused-variable"
+	Severity Severity
+	Message  string
+	Range    Range
+	Fix      *Fix // nil when there is no automatic fix
+}
+
+// an edit that resolves a finding
+type Fix struct {
+	Description string
+	Range       Range
+	Replacement string
+}
+
+// the usable values of an effect argument
+type valueRange struct {
+	min float64
+	max float64
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -15,6 +16,8 @@ import (
 	"time"
 )
 
+var errValidationTimeout = errors.New("validation timeout")
+
 type ValidationResult struct {
 	Valid  bool   `json:"valid"`
 	Error  string `json:"error,omitempty"`
@@ -38,14 +41,21 @@ type validatorResponse struct {
 	Pong   bool   `json:"pong,omitempty"`
 }
 
+// the answer to a request, read from stdout in the background
+type validatorResult struct {
+	resp *validatorResponse
+	err  error
+}
+
 type Validator struct {
 	cmd       *exec.Cmd
 	stdin     io.WriteCloser
 	stdout    *bufio.Scanner
 	mu        sync.Mutex
 	requestID atomic.Uint64
-	ready     bool
+	ready     atomic.Bool
 	scriptDir string
+	abandoned chan validatorResult // answer to a request nobody waited for, read before the next one
 }
 
 // creates a new Strudel code validator.
@@ -110,88 +120,157 @@ func (v *Validator) start() error {
 		return fmt.Errorf("expected ready signal, got: %+v", resp)
 	}
 
-	v.ready = true
+	v.ready.Store(true)
 	return nil
 }
 
 // checks if Strudel code is syntactically valid.
 func (v *Validator) Validate(ctx context.Context, code string) (*ValidationResult, error) {
+	resp, err := v.request(ctx, validatorRequest{Type: "validate", Code: code})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ValidationResult{
+		Valid:  resp.Valid,
+		Error:  resp.Error,
+		Line:   resp.Line,
+		Column: resp.Column,
+	}, nil
+}
+
+// checks that the validator process answers requests.
+func (v *Validator) Ping(ctx context.Context) error {
+	resp, err := v.request(ctx, validatorRequest{Type: "ping"})
+	if err != nil {
+		return err
+	}
+
+	if !resp.Pong {
+		return fmt.Errorf("expected pong, got: %+v", resp)
+	}
+
+	return nil
+}
+
+// sends a request and waits for its response
+func (v *Validator) request(ctx context.Context, req validatorRequest) (*validatorResponse, error) {
 	v.mu.Lock()
 	defer v.mu.Unlock()
 
-	if !v.ready {
+	if !v.ready.Load() {
 		return nil, fmt.Errorf("validator not ready")
 	}
 
-	id := fmt.Sprintf("%d", v.requestID.Add(1))
-
-	req := validatorRequest{
-		Type: "validate",
-		ID:   id,
-		Code: code,
+	if v.abandoned != nil {
+		if err := v.awaitAbandoned(ctx); err != nil {
+			return nil, err
+		}
 	}
 
+	req.ID = fmt.Sprintf("%d", v.requestID.Add(1))
+
 	reqBytes, err := json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	if _, err := v.stdin.Write(append(reqBytes, '\n')); err != nil {
+		v.ready.Store(false)
 		return nil, fmt.Errorf("failed to write to validator: %w", err)
 	}
 
 	// read response with timeout
-	type result struct {
-		resp *validatorResponse
-		err  error
-	}
-	done := make(chan result, 1)
+	done := make(chan validatorResult, 1)
 
 	go func() {
 		resp, err := v.readResponse()
-		done <- result{resp, err}
+		done <- validatorResult{resp, err}
 	}()
 
 	select {
 	case <-ctx.Done():
+		v.abandoned = done
 		return nil, ctx.Err()
 	case <-time.After(5 * time.Second):
-		return nil, fmt.Errorf("validation timeout")
+		v.abandoned = done
+		return nil, errValidationTimeout
 	case r := <-done:
 		if r.err != nil {
+			v.ready.Store(false)
 			return nil, r.err
 		}
-		if r.resp.ID != id {
-			return nil, fmt.Errorf("response ID mismatch: expected %s, got %s", id, r.resp.ID)
+		if r.resp.ID != req.ID {
+			return nil, fmt.Errorf("response ID mismatch: expected %s, got %s", req.ID, r.resp.ID)
 		}
-		return &ValidationResult{
-			Valid:  r.resp.Valid,
-			Error:  r.resp.Error,
-			Line:   r.resp.Line,
-			Column: r.resp.Column,
-		}, nil
+		return r.resp, nil
 	}
 }
 
+// waits for the answer to an abandoned request so it is not taken for the
+// answer to the next one
+func (v *Validator) awaitAbandoned(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(5 * time.Second):
+		return errValidationTimeout
+	case r := <-v.abandoned:
+		v.abandoned = nil
+
+		if r.err != nil {
+			v.ready.Store(false)
+			return r.err
+		}
+
+		return nil
+	}
+}
+
+// kills the validator process and starts a new one.
+func (v *Validator) Restart() error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.stop()
+
+	return v.start()
+}
+
 // shuts down validator process.
 func (v *Validator) Close() error {
 	v.mu.Lock()
 	defer v.mu.Unlock()
 
+	return v.stop()
+}
+
+// closes stdin and kills the process, reaping it so restarts leave no zombies
+func (v *Validator) stop() error {
+	v.ready.Store(false)
+	v.abandoned = nil
+
 	if v.stdin != nil {
 		v.stdin.Close() //nolint:errcheck,gosec // best-effort cleanup
 	}
 
-	if v.cmd != nil && v.cmd.Process != nil {
-		return v.cmd.Process.Kill()
+	if v.cmd == nil || v.cmd.Process == nil {
+		return nil
 	}
 
-	return nil
+	err := v.cmd.Process.Kill()
+	v.cmd.Wait() //nolint:errcheck,gosec // the process was killed, its exit status is expected to be an error
+
+	if errors.Is(err, os.ErrProcessDone) {
+		return nil
+	}
+
+	return err
 }
 
 // returns whether validator is ready to accept requests.
 func (v *Validator) IsReady() bool {
-	return v.ready
+	return v.ready.Load()
 }
 
 // reads the next JSON response from stdout, skipping non-JSON lines.
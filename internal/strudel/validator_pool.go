@@ -0,0 +1,233 @@
+package strudel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	defaultPoolWorkers    = 2
+	defaultRequestTimeout = 5 * time.Second
+	defaultHealthInterval = 30 * time.Second
+)
+
+// starts a pool of validator processes from the validate-strudel script
+// directory. it fails if any worker cannot start.
+func NewValidatorPool(scriptDir string, options PoolOptions) (*ValidatorPool, error) {
+	if options.Workers <= 0 {
+		options.Workers = defaultPoolWorkers
+	}
+
+	if options.RequestTimeout <= 0 {
+		options.RequestTimeout = defaultRequestTimeout
+	}
+
+	if options.HealthInterval <= 0 {
+		options.HealthInterval = defaultHealthInterval
+	}
+
+	p := &ValidatorPool{
+		idle:    make(chan *Validator, options.Workers),
+		options: options,
+		done:    make(chan struct{}),
+	}
+
+	for i := 0; i < options.Workers; i++ {
+		worker, err := NewValidator(scriptDir)
+		if err != nil {
+			p.Close() //nolint:errcheck,gosec // best-effort cleanup of started workers
+			return nil, fmt.Errorf("failed to start validator worker %d: %w", i+1, err)
+		}
+
+		p.workers = append(p.workers, worker)
+		p.idle <- worker
+	}
+
+	p.wg.Add(1)
+	go p.healthLoop()
+
+	return p, nil
+}
+
+// validates code on the next free worker. a worker that fails or exceeds
+// the request timeout is killed and respawned before it takes new requests,
+// one whose caller cancels goes back to the pool as is.
+func (p *ValidatorPool) Validate(ctx context.Context, code string) (*ValidationResult, error) {
+	p.queued.Add(1)
+
+	var worker *Validator
+	select {
+	case worker = <-p.idle:
+		p.queued.Add(-1)
+	case <-ctx.Done():
+		p.queued.Add(-1)
+		return nil, ctx.Err()
+	case <-p.done:
+		p.queued.Add(-1)
+		return nil, fmt.Errorf("validator pool closed")
+	}
+
+	p.inFlight.Add(1)
+	defer p.inFlight.Add(-1)
+
+	if !worker.IsReady() {
+		if err := p.restart(worker); err != nil {
+			p.idle <- worker
+			return nil, err
+		}
+	}
+
+	requestCtx, cancel := context.WithTimeout(ctx, p.options.RequestTimeout)
+	defer cancel()
+
+	result, err := worker.Validate(requestCtx, code)
+	p.validations.Add(1)
+
+	if err == nil {
+		p.idle <- worker
+		return result, nil
+	}
+
+	// the worker reads the abandoned answer before its next request
+	if errors.Is(err, context.Canceled) {
+		p.idle <- worker
+		return nil, fmt.Errorf("failed to validate: %w", err)
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, errValidationTimeout) {
+		p.timeouts.Add(1)
+	}
+
+	// the worker may still answer the abandoned request, so it is replaced
+	// in the background and rejoins the pool once it is ready
+	go func() {
+		p.restart(worker) //nolint:errcheck,gosec // a failed worker is restarted again when next used
+		p.idle <- worker
+	}()
+
+	return nil, fmt.Errorf("failed to validate: %w", err)
+}
+
+// pings idle workers every health interval and restarts those that do not answer
+func (p *ValidatorPool) healthLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.options.HealthInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.checkIdleWorkers()
+		}
+	}
+}
+
+func (p *ValidatorPool) checkIdleWorkers() {
+	checked := make(map[*Validator]bool, len(p.workers))
+
+	for range p.workers {
+		var worker *Validator
+		select {
+		case worker = <-p.idle:
+		default:
+			return
+		}
+
+		if checked[worker] {
+			p.idle <- worker
+			return
+		}
+
+		checked[worker] = true
+
+		ctx, cancel := context.WithTimeout(context.Background(), p.options.RequestTimeout)
+		err := worker.Ping(ctx)
+		cancel()
+
+		if err != nil {
+			p.restart(worker) //nolint:errcheck,gosec // retried on the next health check or request
+		}
+
+		p.idle <- worker
+	}
+}
+
+// replaces a worker's process unless the pool is closed
+func (p *ValidatorPool) restart(worker *Validator) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return fmt.Errorf("validator pool closed")
+	}
+
+	p.restarts.Add(1)
+
+	if err := worker.Restart(); err != nil {
+		return fmt.Errorf("failed to restart validator worker: %w", err)
+	}
+
+	return nil
+}
+
+// returns current pool activity
+func (p *ValidatorPool) Stats() PoolStats {
+	stats := PoolStats{
+		Workers:     len(p.workers),
+		Idle:        len(p.idle),
+		QueueDepth:  p.queued.Load(),
+		InFlight:    p.inFlight.Load(),
+		Validations: p.validations.Load(),
+		Timeouts:    p.timeouts.Load(),
+		Restarts:    p.restarts.Load(),
+	}
+
+	for _, worker := range p.workers {
+		if worker.IsReady() {
+			stats.Ready++
+		}
+	}
+
+	return stats
+}
+
+// returns whether any worker is ready to accept requests
+func (p *ValidatorPool) IsReady() bool {
+	for _, worker := range p.workers {
+		if worker.IsReady() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// stops health checks and shuts down all workers
+func (p *ValidatorPool) Close() error {
+	p.mu.Lock()
+
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+
+	p.closed = true
+	close(p.done)
+
+	var errs []error
+	for _, worker := range p.workers {
+		if err := worker.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	p.mu.Unlock()
+	p.wg.Wait()
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,175 @@
+package strudel
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// a stand-in for validate-strudel that speaks its protocol: every code is
+// valid except "hang", which never gets an answer, "crash", which exits, and
+// "slow", which is answered after 300ms
+const fakeValidatorScript = `
+const readline = require('readline');
+console.log(JSON.stringify({ ready: true }));
+const rl = readline.createInterface({ input: process.stdin, terminal: false });
+rl.on('line', line => {
+  const request = JSON.parse(line);
+  if (request.type === 'ping') {
+    console.log(JSON.stringify({ id: request.id, pong: true }));
+  } else if (request.code === 'crash') {
+    process.exit(1);
+  } else if (request.code === 'slow') {
+    setTimeout(() => console.log(JSON.stringify({ id: request.id, valid: true })), 300);
+  } else if (request.code !== 'hang') {
+    console.log(JSON.stringify({ id: request.id, valid: true }));
+  }
+});
+`
+
+func newFakeValidatorPool(t *testing.T, options PoolOptions) *ValidatorPool {
+	t.Helper()
+
+	if _, err := exec.LookPath("node"); err != nil {
+		t.Skip("node not installed")
+	}
+
+	scriptDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(scriptDir, "validator.js"), []byte(fakeValidatorScript), 0o600); err != nil {
+		t.Fatalf("failed to write validator script: %v", err)
+	}
+
+	pool, err := NewValidatorPool(scriptDir, options)
+	if err != nil {
+		t.Fatalf("failed to create validator pool: %v", err)
+	}
+
+	t.Cleanup(func() {
+		pool.Close() //nolint:errcheck,gosec // cleanup in test
+	})
+
+	return pool
+}
+
+func TestValidatorPool_ConcurrentValidation(t *testing.T) {
+	pool := newFakeValidatorPool(t, PoolOptions{Workers: 3})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			result, err := pool.Validate(context.Background(), `s("bd")`)
+			if err == nil && !result.Valid {
+				t.Errorf("expected valid result")
+			}
+
+			errs <- err
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("validation error: %v", err)
+		}
+	}
+
+	stats := pool.Stats()
+	if stats.Workers != 3 || stats.Validations != 20 || stats.QueueDepth != 0 || stats.InFlight != 0 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestValidatorPool_RespawnsHungWorker(t *testing.T) {
+	pool := newFakeValidatorPool(t, PoolOptions{Workers: 1, RequestTimeout: 200 * time.Millisecond})
+
+	if _, err := pool.Validate(context.Background(), "hang"); err == nil {
+		t.Fatal("expected a timeout error")
+	}
+
+	result, err := pool.Validate(context.Background(), `s("bd")`)
+	if err != nil {
+		t.Fatalf("validation after respawn failed: %v", err)
+	}
+
+	if !result.Valid {
+		t.Error("expected valid result after respawn")
+	}
+
+	stats := pool.Stats()
+	if stats.Timeouts != 1 || stats.Restarts != 1 || stats.Ready != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestValidatorPool_KeepsWorkerOnCancel(t *testing.T) {
+	pool := newFakeValidatorPool(t, PoolOptions{Workers: 1})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	if _, err := pool.Validate(ctx, "slow"); err == nil {
+		t.Fatal("expected a cancellation error")
+	}
+
+	// the late answer to "slow" must not be taken for this one
+	for _, code := range []string{`s("bd")`, `s("hh")`} {
+		result, err := pool.Validate(context.Background(), code)
+		if err != nil {
+			t.Fatalf("validation after cancel failed: %v", err)
+		}
+
+		if !result.Valid {
+			t.Error("expected valid result after cancel")
+		}
+	}
+
+	stats := pool.Stats()
+	if stats.Restarts != 0 || stats.Ready != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestValidatorPool_RestartsCrashedWorker(t *testing.T) {
+	pool := newFakeValidatorPool(t, PoolOptions{Workers: 1, HealthInterval: 50 * time.Millisecond})
+
+	if _, err := pool.Validate(context.Background(), "crash"); err == nil {
+		t.Fatal("expected an error from the crashed worker")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !pool.IsReady() {
+		if time.Now().After(deadline) {
+			t.Fatal("worker was not restarted")
+		}
+
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	if _, err := pool.Validate(context.Background(), `s("bd")`); err != nil {
+		t.Errorf("validation after restart failed: %v", err)
+	}
+}
+
+func TestValidatorPool_ClosedPool(t *testing.T) {
+	pool := newFakeValidatorPool(t, PoolOptions{Workers: 1})
+
+	if err := pool.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	if _, err := pool.Validate(context.Background(), `s("bd")`); err == nil {
+		t.Error("expected an error from a closed pool")
+	}
+}
@@ -0,0 +1,124 @@
+package strudel
+
+import "reflect"
+
+func (r Range) Span() Range { return r }
+
+func (*VarDecl) statementNode()     {}
+func (*FuncDecl) statementNode()    {}
+func (*ExprStmt) statementNode()    {}
+func (*LabeledStmt) statementNode() {}
+func (*BlockStmt) statementNode()   {}
+func (*ReturnStmt) statementNode()  {}
+func (*IfStmt) statementNode()      {}
+
+func (*Identifier) expressionNode()      {}
+func (*NumberLiteral) expressionNode()   {}
+func (*StringLiteral) expressionNode()   {}
+func (*ArrayLiteral) expressionNode()    {}
+func (*ObjectLiteral) expressionNode()   {}
+func (*SpreadExpr) expressionNode()      {}
+func (*CallExpr) expressionNode()        {}
+func (*MemberExpr) expressionNode()      {}
+func (*IndexExpr) expressionNode()       {}
+func (*UnaryExpr) expressionNode()       {}
+func (*BinaryExpr) expressionNode()      {}
+func (*AssignExpr) expressionNode()      {}
+func (*ConditionalExpr) expressionNode() {}
+func (*FuncExpr) expressionNode()        {}
+
+// visits every statement of the program in source order
+func (p *Program) Walk(visit func(Node) bool) {
+	for _, statement := range p.Statements {
+		Walk(statement, visit)
+	}
+}
+
+// visits node and its descendants depth-first in source order. children are
+// skipped when visit returns false.
+func Walk(node Node, visit func(Node) bool) {
+	if isNil(node) || !visit(node) {
+		return
+	}
+
+	var children []Node
+
+	switch n := node.(type) {
+	case *VarDecl:
+		for _, declarator := range n.Declarations {
+			children = append(children, declarator)
+		}
+	case *Declarator:
+		children = []Node{n.Name, n.Value}
+	case *FuncDecl:
+		children = []Node{n.Name}
+		for _, param := range n.Params {
+			children = append(children, param)
+		}
+		children = append(children, n.Body)
+	case *ExprStmt:
+		children = []Node{n.Expr}
+	case *LabeledStmt:
+		children = []Node{n.Label, n.Body}
+	case *BlockStmt:
+		for _, statement := range n.Statements {
+			children = append(children, statement)
+		}
+	case *ReturnStmt:
+		children = []Node{n.Value}
+	case *IfStmt:
+		children = []Node{n.Cond, n.Then, n.Else}
+	case *ArrayLiteral:
+		for _, element := range n.Elements {
+			children = append(children, element)
+		}
+	case *ObjectLiteral:
+		for _, property := range n.Properties {
+			children = append(children, property)
+		}
+	case *Property:
+		children = []Node{n.Value}
+	case *SpreadExpr:
+		children = []Node{n.Value}
+	case *CallExpr:
+		children = []Node{n.Callee}
+		for _, arg := range n.Args {
+			children = append(children, arg)
+		}
+	case *MemberExpr:
+		children = []Node{n.Object, n.Property}
+	case *IndexExpr:
+		children = []Node{n.Object, n.Index}
+	case *UnaryExpr:
+		children = []Node{n.Operand}
+	case *BinaryExpr:
+		children = []Node{n.Left, n.Right}
+	case *AssignExpr:
+		children = []Node{n.Target, n.Value}
+	case *ConditionalExpr:
+		children = []Node{n.Cond, n.Then, n.Else}
+	case *Param:
+		children = []Node{n.Name, n.Default}
+	case *FuncExpr:
+		children = []Node{n.Name}
+		for _, param := range n.Params {
+			children = append(children, param)
+		}
+		children = append(children, n.Body)
+	}
+
+	for _, child := range children {
+		Walk(child, visit)
+	}
+}
+
+// reports whether node is nil, including typed nil pointers held in an interface
+func isNil(node Node) bool {
+	if node == nil {
+		return true
+	}
+
+	v := reflect.ValueOf(node)
+
+	return v.Kind() == reflect.Pointer && v.IsNil()
+}
@@ -0,0 +1,426 @@
+package websocket
+
+import (
+	"context"
+	"time"
+
+	"codeberg.org/algopatterns/server/internal/logger"
+)
+
+// message types that edit the session document and must run on its owning instance
+var documentMessageTypes = map[string]bool{
+	TypeCodeUpdate: true,
+	TypeCodeOps:    true,
+}
+
+// fans broadcasts, presence, sequences and connection limits out through a backplane.
+// must be called before Run.
+func (h *Hub) WithBackplane(backplane Backplane) *Hub {
+	h.backplane = backplane
+	return h
+}
+
+// subscribes to events from other instances (called from Run)
+func (h *Hub) startBackplane() {
+	if h.backplane == nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h.backplaneCancel = cancel
+
+	go func() {
+		if err := h.backplane.Subscribe(ctx, h.handleBackplaneEvent); err != nil && ctx.Err() == nil {
+			logger.ErrorErr(err, "backplane subscription ended")
+		}
+	}()
+}
+
+// stops the subscription and releases this instance's cluster state
+func (h *Hub) stopBackplane() {
+	if h.backplane == nil {
+		return
+	}
+
+	if h.backplaneCancel != nil {
+		h.backplaneCancel()
+	}
+
+	if err := h.backplane.Close(); err != nil {
+		logger.ErrorErr(err, "failed to close backplane")
+	}
+}
+
+// sends an event to other instances, logging failures
+func (h *Hub) publish(event *BackplaneEvent) {
+	if h.backplane == nil {
+		return
+	}
+
+	event.Origin = h.backplane.InstanceID()
+
+	ctx, cancel := context.WithTimeout(context.Background(), backplaneTimeout)
+	defer cancel()
+
+	if err := h.backplane.Publish(ctx, event); err != nil {
+		logger.ErrorErr(err, "failed to publish backplane event",
+			"kind", event.Kind,
+			"session_id", event.SessionID,
+		)
+	}
+}
+
+// returns the next cluster-wide sequence number for a session, or false without
+// a backplane or when it fails. called without the hub lock, it is a round trip.
+func (h *Hub) backplaneSequence(sessionID string) (uint64, bool) {
+	if h.backplane == nil {
+		return 0, false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), backplaneTimeout)
+	defer cancel()
+
+	seq, err := h.backplane.NextSequence(ctx, sessionID)
+	if err != nil {
+		logger.ErrorErr(err, "failed to get backplane sequence, using local counter",
+			"session_id", sessionID,
+		)
+		return 0, false
+	}
+
+	return seq, true
+}
+
+// returns the next local sequence number for a session (must be called with lock held)
+func (h *Hub) localSequence(sessionID string) uint64 {
+	h.sessionSequences[sessionID]++
+	return h.sessionSequences[sessionID]
+}
+
+// adjusts cluster-wide connection counts, logging failures
+func (h *Hub) adjustConnections(userID, ipAddress string, delta int) {
+	if h.backplane == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), backplaneTimeout)
+	defer cancel()
+
+	if err := h.backplane.AdjustConnections(ctx, userID, ipAddress, delta); err != nil {
+		logger.ErrorErr(err, "failed to adjust backplane connection counts")
+	}
+}
+
+// records a client in cluster-wide presence and returns everyone in the session.
+// returns nil without a backplane or when it fails (called without the hub lock).
+func (h *Hub) backplaneParticipants(client *Client) []SessionStateParticipant {
+	if h.backplane == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), backplaneTimeout)
+	defer cancel()
+
+	participant := SessionStateParticipant{
+		UserID:      client.UserID,
+		DisplayName: client.DisplayName,
+		Role:        client.Role,
+	}
+
+	err := h.backplane.AddPresence(ctx, client.SessionID, client.ID, participant)
+	if err == nil {
+		participants, err := h.backplane.Presence(ctx, client.SessionID)
+		if err == nil {
+			return participants
+		}
+	}
+
+	logger.ErrorErr(err, "failed to read backplane presence, using local clients",
+		"session_id", client.SessionID,
+	)
+
+	return nil
+}
+
+// returns the clients in a session on this instance (must be called with lock held)
+func (h *Hub) localParticipants(sessionID string) []SessionStateParticipant {
+	participants := make([]SessionStateParticipant, 0)
+
+	for _, c := range h.sessions[sessionID] {
+		participants = append(participants, SessionStateParticipant{
+			UserID:      c.UserID,
+			DisplayName: c.DisplayName,
+			Role:        c.Role,
+		})
+	}
+
+	return participants
+}
+
+// removes a client from cluster-wide presence
+func (h *Hub) removePresence(client *Client) {
+	if h.backplane == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), backplaneTimeout)
+	defer cancel()
+
+	if err := h.backplane.RemovePresence(ctx, client.SessionID, client.ID); err != nil {
+		logger.ErrorErr(err, "failed to remove backplane presence",
+			"client_id", client.ID,
+			"session_id", client.SessionID,
+		)
+	}
+}
+
+// returns the stored document of a session, which the owning instance may have
+// advanced past the local one. false without a backplane, when none is stored
+// or when loading fails (called without the hub lock).
+func (h *Hub) backplaneDocument(sessionID string) (string, uint64, bool) {
+	if h.backplane == nil {
+		return "", 0, false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), backplaneTimeout)
+	defer cancel()
+
+	code, revision, found, err := h.backplane.LoadDocument(ctx, sessionID)
+	if err != nil {
+		logger.ErrorErr(err, "failed to load backplane document", "session_id", sessionID)
+		return "", 0, false
+	}
+
+	return code, revision, found
+}
+
+// stores a committed document so other instances can snapshot it
+func (h *Hub) storeDocument(sessionID string, commit *DocumentCommit) {
+	if h.backplane == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), backplaneTimeout)
+	defer cancel()
+
+	if err := h.backplane.StoreDocument(ctx, sessionID, commit.Code, commit.Revision); err != nil {
+		logger.ErrorErr(err, "failed to store backplane document", "session_id", sessionID)
+	}
+}
+
+// forwards document edits to the instance owning the session document.
+// returns false when the message should be handled locally.
+func (h *Hub) forwardToOwner(sender *Client, msg *Message) bool {
+	if h.backplane == nil || !documentMessageTypes[msg.Type] {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), backplaneTimeout)
+	defer cancel()
+
+	owner, acquired, err := h.backplane.ClaimSession(ctx, msg.SessionID)
+	if err != nil {
+		logger.ErrorErr(err, "failed to claim session document, handling locally",
+			"session_id", msg.SessionID,
+		)
+		return false
+	}
+
+	if owner == h.backplane.InstanceID() {
+		if acquired {
+			h.reloadDocument(ctx, msg.SessionID, sender.InitialCode)
+		}
+		return false
+	}
+
+	// rate limits live with the real client, so enforce them before forwarding
+	if !sender.checkCodeUpdateRateLimit() {
+		sender.SendError("too_many_requests", "too many code updates. maximum 10 per second.", "")
+		return true
+	}
+
+	h.publish(&BackplaneEvent{
+		Kind:      backplaneForward,
+		Target:    owner,
+		SessionID: msg.SessionID,
+		Message:   msg,
+		Client: &RemoteClient{
+			ID:              sender.ID,
+			UserID:          sender.UserID,
+			DisplayName:     sender.DisplayName,
+			Role:            sender.Role,
+			IsAuthenticated: sender.IsAuthenticated,
+			InitialCode:     sender.InitialCode,
+		},
+	})
+
+	return true
+}
+
+// loads the latest stored document after this instance takes ownership of a session
+func (h *Hub) reloadDocument(ctx context.Context, sessionID, fallbackCode string) *Document {
+	code, revision, found, err := h.backplane.LoadDocument(ctx, sessionID)
+	if err != nil {
+		logger.ErrorErr(err, "failed to load backplane document", "session_id", sessionID)
+	}
+
+	if !found {
+		code, revision = fallbackCode, 0
+	}
+
+	h.mu.Lock()
+	doc, exists := h.documents[sessionID]
+	if !exists {
+		doc = newDocumentAt(code, revision)
+		h.documents[sessionID] = doc
+	}
+	h.mu.Unlock()
+
+	if exists {
+		doc.reset(code, revision)
+	}
+
+	return doc
+}
+
+// processes an event published by another instance
+func (h *Hub) handleBackplaneEvent(event *BackplaneEvent) {
+	if event.Target != "" && event.Target != h.backplane.InstanceID() {
+		return
+	}
+
+	switch event.Kind {
+	case backplaneBroadcast:
+		if event.Message == nil {
+			return
+		}
+
+		h.mu.Lock()
+		h.deliverToSession(event.SessionID, event.Message, event.ExcludeClientID, event.WritersOnly)
+		h.recordReplay(event.SessionID, event.Message, event.ExcludeClientID, event.WritersOnly)
+		h.mu.Unlock()
+
+	case backplaneEndSession:
+		h.endLocalSession(event.SessionID, event.Reason)
+
+	case backplaneForward:
+		go h.handleForward(event)
+
+	case backplaneDirect:
+		h.deliverDirect(event)
+	}
+}
+
+// runs a forwarded document edit on this (owning) instance
+func (h *Hub) handleForward(event *BackplaneEvent) {
+	if event.Message == nil || event.Client == nil {
+		return
+	}
+
+	h.mu.RLock()
+	handler, exists := h.handlers[event.Message.Type]
+	h.mu.RUnlock()
+
+	if !exists {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), backplaneTimeout)
+	owner, acquired, err := h.backplane.ClaimSession(ctx, event.SessionID)
+	if err == nil && owner == h.backplane.InstanceID() && (acquired || h.SessionDocument(event.SessionID) == nil) {
+		h.reloadDocument(ctx, event.SessionID, event.Client.InitialCode)
+	}
+	cancel()
+
+	// a stand-in client whose replies are relayed back to the origin instance
+	proxy := &Client{
+		ID:              event.Client.ID,
+		SessionID:       event.SessionID,
+		UserID:          event.Client.UserID,
+		DisplayName:     event.Client.DisplayName,
+		Role:            event.Client.Role,
+		IsAuthenticated: event.Client.IsAuthenticated,
+		InitialCode:     event.Client.InitialCode,
+		hub:             h,
+		send:            make(chan []byte, 16),
+	}
+
+	msg := event.Message
+	msg.ClientID = proxy.ID
+
+	if err := handler(h, proxy, msg); err != nil {
+		logger.ErrorErr(err, "forwarded handler error",
+			"message_type", msg.Type,
+			"client_id", proxy.ID,
+			"session_id", msg.SessionID,
+		)
+
+		proxy.SendError("server_error", "failed to process message", err.Error())
+	}
+
+	frames := make([][]byte, 0, len(proxy.send))
+	for len(proxy.send) > 0 {
+		frames = append(frames, <-proxy.send)
+	}
+
+	if len(frames) == 0 {
+		return
+	}
+
+	h.publish(&BackplaneEvent{
+		Kind:      backplaneDirect,
+		Target:    event.Origin,
+		SessionID: event.SessionID,
+		ClientID:  proxy.ID,
+		Frames:    frames,
+	})
+}
+
+// writes relayed frames to a local client
+func (h *Hub) deliverDirect(event *BackplaneEvent) {
+	h.mu.RLock()
+	client, exists := h.sessions[event.SessionID][event.ClientID]
+	h.mu.RUnlock()
+
+	if !exists {
+		return
+	}
+
+	for _, frame := range event.Frames {
+		if err := client.sendRaw(frame); err != nil {
+			logger.ErrorErr(err, "failed to relay message to client",
+				"client_id", client.ID,
+				"session_id", event.SessionID,
+			)
+			return
+		}
+	}
+}
+
+// drops documents that have no local clients and no recent edits.
+// their ownership has lapsed by then, so another instance may take over.
+func (h *Hub) evictIdleDocuments() {
+	cutoff := time.Now().Add(-sessionOwnerTTL)
+
+	// collect candidates first, documents are never locked while holding the hub lock
+	h.mu.RLock()
+	candidates := make(map[string]*Document)
+	for sessionID, doc := range h.documents {
+		if len(h.sessions[sessionID]) == 0 {
+			candidates[sessionID] = doc
+		}
+	}
+	h.mu.RUnlock()
+
+	for sessionID, doc := range candidates {
+		if !doc.idleSince(cutoff) {
+			continue
+		}
+
+		h.mu.Lock()
+		if h.documents[sessionID] == doc && len(h.sessions[sessionID]) == 0 {
+			delete(h.documents, sessionID)
+		}
+		h.mu.Unlock()
+	}
+}
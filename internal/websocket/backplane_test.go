@@ -0,0 +1,309 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"codeberg.org/algopatterns/server/algopatterns/sessions"
+)
+
+// records saved code, every other repository method is unused
+type codeRecordingRepo struct {
+	sessions.Repository
+	saved chan string
+}
+
+func (r *codeRecordingRepo) UpdateSessionCode(_ context.Context, _, code string) error {
+	r.saved <- code
+	return nil
+}
+
+// starts two hubs sharing an in-memory backplane network
+func newBackplaneHubs(t *testing.T) (*Hub, *Hub) {
+	t.Helper()
+
+	network := NewMemoryBackplaneNetwork()
+	hub1 := NewHub().WithBackplane(network.Join("instance-1"))
+	hub2 := NewHub().WithBackplane(network.Join("instance-2"))
+
+	go hub1.Run()
+	go hub2.Run()
+
+	t.Cleanup(func() {
+		hub1.Shutdown()
+		hub2.Shutdown()
+	})
+
+	return hub1, hub2
+}
+
+func newBackplaneClient(hub *Hub, id, userID, role string) *Client {
+	return &Client{
+		ID:          id,
+		SessionID:   "session-1",
+		UserID:      userID,
+		DisplayName: id,
+		Role:        role,
+		InitialCode: `s("bd sd")`,
+		hub:         hub,
+		send:        make(chan []byte, 256),
+	}
+}
+
+// waits for the next message of the given type, skipping others
+func receiveType(t *testing.T, client *Client, messageType string) *Message {
+	t.Helper()
+
+	timeout := time.After(time.Second)
+
+	for {
+		select {
+		case raw := <-client.send:
+			var msg Message
+			require.NoError(t, json.Unmarshal(raw, &msg))
+
+			if msg.Type == messageType {
+				return &msg
+			}
+		case <-timeout:
+			t.Fatalf("%s did not receive %s", client.ID, messageType)
+			return nil
+		}
+	}
+}
+
+func TestBackplaneBroadcastAcrossInstances(t *testing.T) {
+	hub1, hub2 := newBackplaneHubs(t)
+
+	host := newBackplaneClient(hub1, "client-1", "user-1", "host")
+	hub1.Register <- host
+	receiveType(t, host, TypeSessionState)
+
+	guest := newBackplaneClient(hub2, "client-2", "user-2", "viewer")
+	hub2.Register <- guest
+
+	// the guest sees participants from both instances
+	var state SessionStatePayload
+	require.NoError(t, receiveType(t, guest, TypeSessionState).UnmarshalPayload(&state))
+	assert.Len(t, state.Participants, 2)
+
+	// the host hears about a guest joining another instance
+	var joined UserJoinedPayload
+	require.NoError(t, receiveType(t, host, TypeUserJoined).UnmarshalPayload(&joined))
+	assert.Equal(t, "user-2", joined.UserID)
+
+	chat, err := NewMessage(TypeChatMessage, "session-1", "user-1", ChatMessagePayload{Message: "hello"})
+	require.NoError(t, err)
+	hub1.BroadcastToSession("session-1", chat, host.ID)
+
+	received := receiveType(t, guest, TypeChatMessage)
+	assert.Equal(t, chat.Sequence, received.Sequence)
+
+	// writers-only broadcasts skip the remote viewer
+	hub1.BroadcastToWriters("session-1", chat, host.ID)
+	hub1.BroadcastToSession("session-1", chat, host.ID)
+
+	next := receiveType(t, guest, TypeChatMessage)
+	assert.Greater(t, next.Sequence, received.Sequence+1, "writers-only message should not reach a viewer")
+
+	// leaving is broadcast to the other instance
+	hub2.Unregister <- guest
+	receiveType(t, host, TypeUserLeft)
+}
+
+func TestBackplaneSequencesAreClusterWide(t *testing.T) {
+	hub1, hub2 := newBackplaneHubs(t)
+
+	client1 := newBackplaneClient(hub1, "client-1", "user-1", "host")
+	client2 := newBackplaneClient(hub2, "client-2", "user-2", "co-author")
+	hub1.Register <- client1
+	hub2.Register <- client2
+	receiveType(t, client1, TypeSessionState)
+	receiveType(t, client2, TypeSessionState)
+
+	msg1, err := NewMessage(TypePlay, "session-1", "user-1", nil)
+	require.NoError(t, err)
+	hub1.BroadcastToSession("session-1", msg1, "")
+
+	msg2, err := NewMessage(TypeStop, "session-1", "user-2", nil)
+	require.NoError(t, err)
+	hub2.BroadcastToSession("session-1", msg2, "")
+
+	play := receiveType(t, client2, TypePlay)
+	stop := receiveType(t, client1, TypeStop)
+
+	assert.NotEqual(t, play.Sequence, stop.Sequence)
+}
+
+func TestBackplaneEndSessionReachesAllInstances(t *testing.T) {
+	hub1, hub2 := newBackplaneHubs(t)
+
+	host := newBackplaneClient(hub1, "client-1", "user-1", "host")
+	guest := newBackplaneClient(hub2, "client-2", "user-2", "viewer")
+	hub1.Register <- host
+	hub2.Register <- guest
+	receiveType(t, guest, TypeSessionState)
+
+	hub1.EndSession("session-1", "host ended the session")
+
+	var ended SessionEndedPayload
+	require.NoError(t, receiveType(t, guest, TypeSessionEnded).UnmarshalPayload(&ended))
+	assert.Equal(t, "host ended the session", ended.Reason)
+
+	assert.Eventually(t, func() bool {
+		return !hub2.IsSessionActive("session-1")
+	}, time.Second, 20*time.Millisecond)
+}
+
+func TestBackplaneConnectionLimitsAreClusterWide(t *testing.T) {
+	// connection tracking does not need the hub loops running
+	network := NewMemoryBackplaneNetwork()
+	hub1 := NewHub().WithBackplane(network.Join("instance-1"))
+	hub2 := NewHub().WithBackplane(network.Join("instance-2"))
+
+	for range maxConnectionsPerIP {
+		hub1.TrackIPConnection("10.0.0.1")
+	}
+
+	allowed, reason := hub2.CanAcceptConnection("", "10.0.0.1")
+	assert.False(t, allowed)
+	assert.Equal(t, "Maximum connections per IP address exceeded", reason)
+
+	hub1.UntrackIPConnection("10.0.0.1")
+
+	allowed, _ = hub2.CanAcceptConnection("", "10.0.0.1")
+	assert.True(t, allowed)
+}
+
+func TestBackplaneForwardsCodeOpsToOwner(t *testing.T) {
+	hub1, hub2 := newBackplaneHubs(t)
+
+	repo := &codeRecordingRepo{saved: make(chan string, 16)}
+	hub1.RegisterHandler(TypeCodeOps, CodeOpsHandler(repo, nil))
+	hub2.RegisterHandler(TypeCodeOps, CodeOpsHandler(repo, nil))
+
+	host := newBackplaneClient(hub1, "client-1", "user-1", "host")
+	guest := newBackplaneClient(hub2, "client-2", "user-2", "co-author")
+	hub1.Register <- host
+	hub2.Register <- guest
+	receiveType(t, host, TypeSessionState)
+	receiveType(t, guest, TypeSessionState)
+
+	send := func(hub *Hub, client *Client, payload CodeOpsPayload) {
+		msg, err := NewMessage(TypeCodeOps, "session-1", client.UserID, payload)
+		require.NoError(t, err)
+		msg.ClientID = client.ID
+		hub.Broadcast <- msg
+	}
+
+	// the first edit makes hub1 the document owner
+	send(hub1, host, CodeOpsPayload{Revision: 0, Changes: []TextChange{{From: 3, To: 3, Insert: "cp "}}})
+
+	var ack CodeOpsAckPayload
+	require.NoError(t, receiveType(t, host, TypeCodeOpsAck).UnmarshalPayload(&ack))
+	assert.Equal(t, uint64(1), ack.Revision)
+	receiveType(t, guest, TypeCodeOps)
+
+	// a concurrent edit from the other instance is transformed by the owner
+	send(hub2, guest, CodeOpsPayload{Revision: 0, Changes: []TextChange{{From: 10, To: 10, Insert: ".fast(2)"}}})
+
+	require.NoError(t, receiveType(t, guest, TypeCodeOpsAck).UnmarshalPayload(&ack))
+	assert.Equal(t, uint64(2), ack.Revision)
+
+	var ops CodeOpsPayload
+	require.NoError(t, receiveType(t, host, TypeCodeOps).UnmarshalPayload(&ops))
+	assert.Equal(t, []TextChange{{From: 13, To: 13, Insert: ".fast(2)"}}, ops.Changes)
+
+	code, revision := hub1.SessionDocument("session-1").Snapshot()
+	assert.Equal(t, `s("cp bd sd").fast(2)`, code)
+	assert.Equal(t, uint64(2), revision)
+
+	// a late joiner on the non-owning instance starts from the owner's document
+	late := newBackplaneClient(hub2, "client-3", "user-3", "viewer")
+	hub2.Register <- late
+
+	var state SessionStatePayload
+	require.NoError(t, receiveType(t, late, TypeSessionState).UnmarshalPayload(&state))
+	assert.Equal(t, `s("cp bd sd").fast(2)`, state.Code)
+	assert.Equal(t, uint64(2), state.Revision)
+}
+
+// a backplane whose sequence requests for one session block until released
+type stalledSequenceBackplane struct {
+	Backplane
+	stalledSession string
+	release        chan struct{}
+}
+
+func (b *stalledSequenceBackplane) NextSequence(ctx context.Context, sessionID string) (uint64, error) {
+	if sessionID == b.stalledSession {
+		<-b.release
+	}
+
+	return b.Backplane.NextSequence(ctx, sessionID)
+}
+
+func TestBackplaneSlowSequenceDoesNotBlockOtherSessions(t *testing.T) {
+	backplane := &stalledSequenceBackplane{
+		Backplane:      NewMemoryBackplaneNetwork().Join("instance-1"),
+		stalledSession: "session-stalled",
+		release:        make(chan struct{}),
+	}
+
+	hub := NewHub().WithBackplane(backplane)
+	go hub.Run()
+	t.Cleanup(hub.Shutdown)
+
+	client := newBackplaneClient(hub, "client-1", "user-1", "host")
+	hub.Register <- client
+	receiveType(t, client, TypeSessionState)
+
+	stalled, err := NewMessage(TypeChatMessage, "session-stalled", "user-2", ChatMessagePayload{Message: "stalled"})
+	require.NoError(t, err)
+
+	go hub.BroadcastToSession("session-stalled", stalled, "")
+	defer close(backplane.release)
+
+	// give the stalled broadcast time to reach the backplane
+	time.Sleep(50 * time.Millisecond)
+
+	msg, err := NewMessage(TypeChatMessage, "session-1", "user-2", ChatMessagePayload{Message: "hello"})
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		hub.BroadcastToSession("session-1", msg, "")
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("broadcast blocked behind another session's backplane round trip")
+	}
+
+	var chat ChatMessagePayload
+	require.NoError(t, receiveType(t, client, TypeChatMessage).UnmarshalPayload(&chat))
+	assert.Equal(t, "hello", chat.Message)
+}
+
+func TestBackplaneBroadcastToRemoteSessionKeepsNoLock(t *testing.T) {
+	hub := NewHub().WithBackplane(NewMemoryBackplaneNetwork().Join("instance-1"))
+
+	// a session whose clients are all on other instances
+	for i := 0; i < 3; i++ {
+		msg, err := NewMessage(TypeChatMessage, "session-remote", "user-1", ChatMessagePayload{Message: "hello"})
+		require.NoError(t, err)
+		hub.BroadcastToSession("session-remote", msg, "")
+	}
+
+	hub.mu.RLock()
+	defer hub.mu.RUnlock()
+
+	assert.Empty(t, hub.broadcastLocks)
+}
@@ -164,6 +164,29 @@ func (c *Client) Send(msg *Message) (err error) {
 	}
 }
 
+// queues an already-marshaled message, used for messages relayed from other instances
+func (c *Client) sendRaw(messageBytes []byte) (err error) {
+	// recover from panic if channel is closed
+	defer func() {
+		if r := recover(); r != nil {
+			err = ErrConnectionClosed
+		}
+	}()
+
+	if c.IsClosed() {
+		return ErrConnectionClosed
+	}
+
+	select {
+	case c.send <- messageBytes:
+		return nil
+	default:
+		c.sendBufferOverflowError()
+		c.Close()
+		return ErrConnectionClosed
+	}
+}
+
 // sends buffer overflow error directly to websocket (bypassing the full channel)
 func (c *Client) sendBufferOverflowError() {
 	errorMsg, err := NewMessage(TypeError, c.SessionID, c.UserID, map[string]string{
@@ -180,6 +203,11 @@ func (c *Client) sendBufferOverflowError() {
 		return
 	}
 
+	// clients relayed from another instance have no connection of their own
+	if c.conn == nil {
+		return
+	}
+
 	// write directly to websocket with short deadline
 	c.conn.SetWriteDeadline(time.Now().Add(2 * time.Second)) //nolint:errcheck,gosec
 	c.conn.WriteMessage(websocket.TextMessage, errorBytes)   //nolint:errcheck,gosec
@@ -0,0 +1,136 @@
+package websocket
+
+import (
+	"fmt"
+	"time"
+)
+
+// creates a new authoritative document for a session at revision 0
+func NewDocument(code string) *Document {
+	return newDocumentAt(code, 0)
+}
+
+// creates a document that continues from a revision committed elsewhere.
+// history starts empty, so clients behind that revision are resynced.
+func newDocumentAt(code string, revision uint64) *Document {
+	return &Document{
+		text:     []rune(code),
+		revision: revision,
+		history:  make([]*textOperation, 0),
+		lastUsed: time.Now(),
+	}
+}
+
+// returns the current code and revision
+func (d *Document) Snapshot() (string, uint64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return string(d.text), d.revision
+}
+
+// applies changes a client made against baseRevision.
+// the changes are transformed over every operation committed since baseRevision,
+// then commit is called with the result while the document is still locked so that
+// persistence and broadcasts happen in revision order.
+func (d *Document) ApplyChanges(baseRevision uint64, changes []TextChange, commit func(*DocumentCommit)) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if baseRevision > d.revision {
+		return fmt.Errorf("%w: revision %d is ahead of document revision %d", ErrInvalidOperation, baseRevision, d.revision)
+	}
+
+	historyStart := d.revision - uint64(len(d.history))
+	if baseRevision < historyStart {
+		return ErrRevisionTooOld
+	}
+
+	// changes are relative to the document at baseRevision, so rebuild its length
+	baseLen := len(d.text)
+	for i := len(d.history) - 1; i >= int(baseRevision-historyStart); i-- {
+		baseLen += d.history[i].baseLen - d.history[i].targetLen
+	}
+
+	op, err := operationFromChanges(changes, baseLen)
+	if err != nil {
+		return err
+	}
+
+	for _, concurrent := range d.history[baseRevision-historyStart:] {
+		op, _, err = transformOperations(op, concurrent)
+		if err != nil {
+			return err
+		}
+	}
+
+	return d.commit(op, commit)
+}
+
+// resets the document to a revision committed elsewhere, dropping history
+func (d *Document) reset(code string, revision uint64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.text = []rune(code)
+	d.revision = revision
+	d.history = d.history[:0]
+	d.lastUsed = time.Now()
+}
+
+// reports whether no operation has been committed since the cutoff
+func (d *Document) idleSince(cutoff time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.lastUsed.Before(cutoff)
+}
+
+// replaces the whole document, used for full-buffer code_update messages
+func (d *Document) Replace(code string, commit func(*DocumentCommit)) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.commit(replacementOperation(d.text, []rune(code)), commit)
+}
+
+// applies a transformed operation and records it (must be called with lock held)
+func (d *Document) commit(op *textOperation, commit func(*DocumentCommit)) error {
+	text, err := op.apply(d.text)
+	if err != nil {
+		return err
+	}
+
+	code := string(text)
+	if len(code) > maxCodeSize {
+		return ErrCodeTooLarge
+	}
+
+	result := &DocumentCommit{
+		Revision:     d.revision,
+		Changes:      op.changes(),
+		PreviousCode: string(d.text),
+		Code:         code,
+	}
+
+	d.lastUsed = time.Now()
+
+	// a no-op still gets acknowledged, but does not advance the revision
+	if !op.isNoop() {
+		d.text = text
+		d.revision++
+		d.history = append(d.history, op)
+
+		if len(d.history) > maxDocumentHistory {
+			d.history = d.history[len(d.history)-maxDocumentHistory:]
+		}
+
+		result.Revision = d.revision
+	}
+
+	if commit != nil {
+		commit(result)
+	}
+
+	return nil
+}
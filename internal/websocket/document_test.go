@@ -0,0 +1,152 @@
+package websocket
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocumentApplyChanges(t *testing.T) {
+	doc := NewDocument(`s("bd sd")`)
+
+	var commit *DocumentCommit
+	err := doc.ApplyChanges(0, []TextChange{{From: 10, To: 10, Insert: ".fast(2)"}}, func(c *DocumentCommit) {
+		commit = c
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, uint64(1), commit.Revision)
+	assert.Equal(t, `s("bd sd")`, commit.PreviousCode)
+	assert.Equal(t, `s("bd sd").fast(2)`, commit.Code)
+
+	code, revision := doc.Snapshot()
+	assert.Equal(t, `s("bd sd").fast(2)`, code)
+	assert.Equal(t, uint64(1), revision)
+}
+
+func TestDocumentConcurrentChangesAreTransformed(t *testing.T) {
+	doc := NewDocument(`s("bd sd")`)
+
+	// both clients edit revision 0 without seeing each other's change
+	require.NoError(t, doc.ApplyChanges(0, []TextChange{{From: 3, To: 3, Insert: "cp "}}, nil))
+
+	var commit *DocumentCommit
+	err := doc.ApplyChanges(0, []TextChange{{From: 10, To: 10, Insert: ".fast(2)"}}, func(c *DocumentCommit) {
+		commit = c
+	})
+	require.NoError(t, err)
+
+	// the second change is rebased past the first insert
+	assert.Equal(t, []TextChange{{From: 13, To: 13, Insert: ".fast(2)"}}, commit.Changes)
+	assert.Equal(t, uint64(2), commit.Revision)
+
+	code, _ := doc.Snapshot()
+	assert.Equal(t, `s("cp bd sd").fast(2)`, code)
+}
+
+func TestDocumentReplaceKeepsConcurrentOps(t *testing.T) {
+	doc := NewDocument(`s("bd sd")`)
+
+	// a full-buffer code_update changes the start of the pattern
+	require.NoError(t, doc.Replace(`s("hh sd")`, nil))
+
+	// a code_ops client that has not seen the replace appends at the end
+	require.NoError(t, doc.ApplyChanges(0, []TextChange{{From: 10, To: 10, Insert: ".slow(2)"}}, nil))
+
+	code, revision := doc.Snapshot()
+	assert.Equal(t, `s("hh sd").slow(2)`, code)
+	assert.Equal(t, uint64(2), revision)
+}
+
+func TestDocumentRejectsInvalidRevisions(t *testing.T) {
+	doc := NewDocument("")
+
+	err := doc.ApplyChanges(5, []TextChange{{From: 0, To: 0, Insert: "x"}}, nil)
+	assert.ErrorIs(t, err, ErrInvalidOperation)
+
+	for i := range maxDocumentHistory + 1 {
+		require.NoError(t, doc.ApplyChanges(uint64(i), []TextChange{{From: i, To: i, Insert: "x"}}, nil))
+	}
+
+	// revision 0 has fallen out of the history window
+	err = doc.ApplyChanges(0, []TextChange{{From: 0, To: 0, Insert: "y"}}, nil)
+	assert.ErrorIs(t, err, ErrRevisionTooOld)
+
+	// the oldest revision still in history can be rebased
+	err = doc.ApplyChanges(1, []TextChange{{From: 0, To: 0, Insert: "y"}}, nil)
+	assert.NoError(t, err)
+}
+
+func TestDocumentRejectsOversizedCode(t *testing.T) {
+	doc := NewDocument("")
+
+	large := make([]byte, maxCodeSize+1)
+	for i := range large {
+		large[i] = 'a'
+	}
+
+	err := doc.ApplyChanges(0, []TextChange{{From: 0, To: 0, Insert: string(large)}}, nil)
+	assert.ErrorIs(t, err, ErrCodeTooLarge)
+
+	_, revision := doc.Snapshot()
+	assert.Equal(t, uint64(0), revision)
+}
+
+func TestHubSessionStateIncludesDocumentRevision(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+	defer hub.Shutdown()
+
+	host := &Client{
+		ID:          "client-1",
+		SessionID:   "session-1",
+		DisplayName: "Host",
+		Role:        "host",
+		InitialCode: `s("bd")`,
+		hub:         hub,
+		send:        make(chan []byte, 256),
+	}
+
+	hub.Register <- host
+	time.Sleep(50 * time.Millisecond)
+
+	doc := hub.SessionDocument("session-1")
+	require.NotNil(t, doc)
+	require.NoError(t, doc.ApplyChanges(0, []TextChange{{From: 7, To: 7, Insert: ".fast(2)"}}, nil))
+
+	// a late joiner gets the materialized document, not its stale initial code
+	guest := &Client{
+		ID:          "client-2",
+		SessionID:   "session-1",
+		DisplayName: "Guest",
+		Role:        "co-author",
+		InitialCode: `s("bd")`,
+		hub:         hub,
+		send:        make(chan []byte, 256),
+	}
+
+	hub.Register <- guest
+
+	select {
+	case raw := <-guest.send:
+		var msg Message
+		require.NoError(t, json.Unmarshal(raw, &msg))
+		require.Equal(t, TypeSessionState, msg.Type)
+
+		var state SessionStatePayload
+		require.NoError(t, msg.UnmarshalPayload(&state))
+		assert.Equal(t, `s("bd").fast(2)`, state.Code)
+		assert.Equal(t, uint64(1), state.Revision)
+	case <-time.After(time.Second):
+		t.Fatal("guest did not receive session_state")
+	}
+
+	// the document is dropped once the session empties
+	hub.Unregister <- host
+	hub.Unregister <- guest
+	time.Sleep(50 * time.Millisecond)
+	assert.Nil(t, hub.SessionDocument("session-1"))
+}
@@ -2,6 +2,7 @@ package websocket
 
 import (
 	"context"
+	"errors"
 	"strings"
 	"time"
 
@@ -42,63 +43,232 @@ func CodeUpdateHandler(sessionRepo sessions.Repository, detector *ccsignals.Dete
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 
-		// get previous code for paste detection
-		session, err := sessionRepo.GetSession(ctx, client.SessionID)
-		previousCode := ""
-		if err == nil && session != nil {
-			previousCode = session.Code
-		}
-
-		// use ccsignals detector for paste detection
-		// only check for 'paste' or 'typed' sources (skip loaded_strudel, forked)
-		shouldCheckPaste := payload.Source == "" || payload.Source == "typed" || payload.Source == "paste"
-		if detector != nil {
-			if shouldCheckPaste {
-				handlePasteDetection(ctx, hub, client, detector, previousCode, payload.Code)
-			} else if payload.Source == "loaded_strudel" {
-				// clear any existing paste lock when loading a new/fresh strudel
-				wasLocked, err := detector.IsLocked(ctx, client.SessionID)
-				if err != nil {
-					logger.ErrorErr(err, "failed to check lock status on strudel load", "session_id", client.SessionID)
-				} else if wasLocked {
-					if err := detector.RemoveLock(ctx, client.SessionID); err != nil {
-						logger.ErrorErr(err, "failed to clear paste lock on strudel load", "session_id", client.SessionID)
-					} else {
-						logger.Info("paste lock cleared on strudel load", "session_id", client.SessionID)
-						sendPasteLockStatus(hub, client, false, "new_strudel")
-					}
-				}
-			}
-		}
-
-		// save code (goes to redis buffer via BufferedRepository)
-		if err := sessionRepo.UpdateSessionCode(ctx, client.SessionID, payload.Code); err != nil {
-			logger.ErrorErr(err, "failed to save code",
-				"client_id", client.ID,
-				"session_id", client.SessionID,
-			)
-			// don't fail the request, broadcast still happens
-		}
-
 		// enrich payload with sender information for cursor tracking
 		payload.DisplayName = client.DisplayName
 		payload.UserID = client.UserID
 		payload.Role = client.Role
 
-		// create new message with updated payload
-		broadcastMsg, err := NewMessage(TypeCodeUpdate, client.SessionID, client.UserID, payload)
+		// route through the session document so code_ops clients can rebase onto it
+		doc := hub.SessionDocument(client.SessionID)
+		if doc == nil {
+			// get previous code for paste detection
+			session, err := sessionRepo.GetSession(ctx, client.SessionID)
+			previousCode := ""
+			if err == nil && session != nil {
+				previousCode = session.Code
+			}
+
+			checkPasteSignals(ctx, hub, client, detector, payload.Source, previousCode, payload.Code)
+			saveCode(ctx, sessionRepo, client, payload.Code)
+
+			return broadcastCodeUpdate(hub, client, payload)
+		}
+
+		var commit *DocumentCommit
+		var broadcastErr error
+
+		err := doc.Replace(payload.Code, func(c *DocumentCommit) {
+			commit = c
+			payload.Revision = c.Revision
+
+			// save code (goes to redis buffer via BufferedRepository)
+			saveCode(ctx, sessionRepo, client, c.Code)
+			hub.storeDocument(client.SessionID, c)
+			broadcastErr = broadcastCodeUpdate(hub, client, payload)
+		})
 		if err != nil {
-			logger.ErrorErr(err, "failed to create broadcast message",
-				"client_id", client.ID,
-				"session_id", client.SessionID,
-			)
+			client.SendError("bad_request", "failed to apply code update", err.Error())
 			return err
 		}
 
-		// broadcast to all other clients in the session
-		hub.BroadcastToSession(client.SessionID, broadcastMsg, client.ID)
+		checkPasteSignals(ctx, hub, client, detector, payload.Source, commit.PreviousCode, commit.Code)
 
-		return nil
+		return broadcastErr
+	}
+}
+
+// handles incremental code edits, transforming them against concurrent edits
+func CodeOpsHandler(sessionRepo sessions.Repository, detector *ccsignals.Detector) MessageHandler {
+	return func(hub *Hub, client *Client, msg *Message) error {
+		// code_ops share the code_update rate limit
+		if !client.checkCodeUpdateRateLimit() {
+			client.SendError("too_many_requests", "too many code updates. maximum 10 per second.", "")
+			return ErrRateLimitExceeded
+		}
+
+		if !client.CanWrite() {
+			client.SendError("forbidden", "you don't have permission to edit code", "")
+			return ErrReadOnly
+		}
+
+		var payload CodeOpsPayload
+		if err := msg.UnmarshalPayload(&payload); err != nil {
+			client.SendError("validation_error", "failed to parse code ops", err.Error())
+			return err
+		}
+
+		doc := hub.SessionDocument(client.SessionID)
+		if doc == nil {
+			return ErrSessionNotFound
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		var commit *DocumentCommit
+		var broadcastErr error
+
+		err := doc.ApplyChanges(payload.Revision, payload.Changes, func(c *DocumentCommit) {
+			commit = c
+
+			// an empty commit means the ops were absorbed by concurrent edits
+			if len(c.Changes) > 0 {
+				// materialize the document (goes to redis buffer via BufferedRepository)
+				saveCode(ctx, sessionRepo, client, c.Code)
+				hub.storeDocument(client.SessionID, c)
+
+				broadcastErr = broadcastCodeOps(hub, client, payload, c)
+			}
+
+			sendCodeOpsAck(client, c.Revision)
+		})
+
+		switch {
+		case errors.Is(err, ErrRevisionTooOld):
+			sendCodeResync(client, doc, "revision_too_old")
+			return nil
+		case errors.Is(err, ErrInvalidOperation):
+			sendCodeResync(client, doc, "invalid_operation")
+			return nil
+		case errors.Is(err, ErrCodeTooLarge):
+			client.SendError("bad_request", "code exceeds maximum size. maximum 100 KB allowed.", "")
+			sendCodeResync(client, doc, "code_too_large")
+			return err
+		case err != nil:
+			return err
+		}
+
+		// paste detection only looks at the source of the edit, like code_update
+		checkPasteSignals(ctx, hub, client, detector, payload.Source, commit.PreviousCode, commit.Code)
+
+		return broadcastErr
+	}
+}
+
+// runs paste detection or lock clearing for a code change depending on its source
+func checkPasteSignals(ctx context.Context, hub *Hub, client *Client, detector *ccsignals.Detector, source, previousCode, newCode string) {
+	if detector == nil {
+		return
+	}
+
+	// use ccsignals detector for paste detection
+	// only check for 'paste' or 'typed' sources (skip loaded_strudel, forked)
+	shouldCheckPaste := source == "" || source == "typed" || source == "paste"
+	if shouldCheckPaste {
+		handlePasteDetection(ctx, hub, client, detector, previousCode, newCode)
+		return
+	}
+
+	if source != "loaded_strudel" {
+		return
+	}
+
+	// clear any existing paste lock when loading a new/fresh strudel
+	wasLocked, err := detector.IsLocked(ctx, client.SessionID)
+	if err != nil {
+		logger.ErrorErr(err, "failed to check lock status on strudel load", "session_id", client.SessionID)
+	} else if wasLocked {
+		if err := detector.RemoveLock(ctx, client.SessionID); err != nil {
+			logger.ErrorErr(err, "failed to clear paste lock on strudel load", "session_id", client.SessionID)
+		} else {
+			logger.Info("paste lock cleared on strudel load", "session_id", client.SessionID)
+			sendPasteLockStatus(hub, client, false, "new_strudel")
+		}
+	}
+}
+
+// persists session code, logging instead of failing so the broadcast still happens
+func saveCode(ctx context.Context, sessionRepo sessions.Repository, client *Client, code string) {
+	if err := sessionRepo.UpdateSessionCode(ctx, client.SessionID, code); err != nil {
+		logger.ErrorErr(err, "failed to save code",
+			"client_id", client.ID,
+			"session_id", client.SessionID,
+		)
+	}
+}
+
+// broadcasts a full-buffer code update to all other clients in the session
+func broadcastCodeUpdate(hub *Hub, client *Client, payload CodeUpdatePayload) error {
+	broadcastMsg, err := NewMessage(TypeCodeUpdate, client.SessionID, client.UserID, payload)
+	if err != nil {
+		logger.ErrorErr(err, "failed to create broadcast message",
+			"client_id", client.ID,
+			"session_id", client.SessionID,
+		)
+		return err
+	}
+
+	hub.BroadcastToSession(client.SessionID, broadcastMsg, client.ID)
+
+	return nil
+}
+
+// broadcasts committed code ops to all other clients in the session
+func broadcastCodeOps(hub *Hub, client *Client, payload CodeOpsPayload, commit *DocumentCommit) error {
+	payload.Revision = commit.Revision
+	payload.Changes = commit.Changes
+	payload.DisplayName = client.DisplayName
+	payload.UserID = client.UserID
+	payload.Role = client.Role
+
+	broadcastMsg, err := NewMessage(TypeCodeOps, client.SessionID, client.UserID, payload)
+	if err != nil {
+		logger.ErrorErr(err, "failed to create code ops broadcast message",
+			"client_id", client.ID,
+			"session_id", client.SessionID,
+		)
+		return err
+	}
+
+	hub.BroadcastToSession(client.SessionID, broadcastMsg, client.ID)
+
+	return nil
+}
+
+// acknowledges committed code ops to their author
+func sendCodeOpsAck(client *Client, revision uint64) {
+	msg, err := NewMessage(TypeCodeOpsAck, client.SessionID, client.UserID, CodeOpsAckPayload{
+		Revision: revision,
+	})
+	if err != nil {
+		return
+	}
+
+	client.Send(msg) //nolint:errcheck,gosec // best-effort ack, client resyncs on mismatch
+}
+
+// sends the authoritative document to a client whose ops could not be applied
+func sendCodeResync(client *Client, doc *Document, reason string) {
+	code, revision := doc.Snapshot()
+
+	msg, err := NewMessage(TypeCodeResync, client.SessionID, client.UserID, CodeResyncPayload{
+		Code:     code,
+		Revision: revision,
+		Reason:   reason,
+	})
+	if err != nil {
+		logger.ErrorErr(err, "failed to create code resync message",
+			"client_id", client.ID,
+			"session_id", client.SessionID,
+		)
+		return
+	}
+
+	if err := client.Send(msg); err != nil {
+		logger.ErrorErr(err, "failed to send code resync",
+			"client_id", client.ID,
+			"session_id", client.SessionID,
+		)
 	}
 }
 
@@ -162,6 +332,26 @@ func handlePasteDetection(ctx context.Context, hub *Hub, client *Client, detecto
 			)
 		}
 	} else {
+		// small updates may still add up to protected code pasted in chunks
+		result, err := detector.DetectChunkedPaste(ctx, client.SessionID, client.UserID, previousCode, newCode)
+		if err != nil {
+			logger.ErrorErr(err, "chunked paste detection failed", "session_id", client.SessionID)
+		} else if result.ShouldLock {
+			config := ccsignals.DefaultConfig()
+			if err := detector.SetLock(ctx, client.SessionID, newCode, config.LockTTL); err != nil {
+				logger.ErrorErr(err, "failed to set paste lock", "session_id", client.SessionID)
+				return
+			}
+
+			logger.Info("paste lock set",
+				"session_id", client.SessionID,
+				"reason", result.Reason,
+			)
+
+			sendPasteLockStatus(hub, client, true, "similar_to_protected")
+			return
+		}
+
 		// no large delta - check if edits are significant enough to unlock
 		// first check if there's actually a lock to potentially remove
 		wasLocked, err := detector.IsLocked(ctx, client.SessionID)
@@ -1,6 +1,8 @@
 package websocket
 
 import (
+	"context"
+	"encoding/json"
 	"time"
 
 	"codeberg.org/algopatterns/server/internal/logger"
@@ -18,6 +20,9 @@ func NewHub() *Hub {
 		userConnections:  make(map[string]int),
 		ipConnections:    make(map[string]int),
 		sessionSequences: make(map[string]uint64),
+		documents:        make(map[string]*Document),
+		replays:          make(map[string]*replayBuffer),
+		broadcastLocks:   make(map[string]*broadcastLock),
 	}
 }
 
@@ -49,6 +54,11 @@ func (h *Hub) Run() {
 		h.running = false
 	}()
 
+	h.startBackplane()
+
+	evictTicker := time.NewTicker(documentEvictionInterval)
+	defer evictTicker.Stop()
+
 	for {
 		select {
 		case client := <-h.Register:
@@ -60,8 +70,15 @@ func (h *Hub) Run() {
 		case message := <-h.Broadcast:
 			h.handleMessage(message)
 
+		case <-evictTicker.C:
+			if h.backplane != nil {
+				h.evictIdleDocuments()
+			}
+			h.evictIdleReplays()
+
 		case <-h.shutdown:
 			h.closeAllConnections()
+			h.stopBackplane()
 			return
 		}
 	}
@@ -69,8 +86,21 @@ func (h *Hub) Run() {
 
 // registerClient adds a client to the hub
 func (h *Hub) registerClient(client *Client) {
+	doc := h.ensureDocument(client.SessionID, client.InitialCode)
+
+	// backplane round trips happen before taking any lock, so a slow backplane
+	// only delays this client and not every session on the instance
+	participants := h.backplaneParticipants(client)
+	storedCode, storedRevision, stored := h.backplaneDocument(client.SessionID)
+
+	if client.UserID != "" {
+		h.adjustConnections(client.UserID, "", 1)
+	}
+
+	// hold the document lock so no ops commit between the snapshot and registration.
+	// lock order is always document then hub, matching code_ops commits.
+	doc.mu.Lock()
 	h.mu.Lock()
-	defer h.mu.Unlock()
 
 	if h.sessions[client.SessionID] == nil {
 		h.sessions[client.SessionID] = make(map[string]*Client)
@@ -91,23 +121,36 @@ func (h *Hub) registerClient(client *Client) {
 	)
 
 	// build participants list from connected clients (including the new client)
-	participants := make([]SessionStateParticipant, 0)
+	if participants == nil {
+		participants = h.localParticipants(client.SessionID)
+	}
 
-	for _, c := range h.sessions[client.SessionID] {
-		participants = append(participants, SessionStateParticipant{
-			UserID:      c.UserID,
-			DisplayName: c.DisplayName,
-			Role:        c.Role,
-		})
+	// the stored document is only newer when another instance owns the session
+	code, revision := string(doc.text), doc.revision
+	if stored && storedRevision >= revision {
+		code, revision = storedCode, storedRevision
+	}
+
+	// a reconnecting client keeps its chat and gets the messages it missed instead
+	replayBuffer := h.ensureReplayBuffer(client.SessionID)
+	missed, replayed := h.missedMessages(client, replayBuffer)
+
+	chatHistory := client.InitialChatHistory
+	if replayed {
+		chatHistory = []SessionStateChatMessage{}
 	}
 
 	// send session_state to connecting client
 	sessionStateMsg, err := NewMessage(TypeSessionState, client.SessionID, client.UserID, SessionStatePayload{
-		Code:            client.InitialCode,
+		Code:            code,
+		Revision:        revision,
+		YourClientID:    client.ID,
 		YourRole:        client.Role,
 		YourDisplayName: client.DisplayName,
 		Participants:    participants,
-		ChatHistory:     client.InitialChatHistory,
+		ChatHistory:     chatHistory,
+		Sequence:        replayBuffer.latest,
+		Replayed:        replayed,
 	})
 	if err == nil {
 		if sendErr := client.Send(sessionStateMsg); sendErr != nil {
@@ -118,6 +161,23 @@ func (h *Hub) registerClient(client *Client) {
 		}
 	}
 
+	// replay under the lock so no new broadcast overtakes a missed one
+	for _, msg := range missed {
+		if sendErr := client.Send(msg); sendErr != nil {
+			logger.ErrorErr(sendErr, "failed to replay message",
+				"client_id", client.ID,
+				"session_id", client.SessionID,
+				"seq", msg.Sequence,
+			)
+			break
+		}
+	}
+
+	callback := h.onClientRegistered
+
+	h.mu.Unlock()
+	doc.mu.Unlock()
+
 	// broadcast user_joined to other clients in the session
 	userJoinedMsg, err := NewMessage(TypeUserJoined, client.SessionID, client.UserID, UserJoinedPayload{
 		UserID:      client.UserID,
@@ -125,12 +185,12 @@ func (h *Hub) registerClient(client *Client) {
 		Role:        client.Role,
 	})
 	if err == nil {
-		h.broadcastToSession(client.SessionID, userJoinedMsg, client.ID)
+		h.BroadcastToSession(client.SessionID, userJoinedMsg, client.ID)
 	}
 
 	// call registered callback (e.g., to send paste lock status)
-	if h.onClientRegistered != nil {
-		go h.onClientRegistered(client)
+	if callback != nil {
+		go callback(client)
 	}
 }
 
@@ -176,25 +236,43 @@ func (h *Hub) unregisterClient(client *Client) {
 		"session_id", client.SessionID,
 	)
 
+	// clients on other instances may still be in the session
+	notifyLeft := len(sessionClients) > 0 || h.backplane != nil
+
+	// the replay buffer and sequence counter outlive the session for reconnecting clients
+	if replayBuffer, exists := h.replays[client.SessionID]; exists {
+		replayBuffer.lastUsed = time.Now()
+	}
+
 	if len(sessionClients) == 0 {
 		delete(h.sessions, client.SessionID)
-		delete(h.sessionSequences, client.SessionID)
+
+		// with a backplane the document may still serve remote clients until it idles out
+		if h.backplane == nil {
+			delete(h.documents, client.SessionID)
+		}
 
 		logger.Info("session has no more clients, removed",
 			"session_id", client.SessionID,
 		)
-	} else {
+	}
+
+	h.mu.Unlock()
+
+	// backplane round trips happen outside the lock
+	h.removePresence(client)
+	h.adjustConnections(client.UserID, client.IPAddress, -1)
+
+	if notifyLeft {
 		userLeftMsg, err := NewMessage(TypeUserLeft, client.SessionID, client.UserID, UserLeftPayload{
 			UserID:      client.UserID,
 			DisplayName: client.DisplayName,
 		})
 		if err == nil {
-			h.broadcastToSession(client.SessionID, userLeftMsg, "")
+			h.BroadcastToSession(client.SessionID, userLeftMsg, "")
 		}
 	}
 
-	h.mu.Unlock()
-
 	// call disconnect callback outside lock (may do DB operations)
 	if callback != nil {
 		callback(client)
@@ -231,6 +309,11 @@ func (h *Hub) handleMessage(msg *Message) {
 	handler, exists := h.handlers[msg.Type]
 	h.mu.RUnlock()
 
+	// document edits run on the instance that owns the session document
+	if exists && h.forwardToOwner(sender, msg) {
+		return
+	}
+
 	if exists {
 		// run handler asynchronously to avoid blocking the hub
 		go func() {
@@ -258,64 +341,95 @@ func (h *Hub) handleMessage(msg *Message) {
 
 // sends a message to all clients in a session
 func (h *Hub) BroadcastToSession(sessionID string, msg *Message, excludeClientID string) {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-	h.broadcastToSession(sessionID, msg, excludeClientID)
+	h.broadcast(sessionID, msg, excludeClientID, false)
 }
 
 // sends a message only to clients with write permissions (host and co-authors)
 func (h *Hub) BroadcastToWriters(sessionID string, msg *Message, excludeClientID string) {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-	h.broadcastToWriters(sessionID, msg, excludeClientID)
+	h.broadcast(sessionID, msg, excludeClientID, true)
 }
 
-// the internal broadcast to writers function (must be called with lock held)
-func (h *Hub) broadcastToWriters(sessionID string, msg *Message, excludeClientID string) {
-	sessionClients, exists := h.sessions[sessionID]
-	if !exists {
+// sequences a message, delivers it locally and fans it out to other instances.
+// broadcasts to one session are serialized so clients get them in sequence
+// order, while backplane round trips run without holding the hub lock
+// (must be called without the hub lock).
+func (h *Hub) broadcast(sessionID string, msg *Message, excludeClientID string, writersOnly bool) {
+	h.mu.Lock()
+	if _, exists := h.sessions[sessionID]; !exists && h.backplane == nil {
+		h.mu.Unlock()
 		return
 	}
 
-	// assign sequence number to message
-	h.sessionSequences[sessionID]++
-	msg.Sequence = h.sessionSequences[sessionID]
+	sessionLock, exists := h.broadcastLocks[sessionID]
+	if !exists {
+		sessionLock = &broadcastLock{}
+		h.broadcastLocks[sessionID] = sessionLock
+	}
+	sessionLock.users++
+	h.mu.Unlock()
 
-	for clientID, client := range sessionClients {
-		if clientID == excludeClientID {
-			continue
-		}
+	sessionLock.Lock()
+	defer h.releaseBroadcastLock(sessionID, sessionLock)
 
-		// only send to clients with write permissions
-		if !client.CanWrite() {
-			continue
-		}
+	seq, ok := h.backplaneSequence(sessionID)
 
-		if err := client.Send(msg); err != nil {
-			logger.ErrorErr(err, "failed to send message to client",
-				"client_id", clientID,
-				"session_id", sessionID,
-			)
-		}
+	h.mu.Lock()
+	if !ok {
+		seq = h.localSequence(sessionID)
 	}
+
+	// assign sequence number to message
+	msg.Sequence = seq
+
+	h.deliverToSession(sessionID, msg, excludeClientID, writersOnly)
+	h.recordReplay(sessionID, msg, excludeClientID, writersOnly)
+	h.mu.Unlock()
+
+	h.publish(&BackplaneEvent{
+		Kind:            backplaneBroadcast,
+		SessionID:       sessionID,
+		Message:         msg,
+		ExcludeClientID: excludeClientID,
+		WritersOnly:     writersOnly,
+	})
 }
 
-// the internal broadcast function (must be called with lock held)
-func (h *Hub) broadcastToSession(sessionID string, msg *Message, excludeClientID string) {
-	sessionClients, exists := h.sessions[sessionID]
-	if !exists {
-		return
+// unlocks a session's broadcast lock and drops it once no broadcast uses it
+// and the session has no local clients or replay buffer left, e.g. sessions
+// that only exist on other instances (must be called without the hub lock)
+func (h *Hub) releaseBroadcastLock(sessionID string, sessionLock *broadcastLock) {
+	sessionLock.Unlock()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	sessionLock.users--
+
+	if len(h.sessions[sessionID]) == 0 && h.replays[sessionID] == nil {
+		h.dropBroadcastLock(sessionID)
 	}
+}
 
-	// assign sequence number to message
-	h.sessionSequences[sessionID]++
-	msg.Sequence = h.sessionSequences[sessionID]
+// forgets a session's broadcast lock unless a broadcast is using it, that
+// broadcast drops it when done (must be called with lock held)
+func (h *Hub) dropBroadcastLock(sessionID string) {
+	if sessionLock, exists := h.broadcastLocks[sessionID]; exists && sessionLock.users == 0 {
+		delete(h.broadcastLocks, sessionID)
+	}
+}
 
-	for clientID, client := range sessionClients {
+// sends an already sequenced message to local clients (must be called with lock held)
+func (h *Hub) deliverToSession(sessionID string, msg *Message, excludeClientID string, writersOnly bool) {
+	for clientID, client := range h.sessions[sessionID] {
 		if clientID == excludeClientID {
 			continue
 		}
 
+		// only send to clients with write permissions
+		if writersOnly && !client.CanWrite() {
+			continue
+		}
+
 		if err := client.Send(msg); err != nil {
 			logger.ErrorErr(err, "failed to send message to client",
 				"client_id", clientID,
@@ -344,6 +458,27 @@ func (h *Hub) GetSessionClients(sessionID string) []*Client {
 	return clients
 }
 
+// returns the authoritative document for a session, or nil if no client is connected
+func (h *Hub) SessionDocument(sessionID string) *Document {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.documents[sessionID]
+}
+
+// returns the session document, creating it from code if the session has none yet
+func (h *Hub) ensureDocument(sessionID, code string) *Document {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	doc, exists := h.documents[sessionID]
+	if !exists {
+		doc = NewDocument(code)
+		h.documents[sessionID] = doc
+	}
+
+	return doc
+}
+
 // returns the number of clients in a session
 func (h *Hub) GetClientCount(sessionID string) int {
 	h.mu.RLock()
@@ -371,6 +506,183 @@ func (h *Hub) IsSessionActive(sessionID string) bool {
 	return exists && len(sessionClients) > 0
 }
 
+// returns the IDs of sessions with clients connected to this instance
+func (h *Hub) ActiveSessionIDs() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	sessionIDs := make([]string, 0, len(h.sessions))
+
+	for sessionID, sessionClients := range h.sessions {
+		if len(sessionClients) > 0 {
+			sessionIDs = append(sessionIDs, sessionID)
+		}
+	}
+
+	return sessionIDs
+}
+
+// returns the connected participants with write access to a session, once per user.
+// uses cluster-wide presence when a backplane is configured.
+func (h *Hub) SessionWriters(sessionID string) []SessionStateParticipant {
+	var participants []SessionStateParticipant
+
+	if h.backplane != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), backplaneTimeout)
+		defer cancel()
+
+		presence, err := h.backplane.Presence(ctx, sessionID)
+		if err != nil {
+			logger.ErrorErr(err, "failed to read backplane presence, using local clients",
+				"session_id", sessionID,
+			)
+		} else {
+			participants = presence
+		}
+	}
+
+	if participants == nil {
+		for _, client := range h.GetSessionClients(sessionID) {
+			participants = append(participants, SessionStateParticipant{
+				UserID:      client.UserID,
+				DisplayName: client.DisplayName,
+				Role:        client.Role,
+			})
+		}
+	}
+
+	writers := make([]SessionStateParticipant, 0, len(participants))
+	seen := make(map[SessionStateParticipant]bool)
+
+	for _, participant := range participants {
+		if participant.Role != "host" && participant.Role != "co-author" {
+			continue
+		}
+
+		if seen[participant] {
+			continue
+		}
+
+		seen[participant] = true
+		writers = append(writers, participant)
+	}
+
+	return writers
+}
+
+// replaces a session's code on behalf of the server (e.g. restoring a snapshot).
+// runs through the registered code_update handler so the document revision,
+// persistence and broadcast behave exactly like a client edit.
+func (h *Hub) ApplyCodeUpdate(sessionID, userID, displayName, code, source string) error {
+	h.mu.RLock()
+	handler, exists := h.handlers[TypeCodeUpdate]
+	h.mu.RUnlock()
+
+	if !exists {
+		return ErrHandlerNotRegistered
+	}
+
+	clientID, err := GenerateClientID()
+	if err != nil {
+		return err
+	}
+
+	// a stand-in writer, it is never registered so every client receives the update
+	server := &Client{
+		ID:          clientID,
+		SessionID:   sessionID,
+		UserID:      userID,
+		DisplayName: displayName,
+		Role:        "host",
+		InitialCode: code,
+		hub:         h,
+		send:        make(chan []byte, 16),
+	}
+
+	msg, err := NewMessage(TypeCodeUpdate, sessionID, userID, CodeUpdatePayload{
+		Code:   code,
+		Source: source,
+	})
+	if err != nil {
+		return err
+	}
+	msg.ClientID = server.ID
+
+	if h.forwardToOwner(server, msg) {
+		return nil
+	}
+
+	return handler(h, server, msg)
+}
+
+// applies a server-side edit of a session's code made against baseCode at
+// baseRevision (e.g. an ai patch of a client's editor state). the line diff
+// from baseCode to code runs through the registered code_ops handler, so it is
+// transformed over edits collaborators made since baseRevision instead of
+// overwriting them. returns ErrRevisionTooOld or ErrInvalidOperation when the
+// edit can't be rebased onto the current document.
+func (h *Hub) ApplyCodeChanges(sessionID, userID, displayName string, baseRevision uint64, baseCode, code, source string) error {
+	h.mu.RLock()
+	handler, exists := h.handlers[TypeCodeOps]
+	h.mu.RUnlock()
+
+	if !exists {
+		return ErrHandlerNotRegistered
+	}
+
+	clientID, err := GenerateClientID()
+	if err != nil {
+		return err
+	}
+
+	// a stand-in writer, it is never registered so every client receives the ops
+	server := &Client{
+		ID:          clientID,
+		SessionID:   sessionID,
+		UserID:      userID,
+		DisplayName: displayName,
+		Role:        "host",
+		InitialCode: baseCode,
+		hub:         h,
+		send:        make(chan []byte, 16),
+	}
+
+	msg, err := NewMessage(TypeCodeOps, sessionID, userID, CodeOpsPayload{
+		Revision: baseRevision,
+		Changes:  diffOperation(baseCode, code).changes(),
+		Source:   source,
+	})
+	if err != nil {
+		return err
+	}
+	msg.ClientID = server.ID
+
+	if h.forwardToOwner(server, msg) {
+		return nil
+	}
+
+	if err := handler(h, server, msg); err != nil {
+		return err
+	}
+
+	// the handler answers a rejected edit with a resync instead of an error
+	for len(server.send) > 0 {
+		var reply Message
+		if err := json.Unmarshal(<-server.send, &reply); err != nil || reply.Type != TypeCodeResync {
+			continue
+		}
+
+		var resync CodeResyncPayload
+		if err := reply.UnmarshalPayload(&resync); err == nil && resync.Reason == "invalid_operation" {
+			return ErrInvalidOperation
+		}
+
+		return ErrRevisionTooOld
+	}
+
+	return nil
+}
+
 func (h *Hub) Shutdown() {
 	if h.running {
 		close(h.shutdown)
@@ -427,50 +739,83 @@ func (h *Hub) closeAllConnections() {
 	h.userConnections = make(map[string]int)
 	h.ipConnections = make(map[string]int)
 	h.sessionSequences = make(map[string]uint64)
+	h.documents = make(map[string]*Document)
+	h.replays = make(map[string]*replayBuffer)
+	h.broadcastLocks = make(map[string]*broadcastLock)
 }
 
 // checks if a new connection should be allowed based on limits
 func (h *Hub) CanAcceptConnection(userID, ipAddress string) (bool, string) {
-	h.mu.RLock()
-	defer h.mu.RUnlock()
+	userCount, ipCount := h.connectionCounts(userID, ipAddress)
 
 	// check per-user limit (only for authenticated users)
-	if userID != "" {
-		count := h.userConnections[userID]
-		if count >= maxConnectionsPerUser {
-			return false, "Maximum connections per user exceeded"
-		}
+	if userID != "" && userCount >= maxConnectionsPerUser {
+		return false, "Maximum connections per user exceeded"
 	}
 
 	// check per-IP limit
-	count := h.ipConnections[ipAddress]
-	if count >= maxConnectionsPerIP {
+	if ipCount >= maxConnectionsPerIP {
 		return false, "Maximum connections per IP address exceeded"
 	}
 
 	return true, ""
 }
 
+// returns connection counts across all instances, or local counts without a backplane
+func (h *Hub) connectionCounts(userID, ipAddress string) (int, int) {
+	if h.backplane != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), backplaneTimeout)
+		defer cancel()
+
+		userCount, ipCount, err := h.backplane.ConnectionCounts(ctx, userID, ipAddress)
+		if err == nil {
+			return userCount, ipCount
+		}
+
+		logger.ErrorErr(err, "failed to read backplane connection counts, using local counts")
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.userConnections[userID], h.ipConnections[ipAddress]
+}
+
 // increments the connection count for an IP address
 func (h *Hub) TrackIPConnection(ipAddress string) {
 	h.mu.Lock()
-	defer h.mu.Unlock()
 	h.ipConnections[ipAddress]++
+	h.mu.Unlock()
+
+	h.adjustConnections("", ipAddress, 1)
 }
 
 // decrements the connection count for an IP address
 func (h *Hub) UntrackIPConnection(ipAddress string) {
 	h.mu.Lock()
-	defer h.mu.Unlock()
 	h.ipConnections[ipAddress]--
 
 	if h.ipConnections[ipAddress] <= 0 {
 		delete(h.ipConnections, ipAddress)
 	}
+	h.mu.Unlock()
+
+	h.adjustConnections("", ipAddress, -1)
 }
 
-// broadcasts session_ended to all clients and closes their connections
+// broadcasts session_ended to all clients, on every instance, and closes their connections
 func (h *Hub) EndSession(sessionID string, reason string) {
+	h.publish(&BackplaneEvent{
+		Kind:      backplaneEndSession,
+		SessionID: sessionID,
+		Reason:    reason,
+	})
+
+	h.endLocalSession(sessionID, reason)
+}
+
+// ends a session for clients connected to this instance
+func (h *Hub) endLocalSession(sessionID string, reason string) {
 	h.mu.Lock()
 
 	sessionClients, exists := h.sessions[sessionID]
@@ -511,15 +856,19 @@ func (h *Hub) EndSession(sessionID string, reason string) {
 	time.Sleep(100 * time.Millisecond)
 
 	h.mu.Lock()
-	defer h.mu.Unlock()
 
 	// close all connections for this session
 	sessionClients, exists = h.sessions[sessionID]
 	if !exists {
+		h.mu.Unlock()
 		return
 	}
 
+	closed := make([]*Client, 0, len(sessionClients))
+
 	for clientID, client := range sessionClients {
+		closed = append(closed, client)
+
 		// update connection tracking
 		if client.UserID != "" {
 			h.userConnections[client.UserID]--
@@ -544,6 +893,17 @@ func (h *Hub) EndSession(sessionID string, reason string) {
 	// remove session from hub
 	delete(h.sessions, sessionID)
 	delete(h.sessionSequences, sessionID)
+	delete(h.documents, sessionID)
+	delete(h.replays, sessionID)
+	h.dropBroadcastLock(sessionID)
+
+	h.mu.Unlock()
+
+	// backplane round trips happen outside the lock
+	for _, client := range closed {
+		h.removePresence(client)
+		h.adjustConnections(client.UserID, client.IPAddress, -1)
+	}
 
 	logger.Info("session ended and removed",
 		"session_id", sessionID,
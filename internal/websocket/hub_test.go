@@ -457,3 +457,83 @@ func TestHubConcurrentBroadcasts(t *testing.T) {
 	// hub explicitly shutdown at the end to avoid race with concurrent broadcasts
 	hub.Shutdown()
 }
+
+func TestHubApplyCodeUpdate(t *testing.T) {
+	hub := NewHub()
+	repo := &codeRecordingRepo{saved: make(chan string, 16)}
+	hub.RegisterHandler(TypeCodeUpdate, CodeUpdateHandler(repo, nil))
+	go hub.Run()
+	defer hub.Shutdown()
+
+	host := newBackplaneClient(hub, "client-1", "user-1", "host")
+	viewer := newBackplaneClient(hub, "client-2", "user-2", "viewer")
+	hub.Register <- host
+	hub.Register <- viewer
+
+	receiveType(t, host, TypeSessionState)
+	receiveType(t, viewer, TypeSessionState)
+
+	writers := hub.SessionWriters("session-1")
+	require.Len(t, writers, 1)
+	assert.Equal(t, "user-1", writers[0].UserID)
+	assert.Equal(t, []string{"session-1"}, hub.ActiveSessionIDs())
+
+	restored := `s("hh*8")`
+	require.NoError(t, hub.ApplyCodeUpdate("session-1", "user-1", "client-1", restored, "restored"))
+
+	assert.Equal(t, restored, <-repo.saved)
+
+	// the author is a stand-in, so every connected client gets the update
+	for _, client := range []*Client{host, viewer} {
+		var payload CodeUpdatePayload
+		require.NoError(t, receiveType(t, client, TypeCodeUpdate).UnmarshalPayload(&payload))
+		assert.Equal(t, restored, payload.Code)
+		assert.Equal(t, "restored", payload.Source)
+		assert.Equal(t, uint64(1), payload.Revision)
+	}
+
+	code, revision := hub.SessionDocument("session-1").Snapshot()
+	assert.Equal(t, restored, code)
+	assert.Equal(t, uint64(1), revision)
+}
+
+func TestHubApplyCodeChangesKeepsConcurrentEdits(t *testing.T) {
+	hub := NewHub()
+	repo := &codeRecordingRepo{saved: make(chan string, 16)}
+	hub.RegisterHandler(TypeCodeOps, CodeOpsHandler(repo, nil))
+	go hub.Run()
+	defer hub.Shutdown()
+
+	base := "s(\"bd sd\")\nnote(\"c e\")"
+
+	host := newBackplaneClient(hub, "client-1", "user-1", "host")
+	host.InitialCode = base
+	hub.Register <- host
+	receiveType(t, host, TypeSessionState)
+
+	// a collaborator edits the second line after the ai read revision 0
+	msg, err := NewMessage(TypeCodeOps, "session-1", host.UserID, CodeOpsPayload{
+		Revision: 0,
+		Changes:  []TextChange{{From: 20, To: 20, Insert: " g"}},
+	})
+	require.NoError(t, err)
+	msg.ClientID = host.ID
+	hub.Broadcast <- msg
+	receiveType(t, host, TypeCodeOpsAck)
+
+	patched := "s(\"bd sd\").fast(2)\nnote(\"c e\")"
+	require.NoError(t, hub.ApplyCodeChanges("session-1", "user-1", "client-1", 0, base, patched, "ai"))
+
+	var ops CodeOpsPayload
+	require.NoError(t, receiveType(t, host, TypeCodeOps).UnmarshalPayload(&ops))
+	assert.Equal(t, "ai", ops.Source)
+	assert.Equal(t, uint64(2), ops.Revision)
+
+	code, revision := hub.SessionDocument("session-1").Snapshot()
+	assert.Equal(t, "s(\"bd sd\").fast(2)\nnote(\"c e g\")", code)
+	assert.Equal(t, uint64(2), revision)
+
+	// a base revision ahead of the document is rejected
+	err = hub.ApplyCodeChanges("session-1", "user-1", "client-1", 99, base, patched, "ai")
+	assert.ErrorIs(t, err, ErrInvalidOperation)
+}
@@ -0,0 +1,287 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+)
+
+// size of each instance's event queue on the in-memory network
+const memoryBackplaneQueueSize = 1024
+
+// an in-process network of backplanes, one per simulated server instance.
+// used in tests and for running several hubs in a single process.
+type MemoryBackplaneNetwork struct {
+	mu        sync.Mutex
+	instances map[string]*MemoryBackplane
+	sequences map[string]uint64
+	presence  map[string]map[string]memoryPresence
+	userConns map[string]map[string]int
+	ipConns   map[string]map[string]int
+	owners    map[string]memoryOwner
+	documents map[string]memoryDocument
+}
+
+// a single instance's view of a MemoryBackplaneNetwork
+type MemoryBackplane struct {
+	network *MemoryBackplaneNetwork
+	id      string
+	events  chan []byte
+	closed  bool
+}
+
+type memoryPresence struct {
+	instanceID  string
+	participant SessionStateParticipant
+}
+
+type memoryOwner struct {
+	instanceID string
+	expiresAt  time.Time
+}
+
+type memoryDocument struct {
+	code     string
+	revision uint64
+}
+
+// creates an empty in-memory backplane network
+func NewMemoryBackplaneNetwork() *MemoryBackplaneNetwork {
+	return &MemoryBackplaneNetwork{
+		instances: make(map[string]*MemoryBackplane),
+		sequences: make(map[string]uint64),
+		presence:  make(map[string]map[string]memoryPresence),
+		userConns: make(map[string]map[string]int),
+		ipConns:   make(map[string]map[string]int),
+		owners:    make(map[string]memoryOwner),
+		documents: make(map[string]memoryDocument),
+	}
+}
+
+// adds an instance to the network
+func (n *MemoryBackplaneNetwork) Join(instanceID string) *MemoryBackplane {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	b := &MemoryBackplane{
+		network: n,
+		id:      instanceID,
+		events:  make(chan []byte, memoryBackplaneQueueSize),
+	}
+	n.instances[instanceID] = b
+
+	return b
+}
+
+func (b *MemoryBackplane) InstanceID() string {
+	return b.id
+}
+
+// queues the event for every other instance. events are serialized like they
+// would be on the wire, so receivers never share memory with the sender.
+func (b *MemoryBackplane) Publish(ctx context.Context, event *BackplaneEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	b.network.mu.Lock()
+	targets := make([]*MemoryBackplane, 0, len(b.network.instances))
+	for id, instance := range b.network.instances {
+		if id != b.id && !instance.closed {
+			targets = append(targets, instance)
+		}
+	}
+	b.network.mu.Unlock()
+
+	for _, target := range targets {
+		select {
+		case target.events <- data:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// delivers queued events in order until ctx is done
+func (b *MemoryBackplane) Subscribe(ctx context.Context, handler func(*BackplaneEvent)) error {
+	for {
+		select {
+		case data := <-b.events:
+			var event BackplaneEvent
+			if err := json.Unmarshal(data, &event); err != nil {
+				continue
+			}
+			handler(&event)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (b *MemoryBackplane) NextSequence(_ context.Context, sessionID string) (uint64, error) {
+	b.network.mu.Lock()
+	defer b.network.mu.Unlock()
+
+	b.network.sequences[sessionID]++
+	return b.network.sequences[sessionID], nil
+}
+
+func (b *MemoryBackplane) AddPresence(_ context.Context, sessionID, clientID string, participant SessionStateParticipant) error {
+	b.network.mu.Lock()
+	defer b.network.mu.Unlock()
+
+	if b.network.presence[sessionID] == nil {
+		b.network.presence[sessionID] = make(map[string]memoryPresence)
+	}
+	b.network.presence[sessionID][clientID] = memoryPresence{instanceID: b.id, participant: participant}
+
+	return nil
+}
+
+func (b *MemoryBackplane) RemovePresence(_ context.Context, sessionID, clientID string) error {
+	b.network.mu.Lock()
+	defer b.network.mu.Unlock()
+
+	delete(b.network.presence[sessionID], clientID)
+	if len(b.network.presence[sessionID]) == 0 {
+		delete(b.network.presence, sessionID)
+	}
+
+	return nil
+}
+
+// returns participants ordered by client ID so results are deterministic
+func (b *MemoryBackplane) Presence(_ context.Context, sessionID string) ([]SessionStateParticipant, error) {
+	b.network.mu.Lock()
+	defer b.network.mu.Unlock()
+
+	clientIDs := make([]string, 0, len(b.network.presence[sessionID]))
+	for clientID := range b.network.presence[sessionID] {
+		clientIDs = append(clientIDs, clientID)
+	}
+	sort.Strings(clientIDs)
+
+	participants := make([]SessionStateParticipant, 0, len(clientIDs))
+	for _, clientID := range clientIDs {
+		participants = append(participants, b.network.presence[sessionID][clientID].participant)
+	}
+
+	return participants, nil
+}
+
+func (b *MemoryBackplane) AdjustConnections(_ context.Context, userID, ipAddress string, delta int) error {
+	b.network.mu.Lock()
+	defer b.network.mu.Unlock()
+
+	adjustMemoryCount(b.network.userConns, userID, b.id, delta)
+	adjustMemoryCount(b.network.ipConns, ipAddress, b.id, delta)
+
+	return nil
+}
+
+func (b *MemoryBackplane) ConnectionCounts(_ context.Context, userID, ipAddress string) (int, int, error) {
+	b.network.mu.Lock()
+	defer b.network.mu.Unlock()
+
+	userCount, ipCount := 0, 0
+	for _, count := range b.network.userConns[userID] {
+		userCount += count
+	}
+	for _, count := range b.network.ipConns[ipAddress] {
+		ipCount += count
+	}
+
+	return userCount, ipCount, nil
+}
+
+func (b *MemoryBackplane) ClaimSession(_ context.Context, sessionID string) (string, bool, error) {
+	b.network.mu.Lock()
+	defer b.network.mu.Unlock()
+
+	now := time.Now()
+	owner, exists := b.network.owners[sessionID]
+
+	if exists && now.Before(owner.expiresAt) && owner.instanceID != b.id {
+		return owner.instanceID, false, nil
+	}
+
+	acquired := !exists || owner.instanceID != b.id || !now.Before(owner.expiresAt)
+	b.network.owners[sessionID] = memoryOwner{instanceID: b.id, expiresAt: now.Add(sessionOwnerTTL)}
+
+	return b.id, acquired, nil
+}
+
+func (b *MemoryBackplane) StoreDocument(_ context.Context, sessionID, code string, revision uint64) error {
+	b.network.mu.Lock()
+	defer b.network.mu.Unlock()
+
+	b.network.documents[sessionID] = memoryDocument{code: code, revision: revision}
+	return nil
+}
+
+func (b *MemoryBackplane) LoadDocument(_ context.Context, sessionID string) (string, uint64, bool, error) {
+	b.network.mu.Lock()
+	defer b.network.mu.Unlock()
+
+	doc, exists := b.network.documents[sessionID]
+	return doc.code, doc.revision, exists, nil
+}
+
+// removes the instance from the network along with its presence, counts and ownership
+func (b *MemoryBackplane) Close() error {
+	b.network.mu.Lock()
+	defer b.network.mu.Unlock()
+
+	b.closed = true
+	delete(b.network.instances, b.id)
+
+	for sessionID, clients := range b.network.presence {
+		for clientID, p := range clients {
+			if p.instanceID == b.id {
+				delete(clients, clientID)
+			}
+		}
+		if len(clients) == 0 {
+			delete(b.network.presence, sessionID)
+		}
+	}
+
+	for _, counts := range b.network.userConns {
+		delete(counts, b.id)
+	}
+	for _, counts := range b.network.ipConns {
+		delete(counts, b.id)
+	}
+
+	for sessionID, owner := range b.network.owners {
+		if owner.instanceID == b.id {
+			delete(b.network.owners, sessionID)
+		}
+	}
+
+	return nil
+}
+
+// adjusts a per-instance counter, skipping empty keys
+func adjustMemoryCount(counts map[string]map[string]int, key, instanceID string, delta int) {
+	if key == "" {
+		return
+	}
+
+	if counts[key] == nil {
+		counts[key] = make(map[string]int)
+	}
+
+	counts[key][instanceID] += delta
+	if counts[key][instanceID] <= 0 {
+		delete(counts[key], instanceID)
+	}
+	if len(counts[key]) == 0 {
+		delete(counts, key)
+	}
+}
@@ -0,0 +1,295 @@
+package websocket
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"codeberg.org/algopatterns/server/internal/diff"
+)
+
+// a single component of a text operation. exactly one field is set:
+// retain skips characters, insert adds text, delete removes characters.
+type opComponent struct {
+	retain int
+	insert string
+	delete int
+}
+
+// an operational-transform text operation that spans the whole document.
+// lengths are measured in unicode code points, not bytes.
+type textOperation struct {
+	components []opComponent
+	baseLen    int
+	targetLen  int
+}
+
+func (o *textOperation) retain(n int) {
+	if n <= 0 {
+		return
+	}
+
+	o.baseLen += n
+	o.targetLen += n
+
+	if last := o.last(); last != nil && last.retain > 0 {
+		last.retain += n
+		return
+	}
+
+	o.components = append(o.components, opComponent{retain: n})
+}
+
+func (o *textOperation) insert(s string) {
+	if s == "" {
+		return
+	}
+
+	o.targetLen += utf8.RuneCountInString(s)
+
+	last := o.last()
+	if last != nil && last.insert != "" {
+		last.insert += s
+		return
+	}
+
+	// keep inserts before deletes so equivalent operations have one representation
+	if last != nil && last.delete > 0 {
+		n := len(o.components)
+		if n > 1 && o.components[n-2].insert != "" {
+			o.components[n-2].insert += s
+			return
+		}
+
+		o.components = append(o.components, *last)
+		o.components[n-1] = opComponent{insert: s}
+		return
+	}
+
+	o.components = append(o.components, opComponent{insert: s})
+}
+
+func (o *textOperation) delete(n int) {
+	if n <= 0 {
+		return
+	}
+
+	o.baseLen += n
+
+	if last := o.last(); last != nil && last.delete > 0 {
+		last.delete += n
+		return
+	}
+
+	o.components = append(o.components, opComponent{delete: n})
+}
+
+func (o *textOperation) last() *opComponent {
+	if len(o.components) == 0 {
+		return nil
+	}
+
+	return &o.components[len(o.components)-1]
+}
+
+// reports whether the operation leaves the document unchanged
+func (o *textOperation) isNoop() bool {
+	for _, c := range o.components {
+		if c.retain == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// applies the operation to a document
+func (o *textOperation) apply(doc []rune) ([]rune, error) {
+	if len(doc) != o.baseLen {
+		return nil, fmt.Errorf("%w: base length %d does not match document length %d", ErrInvalidOperation, o.baseLen, len(doc))
+	}
+
+	result := make([]rune, 0, o.targetLen)
+	pos := 0
+
+	for _, c := range o.components {
+		switch {
+		case c.retain > 0:
+			result = append(result, doc[pos:pos+c.retain]...)
+			pos += c.retain
+		case c.insert != "":
+			result = append(result, []rune(c.insert)...)
+		case c.delete > 0:
+			pos += c.delete
+		}
+	}
+
+	return result, nil
+}
+
+// converts the operation into range changes relative to its base document
+func (o *textOperation) changes() []TextChange {
+	changes := make([]TextChange, 0)
+	pos := 0
+
+	for _, c := range o.components {
+		switch {
+		case c.retain > 0:
+			pos += c.retain
+		case c.insert != "":
+			if n := len(changes); n > 0 && changes[n-1].To == pos {
+				changes[n-1].Insert += c.insert
+				continue
+			}
+
+			changes = append(changes, TextChange{From: pos, To: pos, Insert: c.insert})
+		case c.delete > 0:
+			if n := len(changes); n > 0 && changes[n-1].To == pos {
+				changes[n-1].To += c.delete
+			} else {
+				changes = append(changes, TextChange{From: pos, To: pos + c.delete})
+			}
+
+			pos += c.delete
+		}
+	}
+
+	return changes
+}
+
+// builds an operation from range changes made against a document of baseLen code points.
+// changes must be sorted and non-overlapping, with every range relative to the base document.
+func operationFromChanges(changes []TextChange, baseLen int) (*textOperation, error) {
+	op := &textOperation{}
+	pos := 0
+
+	for _, ch := range changes {
+		if ch.From < pos || ch.To < ch.From || ch.To > baseLen {
+			return nil, fmt.Errorf("%w: change [%d,%d) is out of order or out of bounds", ErrInvalidOperation, ch.From, ch.To)
+		}
+
+		op.retain(ch.From - pos)
+		op.insert(ch.Insert)
+		op.delete(ch.To - ch.From)
+		pos = ch.To
+	}
+
+	op.retain(baseLen - pos)
+
+	return op, nil
+}
+
+// builds the smallest single-range operation that turns oldDoc into newDoc.
+// used when a client sends a full-buffer code_update so concurrent ops elsewhere survive.
+func replacementOperation(oldDoc, newDoc []rune) *textOperation {
+	prefix := 0
+	for prefix < len(oldDoc) && prefix < len(newDoc) && oldDoc[prefix] == newDoc[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(oldDoc)-prefix && suffix < len(newDoc)-prefix &&
+		oldDoc[len(oldDoc)-1-suffix] == newDoc[len(newDoc)-1-suffix] {
+		suffix++
+	}
+
+	op := &textOperation{}
+	op.retain(prefix)
+	op.insert(string(newDoc[prefix : len(newDoc)-suffix]))
+	op.delete(len(oldDoc) - prefix - suffix)
+	op.retain(suffix)
+
+	return op
+}
+
+// builds an operation turning oldCode into newCode from a line diff. unlike
+// replacementOperation it only touches changed lines, so concurrent edits to
+// lines in between survive a transform.
+func diffOperation(oldCode, newCode string) *textOperation {
+	op := &textOperation{}
+
+	for _, line := range diff.Sequences(strings.SplitAfter(oldCode, "\n"), strings.SplitAfter(newCode, "\n")) {
+		switch line.Op {
+		case diff.OpEqual:
+			op.retain(utf8.RuneCountInString(line.Text))
+		case diff.OpInsert:
+			op.insert(line.Text)
+		case diff.OpDelete:
+			op.delete(utf8.RuneCountInString(line.Text))
+		}
+	}
+
+	return op
+}
+
+// transforms two concurrent operations a and b that share a base document.
+// returns a' and b' such that apply(apply(doc, a), b') == apply(apply(doc, b), a').
+// when both insert at the same position, a's text is placed first.
+func transformOperations(a, b *textOperation) (*textOperation, *textOperation, error) {
+	if a.baseLen != b.baseLen {
+		return nil, nil, fmt.Errorf("%w: concurrent operations have different base lengths", ErrInvalidOperation)
+	}
+
+	aPrime := &textOperation{}
+	bPrime := &textOperation{}
+
+	aOps := append([]opComponent(nil), a.components...)
+	bOps := append([]opComponent(nil), b.components...)
+	i, j := 0, 0
+
+	for i < len(aOps) || j < len(bOps) {
+		if i < len(aOps) && aOps[i].insert != "" {
+			aPrime.insert(aOps[i].insert)
+			bPrime.retain(utf8.RuneCountInString(aOps[i].insert))
+			i++
+			continue
+		}
+
+		if j < len(bOps) && bOps[j].insert != "" {
+			aPrime.retain(utf8.RuneCountInString(bOps[j].insert))
+			bPrime.insert(bOps[j].insert)
+			j++
+			continue
+		}
+
+		if i >= len(aOps) || j >= len(bOps) {
+			return nil, nil, fmt.Errorf("%w: operations do not cover the same document", ErrInvalidOperation)
+		}
+
+		ac, bc := &aOps[i], &bOps[j]
+		n := min(ac.retain+ac.delete, bc.retain+bc.delete)
+
+		switch {
+		case ac.retain > 0 && bc.retain > 0:
+			aPrime.retain(n)
+			bPrime.retain(n)
+		case ac.delete > 0 && bc.retain > 0:
+			aPrime.delete(n)
+		case ac.retain > 0 && bc.delete > 0:
+			bPrime.delete(n)
+		}
+
+		// both deleting the same range leaves nothing for either side to do
+		if ac.retain > 0 {
+			ac.retain -= n
+		} else {
+			ac.delete -= n
+		}
+
+		if bc.retain > 0 {
+			bc.retain -= n
+		} else {
+			bc.delete -= n
+		}
+
+		if ac.retain == 0 && ac.delete == 0 {
+			i++
+		}
+
+		if bc.retain == 0 && bc.delete == 0 {
+			j++
+		}
+	}
+
+	return aPrime, bPrime, nil
+}
@@ -0,0 +1,293 @@
+package websocket
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOperationFromChanges(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      string
+		changes  []TextChange
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "insert at start",
+			doc:      `s("bd")`,
+			changes:  []TextChange{{From: 0, To: 0, Insert: "$: "}},
+			expected: `$: s("bd")`,
+		},
+		{
+			name:     "replace range",
+			doc:      `s("bd sd")`,
+			changes:  []TextChange{{From: 6, To: 8, Insert: "hh"}},
+			expected: `s("bd hh")`,
+		},
+		{
+			name: "multiple changes relative to base",
+			doc:  `s("bd sd")`,
+			changes: []TextChange{
+				{From: 3, To: 5, Insert: "cp"},
+				{From: 10, To: 10, Insert: ".fast(2)"},
+			},
+			expected: `s("cp sd").fast(2)`,
+		},
+		{
+			name:     "unicode offsets are code points",
+			doc:      `// ♪ beat`,
+			changes:  []TextChange{{From: 5, To: 5, Insert: "♫ "}},
+			expected: `// ♪ ♫ beat`,
+		},
+		{
+			name:     "empty changes is a no-op",
+			doc:      `s("bd")`,
+			changes:  nil,
+			expected: `s("bd")`,
+		},
+		{
+			name:    "out of bounds",
+			doc:     `s("bd")`,
+			changes: []TextChange{{From: 3, To: 20}},
+			wantErr: true,
+		},
+		{
+			name: "overlapping changes",
+			doc:  `s("bd sd")`,
+			changes: []TextChange{
+				{From: 3, To: 6},
+				{From: 5, To: 7},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "inverted range",
+			doc:     `s("bd")`,
+			changes: []TextChange{{From: 4, To: 2}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := []rune(tt.doc)
+
+			op, err := operationFromChanges(tt.changes, len(doc))
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidOperation)
+				return
+			}
+			require.NoError(t, err)
+
+			result, err := op.apply(doc)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(result))
+		})
+	}
+}
+
+func TestOperationChangesRoundTrip(t *testing.T) {
+	doc := []rune(`note("c e g").s("piano")`)
+	changes := []TextChange{
+		{From: 6, To: 7, Insert: "d"},
+		{From: 15, To: 23, Insert: "sawtooth"},
+	}
+
+	op, err := operationFromChanges(changes, len(doc))
+	require.NoError(t, err)
+	assert.Equal(t, changes, op.changes())
+}
+
+func TestReplacementOperation(t *testing.T) {
+	tests := []struct {
+		name    string
+		oldDoc  string
+		newDoc  string
+		changes []TextChange
+	}{
+		{
+			name:    "middle edit keeps prefix and suffix",
+			oldDoc:  `s("bd sd hh")`,
+			newDoc:  `s("bd cp hh")`,
+			changes: []TextChange{{From: 6, To: 8, Insert: "cp"}},
+		},
+		{
+			name:    "append",
+			oldDoc:  `s("bd")`,
+			newDoc:  `s("bd").fast(2)`,
+			changes: []TextChange{{From: 7, To: 7, Insert: ".fast(2)"}},
+		},
+		{
+			name:    "identical",
+			oldDoc:  `s("bd")`,
+			newDoc:  `s("bd")`,
+			changes: []TextChange{},
+		},
+		{
+			name:    "from empty",
+			oldDoc:  "",
+			newDoc:  `s("bd")`,
+			changes: []TextChange{{From: 0, To: 0, Insert: `s("bd")`}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op := replacementOperation([]rune(tt.oldDoc), []rune(tt.newDoc))
+			assert.Equal(t, tt.changes, op.changes())
+
+			result, err := op.apply([]rune(tt.oldDoc))
+			require.NoError(t, err)
+			assert.Equal(t, tt.newDoc, string(result))
+		})
+	}
+}
+
+func TestDiffOperation(t *testing.T) {
+	tests := []struct {
+		name   string
+		oldDoc string
+		newDoc string
+	}{
+		{name: "changed line", oldDoc: "s(\"bd\")\nnote(\"c\")", newDoc: "s(\"bd\").fast(2)\nnote(\"c\")"},
+		{name: "inserted line", oldDoc: "a\nc", newDoc: "a\nb\nc"},
+		{name: "removed trailing newline", oldDoc: "a\nb\n", newDoc: "a\nb"},
+		{name: "multibyte", oldDoc: "n(\"ä\")\ns(\"bd\")", newDoc: "n(\"ö\")\ns(\"bd\")"},
+		{name: "from empty", oldDoc: "", newDoc: "s(\"bd\")"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op := diffOperation(tt.oldDoc, tt.newDoc)
+
+			result, err := op.apply([]rune(tt.oldDoc))
+			require.NoError(t, err)
+			assert.Equal(t, tt.newDoc, string(result))
+		})
+	}
+}
+
+func TestTransformOperations(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      string
+		a        []TextChange
+		b        []TextChange
+		expected string
+	}{
+		{
+			name:     "inserts at different positions",
+			doc:      `s("bd sd")`,
+			a:        []TextChange{{From: 3, To: 3, Insert: "cp "}},
+			b:        []TextChange{{From: 10, To: 10, Insert: ".fast(2)"}},
+			expected: `s("cp bd sd").fast(2)`,
+		},
+		{
+			name:     "inserts at the same position put a first",
+			doc:      `s("")`,
+			a:        []TextChange{{From: 3, To: 3, Insert: "bd"}},
+			b:        []TextChange{{From: 3, To: 3, Insert: "hh"}},
+			expected: `s("bdhh")`,
+		},
+		{
+			name:     "insert inside a concurrently deleted range",
+			doc:      `s("bd sd hh")`,
+			a:        []TextChange{{From: 6, To: 6, Insert: "cp "}},
+			b:        []TextChange{{From: 3, To: 11}},
+			expected: `s("cp ")`,
+		},
+		{
+			name:     "overlapping deletes",
+			doc:      `s("bd sd hh oh")`,
+			a:        []TextChange{{From: 3, To: 8}},
+			b:        []TextChange{{From: 6, To: 11}},
+			expected: `s(" oh")`,
+		},
+		{
+			name:     "identical deletes",
+			doc:      `s("bd sd")`,
+			a:        []TextChange{{From: 5, To: 8}},
+			b:        []TextChange{{From: 5, To: 8}},
+			expected: `s("bd")`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := []rune(tt.doc)
+
+			a, err := operationFromChanges(tt.a, len(doc))
+			require.NoError(t, err)
+			b, err := operationFromChanges(tt.b, len(doc))
+			require.NoError(t, err)
+
+			aPrime, bPrime, err := transformOperations(a, b)
+			require.NoError(t, err)
+
+			viaA, err := a.apply(doc)
+			require.NoError(t, err)
+			viaA, err = bPrime.apply(viaA)
+			require.NoError(t, err)
+
+			viaB, err := b.apply(doc)
+			require.NoError(t, err)
+			viaB, err = aPrime.apply(viaB)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.expected, string(viaA))
+			assert.Equal(t, tt.expected, string(viaB))
+		})
+	}
+}
+
+func TestTransformOperationsConverges(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+	alphabet := []rune(`bdsh (").♪`)
+
+	randomOp := func(docLen int) *textOperation {
+		var changes []TextChange
+		pos := 0
+
+		for pos <= docLen && rng.Intn(3) > 0 {
+			from := pos + rng.Intn(docLen-pos+1)
+			to := from + rng.Intn(docLen-from+1)
+
+			insert := make([]rune, rng.Intn(4))
+			for i := range insert {
+				insert[i] = alphabet[rng.Intn(len(alphabet))]
+			}
+
+			changes = append(changes, TextChange{From: from, To: to, Insert: string(insert)})
+			pos = to + 1
+		}
+
+		op, err := operationFromChanges(changes, docLen)
+		require.NoError(t, err)
+		return op
+	}
+
+	for i := range 500 {
+		doc := []rune(`stack(s("bd sd"), note("c e g"))`[:rng.Intn(33)])
+		a := randomOp(len(doc))
+		b := randomOp(len(doc))
+
+		aPrime, bPrime, err := transformOperations(a, b)
+		require.NoError(t, err)
+
+		viaA, err := a.apply(doc)
+		require.NoError(t, err)
+		viaA, err = bPrime.apply(viaA)
+		require.NoError(t, err)
+
+		viaB, err := b.apply(doc)
+		require.NoError(t, err)
+		viaB, err = aPrime.apply(viaB)
+		require.NoError(t, err)
+
+		require.Equal(t, string(viaA), string(viaB), "iteration %d diverged", i)
+	}
+}
@@ -0,0 +1,475 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"codeberg.org/algopatterns/server/internal/logger"
+)
+
+const (
+	// pub/sub channel shared by all instances
+	keyBackplaneChannel = "ws:backplane"
+
+	// sorted set of instance IDs scored by last heartbeat (unix seconds)
+	keyBackplaneInstances = "ws:instances"
+
+	// ws:seq:{sessionID} - cluster-wide message sequence counter
+	keyBackplaneSequence = "ws:seq:%s"
+
+	// ws:presence:{sessionID} - hash of "{instanceID}|{clientID}" -> participant JSON
+	keyBackplanePresence = "ws:presence:%s"
+
+	// ws:conns:user:{userID} and ws:conns:ip:{ip} - hash of instanceID -> connection count
+	keyBackplaneUserConns = "ws:conns:user:%s"
+	keyBackplaneIPConns   = "ws:conns:ip:%s"
+
+	// ws:owner:{sessionID} - instance owning the session document
+	keyBackplaneOwner = "ws:owner:%s"
+
+	// ws:document:{sessionID} - hash with the latest committed code and revision
+	keyBackplaneDocument = "ws:document:%s"
+)
+
+const (
+	// how often an instance refreshes its heartbeat
+	backplaneHeartbeatInterval = 10 * time.Second
+
+	// instances without a heartbeat for this long are ignored in presence and counts
+	backplaneInstanceTTL = 30 * time.Second
+
+	// how long sequence counters and document snapshots outlive their last use
+	backplaneSessionKeyTTL = 24 * time.Hour
+)
+
+// deletes a key only if this instance still holds it
+var releaseOwnerScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// implements Backplane using Redis pub/sub and shared keys
+type RedisBackplane struct {
+	client     *redis.Client
+	instanceID string
+
+	// keys this instance wrote, released on Close
+	mu           sync.Mutex
+	presenceKeys map[string]map[string]bool
+	countKeys    map[string]bool
+	ownerKeys    map[string]bool
+
+	stopHeartbeat chan struct{}
+	closeOnce     sync.Once
+}
+
+// creates a Redis backplane for this instance and starts its heartbeat
+func NewRedisBackplane(client *redis.Client, instanceID string) (*RedisBackplane, error) {
+	b := &RedisBackplane{
+		client:        client,
+		instanceID:    instanceID,
+		presenceKeys:  make(map[string]map[string]bool),
+		countKeys:     make(map[string]bool),
+		ownerKeys:     make(map[string]bool),
+		stopHeartbeat: make(chan struct{}),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), backplaneTimeout)
+	defer cancel()
+
+	if err := b.heartbeat(ctx); err != nil {
+		return nil, fmt.Errorf("failed to register backplane instance: %w", err)
+	}
+
+	go b.heartbeatLoop()
+
+	return b, nil
+}
+
+func (b *RedisBackplane) InstanceID() string {
+	return b.instanceID
+}
+
+func (b *RedisBackplane) Publish(ctx context.Context, event *BackplaneEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal backplane event: %w", err)
+	}
+
+	return b.client.Publish(ctx, keyBackplaneChannel, data).Err()
+}
+
+// delivers events from other instances until ctx is done
+func (b *RedisBackplane) Subscribe(ctx context.Context, handler func(*BackplaneEvent)) error {
+	pubsub := b.client.Subscribe(ctx, keyBackplaneChannel)
+	defer pubsub.Close() //nolint:errcheck // best-effort cleanup
+
+	// wait for the subscription to be confirmed before consuming
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return fmt.Errorf("failed to subscribe to backplane: %w", err)
+	}
+
+	messages := pubsub.Channel()
+
+	for {
+		select {
+		case msg, ok := <-messages:
+			if !ok {
+				return nil
+			}
+
+			var event BackplaneEvent
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				logger.Warn("dropping malformed backplane event", "error", err)
+				continue
+			}
+
+			if event.Origin == b.instanceID {
+				continue
+			}
+
+			handler(&event)
+
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (b *RedisBackplane) NextSequence(ctx context.Context, sessionID string) (uint64, error) {
+	key := fmt.Sprintf(keyBackplaneSequence, sessionID)
+
+	pipe := b.client.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.Expire(ctx, key, backplaneSessionKeyTTL)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to increment sequence: %w", err)
+	}
+
+	return uint64(incr.Val()), nil //nolint:gosec // G115: INCR never returns a negative value
+}
+
+func (b *RedisBackplane) AddPresence(ctx context.Context, sessionID, clientID string, participant SessionStateParticipant) error {
+	data, err := json.Marshal(participant)
+	if err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf(keyBackplanePresence, sessionID)
+	if err := b.client.HSet(ctx, key, b.presenceField(clientID), data).Err(); err != nil {
+		return fmt.Errorf("failed to add presence: %w", err)
+	}
+
+	b.mu.Lock()
+	if b.presenceKeys[key] == nil {
+		b.presenceKeys[key] = make(map[string]bool)
+	}
+	b.presenceKeys[key][b.presenceField(clientID)] = true
+	b.mu.Unlock()
+
+	return nil
+}
+
+func (b *RedisBackplane) RemovePresence(ctx context.Context, sessionID, clientID string) error {
+	key := fmt.Sprintf(keyBackplanePresence, sessionID)
+
+	b.mu.Lock()
+	delete(b.presenceKeys[key], b.presenceField(clientID))
+	if len(b.presenceKeys[key]) == 0 {
+		delete(b.presenceKeys, key)
+	}
+	b.mu.Unlock()
+
+	return b.client.HDel(ctx, key, b.presenceField(clientID)).Err()
+}
+
+// returns participants connected to live instances
+func (b *RedisBackplane) Presence(ctx context.Context, sessionID string) ([]SessionStateParticipant, error) {
+	entries, err := b.client.HGetAll(ctx, fmt.Sprintf(keyBackplanePresence, sessionID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read presence: %w", err)
+	}
+
+	alive, err := b.liveInstances(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	participants := make([]SessionStateParticipant, 0, len(entries))
+
+	for field, data := range entries {
+		instanceID, _, _ := strings.Cut(field, "|")
+		if !alive[instanceID] {
+			continue
+		}
+
+		var participant SessionStateParticipant
+		if err := json.Unmarshal([]byte(data), &participant); err != nil {
+			continue
+		}
+
+		participants = append(participants, participant)
+	}
+
+	return participants, nil
+}
+
+func (b *RedisBackplane) AdjustConnections(ctx context.Context, userID, ipAddress string, delta int) error {
+	pipe := b.client.Pipeline()
+	keys := make([]string, 0, 2)
+
+	if userID != "" {
+		keys = append(keys, fmt.Sprintf(keyBackplaneUserConns, userID))
+	}
+
+	if ipAddress != "" {
+		keys = append(keys, fmt.Sprintf(keyBackplaneIPConns, ipAddress))
+	}
+
+	for _, key := range keys {
+		pipe.HIncrBy(ctx, key, b.instanceID, int64(delta))
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to adjust connection counts: %w", err)
+	}
+
+	b.mu.Lock()
+	for _, key := range keys {
+		b.countKeys[key] = true
+	}
+	b.mu.Unlock()
+
+	return nil
+}
+
+// sums connection counts of live instances
+func (b *RedisBackplane) ConnectionCounts(ctx context.Context, userID, ipAddress string) (int, int, error) {
+	alive, err := b.liveInstances(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	pipe := b.client.Pipeline()
+	userCmd := pipe.HGetAll(ctx, fmt.Sprintf(keyBackplaneUserConns, userID))
+	ipCmd := pipe.HGetAll(ctx, fmt.Sprintf(keyBackplaneIPConns, ipAddress))
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, 0, fmt.Errorf("failed to read connection counts: %w", err)
+	}
+
+	userCount := 0
+	if userID != "" {
+		userCount = sumLiveCounts(userCmd.Val(), alive)
+	}
+
+	return userCount, sumLiveCounts(ipCmd.Val(), alive), nil
+}
+
+func (b *RedisBackplane) ClaimSession(ctx context.Context, sessionID string) (string, bool, error) {
+	key := fmt.Sprintf(keyBackplaneOwner, sessionID)
+
+	acquired, err := b.client.SetNX(ctx, key, b.instanceID, sessionOwnerTTL).Result()
+	if err != nil {
+		return "", false, fmt.Errorf("failed to claim session: %w", err)
+	}
+
+	if acquired {
+		b.trackOwnership(key)
+		return b.instanceID, true, nil
+	}
+
+	owner, err := b.client.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		// the claim expired between SETNX and GET, try once more
+		acquired, err = b.client.SetNX(ctx, key, b.instanceID, sessionOwnerTTL).Result()
+		if err != nil {
+			return "", false, fmt.Errorf("failed to claim session: %w", err)
+		}
+
+		if acquired {
+			b.trackOwnership(key)
+			return b.instanceID, true, nil
+		}
+
+		owner, err = b.client.Get(ctx, key).Result()
+	}
+
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read session owner: %w", err)
+	}
+
+	if owner == b.instanceID {
+		if err := b.client.Expire(ctx, key, sessionOwnerTTL).Err(); err != nil {
+			return "", false, fmt.Errorf("failed to refresh session claim: %w", err)
+		}
+	}
+
+	return owner, false, nil
+}
+
+func (b *RedisBackplane) StoreDocument(ctx context.Context, sessionID, code string, revision uint64) error {
+	key := fmt.Sprintf(keyBackplaneDocument, sessionID)
+
+	pipe := b.client.TxPipeline()
+	pipe.HSet(ctx, key, "code", code, "revision", strconv.FormatUint(revision, 10))
+	pipe.Expire(ctx, key, backplaneSessionKeyTTL)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to store document: %w", err)
+	}
+
+	return nil
+}
+
+func (b *RedisBackplane) LoadDocument(ctx context.Context, sessionID string) (string, uint64, bool, error) {
+	fields, err := b.client.HGetAll(ctx, fmt.Sprintf(keyBackplaneDocument, sessionID)).Result()
+	if err != nil {
+		return "", 0, false, fmt.Errorf("failed to load document: %w", err)
+	}
+
+	revisionField, exists := fields["revision"]
+	if !exists {
+		return "", 0, false, nil
+	}
+
+	revision, err := strconv.ParseUint(revisionField, 10, 64)
+	if err != nil {
+		return "", 0, false, fmt.Errorf("invalid document revision: %w", err)
+	}
+
+	return fields["code"], revision, true, nil
+}
+
+// remembers an owner key so it can be released on Close
+func (b *RedisBackplane) trackOwnership(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.ownerKeys[key] = true
+}
+
+// stops the heartbeat and removes this instance's presence, connection counts and ownership
+func (b *RedisBackplane) Close() error {
+	var err error
+
+	b.closeOnce.Do(func() {
+		close(b.stopHeartbeat)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		b.mu.Lock()
+		pipe := b.client.Pipeline()
+
+		for key, fields := range b.presenceKeys {
+			for field := range fields {
+				pipe.HDel(ctx, key, field)
+			}
+		}
+
+		for key := range b.countKeys {
+			pipe.HDel(ctx, key, b.instanceID)
+		}
+
+		pipe.ZRem(ctx, keyBackplaneInstances, b.instanceID)
+
+		ownerKeys := make([]string, 0, len(b.ownerKeys))
+		for key := range b.ownerKeys {
+			ownerKeys = append(ownerKeys, key)
+		}
+		b.mu.Unlock()
+
+		if _, execErr := pipe.Exec(ctx); execErr != nil {
+			err = fmt.Errorf("failed to release backplane state: %w", execErr)
+		}
+
+		// hand sessions over immediately instead of waiting for the claims to expire
+		for _, key := range ownerKeys {
+			if releaseErr := releaseOwnerScript.Run(ctx, b.client, []string{key}, b.instanceID).Err(); releaseErr != nil {
+				logger.ErrorErr(releaseErr, "failed to release session ownership", "key", key)
+			}
+		}
+	})
+
+	return err
+}
+
+func (b *RedisBackplane) presenceField(clientID string) string {
+	return b.instanceID + "|" + clientID
+}
+
+// records this instance as alive
+func (b *RedisBackplane) heartbeat(ctx context.Context) error {
+	return b.client.ZAdd(ctx, keyBackplaneInstances, redis.Z{
+		Score:  float64(time.Now().Unix()),
+		Member: b.instanceID,
+	}).Err()
+}
+
+func (b *RedisBackplane) heartbeatLoop() {
+	ticker := time.NewTicker(backplaneHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), backplaneTimeout)
+			if err := b.heartbeat(ctx); err != nil {
+				logger.ErrorErr(err, "failed to refresh backplane heartbeat")
+			}
+			cancel()
+
+		case <-b.stopHeartbeat:
+			return
+		}
+	}
+}
+
+// returns the set of instances with a recent heartbeat
+func (b *RedisBackplane) liveInstances(ctx context.Context) (map[string]bool, error) {
+	cutoff := time.Now().Add(-backplaneInstanceTTL).Unix()
+
+	ids, err := b.client.ZRangeByScore(ctx, keyBackplaneInstances, &redis.ZRangeBy{
+		Min: strconv.FormatInt(cutoff, 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list live instances: %w", err)
+	}
+
+	alive := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		alive[id] = true
+	}
+
+	return alive, nil
+}
+
+// sums per-instance counts for live instances
+func sumLiveCounts(counts map[string]string, alive map[string]bool) int {
+	total := 0
+
+	for instanceID, value := range counts {
+		if !alive[instanceID] {
+			continue
+		}
+
+		n, err := strconv.Atoi(value)
+		if err == nil && n > 0 {
+			total += n
+		}
+	}
+
+	return total
+}
@@ -0,0 +1,156 @@
+package websocket
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"codeberg.org/algopatterns/server/internal/logger"
+)
+
+// message types whose effect is already part of session_state, so they are never replayed
+var snapshotMessageTypes = map[string]bool{
+	TypeCodeUpdate: true,
+	TypeCodeOps:    true,
+	TypeUserJoined: true,
+	TypeUserLeft:   true,
+}
+
+// creates a replay buffer for a session whose messages up to floor were never recorded
+func newReplayBuffer(floor uint64) *replayBuffer {
+	return &replayBuffer{
+		entries:  make([]replayEntry, 0, maxReplayMessages),
+		floor:    floor,
+		latest:   floor,
+		lastUsed: time.Now(),
+	}
+}
+
+// records a sequenced broadcast, overwriting the oldest entry once full
+func (b *replayBuffer) record(msg *Message, excludeClientID string, writersOnly bool) {
+	// anything before the first recorded message was missed
+	if !b.recorded && msg.Sequence > b.floor+1 {
+		b.floor = msg.Sequence - 1
+	}
+
+	b.recorded = true
+	b.lastUsed = time.Now()
+	b.latest = max(b.latest, msg.Sequence)
+
+	entry := replayEntry{
+		message:         *msg,
+		excludeClientID: excludeClientID,
+		writersOnly:     writersOnly,
+	}
+
+	if len(b.entries) < maxReplayMessages {
+		b.entries = append(b.entries, entry)
+		return
+	}
+
+	b.floor = max(b.floor, b.entries[b.next].message.Sequence)
+	b.entries[b.next] = entry
+	b.next = (b.next + 1) % maxReplayMessages
+}
+
+// returns the entries after lastSeq in sequence order.
+// returns false if some of them are no longer buffered or lastSeq is from an older sequence.
+func (b *replayBuffer) since(lastSeq uint64) ([]replayEntry, bool) {
+	if lastSeq < b.floor || lastSeq > b.latest {
+		return nil, false
+	}
+
+	entries := make([]replayEntry, 0)
+	for _, entry := range b.entries {
+		if entry.message.Sequence > lastSeq {
+			entries = append(entries, entry)
+		}
+	}
+
+	// broadcasts from other instances may be recorded out of order
+	slices.SortFunc(entries, func(a, b replayEntry) int {
+		return cmp.Compare(a.message.Sequence, b.message.Sequence)
+	})
+
+	return entries, true
+}
+
+// returns the replay buffer of a session, creating it if needed (must be called with lock held)
+func (h *Hub) ensureReplayBuffer(sessionID string) *replayBuffer {
+	buffer, exists := h.replays[sessionID]
+	if !exists {
+		buffer = newReplayBuffer(h.sessionSequences[sessionID])
+		h.replays[sessionID] = buffer
+	}
+
+	buffer.lastUsed = time.Now()
+
+	return buffer
+}
+
+// records a delivered broadcast for sessions with a replay buffer (must be called with lock held)
+func (h *Hub) recordReplay(sessionID string, msg *Message, excludeClientID string, writersOnly bool) {
+	if buffer, exists := h.replays[sessionID]; exists {
+		buffer.record(msg, excludeClientID, writersOnly)
+	}
+}
+
+// returns the messages a reconnecting client missed.
+// returns false if the client must rely on a full session_state instead
+// (must be called with lock held).
+func (h *Hub) missedMessages(client *Client, buffer *replayBuffer) ([]*Message, bool) {
+	if client.LastSequence == 0 {
+		return nil, false
+	}
+
+	entries, ok := buffer.since(client.LastSequence)
+	if !ok {
+		logger.Info("replay gap too large, sending full session state",
+			"client_id", client.ID,
+			"session_id", client.SessionID,
+			"last_seq", client.LastSequence,
+			"latest_seq", buffer.latest,
+		)
+		return nil, false
+	}
+
+	messages := make([]*Message, 0, len(entries))
+
+	for _, entry := range entries {
+		if snapshotMessageTypes[entry.message.Type] {
+			continue
+		}
+
+		if entry.writersOnly && !client.CanWrite() {
+			continue
+		}
+
+		// the sender was excluded and already applied its own message locally.
+		// user IDs can't tell senders apart, anonymous clients all have none.
+		if entry.excludeClientID != "" && entry.excludeClientID == client.PreviousClientID {
+			continue
+		}
+
+		msg := entry.message
+		messages = append(messages, &msg)
+	}
+
+	return messages, true
+}
+
+// drops replay buffers and sequence counters of sessions without local clients
+// that have been idle for longer than replayRetention
+func (h *Hub) evictIdleReplays() {
+	cutoff := time.Now().Add(-replayRetention)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sessionID, buffer := range h.replays {
+		if len(h.sessions[sessionID]) == 0 && buffer.lastUsed.Before(cutoff) {
+			delete(h.replays, sessionID)
+			delete(h.sessionSequences, sessionID)
+			h.dropBroadcastLock(sessionID)
+		}
+	}
+}
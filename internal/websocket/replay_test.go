@@ -0,0 +1,261 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplayBufferSince(t *testing.T) {
+	record := func(buffer *replayBuffer, from, to uint64) {
+		for seq := from; seq <= to; seq++ {
+			buffer.record(&Message{Type: TypeChatMessage, Sequence: seq}, "", false)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		setup    func() *replayBuffer
+		lastSeq  uint64
+		wantOK   bool
+		wantSeqs []uint64
+	}{
+		{
+			name: "replays everything after last_seq",
+			setup: func() *replayBuffer {
+				b := newReplayBuffer(0)
+				record(b, 1, 5)
+				return b
+			},
+			lastSeq:  2,
+			wantOK:   true,
+			wantSeqs: []uint64{3, 4, 5},
+		},
+		{
+			name: "client already up to date",
+			setup: func() *replayBuffer {
+				b := newReplayBuffer(0)
+				record(b, 1, 5)
+				return b
+			},
+			lastSeq:  5,
+			wantOK:   true,
+			wantSeqs: []uint64{},
+		},
+		{
+			name: "oldest messages fell out of the ring",
+			setup: func() *replayBuffer {
+				b := newReplayBuffer(0)
+				record(b, 1, maxReplayMessages+10)
+				return b
+			},
+			lastSeq: 5,
+			wantOK:  false,
+		},
+		{
+			name: "last message before the ring is still complete",
+			setup: func() *replayBuffer {
+				b := newReplayBuffer(0)
+				record(b, 1, maxReplayMessages+10)
+				return b
+			},
+			lastSeq:  maxReplayMessages + 8,
+			wantOK:   true,
+			wantSeqs: []uint64{maxReplayMessages + 9, maxReplayMessages + 10},
+		},
+		{
+			name: "buffer created after the client's last message",
+			setup: func() *replayBuffer {
+				b := newReplayBuffer(0)
+				record(b, 40, 42)
+				return b
+			},
+			lastSeq: 20,
+			wantOK:  false,
+		},
+		{
+			name: "sequence ahead of the session (counter was reset)",
+			setup: func() *replayBuffer {
+				b := newReplayBuffer(0)
+				record(b, 1, 3)
+				return b
+			},
+			lastSeq: 50,
+			wantOK:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, ok := tt.setup().since(tt.lastSeq)
+			assert.Equal(t, tt.wantOK, ok)
+
+			if !tt.wantOK {
+				return
+			}
+
+			seqs := make([]uint64, 0, len(entries))
+			for _, entry := range entries {
+				seqs = append(seqs, entry.message.Sequence)
+			}
+			assert.Equal(t, tt.wantSeqs, seqs)
+		})
+	}
+}
+
+func TestHubReplaysMissedMessagesOnReconnect(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+	defer hub.Shutdown()
+
+	newClient := func(id, userID, role string, lastSeq uint64) *Client {
+		return &Client{
+			ID:                 id,
+			SessionID:          "session-1",
+			UserID:             userID,
+			DisplayName:        id,
+			Role:               role,
+			InitialChatHistory: []SessionStateChatMessage{{Content: "from the database"}},
+			LastSequence:       lastSeq,
+			hub:                hub,
+			send:               make(chan []byte, 256),
+		}
+	}
+
+	host := newClient("client-1", "user-1", "host", 0)
+	hub.Register <- host
+
+	viewer := newClient("client-2", "user-2", "viewer", 0)
+	hub.Register <- viewer
+
+	var state SessionStatePayload
+	require.NoError(t, receiveType(t, viewer, TypeSessionState).UnmarshalPayload(&state))
+
+	welcome, err := NewMessage(TypeChatMessage, "session-1", "user-1", ChatMessagePayload{Message: "welcome"})
+	require.NoError(t, err)
+	hub.BroadcastToSession("session-1", welcome, "")
+	lastSeq := receiveType(t, viewer, TypeChatMessage).Sequence
+
+	// the viewer drops, then misses a chat message and a writers-only play
+	hub.Unregister <- viewer
+	time.Sleep(50 * time.Millisecond)
+
+	chat, err := NewMessage(TypeChatMessage, "session-1", "user-1", ChatMessagePayload{Message: "still there?"})
+	require.NoError(t, err)
+	hub.BroadcastToSession("session-1", chat, "")
+
+	play, err := NewMessage(TypePlay, "session-1", "user-1", nil)
+	require.NoError(t, err)
+	hub.BroadcastToWriters("session-1", play, host.ID)
+
+	stop, err := NewMessage(TypeStop, "session-1", "user-1", nil)
+	require.NoError(t, err)
+	hub.BroadcastToSession("session-1", stop, host.ID)
+
+	reconnected := newClient("client-3", "user-2", "viewer", lastSeq)
+	hub.Register <- reconnected
+
+	require.NoError(t, receiveType(t, reconnected, TypeSessionState).UnmarshalPayload(&state))
+	assert.True(t, state.Replayed)
+	assert.Empty(t, state.ChatHistory)
+	assert.Equal(t, stop.Sequence, state.Sequence)
+
+	replayedChat := receiveType(t, reconnected, TypeChatMessage)
+	assert.Equal(t, chat.Sequence, replayedChat.Sequence)
+
+	// the writers-only play is skipped for a viewer
+	replayedStop := receiveType(t, reconnected, TypeStop)
+	assert.Equal(t, stop.Sequence, replayedStop.Sequence)
+
+	// a client too far behind gets the full snapshot instead
+	stale := newClient("client-4", "user-3", "viewer", stop.Sequence+100)
+	hub.Register <- stale
+
+	var staleState SessionStatePayload
+	require.NoError(t, receiveType(t, stale, TypeSessionState).UnmarshalPayload(&staleState))
+	assert.False(t, staleState.Replayed)
+	assert.Len(t, staleState.ChatHistory, 1)
+}
+
+func TestHubKeepsSequenceAcrossEmptySession(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+	defer hub.Shutdown()
+
+	client := newBackplaneClient(hub, "client-1", "user-1", "host")
+	hub.Register <- client
+
+	receiveType(t, client, TypeSessionState)
+
+	play, err := NewMessage(TypePlay, "session-1", "user-1", nil)
+	require.NoError(t, err)
+	hub.BroadcastToSession("session-1", play, "")
+	lastSeq := receiveType(t, client, TypePlay).Sequence
+
+	hub.Unregister <- client
+	time.Sleep(50 * time.Millisecond)
+
+	// the only client reconnects after its session emptied
+	again := newBackplaneClient(hub, "client-2", "user-1", "host")
+	again.LastSequence = lastSeq
+	hub.Register <- again
+
+	var state SessionStatePayload
+	require.NoError(t, receiveType(t, again, TypeSessionState).UnmarshalPayload(&state))
+	assert.True(t, state.Replayed)
+	assert.Equal(t, lastSeq, state.Sequence)
+}
+
+func TestHubReplaySkipsOwnMessagesOfAnonymousClients(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+	defer hub.Shutdown()
+
+	first := newBackplaneClient(hub, "client-1", "", "co-author")
+	hub.Register <- first
+	receiveType(t, first, TypeSessionState)
+
+	second := newBackplaneClient(hub, "client-2", "", "co-author")
+	hub.Register <- second
+	receiveType(t, second, TypeSessionState)
+
+	welcome, err := NewMessage(TypeChatMessage, "session-1", "", ChatMessagePayload{Message: "welcome"})
+	require.NoError(t, err)
+	hub.BroadcastToSession("session-1", welcome, "")
+	lastSeq := receiveType(t, first, TypeChatMessage).Sequence
+
+	// both anonymous clients send, each excluded from its own broadcast
+	own, err := NewMessage(TypeChatMessage, "session-1", "", ChatMessagePayload{Message: "mine"})
+	require.NoError(t, err)
+	hub.BroadcastToSession("session-1", own, first.ID)
+
+	other, err := NewMessage(TypeChatMessage, "session-1", "", ChatMessagePayload{Message: "theirs"})
+	require.NoError(t, err)
+	hub.BroadcastToSession("session-1", other, second.ID)
+
+	hub.Unregister <- first
+	time.Sleep(50 * time.Millisecond)
+
+	reconnected := newBackplaneClient(hub, "client-3", "", "co-author")
+	reconnected.LastSequence = lastSeq
+	reconnected.PreviousClientID = first.ID
+	hub.Register <- reconnected
+
+	var state SessionStatePayload
+	require.NoError(t, receiveType(t, reconnected, TypeSessionState).UnmarshalPayload(&state))
+	assert.True(t, state.Replayed)
+	assert.Equal(t, "client-3", state.YourClientID)
+
+	// only the other client's message comes back
+	var chat ChatMessagePayload
+	require.NoError(t, receiveType(t, reconnected, TypeChatMessage).UnmarshalPayload(&chat))
+	assert.Equal(t, "theirs", chat.Message)
+
+	select {
+	case raw := <-reconnected.send:
+		t.Fatalf("unexpected message after replay: %s", raw)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -1,6 +1,7 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"sync"
@@ -52,6 +53,15 @@ const (
 
 	// is sent when a user moves their cursor
 	TypeCursorPosition = "cursor_position"
+
+	// is sent when a user makes incremental edits against a document revision
+	TypeCodeOps = "code_ops"
+
+	// is sent to the author of code_ops once the server has committed them
+	TypeCodeOpsAck = "code_ops_ack"
+
+	// is sent when a client's ops cannot be rebased and it must reload the document
+	TypeCodeResync = "code_resync"
 )
 
 // client connection constants
@@ -83,6 +93,47 @@ const (
 	maxConnectionsPerIP   = 10
 )
 
+// backplane constants
+const (
+	// time allowed for a single backplane call made while handling a message
+	backplaneTimeout = 2 * time.Second
+
+	// how long a session's document ownership lasts without edits
+	sessionOwnerTTL = 2 * time.Minute
+
+	// how often idle documents without local clients are evicted
+	documentEvictionInterval = time.Minute
+)
+
+// backplane event kinds
+const (
+	// a message to deliver to every client of a session
+	backplaneBroadcast = "broadcast"
+
+	// a session was ended and all its clients must be disconnected
+	backplaneEndSession = "end_session"
+
+	// a document edit forwarded to the instance that owns the session document
+	backplaneForward = "forward"
+
+	// raw messages for a single client connected to another instance
+	backplaneDirect = "direct"
+)
+
+// replay constants
+const (
+	// number of recent broadcasts kept per session for reconnecting clients.
+	// clients further behind than this get a full session_state instead.
+	maxReplayMessages = 256
+
+	// how long a session's replay buffer outlives its last local client
+	replayRetention = 5 * time.Minute
+)
+
+// number of committed operations kept per document for transforming late ops.
+// clients further behind than this are sent a code_resync instead.
+const maxDocumentHistory = 500
+
 // errors
 var (
 	ErrSessionNotFound         = errors.New("session not found")
@@ -95,6 +146,9 @@ var (
 	ErrConnectionClosed        = errors.New("connection closed")
 	ErrRateLimitExceeded       = errors.New("rate limit exceeded")
 	ErrCodeTooLarge            = errors.New("code too large")
+	ErrInvalidOperation        = errors.New("invalid operation")
+	ErrRevisionTooOld          = errors.New("revision too old")
+	ErrHandlerNotRegistered    = errors.New("handler not registered")
 )
 
 // represents a websocket message with typed payload
@@ -115,8 +169,41 @@ type CodeUpdatePayload struct {
 	CursorCol   int    `json:"cursor_col,omitempty"`
 	DisplayName string `json:"display_name,omitempty"`
 	UserID      string `json:"user_id,omitempty"`
-	Role        string `json:"role,omitempty"`   // "host", "co-author" - for cursor tracking
-	Source      string `json:"source,omitempty"` // 'typed' | 'loaded_strudel' | 'forked' | 'paste'
+	Role        string `json:"role,omitempty"`     // "host", "co-author" - for cursor tracking
+	Source      string `json:"source,omitempty"`   // 'typed' | 'loaded_strudel' | 'forked' | 'paste' | 'restored' | 'ai'
+	Revision    uint64 `json:"revision,omitempty"` // document revision after this update (added by backend)
+}
+
+// a single edit against a document revision. offsets are unicode code points,
+// and every change in a batch is relative to the same base document.
+type TextChange struct {
+	From   int    `json:"from"`
+	To     int    `json:"to"`
+	Insert string `json:"insert,omitempty"`
+}
+
+// contains incremental edits made against a document revision
+type CodeOpsPayload struct {
+	Revision    uint64       `json:"revision"` // client: base revision, broadcast: revision after applying
+	Changes     []TextChange `json:"changes"`
+	CursorLine  int          `json:"cursor_line,omitempty"`
+	CursorCol   int          `json:"cursor_col,omitempty"`
+	DisplayName string       `json:"display_name,omitempty"`
+	UserID      string       `json:"user_id,omitempty"`
+	Role        string       `json:"role,omitempty"`
+	Source      string       `json:"source,omitempty"` // 'typed' | 'paste' | 'ai'
+}
+
+// acknowledges that a client's code_ops were committed
+type CodeOpsAckPayload struct {
+	Revision uint64 `json:"revision"`
+}
+
+// contains the authoritative document for a client that fell out of sync
+type CodeResyncPayload struct {
+	Code     string `json:"code"`
+	Revision uint64 `json:"revision"`
+	Reason   string `json:"reason"` // "revision_too_old", "invalid_operation"
 }
 
 // contains information about a newly joined user
@@ -146,10 +233,14 @@ type ServerShutdownPayload struct {
 // contains session info sent to connecting client
 type SessionStatePayload struct {
 	Code            string                    `json:"code"`
+	Revision        uint64                    `json:"revision"`
+	YourClientID    string                    `json:"your_client_id"` // pass as previous_client_id when reconnecting
 	YourRole        string                    `json:"your_role"`
 	YourDisplayName string                    `json:"your_display_name"`
 	Participants    []SessionStateParticipant `json:"participants"`
 	ChatHistory     []SessionStateChatMessage `json:"chat_history"`
+	Sequence        uint64                    `json:"seq"`                // latest message sequence in the session
+	Replayed        bool                      `json:"replayed,omitempty"` // missed messages follow instead of chat history
 }
 
 // represents a chat message in the chat history
@@ -226,6 +317,12 @@ type Client struct {
 	// initial chat history to send on connect
 	InitialChatHistory []SessionStateChatMessage
 
+	// sequence of the last message seen before reconnecting (0 for a fresh connection)
+	LastSequence uint64
+
+	// client ID of the dropped connection when reconnecting, to skip replaying its own messages
+	PreviousClientID string
+
 	// websocket connection
 	conn *websocket.Conn
 
@@ -288,7 +385,156 @@ type Hub struct {
 
 	// callback for client registered (e.g., send paste lock status)
 	onClientRegistered func(client *Client)
+
+	// authoritative documents per session for code_ops
+	documents map[string]*Document
+
+	// recent broadcasts per session for reconnecting clients
+	replays map[string]*replayBuffer
+
+	// serializes broadcasts per session so sequence numbers reach clients in order
+	broadcastLocks map[string]*broadcastLock
+
+	// optional fan-out to other server instances (nil for a single instance)
+	backplane Backplane
+
+	// stops the backplane subscription on shutdown
+	backplaneCancel context.CancelFunc
+}
+
+// fans hub state and messages out across server instances
+type Backplane interface {
+	// identifies this instance in published events
+	InstanceID() string
+
+	// sends an event to every other instance
+	Publish(ctx context.Context, event *BackplaneEvent) error
+
+	// delivers events published by other instances until ctx is done
+	Subscribe(ctx context.Context, handler func(*BackplaneEvent)) error
+
+	// returns the next cluster-wide sequence number for a session
+	NextSequence(ctx context.Context, sessionID string) (uint64, error)
+
+	// maintains the cluster-wide list of connected participants
+	AddPresence(ctx context.Context, sessionID, clientID string, participant SessionStateParticipant) error
+	RemovePresence(ctx context.Context, sessionID, clientID string) error
+	Presence(ctx context.Context, sessionID string) ([]SessionStateParticipant, error)
+
+	// maintains cluster-wide connection counts (empty userID or ipAddress is skipped)
+	AdjustConnections(ctx context.Context, userID, ipAddress string, delta int) error
+	ConnectionCounts(ctx context.Context, userID, ipAddress string) (userCount, ipCount int, err error)
+
+	// claims or refreshes ownership of a session document. returns the owning instance
+	// and whether this call newly acquired it.
+	ClaimSession(ctx context.Context, sessionID string) (owner string, acquired bool, err error)
+
+	// stores and loads the latest committed document so any instance can snapshot it
+	StoreDocument(ctx context.Context, sessionID, code string, revision uint64) error
+	LoadDocument(ctx context.Context, sessionID string) (code string, revision uint64, found bool, err error)
+
+	// releases everything this instance holds (presence, connection counts, ownership)
+	Close() error
+}
+
+// an event exchanged between instances over the backplane
+type BackplaneEvent struct {
+	Kind      string `json:"kind"`
+	Origin    string `json:"origin"`
+	Target    string `json:"target,omitempty"` // instance for forward and direct events
+	SessionID string `json:"session_id"`
+
+	// broadcast: the message and delivery filters
+	Message         *Message `json:"message,omitempty"`
+	ExcludeClientID string   `json:"exclude_client_id,omitempty"`
+	WritersOnly     bool     `json:"writers_only,omitempty"`
+
+	// end_session: why the session ended
+	Reason string `json:"reason,omitempty"`
+
+	// forward: the client that sent the message
+	Client *RemoteClient `json:"client,omitempty"`
+
+	// direct: the recipient and the raw messages to write to it
+	ClientID string   `json:"client_id,omitempty"`
+	Frames   [][]byte `json:"frames,omitempty"`
+}
+
+// identifies a client connected to another instance
+type RemoteClient struct {
+	ID              string `json:"id"`
+	UserID          string `json:"user_id,omitempty"`
+	DisplayName     string `json:"display_name"`
+	Role            string `json:"role"`
+	IsAuthenticated bool   `json:"is_authenticated"`
+	InitialCode     string `json:"initial_code"`
+}
+
+// the authoritative code of a session that concurrent code_ops are transformed against
+type Document struct {
+	// current text as code points so offsets match operation lengths
+	text []rune
+
+	// number of operations committed so far
+	revision uint64
+
+	// most recent committed operations, history[len-1] produced revision
+	history []*textOperation
+
+	// last time an operation was committed or the document was created
+	lastUsed time.Time
+
+	// serializes applies so commits are persisted and broadcast in order
+	mu sync.Mutex
+}
+
+// the result of committing an operation to a document
+type DocumentCommit struct {
+	// revision after the commit
+	Revision uint64
+
+	// transformed changes, relative to the document at Revision-1
+	Changes []TextChange
+
+	// code before and after the commit
+	PreviousCode string
+	Code         string
 }
 
 // processes a specific message type
 type MessageHandler func(hub *Hub, client *Client, msg *Message) error
+
+// serializes broadcasts to one session
+type broadcastLock struct {
+	sync.Mutex
+
+	// broadcasts holding or waiting for the lock (guarded by the hub lock)
+	users int
+}
+
+// a bounded ring of recent broadcasts in a session
+type replayBuffer struct {
+	entries []replayEntry
+
+	// index of the next slot to overwrite once the ring is full
+	next int
+
+	// highest sequence known to be missing from the buffer
+	floor uint64
+
+	// highest sequence recorded
+	latest uint64
+
+	// whether anything has been recorded yet
+	recorded bool
+
+	// last time the buffer was written or a local client joined or left
+	lastUsed time.Time
+}
+
+// a broadcast as it was delivered
+type replayEntry struct {
+	message         Message
+	excludeClientID string
+	writersOnly     bool
+}
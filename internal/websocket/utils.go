@@ -76,3 +76,19 @@ func GenerateClientID() (string, error) {
 
 	return hex.EncodeToString(bytes), nil
 }
+
+// returns a backplane instance ID of the form "{hostname}-{random}"
+func GenerateInstanceID() (string, error) {
+	bytes := make([]byte, 4)
+
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "instance"
+	}
+
+	return hostname + "-" + hex.EncodeToString(bytes), nil
+}
@@ -0,0 +1,19 @@
+-- Session version history: immutable snapshots of session code
+CREATE TABLE IF NOT EXISTS session_snapshots (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  session_id UUID NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+  code TEXT NOT NULL,
+  authors JSONB NOT NULL DEFAULT '[]'::jsonb, -- [{user_id, display_name, role}] connected writers at snapshot time
+  reason TEXT NOT NULL CHECK (reason IN ('periodic', 'manual', 'restore')),
+  label TEXT,
+  created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  restored_from UUID REFERENCES session_snapshots(id) ON DELETE SET NULL,
+  created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Index for listing a session's history newest first
+CREATE INDEX IF NOT EXISTS idx_session_snapshots_session
+ON session_snapshots(session_id, created_at DESC);
+
+COMMENT ON TABLE session_snapshots IS 'Point-in-time copies of session code for history, diff and restore';
+COMMENT ON COLUMN session_snapshots.reason IS 'periodic (background), manual (user request) or restore (written when a snapshot is restored)';
@@ -0,0 +1,27 @@
+-- Strudel revision history: an immutable row for every change to code, title or license
+
+CREATE TABLE IF NOT EXISTS strudel_revisions (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  strudel_id UUID NOT NULL REFERENCES user_strudels(id) ON DELETE CASCADE,
+  revision INTEGER NOT NULL,
+  title TEXT NOT NULL,
+  code TEXT NOT NULL,
+  license TEXT,
+  restored_from INTEGER, -- revision number this one was restored from
+  created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+  created_at TIMESTAMPTZ DEFAULT NOW(),
+  UNIQUE (strudel_id, revision)
+);
+
+-- Index for listing a strudel's revisions newest first
+CREATE INDEX IF NOT EXISTS idx_strudel_revisions_strudel
+ON strudel_revisions(strudel_id, revision DESC);
+
+-- Existing strudels start their history at their current state
+INSERT INTO strudel_revisions (strudel_id, revision, title, code, license, created_by, created_at)
+SELECT id, 1, title, code, license, user_id, updated_at
+FROM user_strudels
+ON CONFLICT (strudel_id, revision) DO NOTHING;
+
+COMMENT ON TABLE strudel_revisions IS 'Immutable versions of saved strudels for history and rollback';
+COMMENT ON COLUMN strudel_revisions.revision IS 'Per-strudel revision number starting at 1';
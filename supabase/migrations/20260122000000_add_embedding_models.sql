@@ -0,0 +1,145 @@
+-- Embedding model registry: tag stored vectors with their model and dimension,
+-- stage vectors for a new model in the background and cut over atomically
+
+CREATE TABLE IF NOT EXISTS embedding_models (
+  model TEXT PRIMARY KEY,
+  provider TEXT NOT NULL,
+  dimension INTEGER NOT NULL CHECK (dimension > 0 AND dimension <= 2000), -- ivfflat limit
+  status TEXT NOT NULL DEFAULT 'building' CHECK (status IN ('building', 'active', 'retired')),
+  created_at TIMESTAMPTZ DEFAULT NOW(),
+  activated_at TIMESTAMPTZ
+);
+
+-- At most one model serves queries at a time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_embedding_models_active
+ON embedding_models(status)
+WHERE status = 'active';
+
+-- Everything stored so far was embedded with text-embedding-3-small
+INSERT INTO embedding_models (model, provider, dimension, status, activated_at)
+VALUES ('text-embedding-3-small', 'openai', 1536, 'active', NOW())
+ON CONFLICT (model) DO NOTHING;
+
+COMMENT ON TABLE embedding_models IS 'Embedding models known to the retriever; the active one embeds queries';
+COMMENT ON COLUMN embedding_models.status IS 'building (re-embed in progress), active (serving queries) or retired';
+
+-- ============================================================================
+-- TAG LIVE VECTORS
+-- ============================================================================
+
+ALTER TABLE doc_embeddings
+ADD COLUMN IF NOT EXISTS embedding_model TEXT,
+ADD COLUMN IF NOT EXISTS embedding_dim INTEGER;
+
+ALTER TABLE user_strudels
+ADD COLUMN IF NOT EXISTS embedding_model TEXT,
+ADD COLUMN IF NOT EXISTS embedding_dim INTEGER;
+
+UPDATE doc_embeddings
+SET embedding_model = 'text-embedding-3-small', embedding_dim = 1536
+WHERE embedding IS NOT NULL AND embedding_model IS NULL;
+
+UPDATE user_strudels
+SET embedding_model = 'text-embedding-3-small', embedding_dim = 1536
+WHERE embedding IS NOT NULL AND embedding_model IS NULL;
+
+COMMENT ON COLUMN doc_embeddings.embedding_model IS 'Model that produced embedding';
+COMMENT ON COLUMN user_strudels.embedding_model IS 'Model that produced embedding';
+
+-- ============================================================================
+-- STAGED VECTORS FOR MODELS BEING BUILT
+-- ============================================================================
+
+-- Dimensionless columns so any model fits; live columns stay typed and indexed
+CREATE TABLE IF NOT EXISTS doc_embeddings_staged (
+  doc_id UUID NOT NULL REFERENCES doc_embeddings(id) ON DELETE CASCADE,
+  model TEXT NOT NULL REFERENCES embedding_models(model) ON DELETE CASCADE,
+  embedding extensions.vector NOT NULL,
+  created_at TIMESTAMPTZ DEFAULT NOW(),
+  PRIMARY KEY (doc_id, model)
+);
+
+CREATE TABLE IF NOT EXISTS user_strudel_embeddings_staged (
+  strudel_id UUID NOT NULL REFERENCES user_strudels(id) ON DELETE CASCADE,
+  model TEXT NOT NULL REFERENCES embedding_models(model) ON DELETE CASCADE,
+  embedding extensions.vector NOT NULL,
+  created_at TIMESTAMPTZ DEFAULT NOW(),
+  PRIMARY KEY (strudel_id, model)
+);
+
+COMMENT ON TABLE doc_embeddings_staged IS 'Doc vectors for an embedding model that is not active yet';
+COMMENT ON TABLE user_strudel_embeddings_staged IS 'Strudel vectors for an embedding model that is not active yet';
+
+-- ============================================================================
+-- CUTOVER
+-- ============================================================================
+
+-- Swaps staged vectors into the live columns in one transaction. The ALTERs
+-- lock both tables until commit, so searches never see a mix of models.
+-- Strudels without a staged vector are left unembedded and picked up by the
+-- embedding backfill.
+CREATE OR REPLACE FUNCTION activate_embedding_model(p_model TEXT)
+RETURNS VOID
+LANGUAGE plpgsql
+AS $$
+DECLARE
+  v_dimension INTEGER;
+  v_missing INTEGER;
+BEGIN
+  SELECT dimension INTO v_dimension
+  FROM embedding_models
+  WHERE model = p_model AND status = 'building'
+  FOR UPDATE;
+
+  IF v_dimension IS NULL THEN
+    RAISE EXCEPTION 'embedding model % is not being built', p_model;
+  END IF;
+
+  SELECT COUNT(*) INTO v_missing
+  FROM doc_embeddings d
+  LEFT JOIN doc_embeddings_staged s ON s.doc_id = d.id AND s.model = p_model
+  WHERE s.doc_id IS NULL;
+
+  IF v_missing > 0 THEN
+    RAISE EXCEPTION '% doc chunks have no % embedding yet', v_missing, p_model;
+  END IF;
+
+  DROP INDEX IF EXISTS doc_embeddings_embedding_idx;
+  DROP INDEX IF EXISTS idx_user_strudels_embedding;
+
+  EXECUTE format('ALTER TABLE doc_embeddings ALTER COLUMN embedding TYPE extensions.vector(%s) USING NULL', v_dimension);
+  EXECUTE format('ALTER TABLE user_strudels ALTER COLUMN embedding TYPE extensions.vector(%s) USING NULL', v_dimension);
+
+  UPDATE doc_embeddings d
+  SET embedding = s.embedding, embedding_model = p_model, embedding_dim = v_dimension
+  FROM doc_embeddings_staged s
+  WHERE s.doc_id = d.id AND s.model = p_model;
+
+  UPDATE user_strudels us
+  SET embedding_model = NULL, embedding_dim = NULL
+  WHERE us.embedding_model IS NOT NULL;
+
+  UPDATE user_strudels us
+  SET embedding = s.embedding, embedding_model = p_model, embedding_dim = v_dimension
+  FROM user_strudel_embeddings_staged s
+  WHERE s.strudel_id = us.id AND s.model = p_model;
+
+  CREATE INDEX doc_embeddings_embedding_idx
+  ON doc_embeddings
+  USING ivfflat (embedding extensions.vector_cosine_ops)
+  WITH (lists = 100);
+
+  CREATE INDEX idx_user_strudels_embedding
+  ON user_strudels
+  USING ivfflat (embedding extensions.vector_cosine_ops)
+  WITH (lists = 100);
+
+  UPDATE embedding_models SET status = 'retired' WHERE status = 'active';
+  UPDATE embedding_models SET status = 'active', activated_at = NOW() WHERE model = p_model;
+
+  DELETE FROM doc_embeddings_staged WHERE model = p_model;
+  DELETE FROM user_strudel_embeddings_staged WHERE model = p_model;
+END;
+$$;
+
+COMMENT ON FUNCTION activate_embedding_model IS 'Atomically replaces live doc and strudel vectors with the staged vectors of a model';
@@ -0,0 +1,14 @@
+-- Incremental documentation ingestion: identify chunks within their page and
+-- hash their content so unchanged chunks are not embedded again
+
+ALTER TABLE doc_embeddings
+ADD COLUMN IF NOT EXISTS chunk_key TEXT,
+ADD COLUMN IF NOT EXISTS content_hash TEXT;
+
+-- Upsert target; rows ingested before this migration have no key and are
+-- replaced on the next ingestion
+CREATE UNIQUE INDEX IF NOT EXISTS idx_doc_embeddings_page_chunk
+ON doc_embeddings(page_url, chunk_key);
+
+COMMENT ON COLUMN doc_embeddings.chunk_key IS 'Section title and position, unique within the page';
+COMMENT ON COLUMN doc_embeddings.content_hash IS 'SHA-256 of url, section title, content and metadata';
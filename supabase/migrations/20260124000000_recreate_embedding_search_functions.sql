@@ -0,0 +1,171 @@
+-- Vector search functions follow the active embedding model: the cutover
+-- recreates search_docs and search_user_strudels for the new dimension in the
+-- same transaction that changes the embedding columns
+
+-- ============================================================================
+-- SEARCH FUNCTIONS FOR A DIMENSION
+-- ============================================================================
+
+CREATE OR REPLACE FUNCTION create_embedding_search_functions(p_dimension INTEGER)
+RETURNS VOID
+LANGUAGE plpgsql
+AS $fn$
+BEGIN
+  EXECUTE format($sql$
+    CREATE OR REPLACE FUNCTION search_docs(
+        query_embedding extensions.vector(%s),
+        match_count int DEFAULT 5
+    )
+    RETURNS TABLE (
+        id UUID,
+        page_name TEXT,
+        page_url TEXT,
+        section_title TEXT,
+        content TEXT,
+        similarity FLOAT
+    )
+    LANGUAGE plpgsql STABLE
+    AS $$
+    BEGIN
+        PERFORM set_config('search_path', 'extensions, public', true);
+
+        RETURN QUERY
+        SELECT
+            d.id,
+            d.page_name,
+            d.page_url,
+            d.section_title,
+            d.content,
+            1 - (d.embedding <=> query_embedding) AS similarity
+        FROM doc_embeddings d
+        ORDER BY d.embedding <=> query_embedding
+        LIMIT match_count;
+    END;
+    $$
+  $sql$, p_dimension);
+
+  EXECUTE format($sql$
+    CREATE OR REPLACE FUNCTION search_user_strudels(
+        query_embedding extensions.vector(%s),
+        match_count int DEFAULT 3
+    )
+    RETURNS TABLE (
+        id UUID,
+        title TEXT,
+        description TEXT,
+        code TEXT,
+        tags TEXT[],
+        user_id UUID,
+        similarity FLOAT
+    )
+    LANGUAGE plpgsql STABLE
+    AS $$
+    BEGIN
+        PERFORM set_config('search_path', 'extensions, public', true);
+
+        RETURN QUERY
+        SELECT
+            us.id,
+            us.title,
+            us.description,
+            us.code,
+            us.tags,
+            us.user_id,
+            1 - (us.embedding <=> query_embedding) AS similarity
+        FROM user_strudels us
+        INNER JOIN users u ON us.user_id = u.id
+        WHERE us.cc_signal IS NOT NULL          -- opt-in: must have signal
+          AND us.cc_signal != 'no-ai'           -- not explicitly blocked
+          AND us.use_in_training = true         -- admin curation
+          AND us.is_public = true
+          AND us.embedding IS NOT NULL
+          AND u.training_consent = true         -- user global consent
+        ORDER BY us.embedding <=> query_embedding
+        LIMIT match_count;
+    END;
+    $$
+  $sql$, p_dimension);
+
+  COMMENT ON FUNCTION search_user_strudels IS 'Search trainable user strudels by vector similarity (requires cc_signal + use_in_training + is_public + user.training_consent)';
+END;
+$fn$;
+
+COMMENT ON FUNCTION create_embedding_search_functions IS 'Recreates search_docs and search_user_strudels for query vectors of the given dimension';
+
+SELECT create_embedding_search_functions(dimension)
+FROM embedding_models
+WHERE status = 'active';
+
+-- ============================================================================
+-- CUTOVER
+-- ============================================================================
+
+-- Same as before, and recreates the search functions for the new dimension
+-- before the model is marked active
+CREATE OR REPLACE FUNCTION activate_embedding_model(p_model TEXT)
+RETURNS VOID
+LANGUAGE plpgsql
+AS $$
+DECLARE
+  v_dimension INTEGER;
+  v_missing INTEGER;
+BEGIN
+  SELECT dimension INTO v_dimension
+  FROM embedding_models
+  WHERE model = p_model AND status = 'building'
+  FOR UPDATE;
+
+  IF v_dimension IS NULL THEN
+    RAISE EXCEPTION 'embedding model % is not being built', p_model;
+  END IF;
+
+  SELECT COUNT(*) INTO v_missing
+  FROM doc_embeddings d
+  LEFT JOIN doc_embeddings_staged s ON s.doc_id = d.id AND s.model = p_model
+  WHERE s.doc_id IS NULL;
+
+  IF v_missing > 0 THEN
+    RAISE EXCEPTION '% doc chunks have no % embedding yet', v_missing, p_model;
+  END IF;
+
+  DROP INDEX IF EXISTS doc_embeddings_embedding_idx;
+  DROP INDEX IF EXISTS idx_user_strudels_embedding;
+
+  EXECUTE format('ALTER TABLE doc_embeddings ALTER COLUMN embedding TYPE extensions.vector(%s) USING NULL', v_dimension);
+  EXECUTE format('ALTER TABLE user_strudels ALTER COLUMN embedding TYPE extensions.vector(%s) USING NULL', v_dimension);
+
+  UPDATE doc_embeddings d
+  SET embedding = s.embedding, embedding_model = p_model, embedding_dim = v_dimension
+  FROM doc_embeddings_staged s
+  WHERE s.doc_id = d.id AND s.model = p_model;
+
+  UPDATE user_strudels us
+  SET embedding_model = NULL, embedding_dim = NULL
+  WHERE us.embedding_model IS NOT NULL;
+
+  UPDATE user_strudels us
+  SET embedding = s.embedding, embedding_model = p_model, embedding_dim = v_dimension
+  FROM user_strudel_embeddings_staged s
+  WHERE s.strudel_id = us.id AND s.model = p_model;
+
+  CREATE INDEX doc_embeddings_embedding_idx
+  ON doc_embeddings
+  USING ivfflat (embedding extensions.vector_cosine_ops)
+  WITH (lists = 100);
+
+  CREATE INDEX idx_user_strudels_embedding
+  ON user_strudels
+  USING ivfflat (embedding extensions.vector_cosine_ops)
+  WITH (lists = 100);
+
+  PERFORM create_embedding_search_functions(v_dimension);
+
+  UPDATE embedding_models SET status = 'retired' WHERE status = 'active';
+  UPDATE embedding_models SET status = 'active', activated_at = NOW() WHERE model = p_model;
+
+  DELETE FROM doc_embeddings_staged WHERE model = p_model;
+  DELETE FROM user_strudel_embeddings_staged WHERE model = p_model;
+END;
+$$;
+
+COMMENT ON FUNCTION activate_embedding_model IS 'Atomically replaces live doc and strudel vectors with the staged vectors of a model and recreates the vector search functions';